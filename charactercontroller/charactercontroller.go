@@ -0,0 +1,228 @@
+package charactercontroller
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+const (
+	// distance kept between the capsule and the world after each sweep
+	skinWidth float64 = 0.001
+
+	epsilon float64 = 0.000001
+
+	// how far the hit normal can deviate from the surface normal before the hit is
+	// considered to be against an edge rather than a face
+	ledgeTolerance float64 = 0.001
+)
+
+var up = mgl64.Vec3{0, 1, 0}
+
+type Settings struct {
+	Radius float64
+	// Height is the total height of the capsule, including the hemispheres
+	Height float64
+
+	// StepHeight is the tallest ledge the character can walk up without jumping
+	StepHeight float64
+	// MaxSlopeAngle is the steepest slope in radians that counts as ground
+	MaxSlopeAngle float64
+	// SnapDistance is how far the character is pulled down to stay on the ground when
+	// walking down slopes and stairs
+	SnapDistance float64
+
+	MaxSlideIterations int
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		Radius:             0.5,
+		Height:             2,
+		StepHeight:         0.4,
+		MaxSlopeAngle:      mgl64.DegToRad(45),
+		SnapDistance:       0.3,
+		MaxSlideIterations: 4,
+	}
+}
+
+// CharacterController is a kinematic capsule that moves through a static world. it only
+// depends on its own state and the inputs to Move so replaying the same moves from the same
+// state produces the same results, which is what client side prediction relies on.
+type CharacterController struct {
+	Settings Settings
+
+	// Position is the bottom of the capsule, where the character's feet are
+	Position     mgl64.Vec3
+	Grounded     bool
+	GroundNormal mgl64.Vec3
+}
+
+func New(settings Settings, position mgl64.Vec3) *CharacterController {
+	return &CharacterController{
+		Settings: settings,
+		Position: position,
+	}
+}
+
+func (c *CharacterController) Capsule() collider.Capsule {
+	return capsuleAt(c.Settings, c.Position)
+}
+
+func capsuleAt(settings Settings, position mgl64.Vec3) collider.Capsule {
+	return collider.NewCapsule(
+		position.Add(up.Mul(settings.Height-settings.Radius)),
+		position.Add(up.Mul(settings.Radius)),
+		settings.Radius,
+	)
+}
+
+// Move attempts to move the character by delta. the world is treated as static geometry and
+// others are capsules of other characters that the character is pushed out of.
+func (c *CharacterController) Move(delta mgl64.Vec3, world collider.TriMesh, others []collider.Capsule) {
+	vertical := delta.Dot(up)
+	horizontal := delta.Sub(up.Mul(vertical))
+	wasGrounded := c.Grounded
+
+	// up pass: raise the character by the step height so that small ledges are cleared by
+	// the side pass. jumping is folded in here as well.
+	var stepOffset float64
+	if wasGrounded && horizontal.LenSqr() > epsilon {
+		stepOffset = c.moveVertical(c.Settings.StepHeight, world)
+	}
+	if vertical > 0 {
+		c.moveVertical(vertical, world)
+	}
+
+	// side pass
+	c.slide(horizontal, world, others)
+
+	// down pass: undo the step offset and apply any downwards movement. if we were on the
+	// ground we also reach a little further down to stay attached to it.
+	downDistance := stepOffset
+	if vertical < 0 {
+		downDistance -= vertical
+	}
+	snapDistance := 0.0
+	if wasGrounded && vertical <= 0 {
+		snapDistance = c.Settings.SnapDistance
+	}
+	c.moveDown(downDistance, snapDistance, world)
+
+	c.pushOut(world, others)
+}
+
+// moveVertical moves the character straight up by distance, stopping at ceilings. the
+// distance actually travelled is returned.
+func (c *CharacterController) moveVertical(distance float64, world collider.TriMesh) float64 {
+	if distance <= 0 {
+		return 0
+	}
+
+	delta := up.Mul(distance)
+	if hit, ok := collision.SweepCapsuleTriMesh(c.Capsule(), delta, world); ok {
+		distance = math.Max(distance*hit.TimeOfImpact-skinWidth, 0)
+	}
+	c.Position = c.Position.Add(up.Mul(distance))
+	return distance
+}
+
+func (c *CharacterController) moveDown(distance float64, snapDistance float64, world collider.TriMesh) {
+	c.Grounded = false
+	c.GroundNormal = mgl64.Vec3{}
+
+	total := distance + snapDistance
+	if total <= 0 {
+		return
+	}
+
+	delta := up.Mul(-total)
+	hit, ok := collision.SweepCapsuleTriMesh(c.Capsule(), delta, world)
+	if !ok {
+		c.Position = c.Position.Sub(up.Mul(distance))
+		return
+	}
+
+	groundNormal := c.surfaceNormal(hit, world)
+	ledge := onLedge(hit, groundNormal)
+	if !c.walkable(groundNormal) && ledge {
+		groundNormal = hit.Normal
+	}
+	if c.walkable(groundNormal) || ledge {
+		c.Position = c.Position.Sub(up.Mul(math.Max(total*hit.TimeOfImpact-skinWidth, 0)))
+		c.Grounded = true
+		c.GroundNormal = groundNormal
+		return
+	}
+
+	// too steep to stand on, slide down along it instead of snapping
+	if distance > 0 {
+		c.slide(up.Mul(-distance), world, nil)
+	}
+}
+
+// slide moves the character along delta, sliding along walls. non walkable surfaces have
+// their normals flattened so that the character can't slide up steep slopes.
+func (c *CharacterController) slide(delta mgl64.Vec3, world collider.TriMesh, others []collider.Capsule) {
+	translation, _ := collision.MoveAndSlideWith(c.Capsule(), delta, world, others, c.Settings.MaxSlideIterations, func(hit collision.SweepHit, remaining mgl64.Vec3) (mgl64.Vec3, bool) {
+		slideNormal := hit.Normal
+		projected := remaining.Sub(slideNormal.Mul(remaining.Dot(slideNormal)))
+		if c.walkable(c.surfaceNormal(hit, world)) || projected.Dot(up) <= epsilon {
+			return slideNormal, true
+		}
+
+		// treat steep surfaces as vertical walls so we can't slide up them
+		slideNormal = slideNormal.Sub(up.Mul(slideNormal.Dot(up)))
+		if slideNormal.LenSqr() <= epsilon {
+			return mgl64.Vec3{}, false
+		}
+		return slideNormal.Normalize(), true
+	})
+	c.Position = c.Position.Add(translation)
+}
+
+// pushOut separates the character from other capsules it overlaps. the push is horizontal
+// and is slid against the world so that it can't shove the character into walls.
+func (c *CharacterController) pushOut(world collider.TriMesh, others []collider.Capsule) {
+	for _, other := range others {
+		contact, collided := collision.CheckCollisionCapsuleCapsule(c.Capsule(), other)
+		if !collided {
+			continue
+		}
+
+		push := contact.SeparatingVector
+		push = push.Sub(up.Mul(push.Dot(up)))
+		if push.LenSqr() <= epsilon {
+			continue
+		}
+		c.slide(push, world, nil)
+	}
+}
+
+// surfaceNormal returns the normal of the hit triangle, oriented towards the character.
+// the normal of the sweep itself is skewed when hitting triangle edges, which would make
+// the edge of a stair look like a slope.
+func (c *CharacterController) surfaceNormal(hit collision.SweepHit, world collider.TriMesh) mgl64.Vec3 {
+	if hit.TriIndex == nil {
+		return hit.Normal
+	}
+
+	normal := world.Triangles[*hit.TriIndex].Normal
+	if normal.Dot(hit.Normal) < 0 {
+		normal = normal.Mul(-1)
+	}
+	return normal
+}
+
+// onLedge reports whether the hit is against the edge of a ledge below the character, such
+// as the lip of a stair. the capsule rests on the edge with its rounded bottom so the hit
+// normal doesn't match the surface normal of either triangle meeting at the edge.
+func onLedge(hit collision.SweepHit, surfaceNormal mgl64.Vec3) bool {
+	return hit.Normal.Dot(up) > 0 && hit.Normal.Dot(surfaceNormal) < 1-ledgeTolerance
+}
+
+func (c *CharacterController) walkable(normal mgl64.Vec3) bool {
+	return normal.Dot(up) >= math.Cos(c.Settings.MaxSlopeAngle)-epsilon
+}
@@ -0,0 +1,244 @@
+package charactercontroller_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/charactercontroller"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+const (
+	timestep = 1.0 / 60
+	speed    = 3.0
+	gravity  = -20.0
+)
+
+// boxTriangles triangulates an axis aligned box with outward facing normals
+func boxTriangles(min, max mgl64.Vec3) []collider.Triangle {
+	center := min.Add(max).Mul(0.5)
+	corner := func(x, y, z int) mgl64.Vec3 {
+		return mgl64.Vec3{
+			[2]float64{min[0], max[0]}[x],
+			[2]float64{min[1], max[1]}[y],
+			[2]float64{min[2], max[2]}[z],
+		}
+	}
+
+	faces := [][4]mgl64.Vec3{
+		{corner(0, 0, 0), corner(0, 1, 0), corner(0, 1, 1), corner(0, 0, 1)},
+		{corner(1, 0, 0), corner(1, 1, 0), corner(1, 1, 1), corner(1, 0, 1)},
+		{corner(0, 0, 0), corner(1, 0, 0), corner(1, 0, 1), corner(0, 0, 1)},
+		{corner(0, 1, 0), corner(1, 1, 0), corner(1, 1, 1), corner(0, 1, 1)},
+		{corner(0, 0, 0), corner(1, 0, 0), corner(1, 1, 0), corner(0, 1, 0)},
+		{corner(0, 0, 1), corner(1, 0, 1), corner(1, 1, 1), corner(0, 1, 1)},
+	}
+
+	var triangles []collider.Triangle
+	for _, face := range faces {
+		faceCenter := face[0].Add(face[2]).Mul(0.5)
+		t1 := collider.NewTriangle([3]mgl64.Vec3{face[0], face[1], face[2]})
+		t2 := collider.NewTriangle([3]mgl64.Vec3{face[0], face[2], face[3]})
+		if t1.Normal.Dot(faceCenter.Sub(center)) < 0 {
+			t1 = collider.NewTriangle([3]mgl64.Vec3{face[0], face[2], face[1]})
+			t2 = collider.NewTriangle([3]mgl64.Vec3{face[0], face[3], face[2]})
+		}
+		triangles = append(triangles, t1, t2)
+	}
+	return triangles
+}
+
+func floor() []collider.Triangle {
+	return boxTriangles(mgl64.Vec3{-50, -1, -50}, mgl64.Vec3{50, 0, 50})
+}
+
+// staircase going up along +x starting at x = 2
+func staircase(stepHeight float64, steps int) collider.TriMesh {
+	triangles := floor()
+	for i := 0; i < steps; i++ {
+		x := 2 + float64(i)
+		triangles = append(triangles, boxTriangles(
+			mgl64.Vec3{x, 0, -5},
+			mgl64.Vec3{x + 1, stepHeight * float64(i+1), 5},
+		)...)
+	}
+	return collider.TriMesh{Triangles: triangles}
+}
+
+// ramp going up along +x starting at x = 2 with the given angle in degrees
+func ramp(angle float64) collider.TriMesh {
+	length := 10.0
+	height := length * math.Tan(mgl64.DegToRad(angle))
+	p0 := mgl64.Vec3{2, 0, -5}
+	p1 := mgl64.Vec3{2, 0, 5}
+	p2 := mgl64.Vec3{2 + length, height, 5}
+	p3 := mgl64.Vec3{2 + length, height, -5}
+
+	triangles := floor()
+	triangles = append(triangles,
+		collider.NewTriangle([3]mgl64.Vec3{p0, p1, p2}),
+		collider.NewTriangle([3]mgl64.Vec3{p0, p2, p3}),
+	)
+	return collider.TriMesh{Triangles: triangles}
+}
+
+// walk runs the controller along +x under gravity for the given number of fixed steps
+func walk(controller *charactercontroller.CharacterController, world collider.TriMesh, others []collider.Capsule, steps int) {
+	var verticalVelocity float64
+	for i := 0; i < steps; i++ {
+		if controller.Grounded {
+			verticalVelocity = 0
+		}
+		verticalVelocity += gravity * timestep
+
+		delta := mgl64.Vec3{speed * timestep, verticalVelocity * timestep, 0}
+		controller.Move(delta, world, others)
+	}
+}
+
+func TestSettlesOnGround(t *testing.T) {
+	world := collider.TriMesh{Triangles: floor()}
+	controller := charactercontroller.New(charactercontroller.DefaultSettings(), mgl64.Vec3{0, 1, 0})
+
+	for i := 0; i < 60; i++ {
+		controller.Move(mgl64.Vec3{0, gravity * timestep * timestep * float64(i), 0}, world, nil)
+	}
+
+	if !controller.Grounded {
+		t.Fatal("expected controller to be grounded")
+	}
+	if math.Abs(controller.Position.Y()) > 0.01 {
+		t.Errorf("expected controller to rest on the floor but was at %v", controller.Position)
+	}
+	if !controller.GroundNormal.ApproxEqual(mgl64.Vec3{0, 1, 0}) {
+		t.Errorf("expected ground normal to point up but got %v", controller.GroundNormal)
+	}
+}
+
+func TestWalkUpStairs(t *testing.T) {
+	world := staircase(0.3, 5)
+	controller := charactercontroller.New(charactercontroller.DefaultSettings(), mgl64.Vec3{0, 0.001, 0})
+	controller.Grounded = true
+
+	walk(controller, world, nil, 125)
+
+	if controller.Position.X() < 6 {
+		t.Fatalf("expected controller to walk up the stairs but was at %v", controller.Position)
+	}
+	if math.Abs(controller.Position.Y()-1.5) > 0.05 {
+		t.Errorf("expected controller to be on top of the stairs but was at %v", controller.Position)
+	}
+	if !controller.Grounded {
+		t.Error("expected controller to be grounded")
+	}
+}
+
+func TestBlockedByTallStep(t *testing.T) {
+	world := staircase(1, 1)
+	controller := charactercontroller.New(charactercontroller.DefaultSettings(), mgl64.Vec3{0, 0.001, 0})
+	controller.Grounded = true
+
+	walk(controller, world, nil, 120)
+
+	if controller.Position.X() > 1.51 {
+		t.Errorf("expected controller to be stopped by the step but was at %v", controller.Position)
+	}
+	if controller.Position.Y() > 0.01 {
+		t.Errorf("expected controller to stay on the floor but was at %v", controller.Position)
+	}
+}
+
+func TestWalkUpShallowRamp(t *testing.T) {
+	world := ramp(30)
+	controller := charactercontroller.New(charactercontroller.DefaultSettings(), mgl64.Vec3{0, 0.001, 0})
+	controller.Grounded = true
+
+	walk(controller, world, nil, 120)
+
+	// the rounded bottom of the capsule touches the ramp uphill of the feet
+	settings := charactercontroller.DefaultSettings()
+	angle := mgl64.DegToRad(30)
+	expectedY := (controller.Position.X()-2)*math.Tan(angle) + settings.Radius*(1/math.Cos(angle)-1)
+	if controller.Position.X() < 4 {
+		t.Fatalf("expected controller to walk up the ramp but was at %v", controller.Position)
+	}
+	if math.Abs(controller.Position.Y()-expectedY) > 0.05 {
+		t.Errorf("expected controller to be on the ramp at height %f but was at %v", expectedY, controller.Position)
+	}
+	if !controller.Grounded {
+		t.Error("expected controller to be grounded")
+	}
+	if controller.GroundNormal.Y() > 0.9 {
+		t.Errorf("expected ground normal to be the ramp normal but got %v", controller.GroundNormal)
+	}
+}
+
+func TestBlockedBySteepRamp(t *testing.T) {
+	world := ramp(60)
+	controller := charactercontroller.New(charactercontroller.DefaultSettings(), mgl64.Vec3{0, 0.001, 0})
+	controller.Grounded = true
+
+	walk(controller, world, nil, 120)
+
+	if controller.Position.Y() > 0.5 {
+		t.Errorf("expected controller to not climb the steep ramp but was at %v", controller.Position)
+	}
+	if controller.Position.X() > 3 {
+		t.Errorf("expected controller to be stopped near the bottom of the ramp but was at %v", controller.Position)
+	}
+}
+
+func TestPushedOutOfOtherCapsule(t *testing.T) {
+	world := collider.TriMesh{Triangles: floor()}
+	settings := charactercontroller.DefaultSettings()
+	controller := charactercontroller.New(settings, mgl64.Vec3{0, 0.001, 0})
+	controller.Grounded = true
+
+	other := charactercontroller.New(settings, mgl64.Vec3{0.5, 0.001, 0}).Capsule()
+	controller.Move(mgl64.Vec3{}, world, []collider.Capsule{other})
+
+	distance := controller.Position.Sub(mgl64.Vec3{0.5, controller.Position.Y(), 0}).Len()
+	if distance < 2*settings.Radius-0.01 {
+		t.Errorf("expected controller to be pushed out of the other capsule but was %f away", distance)
+	}
+	if controller.Position.X() > 0 {
+		t.Errorf("expected controller to be pushed away from the other capsule but was at %v", controller.Position)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	world := staircase(0.3, 5)
+	others := []collider.Capsule{collider.NewCapsule(mgl64.Vec3{4, 2, 0.4}, mgl64.Vec3{4, 1, 0.4}, 0.5)}
+
+	run := func() mgl64.Vec3 {
+		controller := charactercontroller.New(charactercontroller.DefaultSettings(), mgl64.Vec3{0, 0.001, 0})
+		controller.Grounded = true
+		walk(controller, world, others, 240)
+		return controller.Position
+	}
+
+	first := run()
+	for i := 0; i < 5; i++ {
+		if position := run(); position != first {
+			t.Fatalf("expected identical results across runs but got %v and %v", first, position)
+		}
+	}
+}
+
+func TestSnapToGroundWalkingDownRamp(t *testing.T) {
+	world := ramp(20)
+	start := mgl64.Vec3{10, 8*math.Tan(mgl64.DegToRad(20)) + 0.1, 0}
+	controller := charactercontroller.New(charactercontroller.DefaultSettings(), start)
+	controller.Move(mgl64.Vec3{0, -0.5, 0}, world, nil)
+	if !controller.Grounded {
+		t.Fatal("expected controller to land on the ramp")
+	}
+
+	for i := 0; i < 60; i++ {
+		controller.Move(mgl64.Vec3{-speed * timestep, 0, 0}, world, nil)
+		if !controller.Grounded {
+			t.Fatalf("expected controller to stay grounded walking down the ramp, lost ground at %v", controller.Position)
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/checks"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+// a face axis is picked over an edge axis, and a face of b over a face of a, unless the other
+// one is clearly shallower. this keeps the reference face from flipping between frames for a
+// box resting on another.
+const (
+	boxAxisRelativeTolerance float64 = 0.95
+	boxAxisAbsoluteTolerance float64 = 0.001
+)
+
+// incident vertices this close outside a side of the reference face are kept instead of being
+// clipped, so that the corners of equally sized boxes stacked on each other don't flip between
+// vertex and edge features from one frame to the next. vertices this close above the reference
+// face are kept too, with a negative penetration, so that a box settling onto another doesn't
+// land on whichever corners happen to touch first.
+const boxClipTolerance float64 = 0.005
+
+// box features are numbered the same way for every box. a face is axis*2 plus one for the
+// positive side, like aabbFaceIndex. a vertex has a bit per axis that's set on the positive
+// side, in the order of OrientedBoundingBox.Vertices. an edge is the axis it runs along times
+// four plus a bit for the side of each of the other two axes.
+
+type boxAxis struct {
+	// normal points from b towards a
+	normal mgl64.Vec3
+	depth  float64
+	valid  bool
+}
+
+// clipPoint is a point of the incident face being clipped to the reference face. incident
+// holds a bit for each incident vertex the point lies between, one for a vertex and two for
+// an edge. planes holds a bit for each side plane of the reference face the point lies on.
+type clipPoint struct {
+	point    mgl64.Vec3
+	incident uint8
+	planes   uint8
+}
+
+// boxContact collides two boxes. the axis of least penetration is found with the separating
+// axis test. when it's a face normal the face of the other box that faces it the most is
+// clipped against the sides of the reference face, which gives up to four points so that a
+// box resting on another doesn't rock. when it's the cross product of two edges the boxes
+// touch at a single point between the edges.
+func boxContact(a, b collider.OrientedBoundingBox) (Contact, bool) {
+	var faceA, faceB, edge boxAxis
+	var faceAxisA, faceAxisB, edgeAxisA, edgeAxisB int
+
+	test := func(axis mgl64.Vec3) (boxAxis, bool) {
+		distance := a.Center.Sub(b.Center).Dot(axis)
+		depth := boxRadius(a, axis) + boxRadius(b, axis) - math.Abs(distance)
+		if depth < 0 {
+			return boxAxis{}, false
+		}
+		if distance < 0 {
+			axis = axis.Mul(-1)
+		}
+		return boxAxis{normal: axis, depth: depth, valid: true}, true
+	}
+
+	for i := 0; i < 3; i++ {
+		axis, ok := test(a.Axes[i])
+		if !ok {
+			return Contact{}, false
+		}
+		if !faceA.valid || axis.depth < faceA.depth {
+			faceA, faceAxisA = axis, i
+		}
+	}
+	for i := 0; i < 3; i++ {
+		axis, ok := test(b.Axes[i])
+		if !ok {
+			return Contact{}, false
+		}
+		if !faceB.valid || axis.depth < faceB.depth {
+			faceB, faceAxisB = axis, i
+		}
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			cross := a.Axes[i].Cross(b.Axes[j])
+			if cross.Len() <= manifoldNormalTolerance {
+				continue
+			}
+			axis, ok := test(cross.Normalize())
+			if !ok {
+				return Contact{}, false
+			}
+			if !edge.valid || axis.depth < edge.depth {
+				edge, edgeAxisA, edgeAxisB = axis, i, j
+			}
+		}
+	}
+
+	shallower := func(depth, than float64) bool {
+		return depth < than*boxAxisRelativeTolerance-boxAxisAbsoluteTolerance
+	}
+	referenceIsA := shallower(faceA.depth, faceB.depth)
+	face := faceB
+	if referenceIsA {
+		face = faceA
+	}
+	if edge.valid && shallower(edge.depth, face.depth) {
+		return boxEdgeContact(a, b, edgeAxisA, edgeAxisB, edge), true
+	}
+
+	contact := Contact{
+		Normal:             face.normal,
+		SeparatingVector:   face.normal.Mul(face.depth),
+		SeparatingDistance: face.depth,
+		Type:               ContactTypeBoxBox,
+	}
+	if referenceIsA {
+		boxFaceManifold(&contact, a, b, faceAxisA, face.normal.Mul(-1), true)
+	} else {
+		boxFaceManifold(&contact, b, a, faceAxisB, face.normal, false)
+	}
+	if contact.PointCount == 0 {
+		return Contact{}, false
+	}
+	return contact, true
+}
+
+// boxFaceManifold clips the incident box's face that faces the reference face the most to the
+// sides of the reference face. outward is the reference face's normal, pointing towards the
+// incident box. referenceIsA says whether the reference box is collider A, which decides
+// which surface the points are on and the order of the features.
+func boxFaceManifold(contact *Contact, reference, incident collider.OrientedBoundingBox, axis int, outward mgl64.Vec3, referenceIsA bool) {
+	var referenceSigns [3]bool
+	referenceSigns[axis] = reference.Axes[axis].Dot(outward) > 0
+	referenceFace := boxFaceIndex(axis, referenceSigns[axis])
+	offset := outward.Dot(reference.Center) + reference.HalfExtents[axis]
+
+	// the incident face is the one facing against the reference face the most
+	incidentAxis := 0
+	for i := 1; i < 3; i++ {
+		if math.Abs(incident.Axes[i].Dot(outward)) > math.Abs(incident.Axes[incidentAxis].Dot(outward)) {
+			incidentAxis = i
+		}
+	}
+	var incidentSigns [3]bool
+	incidentSigns[incidentAxis] = incident.Axes[incidentAxis].Dot(outward) < 0
+	incidentFace := boxFaceIndex(incidentAxis, incidentSigns[incidentAxis])
+
+	u, v := (incidentAxis+1)%3, (incidentAxis+2)%3
+	var polygon []clipPoint
+	for _, corner := range [4][2]bool{{false, false}, {true, false}, {true, true}, {false, true}} {
+		signs := incidentSigns
+		signs[u], signs[v] = corner[0], corner[1]
+		polygon = append(polygon, clipPoint{point: boxPoint(incident, signs), incident: 1 << boxVertexIndex(signs)})
+	}
+
+	// the side planes of the reference face, the plane bit is 2*j plus one for the positive side
+	sides := [2]int{(axis + 1) % 3, (axis + 2) % 3}
+	for j, side := range sides {
+		for k, positive := range []bool{false, true} {
+			normal := reference.Axes[side]
+			if !positive {
+				normal = normal.Mul(-1)
+			}
+			limit := normal.Dot(reference.Center) + reference.HalfExtents[side]
+			polygon = clipPolygon(polygon, normal, limit, uint8(1)<<(j*2+k))
+		}
+	}
+
+	var points []ContactPoint
+	for _, p := range polygon {
+		depth := offset - outward.Dot(p.point)
+		if depth <= -boxClipTolerance {
+			continue
+		}
+
+		incidentType, incidentIndex := FeatureTypeFace, incidentFace
+		var vertices []int
+		for i := 0; i < 8; i++ {
+			if p.incident&(1<<i) != 0 {
+				vertices = append(vertices, i)
+			}
+		}
+		if len(vertices) == 1 {
+			incidentType, incidentIndex = FeatureTypeVertex, uint8(vertices[0])
+		} else if len(vertices) == 2 {
+			incidentType, incidentIndex = FeatureTypeEdge, boxEdgeBetween(vertices[0], vertices[1])
+		}
+
+		referenceType, referenceIndex := FeatureTypeFace, referenceFace
+		signs := referenceSigns
+		var onPlanes []int
+		for j, side := range sides {
+			for k, positive := range []bool{false, true} {
+				if p.planes&(1<<(j*2+k)) != 0 {
+					signs[side] = positive
+					onPlanes = append(onPlanes, side)
+				}
+			}
+		}
+		if len(onPlanes) == 1 {
+			along := sides[0]
+			if onPlanes[0] == sides[0] {
+				along = sides[1]
+			}
+			referenceType, referenceIndex = FeatureTypeEdge, boxEdgeIndex(along, signs)
+		} else if len(onPlanes) == 2 {
+			referenceType, referenceIndex = FeatureTypeVertex, uint8(boxVertexIndex(signs))
+		}
+
+		// the points go on b's surface, which is the reference face when b is the reference
+		point := p.point
+		featureID := NewFeatureID(incidentType, incidentIndex, referenceType, referenceIndex)
+		if referenceIsA {
+			featureID = featureID.Flip()
+		} else {
+			point = point.Add(outward.Mul(depth))
+		}
+		points = append(points, ContactPoint{Point: point, Penetration: depth, FeatureID: featureID})
+	}
+
+	for i, p := range reduceManifold(points, outward) {
+		contact.addPoint(p.Point, p.Penetration, p.FeatureID)
+		if i == 0 {
+			contact.Point = p.Point
+		}
+	}
+}
+
+// clipPolygon keeps the part of the polygon where normal·p <= limit. points made where an edge
+// of the polygon crosses the plane lie on the plane and on whatever the edge lies along.
+// vertices within boxClipTolerance outside the plane are kept as they are.
+func clipPolygon(polygon []clipPoint, normal mgl64.Vec3, limit float64, plane uint8) []clipPoint {
+	var clipped []clipPoint
+	for i, p := range polygon {
+		q := polygon[(i+1)%len(polygon)]
+		dp, dq := normal.Dot(p.point)-limit, normal.Dot(q.point)-limit
+		if dp <= boxClipTolerance {
+			clipped = append(clipped, p)
+		}
+		if (dp < 0 && dq > boxClipTolerance) || (dp > boxClipTolerance && dq < 0) {
+			t := dp / (dp - dq)
+			clipped = append(clipped, clipPoint{
+				point:    p.point.Add(q.point.Sub(p.point).Mul(t)),
+				incident: p.incident | q.incident,
+				planes:   plane | (p.planes & q.planes),
+			})
+		}
+	}
+	return clipped
+}
+
+// reduceManifold picks up to MaxManifoldPoints points that cover the most area, starting from
+// the deepest one so that it's always kept
+func reduceManifold(points []ContactPoint, normal mgl64.Vec3) []ContactPoint {
+	if len(points) == 0 {
+		return nil
+	}
+	deepest := 0
+	for i, p := range points {
+		if p.Penetration > points[deepest].Penetration {
+			deepest = i
+		}
+	}
+	if len(points) <= MaxManifoldPoints {
+		points[0], points[deepest] = points[deepest], points[0]
+		return points
+	}
+
+	first := points[deepest].Point
+
+	farthest := 0
+	for i, p := range points {
+		if p.Point.Sub(first).LenSqr() > points[farthest].Point.Sub(first).LenSqr() {
+			farthest = i
+		}
+	}
+	second := points[farthest].Point
+
+	// the points furthest out on either side of the line between the first two
+	most, least := -1, -1
+	var mostArea, leastArea float64
+	for i, p := range points {
+		area := second.Sub(first).Cross(p.Point.Sub(first)).Dot(normal)
+		if area > mostArea {
+			most, mostArea = i, area
+		}
+		if area < leastArea {
+			least, leastArea = i, area
+		}
+	}
+
+	reduced := []ContactPoint{points[deepest], points[farthest]}
+	for _, i := range []int{most, least} {
+		if i >= 0 {
+			reduced = append(reduced, points[i])
+		}
+	}
+	return reduced
+}
+
+// capsuleBoxFaceManifold replaces the single point of a capsule lying on a face of the box with
+// the ends of the capsule's segment clipped to the face, like capsuleTriangleFaceManifold
+func capsuleBoxFaceManifold(contact *Contact, capsule collider.Capsule, box collider.OrientedBoundingBox) {
+	p0, p1 := capsule.Top, capsule.Bottom
+	segment := p1.Sub(p0)
+	if segment.Len() <= epsilon {
+		return
+	}
+
+	axis := -1
+	for i := 0; i < 3; i++ {
+		if math.Abs(box.Axes[i].Dot(contact.Normal)) > 1-manifoldNormalTolerance {
+			axis = i
+		}
+	}
+	if axis < 0 {
+		return
+	}
+	outward := box.Axes[axis]
+	if outward.Dot(contact.Normal) < 0 {
+		outward = outward.Mul(-1)
+	}
+	face := boxFaceIndex(axis, box.Axes[axis].Dot(outward) > 0)
+	offset := outward.Dot(box.Center) + box.HalfExtents[axis]
+
+	t0, t1 := 0.0, 1.0
+	for _, side := range [2]int{(axis + 1) % 3, (axis + 2) % 3} {
+		for _, sign := range [2]float64{-1, 1} {
+			normal := box.Axes[side].Mul(sign)
+			limit := normal.Dot(box.Center) + box.HalfExtents[side]
+			d0, d1 := normal.Dot(p0)-limit, normal.Dot(p1)-limit
+			if d0 > 0 && d1 > 0 {
+				return
+			}
+			if d0 > 0 {
+				t0 = math.Max(t0, d0/(d0-d1))
+			} else if d1 > 0 {
+				t1 = math.Min(t1, d0/(d0-d1))
+			}
+		}
+	}
+	if t1-t0 <= epsilon {
+		return
+	}
+
+	var points []ContactPoint
+	for i, t := range [2]float64{t0, t1} {
+		point := p0.Add(segment.Mul(t))
+		distance := outward.Dot(point) - offset
+		penetration := capsule.Radius - distance
+		if penetration <= 0 {
+			continue
+		}
+
+		featureA := FeatureTypeVertex
+		if (i == 0 && t > 0) || (i == 1 && t < 1) {
+			featureA = FeatureTypeEdge
+		}
+		points = append(points, ContactPoint{
+			Point:       point.Sub(outward.Mul(distance)),
+			Penetration: penetration,
+			FeatureID:   NewFeatureID(featureA, uint8(i), FeatureTypeFace, face),
+		})
+	}
+	if len(points) < 2 {
+		return
+	}
+
+	contact.PointCount = 0
+	for _, p := range points {
+		contact.addPoint(p.Point, p.Penetration, p.FeatureID)
+	}
+}
+
+// boxEdgeContact is the single point where an edge of a crosses an edge of b
+func boxEdgeContact(a, b collider.OrientedBoundingBox, axisA, axisB int, edge boxAxis) Contact {
+	// the edges that are furthest into each other along the normal
+	var signsA, signsB [3]bool
+	for i := 0; i < 3; i++ {
+		signsA[i] = a.Axes[i].Dot(edge.normal) < 0
+		signsB[i] = b.Axes[i].Dot(edge.normal) > 0
+	}
+	centerA, centerB := boxPoint(a, signsA), boxPoint(b, signsB)
+	halfA := a.Axes[axisA].Mul(a.HalfExtents[axisA])
+	halfB := b.Axes[axisB].Mul(b.HalfExtents[axisB])
+	centerA = centerA.Sub(a.Axes[axisA].Mul(a.Axes[axisA].Dot(centerA.Sub(a.Center))))
+	centerB = centerB.Sub(b.Axes[axisB].Mul(b.Axes[axisB].Dot(centerB.Sub(b.Center))))
+
+	closestPoints, _ := checks.ClosestPointsLineVSLine(
+		collider.Line{P1: centerA.Sub(halfA), P2: centerA.Add(halfA)},
+		collider.Line{P1: centerB.Sub(halfB), P2: centerB.Add(halfB)},
+	)
+
+	contact := Contact{
+		Point:              closestPoints[1],
+		Normal:             edge.normal,
+		SeparatingVector:   edge.normal.Mul(edge.depth),
+		SeparatingDistance: edge.depth,
+		Type:               ContactTypeBoxBox,
+	}
+	contact.addPoint(closestPoints[1], edge.depth, NewFeatureID(FeatureTypeEdge, boxEdgeIndex(axisA, signsA), FeatureTypeEdge, boxEdgeIndex(axisB, signsB)))
+	return contact
+}
+
+// boxRadius is half the length of the box projected onto axis
+func boxRadius(box collider.OrientedBoundingBox, axis mgl64.Vec3) float64 {
+	var radius float64
+	for i := 0; i < 3; i++ {
+		radius += math.Abs(box.Axes[i].Dot(axis)) * box.HalfExtents[i]
+	}
+	return radius
+}
+
+// boxPoint returns the corner of the box on the positive or negative side of each axis
+func boxPoint(box collider.OrientedBoundingBox, positive [3]bool) mgl64.Vec3 {
+	point := box.Center
+	for i := 0; i < 3; i++ {
+		side := -1.0
+		if positive[i] {
+			side = 1
+		}
+		point = point.Add(box.Axes[i].Mul(side * box.HalfExtents[i]))
+	}
+	return point
+}
+
+func boxFaceIndex(axis int, positive bool) uint8 {
+	if positive {
+		return uint8(axis*2 + 1)
+	}
+	return uint8(axis * 2)
+}
+
+func boxVertexIndex(positive [3]bool) int {
+	var index int
+	for i := 0; i < 3; i++ {
+		if positive[i] {
+			index |= 1 << (2 - i)
+		}
+	}
+	return index
+}
+
+func boxEdgeIndex(axis int, positive [3]bool) uint8 {
+	index := uint8(axis * 4)
+	if positive[(axis+1)%3] {
+		index |= 1
+	}
+	if positive[(axis+2)%3] {
+		index |= 2
+	}
+	return index
+}
+
+// boxEdgeBetween returns the edge joining two vertices that differ along one axis
+func boxEdgeBetween(vertexA, vertexB int) uint8 {
+	var positive [3]bool
+	axis := 0
+	for i := 0; i < 3; i++ {
+		bit := 1 << (2 - i)
+		positive[i] = vertexA&bit != 0
+		if (vertexA^vertexB)&bit != 0 {
+			axis = i
+		}
+	}
+	return boxEdgeIndex(axis, positive)
+}
@@ -116,7 +116,7 @@ func ClosestPointRayVsPoint(origin mgl64.Vec3, dir mgl64.Vec3, point mgl64.Vec3)
 // segment endpoint P and plane of triangle (when P projects inside ABC), and
 // segment endpoint Q and plane of triangle (when Q projects inside ABC)
 
-// the first point belongs to the triangle, the second point belongs to the line
+// the first point belongs to the line, the second point belongs to the triangle
 func ClosestPointsLineVSTriangle(line collider.Line, triangle collider.Triangle) ([2]mgl64.Vec3, float64) {
 	var closestPoints [2]mgl64.Vec3
 	var closestDistance float64
@@ -273,6 +273,15 @@ func PointInAABB(point mgl64.Vec3, boundingBox *collider.BoundingBox) bool {
 	return true
 }
 
+// ClosestPointOnAABBToPoint returns the point on or in the bounding box that is closest to point
+func ClosestPointOnAABBToPoint(point mgl64.Vec3, boundingBox *collider.BoundingBox) mgl64.Vec3 {
+	var result mgl64.Vec3
+	for i := 0; i < 3; i++ {
+		result[i] = mgl64.Clamp(point[i], boundingBox.MinVertex[i], boundingBox.MaxVertex[i])
+	}
+	return result
+}
+
 func ClosestPointsInfiniteLines(p1, q1, p2, q2 mgl64.Vec3) (mgl64.Vec3, mgl64.Vec3, bool) {
 	s, t, nonParallel := closestPointsInfiniteLinesMathTest(p1, q1, p2, q2)
 	if !nonParallel {
@@ -330,3 +339,226 @@ func ClosestPointsInfiniteLineVSLine(p1, q1, p2, q2 mgl64.Vec3) (mgl64.Vec3, mgl
 
 	return l1, p2, nonParallel
 }
+
+// ClosestPointOnTriangleToPoint returns the point on triangle ABC closest to point along with
+// its barycentric weights for A, B and C
+// Real Time Collision Detection - page 141
+func ClosestPointOnTriangleToPoint(point, a, b, c mgl64.Vec3) (mgl64.Vec3, [3]float64) {
+	ab := b.Sub(a)
+	ac := c.Sub(a)
+
+	ap := point.Sub(a)
+	d1 := ab.Dot(ap)
+	d2 := ac.Dot(ap)
+	if d1 <= 0 && d2 <= 0 {
+		return a, [3]float64{1, 0, 0}
+	}
+
+	bp := point.Sub(b)
+	d3 := ab.Dot(bp)
+	d4 := ac.Dot(bp)
+	if d3 >= 0 && d4 <= d3 {
+		return b, [3]float64{0, 1, 0}
+	}
+
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		v := d1 / (d1 - d3)
+		return a.Add(ab.Mul(v)), [3]float64{1 - v, v, 0}
+	}
+
+	cp := point.Sub(c)
+	d5 := ab.Dot(cp)
+	d6 := ac.Dot(cp)
+	if d6 >= 0 && d5 <= d6 {
+		return c, [3]float64{0, 0, 1}
+	}
+
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		w := d2 / (d2 - d6)
+		return a.Add(ac.Mul(w)), [3]float64{1 - w, 0, w}
+	}
+
+	va := d3*d6 - d5*d4
+	if va <= 0 && (d4-d3) >= 0 && (d5-d6) >= 0 {
+		w := (d4 - d3) / ((d4 - d3) + (d5 - d6))
+		return b.Add(c.Sub(b).Mul(w)), [3]float64{0, 1 - w, w}
+	}
+
+	denom := 1 / (va + vb + vc)
+	v := vb * denom
+	w := vc * denom
+	return a.Add(ab.Mul(v)).Add(ac.Mul(w)), [3]float64{1 - v - w, v, w}
+}
+
+// ClosestPointOnOBBToPoint returns the point on or in the box that is closest to point
+func ClosestPointOnOBBToPoint(point mgl64.Vec3, obb collider.OrientedBoundingBox) mgl64.Vec3 {
+	delta := point.Sub(obb.Center)
+	result := obb.Center
+	for i, axis := range obb.Axes {
+		distance := mgl64.Clamp(delta.Dot(axis), -obb.HalfExtents[i], obb.HalfExtents[i])
+		result = result.Add(axis.Mul(distance))
+	}
+	return result
+}
+
+func PointInOBB(point mgl64.Vec3, obb collider.OrientedBoundingBox) bool {
+	delta := point.Sub(obb.Center)
+	for i, axis := range obb.Axes {
+		if math.Abs(delta.Dot(axis)) > obb.HalfExtents[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectRaySphere returns where the ray enters the sphere, or the ray's origin if it
+// starts inside
+func IntersectRaySphere(ray collider.Ray, sphere collider.Sphere) (mgl64.Vec3, bool) {
+	t, hit := rayTSphere(ray, sphere.Center, sphere.Radius)
+	if !hit {
+		return mgl64.Vec3{}, false
+	}
+	return ray.Origin.Add(ray.Direction.Mul(t)), true
+}
+
+// IntersectRayAABB returns where the ray enters the box, or the ray's origin if it starts
+// inside
+func IntersectRayAABB(ray collider.Ray, boundingBox *collider.BoundingBox) (mgl64.Vec3, bool) {
+	t, hit := rayTSlabs(ray.Origin, ray.Direction, boundingBox.MinVertex, boundingBox.MaxVertex)
+	if !hit {
+		return mgl64.Vec3{}, false
+	}
+	return ray.Origin.Add(ray.Direction.Mul(t)), true
+}
+
+// IntersectRayOBB returns where the ray enters the box, or the ray's origin if it starts
+// inside
+func IntersectRayOBB(ray collider.Ray, obb collider.OrientedBoundingBox) (mgl64.Vec3, bool) {
+	// test against the box in its local space
+	delta := ray.Origin.Sub(obb.Center)
+	var localOrigin, localDirection mgl64.Vec3
+	for i, axis := range obb.Axes {
+		localOrigin[i] = delta.Dot(axis)
+		localDirection[i] = ray.Direction.Dot(axis)
+	}
+
+	t, hit := rayTSlabs(localOrigin, localDirection, obb.HalfExtents.Mul(-1), obb.HalfExtents)
+	if !hit {
+		return mgl64.Vec3{}, false
+	}
+	return ray.Origin.Add(ray.Direction.Mul(t)), true
+}
+
+// IntersectRayCapsule returns where the ray enters the capsule, or the ray's origin if it
+// starts inside
+func IntersectRayCapsule(ray collider.Ray, capsule collider.Capsule) (mgl64.Vec3, bool) {
+	minT := math.MaxFloat64
+	for _, center := range []mgl64.Vec3{capsule.Top, capsule.Bottom} {
+		if t, hit := rayTSphere(ray, center, capsule.Radius); hit {
+			minT = math.Min(minT, t)
+		}
+	}
+	if t, hit := rayTCylinder(ray, capsule.Bottom, capsule.Top, capsule.Radius); hit {
+		minT = math.Min(minT, t)
+	}
+
+	if minT == math.MaxFloat64 {
+		return mgl64.Vec3{}, false
+	}
+	return ray.Origin.Add(ray.Direction.Mul(minT)), true
+}
+
+// rayTSphere returns the ray parameter where the ray enters the sphere, zero if it starts
+// inside
+// Real Time Collision Detection - page 178
+func rayTSphere(ray collider.Ray, center mgl64.Vec3, radius float64) (float64, bool) {
+	m := ray.Origin.Sub(center)
+	a := ray.Direction.Dot(ray.Direction)
+	b := m.Dot(ray.Direction)
+	c := m.Dot(m) - radius*radius
+
+	if c <= 0 {
+		return 0, true
+	}
+	// starts outside and points away
+	if b > 0 || a == 0 {
+		return 0, false
+	}
+
+	discriminant := b*b - a*c
+	if discriminant < 0 {
+		return 0, false
+	}
+	return (-b - math.Sqrt(discriminant)) / a, true
+}
+
+// rayTSlabs returns the ray parameter where the ray enters the box between min and max, zero
+// if it starts inside
+// Real Time Collision Detection - page 180
+func rayTSlabs(origin, direction, min, max mgl64.Vec3) (float64, bool) {
+	tMin := 0.0
+	tMax := math.MaxFloat64
+	for i := 0; i < 3; i++ {
+		if math.Abs(direction[i]) < epsilon {
+			if origin[i] < min[i] || origin[i] > max[i] {
+				return 0, false
+			}
+			continue
+		}
+
+		t1 := (min[i] - origin[i]) / direction[i]
+		t2 := (max[i] - origin[i]) / direction[i]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin = math.Max(tMin, t1)
+		tMax = math.Min(tMax, t2)
+		if tMin > tMax {
+			return 0, false
+		}
+	}
+	return tMin, true
+}
+
+// rayTCylinder returns the ray parameter where the ray enters the side of the finite
+// cylinder from p to q, zero if it starts inside
+func rayTCylinder(ray collider.Ray, p, q mgl64.Vec3, radius float64) (float64, bool) {
+	axis := q.Sub(p)
+	length := axis.Len()
+	if length < epsilon {
+		return 0, false
+	}
+	axis = axis.Mul(1 / length)
+
+	// work in the plane perpendicular to the axis
+	m := ray.Origin.Sub(p)
+	mPerp := m.Sub(axis.Mul(m.Dot(axis)))
+	dPerp := ray.Direction.Sub(axis.Mul(ray.Direction.Dot(axis)))
+
+	a := dPerp.Dot(dPerp)
+	b := mPerp.Dot(dPerp)
+	c := mPerp.Dot(mPerp) - radius*radius
+
+	var t float64
+	if c <= 0 {
+		t = 0
+	} else {
+		if a < epsilon || b > 0 {
+			return 0, false
+		}
+		discriminant := b*b - a*c
+		if discriminant < 0 {
+			return 0, false
+		}
+		t = (-b - math.Sqrt(discriminant)) / a
+	}
+
+	// only count hits between the two ends
+	height := m.Add(ray.Direction.Mul(t)).Dot(axis)
+	if height < 0 || height > length {
+		return 0, false
+	}
+	return t, true
+}
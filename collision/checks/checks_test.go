@@ -2,6 +2,7 @@ package checks_test
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/go-gl/mathgl/mgl64"
@@ -137,12 +138,15 @@ func TestCheckCollisionCapsuleTriangle(t *testing.T) {
 	}
 
 	triangle := collider.NewTriangle(trianglePoints)
-	contact := collision.CheckCollisionCapsuleTriangle(capsule, triangle)
+	contact, collided := collision.CheckCollisionCapsuleTriangle(capsule, triangle)
+	if !collided {
+		t.Fatal("expected capsule to collide with triangle")
+	}
 
-	// expectedNormal := mgl64.Vec3{0, 1, 0}
-	// if contact.Normal != expectedNormal {
-	// 	t.Errorf("expected contact normal to be %v but got %v", expectedNormal, contact.Normal)
-	// }
+	expectedNormal := mgl64.Vec3{0, 1, 0}
+	if contact.Normal != expectedNormal {
+		t.Errorf("expected contact normal to be %v but got %v", expectedNormal, contact.Normal)
+	}
 
 	if contact.SeparatingDistance != 0.5 {
 		t.Errorf("expected separating distance to be %f but got %f", 0.5, contact.SeparatingDistance)
@@ -169,7 +173,7 @@ func TestNegativeSeparatingVector(t *testing.T) {
 	}
 
 	triangle := collider.NewTriangle(trianglePoints)
-	contact := collision.CheckCollisionCapsuleTriangle(capsule, triangle)
+	contact, _ := collision.CheckCollisionCapsuleTriangle(capsule, triangle)
 	fmt.Println(contact.SeparatingVector)
 }
 
@@ -187,7 +191,7 @@ func TestPartWayCapsule(t *testing.T) {
 	}
 
 	triangle := collider.NewTriangle(trianglePoints)
-	contact := collision.CheckCollisionCapsuleTriangle(capsule, triangle)
+	contact, _ := collision.CheckCollisionCapsuleTriangle(capsule, triangle)
 
 	fmt.Println(contact.SeparatingVector)
 }
@@ -282,3 +286,40 @@ func TestProjectPointOnPlane(t *testing.T) {
 		t.Errorf("expected %v but instead got %v", expectedProjectedPoint, projectedPoint)
 	}
 }
+
+func TestClosestPointOnTriangleToPoint(t *testing.T) {
+	a, b, c := mgl64.Vec3{0, 0, 0}, mgl64.Vec3{2, 0, 0}, mgl64.Vec3{0, 2, 0}
+
+	cases := []struct {
+		point    mgl64.Vec3
+		expected mgl64.Vec3
+	}{
+		{point: mgl64.Vec3{0.5, 0.5, 3}, expected: mgl64.Vec3{0.5, 0.5, 0}},
+		{point: mgl64.Vec3{-1, -1, 0}, expected: a},
+		{point: mgl64.Vec3{1, -1, 1}, expected: mgl64.Vec3{1, 0, 0}},
+		{point: mgl64.Vec3{2, 2, 0}, expected: mgl64.Vec3{1, 1, 0}},
+	}
+
+	for _, tc := range cases {
+		point, weights := checks.ClosestPointOnTriangleToPoint(tc.point, a, b, c)
+		if !point.ApproxEqual(tc.expected) {
+			t.Errorf("expected the closest point to %v to be %v but got %v", tc.point, tc.expected, point)
+		}
+		fromWeights := a.Mul(weights[0]).Add(b.Mul(weights[1])).Add(c.Mul(weights[2]))
+		if !fromWeights.ApproxEqual(point) {
+			t.Errorf("expected the weights %v to produce %v but got %v", weights, point, fromWeights)
+		}
+	}
+}
+
+func TestClosestPointOnOBBToPoint(t *testing.T) {
+	obb := collider.NewOrientedBoundingBox(mgl64.Vec3{1, 0, 0}, mgl64.Vec3{1, 1, 1}, mgl64.QuatRotate(math.Pi/4, mgl64.Vec3{0, 0, 1}))
+
+	point := checks.ClosestPointOnOBBToPoint(mgl64.Vec3{1, 5, 0}, obb)
+	if expected := (mgl64.Vec3{1, math.Sqrt2, 0}); !point.ApproxEqual(expected) {
+		t.Errorf("expected the closest point to be the top corner %v but got %v", expected, point)
+	}
+	if !checks.PointInOBB(mgl64.Vec3{1, 1.4, 0}, obb) || checks.PointInOBB(mgl64.Vec3{1.9, 0.9, 0}, obb) {
+		t.Error("expected the point test to follow the box's rotation")
+	}
+}
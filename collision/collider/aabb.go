@@ -26,6 +26,8 @@ func boundingBoxVertices(min, max mgl64.Vec3) []mgl64.Vec3 {
 	}
 }
 
+// Transform re-fits the box to the world axes, which makes it grow when it's rotated. use
+// TransformOriented to keep a tight box.
 func (c BoundingBox) Transform(mat mgl64.Mat4) BoundingBox {
 	transformedVerts := boundingBoxVertices(c.MinVertex, c.MaxVertex)
 	for i := range transformedVerts {
@@ -34,6 +36,11 @@ func (c BoundingBox) Transform(mat mgl64.Mat4) BoundingBox {
 	return BoundingBoxFromVertices(transformedVerts)
 }
 
+// TransformOriented applies the transform while keeping the box's orientation
+func (c BoundingBox) TransformOriented(mat mgl64.Mat4) OrientedBoundingBox {
+	return OrientedBoundingBoxFromAABB(c).Transform(mat)
+}
+
 func BoundingBoxFromVertices(vertices []mgl64.Vec3) BoundingBox {
 	var minX, minY, minZ, maxX, maxY, maxZ float64
 
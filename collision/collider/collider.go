@@ -0,0 +1,15 @@
+package collider
+
+// Collider is implemented by the shapes in this package that can be passed to the
+// collision checks
+type Collider interface {
+	isCollider()
+}
+
+func (Sphere) isCollider()              {}
+func (Capsule) isCollider()             {}
+func (BoundingBox) isCollider()         {}
+func (OrientedBoundingBox) isCollider() {}
+func (Triangle) isCollider()            {}
+func (Ray) isCollider()                 {}
+func (ConvexHull) isCollider()          {}
@@ -0,0 +1,311 @@
+package collider
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/modelspec"
+	"github.com/kkevinchou/kitolib/utils"
+)
+
+// ConvexHull is a closed convex polyhedron. Faces index into Points and wind counter
+// clockwise when viewed from outside the hull.
+type ConvexHull struct {
+	Points []mgl64.Vec3
+	Faces  [][3]int
+}
+
+// NewConvexHull builds the convex hull of points with quickhull. false is returned when the
+// points are coplanar and don't enclose any volume.
+func NewConvexHull(points []mgl64.Vec3) (ConvexHull, bool) {
+	return quickhull(points, math.MaxInt)
+}
+
+// CreateConvexHullFromPrimitives builds the convex hull around every vertex in the primitives
+func CreateConvexHullFromPrimitives(primitives []*modelspec.PrimitiveSpecification) (ConvexHull, bool) {
+	var points []mgl64.Vec3
+	for _, p := range primitives {
+		points = append(points, utils.ModelSpecVertsToVec3(p.UniqueVertices)...)
+	}
+	return NewConvexHull(points)
+}
+
+// Simplify returns a hull with at most maxVertices vertices that's made from a subset of this
+// hull's vertices. vertices are picked by how far they stick out so the overall shape is
+// kept. maxVertices below 4 is treated as 4.
+func (h ConvexHull) Simplify(maxVertices int) ConvexHull {
+	if len(h.Points) <= maxVertices {
+		return h
+	}
+	simplified, ok := quickhull(h.Points, maxVertices)
+	if !ok {
+		return h
+	}
+	return simplified
+}
+
+func (h ConvexHull) Vertices() []mgl64.Vec3 {
+	return h.Points
+}
+
+func (h ConvexHull) Transform(transform mgl64.Mat4) ConvexHull {
+	result := ConvexHull{Points: make([]mgl64.Vec3, len(h.Points)), Faces: h.Faces}
+	for i, point := range h.Points {
+		result.Points[i] = transform.Mul4x1(point.Vec4(1)).Vec3()
+	}
+	return result
+}
+
+func (h ConvexHull) FaceNormal(face int) mgl64.Vec3 {
+	a, b, c := h.Points[h.Faces[face][0]], h.Points[h.Faces[face][1]], h.Points[h.Faces[face][2]]
+	return b.Sub(a).Cross(c.Sub(a)).Normalize()
+}
+
+func (h ConvexHull) Triangles() []Triangle {
+	triangles := make([]Triangle, len(h.Faces))
+	for i, face := range h.Faces {
+		triangles[i] = NewTriangle([3]mgl64.Vec3{h.Points[face[0]], h.Points[face[1]], h.Points[face[2]]})
+	}
+	return triangles
+}
+
+func (h ConvexHull) BoundingBox() BoundingBox {
+	return BoundingBoxFromVertices(h.Points)
+}
+
+func (h ConvexHull) Volume() float64 {
+	var volume float64
+	for _, face := range h.Faces {
+		a, b, c := h.Points[face[0]], h.Points[face[1]], h.Points[face[2]]
+		volume += a.Dot(b.Cross(c)) / 6
+	}
+	return volume
+}
+
+// ContainsPoint returns whether the point is inside or on the hull
+func (h ConvexHull) ContainsPoint(point mgl64.Vec3, tolerance float64) bool {
+	for i, face := range h.Faces {
+		if point.Sub(h.Points[face[0]]).Dot(h.FaceNormal(i)) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+type hullFace struct {
+	vertices [3]int
+	normal   mgl64.Vec3
+	offset   float64
+	outside  []int
+	removed  bool
+}
+
+func newHullFace(points []mgl64.Vec3, a, b, c int) *hullFace {
+	normal := points[b].Sub(points[a]).Cross(points[c].Sub(points[a])).Normalize()
+	return &hullFace{vertices: [3]int{a, b, c}, normal: normal, offset: normal.Dot(points[a])}
+}
+
+func (f *hullFace) distance(point mgl64.Vec3) float64 {
+	return f.normal.Dot(point) - f.offset
+}
+
+// quickhull adds the point furthest outside the hull one at a time until every point is
+// inside or the hull has maxVertices vertices
+func quickhull(points []mgl64.Vec3, maxVertices int) (ConvexHull, bool) {
+	if len(points) < 4 {
+		return ConvexHull{}, false
+	}
+
+	box := BoundingBoxFromVertices(points)
+	tolerance := box.MaxVertex.Sub(box.MinVertex).Len() * 1e-9
+
+	initial, ok := initialSimplex(points, tolerance)
+	if !ok {
+		return ConvexHull{}, false
+	}
+
+	a, b, c, d := initial[0], initial[1], initial[2], initial[3]
+	// wind the faces so that they face away from the fourth point
+	if newHullFace(points, a, b, c).distance(points[d]) > 0 {
+		b, c = c, b
+	}
+	faces := []*hullFace{
+		newHullFace(points, a, b, c),
+		newHullFace(points, a, d, b),
+		newHullFace(points, b, d, c),
+		newHullFace(points, c, d, a),
+	}
+
+	used := map[int]bool{a: true, b: true, c: true, d: true}
+	var remaining []int
+	for i := range points {
+		if !used[i] {
+			remaining = append(remaining, i)
+		}
+	}
+	assignOutside(points, faces, remaining, tolerance)
+
+	vertexCount := 4
+	for vertexCount < maxVertices {
+		// the point furthest outside any face
+		var face *hullFace
+		apex := -1
+		apexDistance := 0.0
+		for _, f := range faces {
+			for _, i := range f.outside {
+				if distance := f.distance(points[i]); distance > apexDistance {
+					face, apex, apexDistance = f, i, distance
+				}
+			}
+		}
+		if face == nil {
+			break
+		}
+
+		// every face the apex can see gets replaced by a fan from the horizon to the apex
+		visibleEdges := map[[2]int]bool{}
+		var orphans []int
+		for _, f := range faces {
+			if f.distance(points[apex]) > tolerance {
+				f.removed = true
+				orphans = append(orphans, f.outside...)
+				for k := 0; k < 3; k++ {
+					visibleEdges[[2]int{f.vertices[k], f.vertices[(k+1)%3]}] = true
+				}
+			}
+		}
+
+		var newFaces []*hullFace
+		for edge := range visibleEdges {
+			if visibleEdges[[2]int{edge[1], edge[0]}] {
+				continue
+			}
+			newFaces = append(newFaces, newHullFace(points, edge[0], edge[1], apex))
+		}
+		// map iteration order is random, keep the output stable
+		sortHullFaces(newFaces)
+
+		kept := faces[:0]
+		for _, f := range faces {
+			if !f.removed {
+				kept = append(kept, f)
+			}
+		}
+		faces = append(kept, newFaces...)
+
+		var unassigned []int
+		for _, i := range orphans {
+			if i != apex {
+				unassigned = append(unassigned, i)
+			}
+		}
+		assignOutside(points, faces, unassigned, tolerance)
+		vertexCount++
+	}
+
+	return compactHull(points, faces), true
+}
+
+// initialSimplex returns four points that form a tetrahedron with as much volume as the
+// extreme points allow
+func initialSimplex(points []mgl64.Vec3, tolerance float64) ([4]int, bool) {
+	// the two extreme points that are furthest apart along an axis
+	var a, b int
+	maxSpread := -1.0
+	for axis := 0; axis < 3; axis++ {
+		minIndex, maxIndex := 0, 0
+		for i, point := range points {
+			if point[axis] < points[minIndex][axis] {
+				minIndex = i
+			}
+			if point[axis] > points[maxIndex][axis] {
+				maxIndex = i
+			}
+		}
+		if spread := points[maxIndex][axis] - points[minIndex][axis]; spread > maxSpread {
+			a, b, maxSpread = minIndex, maxIndex, spread
+		}
+	}
+	if maxSpread <= tolerance {
+		return [4]int{}, false
+	}
+
+	// the point furthest from the line
+	c := -1
+	maxDistance := tolerance
+	direction := points[b].Sub(points[a]).Normalize()
+	for i, point := range points {
+		offset := point.Sub(points[a])
+		if distance := offset.Sub(direction.Mul(offset.Dot(direction))).Len(); distance > maxDistance {
+			c, maxDistance = i, distance
+		}
+	}
+	if c == -1 {
+		return [4]int{}, false
+	}
+
+	// the point furthest from the plane
+	d := -1
+	maxDistance = tolerance
+	normal := points[b].Sub(points[a]).Cross(points[c].Sub(points[a])).Normalize()
+	for i, point := range points {
+		if distance := math.Abs(point.Sub(points[a]).Dot(normal)); distance > maxDistance {
+			d, maxDistance = i, distance
+		}
+	}
+	if d == -1 {
+		return [4]int{}, false
+	}
+
+	return [4]int{a, b, c, d}, true
+}
+
+// assignOutside gives each point to the first face it's in front of, points that aren't in
+// front of any face are inside the hull and dropped
+func assignOutside(points []mgl64.Vec3, faces []*hullFace, indices []int, tolerance float64) {
+	for _, i := range indices {
+		for _, f := range faces {
+			if f.distance(points[i]) > tolerance {
+				f.outside = append(f.outside, i)
+				break
+			}
+		}
+	}
+}
+
+func sortHullFaces(faces []*hullFace) {
+	less := func(a, b *hullFace) bool {
+		for k := 0; k < 3; k++ {
+			if a.vertices[k] != b.vertices[k] {
+				return a.vertices[k] < b.vertices[k]
+			}
+		}
+		return false
+	}
+	// insertion sort, the fans are small
+	for i := 1; i < len(faces); i++ {
+		for j := i; j > 0 && less(faces[j], faces[j-1]); j-- {
+			faces[j], faces[j-1] = faces[j-1], faces[j]
+		}
+	}
+}
+
+// compactHull drops the points that aren't on the hull
+func compactHull(points []mgl64.Vec3, faces []*hullFace) ConvexHull {
+	var hull ConvexHull
+	remap := map[int]int{}
+	for _, f := range faces {
+		var face [3]int
+		for k, i := range f.vertices {
+			index, ok := remap[i]
+			if !ok {
+				index = len(hull.Points)
+				remap[i] = index
+				hull.Points = append(hull.Points, points[i])
+			}
+			face[k] = index
+		}
+		hull.Faces = append(hull.Faces, face)
+	}
+	return hull
+}
@@ -0,0 +1,83 @@
+package collider
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// OrientedBoundingBox is a box that rotates with its transform rather than being re-fit to
+// the world axes like BoundingBox
+type OrientedBoundingBox struct {
+	Center mgl64.Vec3
+	// Axes are the box's local x, y and z axes in world space, they're unit length and
+	// perpendicular to each other
+	Axes        [3]mgl64.Vec3
+	HalfExtents mgl64.Vec3
+}
+
+func NewOrientedBoundingBox(center, halfExtents mgl64.Vec3, orientation mgl64.Quat) OrientedBoundingBox {
+	return OrientedBoundingBox{
+		Center: center,
+		Axes: [3]mgl64.Vec3{
+			orientation.Rotate(mgl64.Vec3{1, 0, 0}),
+			orientation.Rotate(mgl64.Vec3{0, 1, 0}),
+			orientation.Rotate(mgl64.Vec3{0, 0, 1}),
+		},
+		HalfExtents: halfExtents,
+	}
+}
+
+func OrientedBoundingBoxFromAABB(boundingBox BoundingBox) OrientedBoundingBox {
+	return OrientedBoundingBox{
+		Center:      boundingBox.MinVertex.Add(boundingBox.MaxVertex).Mul(0.5),
+		Axes:        [3]mgl64.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+		HalfExtents: boundingBox.MaxVertex.Sub(boundingBox.MinVertex).Mul(0.5),
+	}
+}
+
+// Transform applies a transform made up of translation, rotation and scale. shearing isn't
+// supported.
+func (c OrientedBoundingBox) Transform(transform mgl64.Mat4) OrientedBoundingBox {
+	result := OrientedBoundingBox{Center: transform.Mul4x1(c.Center.Vec4(1)).Vec3()}
+	for i, axis := range c.Axes {
+		transformedAxis := transform.Mul4x1(axis.Vec4(0)).Vec3()
+		length := transformedAxis.Len()
+		result.Axes[i] = transformedAxis.Mul(1 / length)
+		result.HalfExtents[i] = c.HalfExtents[i] * length
+	}
+	return result
+}
+
+// Vertices returns the eight corners of the box
+func (c OrientedBoundingBox) Vertices() []mgl64.Vec3 {
+	x := c.Axes[0].Mul(c.HalfExtents[0])
+	y := c.Axes[1].Mul(c.HalfExtents[1])
+	z := c.Axes[2].Mul(c.HalfExtents[2])
+
+	vertices := make([]mgl64.Vec3, 0, 8)
+	for _, sx := range []float64{-1, 1} {
+		for _, sy := range []float64{-1, 1} {
+			for _, sz := range []float64{-1, 1} {
+				vertices = append(vertices, c.Center.Add(x.Mul(sx)).Add(y.Mul(sy)).Add(z.Mul(sz)))
+			}
+		}
+	}
+	return vertices
+}
+
+// BoundingBox returns the axis aligned box that fits around the oriented box
+func (c OrientedBoundingBox) BoundingBox() BoundingBox {
+	var extents mgl64.Vec3
+	for i := 0; i < 3; i++ {
+		for j, axis := range c.Axes {
+			extents[i] += abs(axis[i]) * c.HalfExtents[j]
+		}
+	}
+	return BoundingBox{MinVertex: c.Center.Sub(extents), MaxVertex: c.Center.Add(extents)}
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
@@ -1,11 +1,21 @@
 package collision
 
 import (
+	"math"
+
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/kkevinchou/kitolib/collision/checks"
 	"github.com/kkevinchou/kitolib/collision/collider"
 )
 
+const (
+	epsilon float64 = 0.000001
+
+	// how closely two directions need to line up before we treat them as parallel when
+	// building manifolds
+	manifoldNormalTolerance float64 = 0.001
+)
+
 type ContactsBySeparatingDistance []Contact
 
 func (c ContactsBySeparatingDistance) Len() int {
@@ -18,11 +28,28 @@ func (c ContactsBySeparatingDistance) Less(i, j int) bool {
 	return c[i].SeparatingDistance < c[j].SeparatingDistance
 }
 
+// Contact describes the collision between collider A and collider B, where A is the first
+// collider passed to a check and B is the second. PackedIndexA and PackedIndexB are the ids
+// of the objects that own A and B. the checks only see shapes so they always leave them at
+// zero for the caller to fill in, the physics world sets them to body ids. which triangle of
+// a mesh was hit is in TriIndex.
+//
+// Normal points from B towards A, so moving A along SeparatingVector resolves the collision.
+// Point is the deepest contact point and Points holds the full manifold.
 type Contact struct {
 	PackedIndexA int
 	PackedIndexB int
 	Type         ContactType
 
+	// TriIndex is the index of the triangle that was hit for trimesh contacts
+	TriIndex *int
+
+	Point  mgl64.Vec3
+	Normal mgl64.Vec3
+
+	Points     [MaxManifoldPoints]ContactPoint
+	PointCount int
+
 	SeparatingVector   mgl64.Vec3
 	SeparatingDistance float64
 }
@@ -31,13 +58,17 @@ type ContactType string
 
 var ContactTypeCapsuleTriMesh ContactType = "TRIMESH"
 var ContactTypeCapsuleCapsule ContactType = "CAPSULE"
+var ContactTypeSphereBox ContactType = "SPHERE_BOX"
+var ContactTypeBoxBox ContactType = "BOX"
+var ContactTypeConvex ContactType = "CONVEX"
+var ContactTypeRay ContactType = "RAY"
 
 func CheckCollisionCapsuleTriMesh(capsule collider.Capsule, triangulatedMesh collider.TriMesh) []Contact {
 	var contacts []Contact
-	for _, tri := range triangulatedMesh.Triangles {
+	for i, tri := range triangulatedMesh.Triangles {
 		if triContact, collision := CheckCollisionCapsuleTriangle(capsule, tri); collision {
-			// index := i
-			// triContact.TriIndex = &index
+			index := i
+			triContact.TriIndex = &index
 			contacts = append(contacts, triContact)
 		}
 	}
@@ -65,18 +96,80 @@ func CheckCollisionCapsuleTriangle(capsule collider.Capsule, triangle collider.T
 			separatingVec = separatingVec.Add(triangle.Normal.Mul(capsule.Radius * 2))
 			separatingDistance = separatingVec.Len()
 		}
-		return Contact{
-			// Point:              closestPoints[1],
-			// Normal:             triangle.Normal,
+		contact := Contact{
+			Point:              closestPoints[1],
+			Normal:             separatingVec.Normalize(),
 			SeparatingVector:   separatingVec,
 			SeparatingDistance: separatingDistance,
 			Type:               ContactTypeCapsuleTriMesh,
-		}, true
+		}
+
+		// a capsule lying on the face of a triangle touches it along a segment rather than at
+		// a single point. clip the capsule's segment against the triangle and keep both ends.
+		if contact.Normal.Dot(triangle.Normal) > 1-manifoldNormalTolerance {
+			capsuleTriangleFaceManifold(&contact, capsule, triangle)
+		}
+
+		if contact.PointCount == 0 {
+			contact.addPoint(closestPoints[1], separatingDistance, NewFeatureID(FeatureTypeEdge, 0, FeatureTypeFace, 0))
+		}
+
+		return contact, true
 	}
 
 	return Contact{}, false
 }
 
+// capsuleTriangleFaceManifold clips the capsule segment to the prism formed by the triangle
+// edges and adds a contact point for each clipped end that penetrates the triangle face
+func capsuleTriangleFaceManifold(contact *Contact, capsule collider.Capsule, triangle collider.Triangle) {
+	p0 := capsule.Top
+	p1 := capsule.Bottom
+	t0, t1 := 0.0, 1.0
+
+	for i := 0; i < 3; i++ {
+		a := triangle.Points[i]
+		b := triangle.Points[(i+1)%3]
+		inward := triangle.Normal.Cross(b.Sub(a))
+
+		d0 := inward.Dot(p0.Sub(a))
+		d1 := inward.Dot(p1.Sub(a))
+		if d0 < 0 && d1 < 0 {
+			return
+		}
+		if d0 < 0 {
+			t0 = math.Max(t0, d0/(d0-d1))
+		} else if d1 < 0 {
+			t1 = math.Min(t1, d0/(d0-d1))
+		}
+	}
+
+	if t1-t0 <= epsilon {
+		return
+	}
+
+	segment := p1.Sub(p0)
+	ends := [2]float64{t0, t1}
+	for i, t := range ends {
+		point := p0.Add(segment.Mul(t))
+		distance := point.Sub(triangle.Points[0]).Dot(triangle.Normal)
+		penetration := capsule.Radius - distance
+		if penetration <= 0 {
+			continue
+		}
+
+		featureA := FeatureTypeVertex
+		if (i == 0 && t > 0) || (i == 1 && t < 1) {
+			featureA = FeatureTypeEdge
+		}
+		contact.addPoint(point.Sub(triangle.Normal.Mul(distance)), penetration, NewFeatureID(featureA, uint8(i), FeatureTypeFace, 0))
+	}
+
+	if contact.PointCount < 2 {
+		contact.PointCount = 0
+	}
+}
+
 // for now assumes vertical capsules only
 func CheckCollisionCapsuleCapsule(capsule1 collider.Capsule, capsule2 collider.Capsule) (Contact, bool) {
 	closestPoints, closestPointsDistance := checks.ClosestPointsLineVSLine(
@@ -96,17 +189,204 @@ func CheckCollisionCapsuleCapsule(capsule1 collider.Capsule, capsule2 collider.C
 
 		capsule2To1Dir := capsule2To1.Normalize()
 		separatingVec := capsule2To1Dir.Mul(separatingDistance)
-		return Contact{
-			// Point:              capsule2To1Dir.Mul(closestPointsDistance),
+		contact := Contact{
+			Point:              closestPoints[1].Add(capsule2To1Dir.Mul(capsule2.Radius)),
+			Normal:             capsule2To1Dir,
 			SeparatingVector:   separatingVec,
 			SeparatingDistance: separatingDistance,
 			Type:               ContactTypeCapsuleCapsule,
-		}, true
+		}
+
+		capsuleCapsuleParallelManifold(&contact, capsule1, capsule2)
+		if contact.PointCount == 0 {
+			contact.addPoint(contact.Point, separatingDistance, NewFeatureID(FeatureTypeEdge, 0, FeatureTypeEdge, 0))
+		}
+
+		return contact, true
 	}
 
 	return Contact{}, false
 }
 
+// capsuleCapsuleParallelManifold handles capsules lying side by side. the overlapping range
+// of the two segments produces a contact point at each end.
+func capsuleCapsuleParallelManifold(contact *Contact, capsule1 collider.Capsule, capsule2 collider.Capsule) {
+	d1 := capsule1.Top.Sub(capsule1.Bottom)
+	d2 := capsule2.Top.Sub(capsule2.Bottom)
+	length2 := d2.Len()
+	if d1.Len() <= epsilon || length2 <= epsilon {
+		return
+	}
+
+	dir := d2.Mul(1 / length2)
+	if math.Abs(d1.Normalize().Dot(dir)) < 1-manifoldNormalTolerance {
+		return
+	}
+
+	s1 := capsule1.Bottom.Sub(capsule2.Bottom).Dot(dir)
+	s2 := capsule1.Top.Sub(capsule2.Bottom).Dot(dir)
+	start := math.Max(math.Min(s1, s2), 0)
+	end := math.Min(math.Max(s1, s2), length2)
+	if end-start <= epsilon {
+		return
+	}
+
+	ends := [2]float64{start, end}
+	for i, s := range ends {
+		pointOn2 := capsule2.Bottom.Add(dir.Mul(s))
+		pointOn1 := checks.ClosestPointOnLineToPoint(capsule1.Top, capsule1.Bottom, pointOn2)
+		penetration := capsule1.Radius + capsule2.Radius - pointOn1.Sub(pointOn2).Len()
+		if penetration <= 0 {
+			continue
+		}
+		contact.addPoint(pointOn2.Add(contact.Normal.Mul(capsule2.Radius)), penetration, NewFeatureID(FeatureTypeEdge, uint8(i), FeatureTypeEdge, uint8(i)))
+	}
+}
+
+func CheckCollisionSphereAABB(sphere collider.Sphere, aabb *collider.BoundingBox) (Contact, bool) {
+	closestPoint := checks.ClosestPointOnAABBToPoint(sphere.Center, aabb)
+	delta := sphere.Center.Sub(closestPoint)
+	if delta.LenSqr() > sphere.RadiusSquared {
+		return Contact{}, false
+	}
+
+	var normal mgl64.Vec3
+	var penetration float64
+	var feature FeatureType
+	var featureIndex uint8
+
+	if delta.LenSqr() > epsilon*epsilon {
+		distance := delta.Len()
+		normal = delta.Mul(1 / distance)
+		penetration = sphere.Radius - distance
+
+		// the number of clamped axes tells us whether we hit a face, edge or vertex. faces use
+		// the same index as the inside branch so a sphere sinking into a face keeps its feature,
+		// edges and vertices set a bit for each side they're clamped to.
+		clampedAxes := 0
+		faceAxis, faceSign := 0, 0.0
+		for i := 0; i < 3; i++ {
+			if sphere.Center[i] < aabb.MinVertex[i] {
+				clampedAxes++
+				featureIndex |= 1 << (i * 2)
+				faceAxis, faceSign = i, -1
+			} else if sphere.Center[i] > aabb.MaxVertex[i] {
+				clampedAxes++
+				featureIndex |= 1 << (i*2 + 1)
+				faceAxis, faceSign = i, 1
+			}
+		}
+		feature = [4]FeatureType{FeatureTypeFace, FeatureTypeFace, FeatureTypeEdge, FeatureTypeVertex}[clampedAxes]
+		if clampedAxes == 1 {
+			featureIndex = aabbFaceIndex(faceAxis, faceSign)
+		}
+	} else {
+		// the sphere center is inside the box, push out through the closest face
+		axis, sign, distance := closestAABBFace(sphere.Center, aabb)
+		normal[axis] = sign
+		penetration = sphere.Radius + distance
+		closestPoint = sphere.Center.Add(normal.Mul(distance))
+		feature = FeatureTypeFace
+		featureIndex = aabbFaceIndex(axis, sign)
+	}
+
+	contact := Contact{
+		Point:              closestPoint,
+		Normal:             normal,
+		SeparatingVector:   normal.Mul(penetration),
+		SeparatingDistance: penetration,
+		Type:               ContactTypeSphereBox,
+	}
+	contact.addPoint(closestPoint, penetration, NewFeatureID(FeatureTypeVertex, 0, feature, featureIndex))
+
+	return contact, true
+}
+
+// CheckCollisionAABBAABB resolves along the axis of least overlap and produces a manifold
+// from the corners of the overlapping region on the touching face of aabb2
+func CheckCollisionAABBAABB(aabb1 *collider.BoundingBox, aabb2 *collider.BoundingBox) (Contact, bool) {
+	var overlapMin, overlapMax mgl64.Vec3
+	axis := -1
+	minOverlap := math.MaxFloat64
+
+	for i := 0; i < 3; i++ {
+		overlapMin[i] = math.Max(aabb1.MinVertex[i], aabb2.MinVertex[i])
+		overlapMax[i] = math.Min(aabb1.MaxVertex[i], aabb2.MaxVertex[i])
+		overlap := overlapMax[i] - overlapMin[i]
+		if overlap <= 0 {
+			return Contact{}, false
+		}
+		if overlap < minOverlap {
+			minOverlap = overlap
+			axis = i
+		}
+	}
+
+	center1 := aabb1.MinVertex.Add(aabb1.MaxVertex).Mul(0.5)
+	center2 := aabb2.MinVertex.Add(aabb2.MaxVertex).Mul(0.5)
+
+	var normal mgl64.Vec3
+	sign := 1.0
+	faceValue := aabb2.MaxVertex[axis]
+	if center1[axis] < center2[axis] {
+		sign = -1
+		faceValue = aabb2.MinVertex[axis]
+	}
+	normal[axis] = sign
+
+	contact := Contact{
+		Normal:             normal,
+		SeparatingVector:   normal.Mul(minOverlap),
+		SeparatingDistance: minOverlap,
+		Type:               ContactTypeBoxBox,
+	}
+
+	u := (axis + 1) % 3
+	v := (axis + 2) % 3
+	faceIndex := aabbFaceIndex(axis, sign)
+	for i := 0; i < 4; i++ {
+		var point mgl64.Vec3
+		point[axis] = faceValue
+		point[u] = overlapMin[u]
+		if i == 1 || i == 2 {
+			point[u] = overlapMax[u]
+		}
+		point[v] = overlapMin[v]
+		if i >= 2 {
+			point[v] = overlapMax[v]
+		}
+		contact.addPoint(point, minOverlap, NewFeatureID(FeatureTypeVertex, uint8(i), FeatureTypeFace, faceIndex))
+	}
+	contact.Point = overlapMin.Add(overlapMax).Mul(0.5)
+	contact.Point[axis] = faceValue
+
+	return contact, true
+}
+
+// closestAABBFace returns the axis and direction of the face closest to a point inside the
+// box, along with the distance to that face
+func closestAABBFace(point mgl64.Vec3, aabb *collider.BoundingBox) (int, float64, float64) {
+	axis := 0
+	sign := -1.0
+	distance := math.MaxFloat64
+	for i := 0; i < 3; i++ {
+		if d := point[i] - aabb.MinVertex[i]; d < distance {
+			axis, sign, distance = i, -1, d
+		}
+		if d := aabb.MaxVertex[i] - point[i]; d < distance {
+			axis, sign, distance = i, 1, d
+		}
+	}
+	return axis, sign, distance
+}
+
+func aabbFaceIndex(axis int, sign float64) uint8 {
+	if sign > 0 {
+		return uint8(axis*2 + 1)
+	}
+	return uint8(axis * 2)
+}
+
 func CheckOverlapAABBAABB(aabb1 *collider.BoundingBox, aabb2 *collider.BoundingBox) bool {
 	if aabb1.MaxVertex.X() < aabb2.MinVertex.X() || aabb1.MinVertex.X() > aabb2.MaxVertex.X() {
 		return false
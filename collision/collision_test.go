@@ -0,0 +1,787 @@
+package collision_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/modelspec"
+)
+
+// capsule lying flat on a triangle should touch it at both ends of its segment
+func TestCapsuleLyingOnTriangleManifold(t *testing.T) {
+	capsule := collider.Capsule{
+		Radius: 1,
+		Top:    mgl64.Vec3{-1, 0.5, 0},
+		Bottom: mgl64.Vec3{1, 0.5, 0},
+	}
+	triangle := collider.NewTriangle([3]mgl64.Vec3{
+		{-10, 0, 10},
+		{10, 0, 10},
+		{0, 0, -10},
+	})
+
+	contact, collided := collision.CheckCollisionCapsuleTriangle(capsule, triangle)
+	if !collided {
+		t.Fatal("expected capsule to collide with triangle")
+	}
+
+	if contact.PointCount != 2 {
+		t.Fatalf("expected 2 contact points but got %d", contact.PointCount)
+	}
+
+	expectedPoints := []mgl64.Vec3{{-1, 0, 0}, {1, 0, 0}}
+	for i, point := range contact.ManifoldPoints() {
+		if !point.Point.ApproxEqual(expectedPoints[i]) {
+			t.Errorf("expected contact point %d to be %v but got %v", i, expectedPoints[i], point.Point)
+		}
+		if math.Abs(point.Penetration-0.5) > 1e-9 {
+			t.Errorf("expected penetration of 0.5 but got %f", point.Penetration)
+		}
+	}
+
+	if contact.Points[0].FeatureID == contact.Points[1].FeatureID {
+		t.Error("expected contact points to have distinct feature ids")
+	}
+}
+
+// capsule lying flat but hanging off the edge of the triangle gets clipped to the triangle
+func TestCapsuleClippedByTriangleEdge(t *testing.T) {
+	capsule := collider.Capsule{
+		Radius: 1,
+		Top:    mgl64.Vec3{-5, 0.5, 0},
+		Bottom: mgl64.Vec3{5, 0.5, 0},
+	}
+	triangle := collider.NewTriangle([3]mgl64.Vec3{
+		{-2, 0, 2},
+		{2, 0, 2},
+		{2, 0, -2},
+	})
+
+	contact, collided := collision.CheckCollisionCapsuleTriangle(capsule, triangle)
+	if !collided {
+		t.Fatal("expected capsule to collide with triangle")
+	}
+
+	if contact.PointCount != 2 {
+		t.Fatalf("expected 2 contact points but got %d", contact.PointCount)
+	}
+
+	expectedPoints := []mgl64.Vec3{{0, 0, 0}, {2, 0, 0}}
+	for i, point := range contact.ManifoldPoints() {
+		if !point.Point.ApproxEqual(expectedPoints[i]) {
+			t.Errorf("expected contact point %d to be %v but got %v", i, expectedPoints[i], point.Point)
+		}
+	}
+}
+
+func TestCapsuleTriMeshTriIndex(t *testing.T) {
+	capsule := collider.Capsule{
+		Radius: 1,
+		Top:    mgl64.Vec3{5, 5, 0},
+		Bottom: mgl64.Vec3{5, 0.5, 0},
+	}
+	triMesh := collider.TriMesh{
+		Triangles: []collider.Triangle{
+			collider.NewTriangle([3]mgl64.Vec3{{-10, 0, 1}, {-1, 0, 1}, {-1, 0, -1}}),
+			collider.NewTriangle([3]mgl64.Vec3{{1, 0, 1}, {10, 0, 1}, {10, 0, -1}}),
+		},
+	}
+
+	contacts := collision.CheckCollisionCapsuleTriMesh(capsule, triMesh)
+	if len(contacts) != 1 {
+		t.Fatalf("expected 1 contact but got %d", len(contacts))
+	}
+
+	if contacts[0].TriIndex == nil || *contacts[0].TriIndex != 1 {
+		t.Errorf("expected contact with triangle 1 but got %v", contacts[0].TriIndex)
+	}
+	if contacts[0].PackedIndexA != 0 || contacts[0].PackedIndexB != 0 {
+		t.Errorf("expected the packed indices to be left for the caller but got %d and %d", contacts[0].PackedIndexA, contacts[0].PackedIndexB)
+	}
+}
+
+func TestFlipKeepsPackedIndices(t *testing.T) {
+	box := collider.NewBoundingBox(mgl64.Vec3{-1, -1, -1}, mgl64.Vec3{1, 1, 1})
+	contact, _ := collision.CheckCollisionSphereAABB(collider.NewSphere(mgl64.Vec3{0, 1.5, 0}, 1), box)
+	contact.PackedIndexA, contact.PackedIndexB = 7, 9
+
+	// from the box's side the normal points from the sphere towards the box
+	flipped := contact.Flip()
+	if flipped.PackedIndexA != 9 || flipped.PackedIndexB != 7 {
+		t.Errorf("expected the box to be A and the sphere B but got %d and %d", flipped.PackedIndexA, flipped.PackedIndexB)
+	}
+	if !flipped.Normal.ApproxEqual(mgl64.Vec3{0, -1, 0}) {
+		t.Errorf("expected the normal to point down towards the box but got %v", flipped.Normal)
+	}
+	if back := flipped.Flip(); back.PackedIndexA != 7 || back.PackedIndexB != 9 || !back.Normal.ApproxEqual(contact.Normal) {
+		t.Errorf("expected flipping twice to give back the contact but got %v", back)
+	}
+}
+
+func TestParallelCapsulesManifold(t *testing.T) {
+	capsule1 := collider.NewCapsule(mgl64.Vec3{1.5, 3, 0}, mgl64.Vec3{1.5, 0, 0}, 1)
+	capsule2 := collider.NewCapsule(mgl64.Vec3{0, 4, 0}, mgl64.Vec3{0, 1, 0}, 1)
+
+	contact, collided := collision.CheckCollisionCapsuleCapsule(capsule1, capsule2)
+	if !collided {
+		t.Fatal("expected capsules to collide")
+	}
+
+	expectedNormal := mgl64.Vec3{1, 0, 0}
+	if !contact.Normal.ApproxEqual(expectedNormal) {
+		t.Errorf("expected normal to be %v but got %v", expectedNormal, contact.Normal)
+	}
+
+	if contact.PointCount != 2 {
+		t.Fatalf("expected 2 contact points but got %d", contact.PointCount)
+	}
+
+	expectedPoints := []mgl64.Vec3{{1, 1, 0}, {1, 3, 0}}
+	for i, point := range contact.ManifoldPoints() {
+		if !point.Point.ApproxEqual(expectedPoints[i]) {
+			t.Errorf("expected contact point %d to be %v but got %v", i, expectedPoints[i], point.Point)
+		}
+		if math.Abs(point.Penetration-0.5) > 1e-9 {
+			t.Errorf("expected penetration of 0.5 but got %f", point.Penetration)
+		}
+	}
+}
+
+func TestSphereAABB(t *testing.T) {
+	box := collider.NewBoundingBox(mgl64.Vec3{-1, -1, -1}, mgl64.Vec3{1, 1, 1})
+
+	contact, collided := collision.CheckCollisionSphereAABB(collider.NewSphere(mgl64.Vec3{0, 1.5, 0}, 1), box)
+	if !collided {
+		t.Fatal("expected sphere to collide with box")
+	}
+	if !contact.Normal.ApproxEqual(mgl64.Vec3{0, 1, 0}) {
+		t.Errorf("expected normal to point up but got %v", contact.Normal)
+	}
+	if math.Abs(contact.SeparatingDistance-0.5) > 1e-9 {
+		t.Errorf("expected separating distance of 0.5 but got %f", contact.SeparatingDistance)
+	}
+	if !contact.Point.ApproxEqual(mgl64.Vec3{0, 1, 0}) {
+		t.Errorf("expected contact point on the top face but got %v", contact.Point)
+	}
+
+	// sinking until the center is inside the box is still the same face
+	sunk, _ := collision.CheckCollisionSphereAABB(collider.NewSphere(mgl64.Vec3{0, 0.9, 0}, 1), box)
+	if sunk.Points[0].FeatureID != contact.Points[0].FeatureID {
+		t.Errorf("expected the feature to stay %v as the sphere sinks in but got %v", contact.Points[0].FeatureID, sunk.Points[0].FeatureID)
+	}
+
+	// center inside the box pushes out through the nearest face
+	contact, collided = collision.CheckCollisionSphereAABB(collider.NewSphere(mgl64.Vec3{0.8, 0, 0}, 0.5), box)
+	if !collided {
+		t.Fatal("expected sphere to collide with box")
+	}
+	if !contact.Normal.ApproxEqual(mgl64.Vec3{1, 0, 0}) {
+		t.Errorf("expected normal to point along +x but got %v", contact.Normal)
+	}
+	if math.Abs(contact.SeparatingDistance-0.7) > 1e-9 {
+		t.Errorf("expected separating distance of 0.7 but got %f", contact.SeparatingDistance)
+	}
+
+	if _, collided = collision.CheckCollisionSphereAABB(collider.NewSphere(mgl64.Vec3{0, 3, 0}, 1), box); collided {
+		t.Error("expected sphere to not collide with box")
+	}
+}
+
+func TestBoxStackedOnBox(t *testing.T) {
+	bottom := collider.NewBoundingBox(mgl64.Vec3{-2, 0, -2}, mgl64.Vec3{2, 1, 2})
+	top := collider.NewBoundingBox(mgl64.Vec3{-0.5, 0.9, -0.5}, mgl64.Vec3{0.5, 1.9, 0.5})
+
+	contact, collided := collision.CheckCollisionAABBAABB(top, bottom)
+	if !collided {
+		t.Fatal("expected boxes to collide")
+	}
+
+	if !contact.Normal.ApproxEqual(mgl64.Vec3{0, 1, 0}) {
+		t.Errorf("expected normal to point up but got %v", contact.Normal)
+	}
+
+	if contact.PointCount != 4 {
+		t.Fatalf("expected 4 contact points but got %d", contact.PointCount)
+	}
+
+	seen := map[collision.FeatureID]bool{}
+	for _, point := range contact.ManifoldPoints() {
+		if math.Abs(point.Point.Y()-1) > 1e-9 {
+			t.Errorf("expected contact point on the top face of the bottom box but got %v", point.Point)
+		}
+		if math.Abs(point.Penetration-0.1) > 1e-9 {
+			t.Errorf("expected penetration of 0.1 but got %f", point.Penetration)
+		}
+		seen[point.FeatureID] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("expected 4 distinct feature ids but got %d", len(seen))
+	}
+
+	flipped := contact.Flip()
+	if !flipped.Normal.ApproxEqual(mgl64.Vec3{0, -1, 0}) {
+		t.Errorf("expected flipped normal to point down but got %v", flipped.Normal)
+	}
+}
+
+func floorTriMesh(y float64) collider.TriMesh {
+	return collider.TriMesh{
+		Triangles: []collider.Triangle{
+			collider.NewTriangle([3]mgl64.Vec3{{-100, y, 100}, {100, y, 100}, {100, y, -100}}),
+			collider.NewTriangle([3]mgl64.Vec3{{-100, y, 100}, {100, y, -100}, {-100, y, -100}}),
+		},
+	}
+}
+
+// wall facing -x at the given x value
+func wallTriMesh(x float64) collider.TriMesh {
+	return collider.TriMesh{
+		Triangles: []collider.Triangle{
+			collider.NewTriangle([3]mgl64.Vec3{{x, -100, -100}, {x, -100, 100}, {x, 100, 100}}),
+			collider.NewTriangle([3]mgl64.Vec3{{x, -100, -100}, {x, 100, 100}, {x, 100, -100}}),
+		},
+	}
+}
+
+// a fast sphere should not tunnel through a paper thin floor
+func TestSweepSphereDoesNotTunnel(t *testing.T) {
+	sphere := collider.NewSphere(mgl64.Vec3{0, 10, 0}, 1)
+	hit, ok := collision.SweepSphereTriMesh(sphere, mgl64.Vec3{0, -1000, 0}, floorTriMesh(0))
+	if !ok {
+		t.Fatal("expected the sphere to hit the floor")
+	}
+
+	expectedTOI := 9.0 / 1000
+	if math.Abs(hit.TimeOfImpact-expectedTOI) > 1e-5 {
+		t.Errorf("expected time of impact %f but got %f", expectedTOI, hit.TimeOfImpact)
+	}
+	if !hit.Normal.ApproxEqualThreshold(mgl64.Vec3{0, 1, 0}, 1e-4) {
+		t.Errorf("expected normal to point up but got %v", hit.Normal)
+	}
+	if hit.TriIndex == nil {
+		t.Error("expected the hit triangle to be set")
+	}
+}
+
+// a sphere skimming down at a shallow angle closes the gap slowly, it should still hit
+func TestSweepSphereShallowAngle(t *testing.T) {
+	floor := collider.TriMesh{
+		Triangles: []collider.Triangle{
+			collider.NewTriangle([3]mgl64.Vec3{{-2000, 0, 2000}, {2000, 0, 2000}, {2000, 0, -2000}}),
+			collider.NewTriangle([3]mgl64.Vec3{{-2000, 0, 2000}, {2000, 0, -2000}, {-2000, 0, -2000}}),
+		},
+	}
+	sphere := collider.NewSphere(mgl64.Vec3{0, 10, 0}, 0.5)
+	hit, ok := collision.SweepSphereTriMesh(sphere, mgl64.Vec3{1000, -20, 0}, floor)
+	if !ok {
+		t.Fatal("expected the sphere to hit the floor")
+	}
+
+	expectedTOI := 9.5 / 20
+	if math.Abs(hit.TimeOfImpact-expectedTOI) > 1e-5 {
+		t.Errorf("expected time of impact %f but got %f", expectedTOI, hit.TimeOfImpact)
+	}
+	if !hit.Normal.ApproxEqualThreshold(mgl64.Vec3{0, 1, 0}, 1e-4) {
+		t.Errorf("expected normal to point up but got %v", hit.Normal)
+	}
+}
+
+func TestSweepCapsuleMiss(t *testing.T) {
+	capsule := collider.NewCapsule(mgl64.Vec3{0, 5, 0}, mgl64.Vec3{0, 2, 0}, 1)
+	if _, ok := collision.SweepCapsuleTriMesh(capsule, mgl64.Vec3{0, -0.5, 0}, floorTriMesh(0)); ok {
+		t.Error("expected the capsule to stop short of the floor")
+	}
+	if _, ok := collision.SweepCapsuleTriMesh(capsule, mgl64.Vec3{0, 5, 0}, floorTriMesh(0)); ok {
+		t.Error("expected the capsule moving away from the floor to not hit it")
+	}
+}
+
+func TestSweepCapsuleCapsule(t *testing.T) {
+	capsule1 := collider.NewCapsule(mgl64.Vec3{0, 3, 0}, mgl64.Vec3{0, 1, 0}, 1)
+	capsule2 := collider.NewCapsule(mgl64.Vec3{10, 3, 0}, mgl64.Vec3{10, 1, 0}, 1)
+
+	hit, ok := collision.SweepCapsuleCapsule(capsule1, mgl64.Vec3{20, 0, 0}, capsule2)
+	if !ok {
+		t.Fatal("expected the capsules to hit")
+	}
+	if math.Abs(hit.TimeOfImpact-0.4) > 1e-5 {
+		t.Errorf("expected time of impact 0.4 but got %f", hit.TimeOfImpact)
+	}
+	if !hit.Normal.ApproxEqualThreshold(mgl64.Vec3{-1, 0, 0}, 1e-4) {
+		t.Errorf("expected normal to point along -x but got %v", hit.Normal)
+	}
+}
+
+func TestMoveAndSlideAlongWall(t *testing.T) {
+	capsule := collider.NewCapsule(mgl64.Vec3{0, 3, 0}, mgl64.Vec3{0, 1, 0}, 1)
+	translation, hits := collision.MoveAndSlide(capsule, mgl64.Vec3{10, 0, 10}, wallTriMesh(5), nil, 4)
+
+	if len(hits) != 1 {
+		t.Fatalf("expected 1 hit but got %d", len(hits))
+	}
+	if translation.X() > 4 || translation.X() < 3.99 {
+		t.Errorf("expected capsule to stop at the wall but got translation %v", translation)
+	}
+	if math.Abs(translation.Z()-10) > 1e-2 {
+		t.Errorf("expected capsule to keep sliding along z but got translation %v", translation)
+	}
+}
+
+func TestMoveAndSlideIntoCorner(t *testing.T) {
+	triMesh := wallTriMesh(5)
+	triMesh.Triangles = append(triMesh.Triangles, floorTriMesh(0).Triangles...)
+
+	capsule := collider.NewCapsule(mgl64.Vec3{0, 3, 0}, mgl64.Vec3{0, 1.5, 0}, 1)
+	translation, _ := collision.MoveAndSlide(capsule, mgl64.Vec3{10, -10, 10}, triMesh, nil, 4)
+
+	if translation.X() > 4 || translation.Y() < -0.5 {
+		t.Errorf("expected capsule to be stopped by the wall and floor but got translation %v", translation)
+	}
+	if translation.Z() < 1 {
+		t.Errorf("expected capsule to slide along the crease but got translation %v", translation)
+	}
+}
+
+func TestOBBTransformStaysTight(t *testing.T) {
+	box := collider.BoundingBox{MinVertex: mgl64.Vec3{-1, -0.5, -0.5}, MaxVertex: mgl64.Vec3{1, 0.5, 0.5}}
+	transform := mgl64.Translate3D(5, 0, 0).Mul4(mgl64.HomogRotate3DZ(math.Pi / 4))
+
+	obb := box.TransformOriented(transform)
+	if !obb.Center.ApproxEqual(mgl64.Vec3{5, 0, 0}) {
+		t.Errorf("expected the box to be centered at <5, 0, 0> but got %v", obb.Center)
+	}
+	if !obb.HalfExtents.ApproxEqual(mgl64.Vec3{1, 0.5, 0.5}) {
+		t.Errorf("expected the box to keep its size but got half extents %v", obb.HalfExtents)
+	}
+
+	// a point that's inside the re-fit box but outside the rotated one
+	point := mgl64.Vec3{5.9, 0.9, 0}
+	refit := box.Transform(transform)
+	if _, collided := collision.CheckCollision(collider.NewSphere(point, 0.01), refit); !collided {
+		t.Error("expected the re-fit box to contain the point")
+	}
+	if _, collided := collision.CheckCollision(collider.NewSphere(point, 0.01), obb); collided {
+		t.Error("expected the oriented box to not contain the point")
+	}
+}
+
+func TestSphereOBB(t *testing.T) {
+	obb := collider.NewOrientedBoundingBox(mgl64.Vec3{}, mgl64.Vec3{1, 1, 1}, mgl64.QuatRotate(math.Pi/4, mgl64.Vec3{0, 0, 1}))
+
+	// resting against the corner that points up
+	contact, collided := collision.CheckCollisionSphereOBB(collider.NewSphere(mgl64.Vec3{0, math.Sqrt2 + 0.4, 0}, 0.5), obb)
+	if !collided {
+		t.Fatal("expected the sphere to touch the corner of the box")
+	}
+	if !contact.Normal.ApproxEqualThreshold(mgl64.Vec3{0, 1, 0}, 1e-6) {
+		t.Errorf("expected the normal to point up but got %v", contact.Normal)
+	}
+	if math.Abs(contact.SeparatingDistance-0.1) > 1e-6 {
+		t.Errorf("expected a penetration of 0.1 but got %f", contact.SeparatingDistance)
+	}
+	if !contact.Point.ApproxEqualThreshold(mgl64.Vec3{0, math.Sqrt2, 0}, 1e-6) {
+		t.Errorf("expected the contact point on the corner but got %v", contact.Point)
+	}
+
+	// the center is inside the box
+	contact, collided = collision.CheckCollisionSphereOBB(collider.NewSphere(mgl64.Vec3{0.3, 0.3, 0}, 0.5), obb)
+	if !collided {
+		t.Fatal("expected the sphere inside the box to collide")
+	}
+	face := mgl64.Vec3{1, 1, 0}.Normalize()
+	if !contact.Normal.ApproxEqualThreshold(face, 1e-6) {
+		t.Errorf("expected the normal to point out of the closest face %v but got %v", face, contact.Normal)
+	}
+	if expected := 1 - 0.3*math.Sqrt2 + 0.5; math.Abs(contact.SeparatingDistance-expected) > 1e-6 {
+		t.Errorf("expected a penetration of %f but got %f", expected, contact.SeparatingDistance)
+	}
+}
+
+func TestOBBOBB(t *testing.T) {
+	ground := collider.NewOrientedBoundingBox(mgl64.Vec3{0, -1, 0}, mgl64.Vec3{5, 1, 5}, mgl64.QuatIdent())
+
+	// a box tipped onto its edge sinking into the ground
+	tipped := collider.NewOrientedBoundingBox(mgl64.Vec3{0, math.Sqrt2/2 - 0.1, 0}, mgl64.Vec3{0.5, 0.5, 0.5}, mgl64.QuatRotate(math.Pi/4, mgl64.Vec3{0, 0, 1}))
+	contact, collided := collision.CheckCollisionOBBOBB(tipped, ground)
+	if !collided {
+		t.Fatal("expected the tipped box to collide with the ground")
+	}
+	if !contact.Normal.ApproxEqualThreshold(mgl64.Vec3{0, 1, 0}, 1e-6) {
+		t.Errorf("expected the normal to point up but got %v", contact.Normal)
+	}
+	if math.Abs(contact.SeparatingDistance-0.1) > 1e-6 {
+		t.Errorf("expected a penetration of 0.1 but got %f", contact.SeparatingDistance)
+	}
+	if math.Abs(contact.Point.Y()) > 1e-6 {
+		t.Errorf("expected the contact point on the ground's surface but got %v", contact.Point)
+	}
+
+	lifted := tipped
+	lifted.Center = lifted.Center.Add(mgl64.Vec3{0, 0.3, 0})
+	if _, collided := collision.CheckCollisionOBBOBB(lifted, ground); collided {
+		t.Error("expected the lifted box to not collide with the ground")
+	}
+	points, distance, _ := collision.ClosestPoints(lifted, ground)
+	if math.Abs(distance-0.2) > 1e-6 {
+		t.Errorf("expected the lifted box to be 0.2 above the ground but got %f", distance)
+	}
+	// any point along the bottom edge is as close as any other
+	if math.Abs(points[0].X()) > 1e-6 || math.Abs(points[0].Y()-0.2) > 1e-6 || !points[1].ApproxEqualThreshold(mgl64.Vec3{0, 0, points[0].Z()}, 1e-6) {
+		t.Errorf("expected the closest points to be on the box's bottom edge and the ground below it but got %v", points)
+	}
+}
+
+func TestOBBOBBManifold(t *testing.T) {
+	ground := collider.NewOrientedBoundingBox(mgl64.Vec3{0, -1, 0}, mgl64.Vec3{5, 1, 5}, mgl64.QuatIdent())
+
+	// a box resting flat on the ground, turned about the up axis
+	resting := func(yaw float64) collision.Contact {
+		box := collider.NewOrientedBoundingBox(mgl64.Vec3{0.2, 0.45, 0}, mgl64.Vec3{0.5, 0.5, 0.5}, mgl64.QuatRotate(yaw, mgl64.Vec3{0, 1, 0}))
+		contact, collided := collision.CheckCollisionOBBOBB(box, ground)
+		if !collided {
+			t.Fatal("expected the resting box to collide with the ground")
+		}
+		return contact
+	}
+
+	contact := resting(0.5)
+	if contact.PointCount != 4 {
+		t.Fatalf("expected a point under each corner but got %d", contact.PointCount)
+	}
+	seen := map[collision.FeatureID]bool{}
+	for _, point := range contact.ManifoldPoints() {
+		if math.Abs(point.Point.Y()) > 1e-6 || math.Abs(point.Penetration-0.05) > 1e-6 {
+			t.Errorf("expected the points on the ground 0.05 deep but got %v", point)
+		}
+		seen[point.FeatureID] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("expected every point to have its own feature but got %v", contact.ManifoldPoints())
+	}
+
+	// turning the box a little keeps the features so warm starting can match them up
+	turned := resting(0.51)
+	for _, point := range turned.ManifoldPoints() {
+		if !seen[point.FeatureID] {
+			t.Errorf("expected feature %v to be one of the previous frame's", point.FeatureID)
+		}
+	}
+
+	// hanging over the edge of the ground the points are clipped to it
+	over := collider.NewOrientedBoundingBox(mgl64.Vec3{5, 0.45, 0}, mgl64.Vec3{0.5, 0.5, 0.5}, mgl64.QuatIdent())
+	contact, _ = collision.CheckCollisionOBBOBB(over, ground)
+	if contact.PointCount != 4 {
+		t.Fatalf("expected 4 clipped points but got %d", contact.PointCount)
+	}
+	clipped := 0
+	for _, point := range contact.ManifoldPoints() {
+		if point.Point.X() > 5+1e-6 {
+			t.Errorf("expected %v to be clipped to the ground", point.Point)
+		}
+		if typeA, _ := point.FeatureID.FeatureA(); typeA == collision.FeatureTypeEdge {
+			clipped++
+		}
+	}
+	if clipped != 2 {
+		t.Errorf("expected 2 points where the box's edges cross the ground's edge but got %d", clipped)
+	}
+}
+
+func TestConvexFeatureIDs(t *testing.T) {
+	obb := collider.NewOrientedBoundingBox(mgl64.Vec3{}, mgl64.Vec3{1, 1, 1}, mgl64.QuatRotate(0.3, mgl64.Vec3{0, 1, 0}))
+
+	feature := func(center mgl64.Vec3) collision.FeatureID {
+		contact, collided := collision.CheckCollisionSphereOBB(collider.NewSphere(center, 0.5), obb)
+		if !collided {
+			t.Fatalf("expected the sphere at %v to collide with the box", center)
+		}
+		return contact.Points[0].FeatureID
+	}
+
+	face := feature(mgl64.Vec3{0, 1.4, 0})
+	if typeB, _ := face.FeatureB(); typeB != collision.FeatureTypeFace {
+		t.Errorf("expected the sphere on top to touch a face but got %v", face)
+	}
+	if moved := feature(mgl64.Vec3{0.1, 1.4, 0.1}); moved != face {
+		t.Errorf("expected the same feature as the sphere moves along the face but got %v and %v", face, moved)
+	}
+	corner := obb.Vertices()[7]
+	vertex := feature(corner.Add(corner.Normalize().Mul(0.4)))
+	if typeB, _ := vertex.FeatureB(); typeB != collision.FeatureTypeVertex {
+		t.Errorf("expected the sphere off the corner to touch a vertex but got %v", vertex)
+	}
+}
+
+func TestCapsuleOBB(t *testing.T) {
+	obb := collider.NewOrientedBoundingBox(mgl64.Vec3{}, mgl64.Vec3{1, 1, 1}, mgl64.QuatRotate(math.Pi/4, mgl64.Vec3{0, 1, 0}))
+
+	// a capsule lying across the top of the box
+	capsule := collider.NewCapsule(mgl64.Vec3{-2, 1.4, 0}, mgl64.Vec3{2, 1.4, 0}, 0.5)
+	contact, collided := collision.CheckCollisionCapsuleOBB(capsule, obb)
+	if !collided {
+		t.Fatal("expected the capsule to collide with the box")
+	}
+	if !contact.Normal.ApproxEqualThreshold(mgl64.Vec3{0, 1, 0}, 1e-6) {
+		t.Errorf("expected the normal to point up but got %v", contact.Normal)
+	}
+	if math.Abs(contact.SeparatingDistance-0.1) > 1e-6 {
+		t.Errorf("expected a penetration of 0.1 but got %f", contact.SeparatingDistance)
+	}
+	// held up where it crosses the edges of the top face
+	if contact.PointCount != 2 {
+		t.Fatalf("expected the capsule to rest on 2 points but got %d", contact.PointCount)
+	}
+	for _, point := range contact.ManifoldPoints() {
+		if math.Abs(math.Abs(point.Point.X())-math.Sqrt2) > 1e-6 || math.Abs(point.Point.Y()-1) > 1e-6 {
+			t.Errorf("expected the point to be on an edge of the top face but got %v", point.Point)
+		}
+	}
+
+	// a capsule hanging off one corner of the top face is held up by the part over the face
+	capsule = collider.NewCapsule(mgl64.Vec3{0, 1.4, 0}, mgl64.Vec3{3, 1.4, 0}, 0.5)
+	contact, collided = collision.CheckCollisionCapsuleOBB(capsule, obb)
+	if !collided || contact.PointCount != 2 {
+		t.Fatalf("expected the capsule to rest on 2 points but got %v", contact)
+	}
+	for _, point := range contact.ManifoldPoints() {
+		if point.Point.X() < -1e-6 || point.Point.X() > math.Sqrt2+1e-6 {
+			t.Errorf("expected the point to be over the top face but got %v", point.Point)
+		}
+	}
+
+	// a capsule that pierces straight through the box
+	capsule = collider.NewCapsule(mgl64.Vec3{0.5, 3, 0}, mgl64.Vec3{0.5, -3, 0}, 0.25)
+	contact, collided = collision.CheckCollisionCapsuleOBB(capsule, obb)
+	if !collided {
+		t.Fatal("expected the piercing capsule to collide with the box")
+	}
+	// out through one of the two faces it's closest to
+	if math.Abs(contact.Normal.Y()) > 1e-6 || math.Abs(contact.Normal.X()-math.Sqrt2/2) > 1e-6 {
+		t.Errorf("expected the capsule to be pushed out sideways through the closest face but got normal %v", contact.Normal)
+	}
+	if expected := 1 - 0.5/math.Sqrt2 + 0.25; math.Abs(contact.SeparatingDistance-expected) > 1e-6 {
+		t.Errorf("expected a penetration of %f but got %f", expected, contact.SeparatingDistance)
+	}
+}
+
+func TestTrianglePairs(t *testing.T) {
+	floor := collider.NewTriangle([3]mgl64.Vec3{{-5, 0, -5}, {-5, 0, 5}, {5, 0, 0}})
+
+	contact, collided := collision.CheckCollisionSphereTriangle(collider.NewSphere(mgl64.Vec3{0, 0.4, 0}, 0.5), floor)
+	if !collided || math.Abs(contact.SeparatingDistance-0.1) > 1e-6 || !contact.Normal.ApproxEqualThreshold(mgl64.Vec3{0, 1, 0}, 1e-6) {
+		t.Errorf("expected the sphere to sink 0.1 into the floor but got %v", contact)
+	}
+
+	box := collider.NewOrientedBoundingBox(mgl64.Vec3{0, 0.4, 0}, mgl64.Vec3{0.5, 0.5, 0.5}, mgl64.QuatIdent())
+	contact, collided = collision.CheckCollisionOBBTriangle(box, floor)
+	if !collided || math.Abs(contact.SeparatingDistance-0.1) > 1e-6 || !contact.Normal.ApproxEqualThreshold(mgl64.Vec3{0, 1, 0}, 1e-6) {
+		t.Errorf("expected the box to sink 0.1 into the floor but got %v", contact)
+	}
+
+	// a triangle standing up through the floor
+	wall := collider.NewTriangle([3]mgl64.Vec3{{0, -1, -1}, {0, 3, 0}, {0, -1, 1}})
+	if _, collided := collision.CheckCollisionTriangleTriangle(wall, floor); !collided {
+		t.Error("expected the triangles to intersect")
+	}
+	raised := collider.NewTriangle([3]mgl64.Vec3{{0, 1, -1}, {0, 3, 0}, {0, 1, 1}})
+	if _, collided := collision.CheckCollisionTriangleTriangle(raised, floor); collided {
+		t.Error("expected the raised triangle to not touch the floor")
+	}
+}
+
+func TestRayPairs(t *testing.T) {
+	ray := collider.Ray{Origin: mgl64.Vec3{-10, 0, 0}, Direction: mgl64.Vec3{1, 0, 0}}
+	colliders := []collider.Collider{
+		collider.NewSphere(mgl64.Vec3{}, 1),
+		collider.NewCapsule(mgl64.Vec3{0, 5, 0}, mgl64.Vec3{0, -5, 0}, 1),
+		collider.NewBoundingBox(mgl64.Vec3{-1, -1, -1}, mgl64.Vec3{1, 1, 1}),
+		collider.NewOrientedBoundingBox(mgl64.Vec3{}, mgl64.Vec3{1, 1, 1}, mgl64.QuatRotate(math.Pi/3, mgl64.Vec3{1, 0, 0})),
+		collider.NewTriangle([3]mgl64.Vec3{{-1, -1, -1}, {-1, 2, 0}, {-1, -1, 1}}),
+	}
+
+	for _, c := range colliders {
+		contact, collided := collision.CheckCollision(ray, c)
+		if !collided {
+			t.Errorf("expected the ray to hit %T", c)
+			continue
+		}
+		if !contact.Point.ApproxEqualThreshold(mgl64.Vec3{-1, 0, 0}, 1e-6) {
+			t.Errorf("expected the ray to hit %T at <-1, 0, 0> but got %v", c, contact.Point)
+		}
+		if !contact.Normal.ApproxEqualThreshold(mgl64.Vec3{-1, 0, 0}, 1e-6) {
+			t.Errorf("expected the surface normal of %T to face the ray but got %v", c, contact.Normal)
+		}
+
+		// flipped so that the normal points from the ray to the collider
+		contact, _ = collision.CheckCollision(c, ray)
+		if !contact.Normal.ApproxEqualThreshold(mgl64.Vec3{1, 0, 0}, 1e-6) {
+			t.Errorf("expected the normal for %T to be flipped towards the collider but got %v", c, contact.Normal)
+		}
+
+		missed := collider.Ray{Origin: mgl64.Vec3{-10, 0, 0}, Direction: mgl64.Vec3{-1, 0, 0}}
+		if _, collided := collision.CheckCollision(missed, c); collided {
+			t.Errorf("expected the ray pointing away to miss %T", c)
+		}
+	}
+
+	if _, collided := collision.CheckCollision(ray, ray); collided {
+		t.Error("expected rays to not collide with each other")
+	}
+}
+
+// every pair agrees with itself when the order is swapped
+func TestCheckCollisionDispatchIsSymmetric(t *testing.T) {
+	colliders := []collider.Collider{
+		collider.NewSphere(mgl64.Vec3{0.2, 0.1, 0}, 0.6),
+		collider.NewCapsule(mgl64.Vec3{0, 1, 0.3}, mgl64.Vec3{0, -1, 0.3}, 0.3),
+		collider.NewBoundingBox(mgl64.Vec3{-0.5, -0.5, -0.5}, mgl64.Vec3{0.5, 0.5, 0.5}),
+		collider.NewOrientedBoundingBox(mgl64.Vec3{0.6, 0, 0}, mgl64.Vec3{0.5, 0.5, 0.5}, mgl64.QuatRotate(0.3, mgl64.Vec3{1, 1, 0}.Normalize())),
+		collider.NewTriangle([3]mgl64.Vec3{{-1, 0.2, -1}, {-1, 0.2, 1}, {1, 0.2, 0}}),
+		cubeHull(mgl64.Vec3{0, 0.3, 0.2}, 0.5),
+	}
+
+	for i, a := range colliders {
+		for _, b := range colliders[i+1:] {
+			contactAB, collidedAB := collision.CheckCollision(a, b)
+			contactBA, collidedBA := collision.CheckCollision(b, a)
+			if !collidedAB || !collidedBA {
+				t.Errorf("expected %T and %T to collide", a, b)
+				continue
+			}
+			if math.Abs(contactAB.SeparatingDistance-contactBA.SeparatingDistance) > 1e-6 {
+				t.Errorf("expected %T and %T to have the same penetration in either order but got %f and %f", a, b, contactAB.SeparatingDistance, contactBA.SeparatingDistance)
+			}
+			if !contactAB.Normal.ApproxEqualThreshold(contactBA.Normal.Mul(-1), 1e-6) {
+				t.Errorf("expected %T and %T to have opposite normals but got %v and %v", a, b, contactAB.Normal, contactBA.Normal)
+			}
+		}
+	}
+}
+
+// cubeHull builds the hull of a cube along with a bunch of points inside it
+func cubeHull(center mgl64.Vec3, halfExtent float64) collider.ConvexHull {
+	var points []mgl64.Vec3
+	for x := -1.0; x <= 1; x += 0.5 {
+		for y := -1.0; y <= 1; y += 0.5 {
+			for z := -1.0; z <= 1; z += 0.5 {
+				points = append(points, center.Add(mgl64.Vec3{x, y, z}.Mul(halfExtent)))
+			}
+		}
+	}
+	hull, _ := collider.NewConvexHull(points)
+	return hull
+}
+
+func TestConvexHullOfCube(t *testing.T) {
+	hull, ok := collider.NewConvexHull(cubeHull(mgl64.Vec3{}, 1).Points)
+	if !ok {
+		t.Fatal("expected a hull")
+	}
+	if len(hull.Points) != 8 {
+		t.Errorf("expected the interior and edge points to be dropped leaving 8 but got %d", len(hull.Points))
+	}
+	if len(hull.Faces) != 12 {
+		t.Errorf("expected 12 faces but got %d", len(hull.Faces))
+	}
+	if math.Abs(hull.Volume()-8) > 1e-9 {
+		t.Errorf("expected a volume of 8 but got %f", hull.Volume())
+	}
+	for i := range hull.Faces {
+		center := hull.Triangles()[i].Points[0]
+		if hull.FaceNormal(i).Dot(center) <= 0 {
+			t.Errorf("expected face %d to point outwards", i)
+		}
+	}
+}
+
+func TestConvexHullOfCoplanarPoints(t *testing.T) {
+	_, ok := collider.NewConvexHull([]mgl64.Vec3{{0, 0, 0}, {1, 0, 0}, {0, 0, 1}, {1, 0, 1}, {0.5, 0, 0.5}})
+	if ok {
+		t.Error("expected coplanar points to not have a hull")
+	}
+}
+
+func TestConvexHullSimplify(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var points []mgl64.Vec3
+	for i := 0; i < 500; i++ {
+		points = append(points, mgl64.Vec3{r.NormFloat64(), r.NormFloat64(), r.NormFloat64()}.Normalize())
+	}
+
+	hull, ok := collider.NewConvexHull(points)
+	if !ok {
+		t.Fatal("expected a hull")
+	}
+	if len(hull.Points) != len(points) {
+		t.Errorf("expected every point on the sphere to be on the hull but got %d of %d", len(hull.Points), len(points))
+	}
+
+	simplified := hull.Simplify(32)
+	if len(simplified.Points) > 32 {
+		t.Errorf("expected at most 32 vertices but got %d", len(simplified.Points))
+	}
+	sphereVolume := 4.0 / 3 * math.Pi
+	if volume := simplified.Volume(); volume > hull.Volume() || volume < 0.6*sphereVolume {
+		t.Errorf("expected the simplified hull to be inside the hull and keep most of its volume but got %f", volume)
+	}
+	for _, point := range simplified.Points {
+		if !hull.ContainsPoint(point, 1e-9) {
+			t.Errorf("expected simplified point %v to be inside the original hull", point)
+		}
+	}
+}
+
+func TestConvexHullFromPrimitives(t *testing.T) {
+	var vertices []modelspec.Vertex
+	for _, position := range []mgl32.Vec3{{0, 0, 0}, {2, 0, 0}, {0, 2, 0}, {0, 0, 2}, {0.2, 0.2, 0.2}} {
+		vertices = append(vertices, modelspec.Vertex{Position: position})
+	}
+
+	hull, ok := collider.CreateConvexHullFromPrimitives([]*modelspec.PrimitiveSpecification{{UniqueVertices: vertices}})
+	if !ok {
+		t.Fatal("expected a hull")
+	}
+	if len(hull.Points) != 4 {
+		t.Errorf("expected the tetrahedron's 4 corners but got %d", len(hull.Points))
+	}
+	if math.Abs(hull.Volume()-8.0/6) > 1e-6 {
+		t.Errorf("expected a volume of 8/6 but got %f", hull.Volume())
+	}
+}
+
+func TestConvexHullCollision(t *testing.T) {
+	hull := cubeHull(mgl64.Vec3{}, 1)
+
+	sphere := collider.NewSphere(mgl64.Vec3{1.5, 0, 0}, 1)
+	contact, collided := collision.CheckCollision(sphere, hull)
+	if !collided {
+		t.Fatal("expected the sphere to collide with the hull")
+	}
+	if math.Abs(contact.SeparatingDistance-0.5) > 1e-6 {
+		t.Errorf("expected a penetration of 0.5 but got %f", contact.SeparatingDistance)
+	}
+	if !contact.Normal.ApproxEqualThreshold(mgl64.Vec3{1, 0, 0}, 1e-6) {
+		t.Errorf("expected the normal to point towards the sphere but got %v", contact.Normal)
+	}
+
+	if _, collided := collision.CheckCollision(collider.NewSphere(mgl64.Vec3{3, 0, 0}, 1), hull); collided {
+		t.Error("expected a sphere outside the hull to not collide")
+	}
+
+	ray := collider.Ray{Origin: mgl64.Vec3{-5, 0.5, 0.5}, Direction: mgl64.Vec3{1, 0, 0}}
+	contact, collided = collision.CheckCollision(ray, hull)
+	if !collided {
+		t.Fatal("expected the ray to hit the hull")
+	}
+	if !contact.Point.ApproxEqualThreshold(mgl64.Vec3{-1, 0.5, 0.5}, 1e-6) {
+		t.Errorf("expected the ray to enter the hull at (-1, 0.5, 0.5) but got %v", contact.Point)
+	}
+	if !contact.Normal.ApproxEqualThreshold(mgl64.Vec3{-1, 0, 0}, 1e-6) {
+		t.Errorf("expected the surface normal (-1, 0, 0) but got %v", contact.Normal)
+	}
+}
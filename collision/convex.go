@@ -0,0 +1,352 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/checks"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+const (
+	maxGJKIterations = 64
+	gjkTolerance     = 1e-10
+
+	// vertices within this distance of the furthest one along a direction are part of the
+	// same feature
+	supportFeatureTolerance = 0.001
+)
+
+// convexCore is a convex polytope that can be inflated by a radius, so spheres and capsules
+// are a point and a segment with a radius
+type convexCore struct {
+	vertices []mgl64.Vec3
+	radius   float64
+
+	// faceNormals and edges are where the separating axes come from when the cores overlap
+	faceNormals []mgl64.Vec3
+	edges       []mgl64.Vec3
+}
+
+func sphereCore(sphere collider.Sphere) convexCore {
+	return convexCore{vertices: []mgl64.Vec3{sphere.Center}, radius: sphere.Radius}
+}
+
+func capsuleCore(capsule collider.Capsule) convexCore {
+	core := convexCore{vertices: []mgl64.Vec3{capsule.Top, capsule.Bottom}, radius: capsule.Radius}
+	if axis := capsule.Top.Sub(capsule.Bottom); axis.Len() > epsilon {
+		core.edges = []mgl64.Vec3{axis.Normalize()}
+	}
+	return core
+}
+
+func obbCore(obb collider.OrientedBoundingBox) convexCore {
+	axes := obb.Axes[:]
+	return convexCore{vertices: obb.Vertices(), faceNormals: axes, edges: axes}
+}
+
+func triangleCore(triangle collider.Triangle) convexCore {
+	points := triangle.Points
+	return convexCore{
+		vertices:    points[:],
+		faceNormals: []mgl64.Vec3{triangle.Normal},
+		edges: []mgl64.Vec3{
+			points[1].Sub(points[0]).Normalize(),
+			points[2].Sub(points[1]).Normalize(),
+			points[0].Sub(points[2]).Normalize(),
+		},
+	}
+}
+
+func convexHullCore(hull collider.ConvexHull) convexCore {
+	core := convexCore{vertices: hull.Points}
+	for i, face := range hull.Faces {
+		core.faceNormals = appendUniqueDirection(core.faceNormals, hull.FaceNormal(i), false)
+		for k := 0; k < 3; k++ {
+			edge := hull.Points[face[(k+1)%3]].Sub(hull.Points[face[k]]).Normalize()
+			core.edges = appendUniqueDirection(core.edges, edge, true)
+		}
+	}
+	return core
+}
+
+// appendUniqueDirection adds direction unless it's already in directions, optionally treating
+// opposite directions as the same
+func appendUniqueDirection(directions []mgl64.Vec3, direction mgl64.Vec3, ignoreSign bool) []mgl64.Vec3 {
+	for _, d := range directions {
+		dot := d.Dot(direction)
+		if ignoreSign {
+			dot = math.Abs(dot)
+		}
+		if dot > 1-manifoldNormalTolerance {
+			return directions
+		}
+	}
+	return append(directions, direction)
+}
+
+// support returns the index of the vertex of the core furthest along direction
+func (c convexCore) support(direction mgl64.Vec3) int {
+	best := 0
+	bestDot := c.vertices[0].Dot(direction)
+	for i, vertex := range c.vertices[1:] {
+		if dot := vertex.Dot(direction); dot > bestDot {
+			best, bestDot = i+1, dot
+		}
+	}
+	return best
+}
+
+// supportFeature returns the feature of the core furthest along direction, made up of all of
+// the vertices about as far along it as the furthest one
+func (c convexCore) supportFeature(direction mgl64.Vec3) (FeatureType, uint8) {
+	direction = direction.Normalize()
+	furthest := c.vertices[c.support(direction)].Dot(direction)
+	var indices []int
+	for i, vertex := range c.vertices {
+		if vertex.Dot(direction) >= furthest-supportFeatureTolerance {
+			indices = append(indices, i)
+		}
+	}
+	return featureOf(indices)
+}
+
+// featureOf names the feature made up of the vertices with the given indices, in increasing
+// order. one vertex is a vertex and two are an edge, the index of those mixes in all of the
+// vertices so that different edges and faces of a shape get different ids.
+func featureOf(indices []int) (FeatureType, uint8) {
+	featureType := FeatureTypeFace
+	if len(indices) == 1 {
+		featureType = FeatureTypeVertex
+	} else if len(indices) == 2 {
+		featureType = FeatureTypeEdge
+	}
+
+	var index uint8
+	for _, i := range indices {
+		index = index*31 + uint8(i)
+	}
+	return featureType, index
+}
+
+func (c convexCore) project(axis mgl64.Vec3) (float64, float64) {
+	min, max := math.MaxFloat64, -math.MaxFloat64
+	for _, vertex := range c.vertices {
+		dot := vertex.Dot(axis)
+		min = math.Min(min, dot)
+		max = math.Max(max, dot)
+	}
+	return min, max
+}
+
+// closestPointsConvex returns the closest points between two shapes, the first on a and the
+// second on b, along with the normal pointing from b to a. the distance is negative when
+// the shapes overlap, in which case it's the penetration depth along the normal. the feature
+// id is made from the features of each shape that support the normal, the face rather than
+// whichever of its triangles the closest point landed on.
+func closestPointsConvex(a, b convexCore) ([2]mgl64.Vec3, mgl64.Vec3, float64, FeatureID) {
+	closestPoints, distance := gjkClosestPoints(a, b)
+	if distance > epsilon {
+		normal := closestPoints[0].Sub(closestPoints[1]).Mul(1 / distance)
+		return [2]mgl64.Vec3{
+			closestPoints[0].Sub(normal.Mul(a.radius)),
+			closestPoints[1].Add(normal.Mul(b.radius)),
+		}, normal, distance - a.radius - b.radius, supportFeatures(a, b, normal)
+	}
+
+	// the cores overlap so fall back to finding the axis of least penetration
+	normal, depth := satPenetration(a, b)
+	deepest := a.vertices[a.support(normal.Mul(-1))]
+	return [2]mgl64.Vec3{
+		deepest.Sub(normal.Mul(a.radius)),
+		deepest.Add(normal.Mul(depth + b.radius)),
+	}, normal, -(depth + a.radius + b.radius), supportFeatures(a, b, normal)
+}
+
+// supportFeatures returns the features of a and b that face each other along the normal
+// pointing from b to a
+func supportFeatures(a, b convexCore, normal mgl64.Vec3) FeatureID {
+	typeA, indexA := a.supportFeature(normal.Mul(-1))
+	typeB, indexB := b.supportFeature(normal)
+	return NewFeatureID(typeA, indexA, typeB, indexB)
+}
+
+// convexContact turns the closest points of two overlapping shapes into a contact
+func convexContact(a, b convexCore) (Contact, bool) {
+	closestPoints, normal, distance, featureID := closestPointsConvex(a, b)
+	if distance >= 0 {
+		return Contact{}, false
+	}
+
+	penetration := -distance
+	contact := Contact{
+		Point:              closestPoints[1],
+		Normal:             normal,
+		SeparatingVector:   normal.Mul(penetration),
+		SeparatingDistance: penetration,
+		Type:               ContactTypeConvex,
+	}
+	contact.addPoint(closestPoints[1], penetration, featureID)
+	return contact, true
+}
+
+type simplexVertex struct {
+	// w is a point on the minkowski difference a - b made from the points a and b
+	w mgl64.Vec3
+	a mgl64.Vec3
+	b mgl64.Vec3
+}
+
+// gjkClosestPoints finds the closest points between the cores of a and b, ignoring their
+// radius. the distance is zero when they overlap.
+// Real Time Collision Detection - page 399
+func gjkClosestPoints(a, b convexCore) ([2]mgl64.Vec3, float64) {
+	newVertex := func(direction mgl64.Vec3) simplexVertex {
+		pointA := a.vertices[a.support(direction)]
+		pointB := b.vertices[b.support(direction.Mul(-1))]
+		return simplexVertex{w: pointA.Sub(pointB), a: pointA, b: pointB}
+	}
+
+	simplex := []simplexVertex{{w: a.vertices[0].Sub(b.vertices[0]), a: a.vertices[0], b: b.vertices[0]}}
+	weights := []float64{1}
+	v := simplex[0].w
+
+	for i := 0; i < maxGJKIterations; i++ {
+		vLenSqr := v.LenSqr()
+		if vLenSqr < gjkTolerance {
+			break
+		}
+
+		next := newVertex(v.Mul(-1))
+		// no vertex gets us any closer to the origin
+		if vLenSqr-v.Dot(next.w) <= gjkTolerance*math.Max(1, vLenSqr) {
+			break
+		}
+
+		simplex = append(simplex, next)
+		var closest mgl64.Vec3
+		closest, simplex, weights = closestOnSimplex(simplex)
+		if len(simplex) == 4 || closest.LenSqr() >= vLenSqr {
+			v = closest
+			break
+		}
+		v = closest
+	}
+
+	var pointA, pointB mgl64.Vec3
+	for i, vertex := range simplex {
+		pointA = pointA.Add(vertex.a.Mul(weights[i]))
+		pointB = pointB.Add(vertex.b.Mul(weights[i]))
+	}
+	return [2]mgl64.Vec3{pointA, pointB}, v.Len()
+}
+
+// closestOnSimplex returns the point on the simplex closest to the origin, the smallest part
+// of the simplex that contains it and the barycentric weights of that point
+func closestOnSimplex(simplex []simplexVertex) (mgl64.Vec3, []simplexVertex, []float64) {
+	switch len(simplex) {
+	case 1:
+		return simplex[0].w, simplex, []float64{1}
+	case 2:
+		a, b := simplex[0].w, simplex[1].w
+		ab := b.Sub(a)
+		lenSqr := ab.LenSqr()
+		if lenSqr < gjkTolerance {
+			return a, simplex[:1], []float64{1}
+		}
+		t := -a.Dot(ab) / lenSqr
+		if t <= 0 {
+			return a, simplex[:1], []float64{1}
+		} else if t >= 1 {
+			return b, simplex[1:], []float64{1}
+		}
+		return a.Add(ab.Mul(t)), simplex, []float64{1 - t, t}
+	case 3:
+		return closestOnSimplexTriangle(simplex)
+	}
+	return closestOnSimplexTetrahedron(simplex)
+}
+
+func closestOnSimplexTriangle(simplex []simplexVertex) (mgl64.Vec3, []simplexVertex, []float64) {
+	point, barycentric := checks.ClosestPointOnTriangleToPoint(mgl64.Vec3{}, simplex[0].w, simplex[1].w, simplex[2].w)
+
+	// drop the vertices that don't contribute
+	var reduced []simplexVertex
+	var weights []float64
+	for i, weight := range barycentric {
+		if weight > 0 {
+			reduced = append(reduced, simplex[i])
+			weights = append(weights, weight)
+		}
+	}
+	return point, reduced, weights
+}
+
+func closestOnSimplexTetrahedron(simplex []simplexVertex) (mgl64.Vec3, []simplexVertex, []float64) {
+	faces := [4][4]int{{0, 1, 2, 3}, {0, 1, 3, 2}, {0, 2, 3, 1}, {1, 2, 3, 0}}
+
+	inside := true
+	bestDistance := math.MaxFloat64
+	var bestPoint mgl64.Vec3
+	var bestSimplex []simplexVertex
+	var bestWeights []float64
+
+	for _, face := range faces {
+		a, b, c, opposite := simplex[face[0]].w, simplex[face[1]].w, simplex[face[2]].w, simplex[face[3]].w
+		normal := b.Sub(a).Cross(c.Sub(a))
+		originSide := a.Mul(-1).Dot(normal)
+		oppositeSide := opposite.Sub(a).Dot(normal)
+
+		// the origin is on the same side of this face as the rest of the tetrahedron
+		if originSide*oppositeSide > 0 {
+			continue
+		}
+		inside = false
+
+		faceSimplex := []simplexVertex{simplex[face[0]], simplex[face[1]], simplex[face[2]]}
+		point, reduced, weights := closestOnSimplexTriangle(faceSimplex)
+		if distance := point.LenSqr(); distance < bestDistance {
+			bestDistance = distance
+			bestPoint, bestSimplex, bestWeights = point, reduced, weights
+		}
+	}
+
+	if inside {
+		return mgl64.Vec3{}, simplex, []float64{0.25, 0.25, 0.25, 0.25}
+	}
+	return bestPoint, bestSimplex, bestWeights
+}
+
+// satPenetration finds the axis that separates two overlapping cores with the least
+// movement. the normal points from b to a.
+func satPenetration(a, b convexCore) (mgl64.Vec3, float64) {
+	var axes []mgl64.Vec3
+	axes = append(axes, a.faceNormals...)
+	axes = append(axes, b.faceNormals...)
+	for _, edgeA := range a.edges {
+		for _, edgeB := range b.edges {
+			if axis := edgeA.Cross(edgeB); axis.Len() > epsilon {
+				axes = append(axes, axis.Normalize())
+			}
+		}
+	}
+	// points and parallel segments don't have any axes of their own
+	if len(axes) == 0 {
+		axes = []mgl64.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	}
+
+	bestNormal := mgl64.Vec3{0, 1, 0}
+	bestDepth := math.MaxFloat64
+	for _, axis := range axes {
+		minA, maxA := a.project(axis)
+		minB, maxB := b.project(axis)
+
+		if depth := maxB - minA; depth < bestDepth {
+			bestNormal, bestDepth = axis, depth
+		}
+		if depth := maxA - minB; depth < bestDepth {
+			bestNormal, bestDepth = axis.Mul(-1), depth
+		}
+	}
+	return bestNormal, bestDepth
+}
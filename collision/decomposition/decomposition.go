@@ -0,0 +1,354 @@
+// Package decomposition splits concave meshes into convex hulls so that they can be used as
+// colliders. it's a simplified take on V-HACD, the mesh is voxelized and the voxels are
+// split with axis aligned planes until every part is close enough to its convex hull.
+package decomposition
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/modelspec"
+)
+
+type Settings struct {
+	// Resolution is the number of voxels along the longest side of the mesh
+	Resolution int
+	MaxHulls   int
+	// Concavity is how much of the mesh's volume the empty space inside a hull can be before
+	// the hull is split
+	Concavity          float64
+	MaxVerticesPerHull int
+	// PlaneDownsampling only considers every nth plane when looking for where to split
+	PlaneDownsampling int
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		Resolution:         32,
+		MaxHulls:           16,
+		Concavity:          0.01,
+		MaxVerticesPerHull: 32,
+		PlaneDownsampling:  2,
+	}
+}
+
+// DecomposePrimitives decomposes the mesh made from the primitives
+func DecomposePrimitives(primitives []*modelspec.PrimitiveSpecification, settings Settings) []collider.ConvexHull {
+	return Decompose(*collider.CreateTriMeshFromPrimitives(primitives), settings)
+}
+
+// Decompose splits the mesh into at most settings.MaxHulls convex hulls. the mesh is expected
+// to be closed, holes let the outside leak in and only the surface is kept.
+func Decompose(mesh collider.TriMesh, settings Settings) []collider.ConvexHull {
+	grid, ok := voxelize(mesh, settings.Resolution)
+	if !ok {
+		return nil
+	}
+
+	var cells [][3]int
+	for i, solid := range grid.solid {
+		if solid {
+			cells = append(cells, grid.coordinates(i))
+		}
+	}
+	if len(cells) == 0 {
+		return nil
+	}
+
+	parts := []*part{grid.newPart(cells, len(cells))}
+	for len(parts) < settings.MaxHulls {
+		// always split the part that's the furthest from being convex
+		worst := -1
+		for i, p := range parts {
+			if p.concavity > settings.Concavity && len(p.cells) > 1 && (worst == -1 || p.concavity > parts[worst].concavity) {
+				worst = i
+			}
+		}
+		if worst == -1 {
+			break
+		}
+
+		left, right, ok := grid.split(parts[worst], settings.PlaneDownsampling, len(cells))
+		if !ok {
+			// nothing to split along, don't try again
+			parts[worst].concavity = 0
+			continue
+		}
+		parts[worst] = left
+		parts = append(parts, right)
+	}
+
+	var hulls []collider.ConvexHull
+	for _, p := range parts {
+		if !p.hullOK {
+			continue
+		}
+		hulls = append(hulls, p.hull.Simplify(settings.MaxVerticesPerHull))
+	}
+	return hulls
+}
+
+type voxelGrid struct {
+	// origin is the center of the first voxel
+	origin mgl64.Vec3
+	size   float64
+	dims   [3]int
+	solid  []bool
+}
+
+type part struct {
+	cells     [][3]int
+	hull      collider.ConvexHull
+	hullOK    bool
+	concavity float64
+}
+
+// voxelize marks the voxels that touch the surface of the mesh and fills in the ones the
+// surface encloses. voxel centers are lined up with the mesh's bounds so that flat sides
+// end up exactly on the hull.
+func voxelize(mesh collider.TriMesh, resolution int) (*voxelGrid, bool) {
+	var vertices []mgl64.Vec3
+	for _, triangle := range mesh.Triangles {
+		vertices = append(vertices, triangle.Points[:]...)
+	}
+	if len(vertices) == 0 || resolution < 1 {
+		return nil, false
+	}
+
+	bounds := collider.BoundingBoxFromVertices(vertices)
+	extents := bounds.MaxVertex.Sub(bounds.MinVertex)
+	size := math.Max(extents[0], math.Max(extents[1], extents[2])) / float64(resolution)
+	if size <= 0 {
+		return nil, false
+	}
+
+	grid := &voxelGrid{size: size}
+	for axis := 0; axis < 3; axis++ {
+		// a layer of padding on both sides so that the outside is connected
+		grid.dims[axis] = int(math.Ceil(extents[axis]/size-1e-9)) + 3
+		grid.origin[axis] = bounds.MinVertex[axis] - size
+	}
+	grid.solid = make([]bool, grid.dims[0]*grid.dims[1]*grid.dims[2])
+
+	half := mgl64.Vec3{size / 2, size / 2, size / 2}
+	for _, triangle := range mesh.Triangles {
+		box := collider.BoundingBoxFromVertices(triangle.Points[:])
+		min, max := grid.cell(box.MinVertex.Sub(half)), grid.cell(box.MaxVertex.Add(half))
+		for z := min[2]; z <= max[2]; z++ {
+			for y := min[1]; y <= max[1]; y++ {
+				for x := min[0]; x <= max[0]; x++ {
+					center := grid.center([3]int{x, y, z})
+					voxel := collider.BoundingBox{MinVertex: center.Sub(half), MaxVertex: center.Add(half)}
+					if _, hit := collision.CheckCollisionAABBTriangle(&voxel, triangle); hit {
+						grid.solid[grid.index([3]int{x, y, z})] = true
+					}
+				}
+			}
+		}
+	}
+
+	grid.fillInterior()
+	return grid, true
+}
+
+// fillInterior flood fills the outside from a padding voxel, everything it doesn't reach is
+// inside the mesh
+func (g *voxelGrid) fillInterior() {
+	outside := make([]bool, len(g.solid))
+	outside[0] = true
+	stack := [][3]int{{0, 0, 0}}
+	for len(stack) > 0 {
+		cell := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, neighbor := range neighbors(cell) {
+			if !g.inBounds(neighbor) {
+				continue
+			}
+			index := g.index(neighbor)
+			if outside[index] || g.solid[index] {
+				continue
+			}
+			outside[index] = true
+			stack = append(stack, neighbor)
+		}
+	}
+
+	for i := range g.solid {
+		g.solid[i] = !outside[i]
+	}
+}
+
+// split finds the axis aligned plane that cuts the part into two parts with the least total
+// hull volume. since the voxels are the same either way, that's also the cut that leaves the
+// least empty space in the hulls.
+func (g *voxelGrid) split(p *part, downsampling int, totalCells int) (*part, *part, bool) {
+	if downsampling < 1 {
+		downsampling = 1
+	}
+
+	min, max := p.cells[0], p.cells[0]
+	for _, cell := range p.cells {
+		for axis := 0; axis < 3; axis++ {
+			min[axis] = minInt(min[axis], cell[axis])
+			max[axis] = maxInt(max[axis], cell[axis])
+		}
+	}
+
+	bestVolume := math.MaxFloat64
+	var bestAxis, bestPlane int
+	found := false
+	for axis := 0; axis < 3; axis++ {
+		for plane := min[axis] + 1; plane <= max[axis]; plane += downsampling {
+			left, right := splitCells(p.cells, axis, plane)
+			leftHull, leftOK := g.hull(left)
+			rightHull, rightOK := g.hull(right)
+			volume := leftHull.Volume() + rightHull.Volume()
+			if !leftOK || !rightOK {
+				// flat parts don't have a hull, fall back to their voxels
+				volume = g.cellVolume(left, leftHull, leftOK) + g.cellVolume(right, rightHull, rightOK)
+			}
+			if volume < bestVolume {
+				bestVolume, bestAxis, bestPlane, found = volume, axis, plane, true
+			}
+		}
+	}
+	if !found {
+		return nil, nil, false
+	}
+
+	left, right := splitCells(p.cells, bestAxis, bestPlane)
+	return g.newPart(left, totalCells), g.newPart(right, totalCells), true
+}
+
+func (g *voxelGrid) cellVolume(cells [][3]int, hull collider.ConvexHull, ok bool) float64 {
+	if ok {
+		return hull.Volume()
+	}
+	return float64(len(cells)) * g.size * g.size * g.size
+}
+
+// newPart builds the hull around the cells and measures how much empty space is in it as a
+// fraction of the whole mesh
+func (g *voxelGrid) newPart(cells [][3]int, totalCells int) *part {
+	p := &part{cells: cells}
+	p.hull, p.hullOK = g.hull(cells)
+	if !p.hullOK {
+		return p
+	}
+
+	inside := 0
+	tolerance := g.size * 1e-3
+	box := p.hull.BoundingBox()
+	min, max := g.cell(box.MinVertex), g.cell(box.MaxVertex)
+	for z := min[2]; z <= max[2]; z++ {
+		for y := min[1]; y <= max[1]; y++ {
+			for x := min[0]; x <= max[0]; x++ {
+				if p.hull.ContainsPoint(g.center([3]int{x, y, z}), tolerance) {
+					inside++
+				}
+			}
+		}
+	}
+	p.concavity = float64(inside-len(cells)) / float64(totalCells)
+	return p
+}
+
+// hull builds the convex hull of the voxel centers. only the first and last voxel of each row
+// along x can be on the hull so the rest are skipped.
+func (g *voxelGrid) hull(cells [][3]int) (collider.ConvexHull, bool) {
+	type row struct{ min, max int }
+	rows := map[[2]int]*row{}
+	var order [][2]int
+	for _, cell := range cells {
+		key := [2]int{cell[1], cell[2]}
+		r, ok := rows[key]
+		if !ok {
+			rows[key] = &row{min: cell[0], max: cell[0]}
+			order = append(order, key)
+			continue
+		}
+		r.min = minInt(r.min, cell[0])
+		r.max = maxInt(r.max, cell[0])
+	}
+
+	var points []mgl64.Vec3
+	for _, key := range order {
+		r := rows[key]
+		points = append(points, g.center([3]int{r.min, key[0], key[1]}))
+		if r.max != r.min {
+			points = append(points, g.center([3]int{r.max, key[0], key[1]}))
+		}
+	}
+	return collider.NewConvexHull(points)
+}
+
+func splitCells(cells [][3]int, axis int, plane int) ([][3]int, [][3]int) {
+	var left, right [][3]int
+	for _, cell := range cells {
+		if cell[axis] < plane {
+			left = append(left, cell)
+		} else {
+			right = append(right, cell)
+		}
+	}
+	return left, right
+}
+
+func (g *voxelGrid) index(cell [3]int) int {
+	return cell[0] + g.dims[0]*(cell[1]+g.dims[1]*cell[2])
+}
+
+func (g *voxelGrid) coordinates(index int) [3]int {
+	x := index % g.dims[0]
+	y := (index / g.dims[0]) % g.dims[1]
+	z := index / (g.dims[0] * g.dims[1])
+	return [3]int{x, y, z}
+}
+
+func (g *voxelGrid) center(cell [3]int) mgl64.Vec3 {
+	return mgl64.Vec3{
+		g.origin[0] + float64(cell[0])*g.size,
+		g.origin[1] + float64(cell[1])*g.size,
+		g.origin[2] + float64(cell[2])*g.size,
+	}
+}
+
+// cell returns the voxel that contains the point, clamped to the grid
+func (g *voxelGrid) cell(point mgl64.Vec3) [3]int {
+	var cell [3]int
+	for axis := 0; axis < 3; axis++ {
+		index := int(math.Floor((point[axis]-g.origin[axis])/g.size + 0.5))
+		cell[axis] = maxInt(0, minInt(g.dims[axis]-1, index))
+	}
+	return cell
+}
+
+func (g *voxelGrid) inBounds(cell [3]int) bool {
+	for axis := 0; axis < 3; axis++ {
+		if cell[axis] < 0 || cell[axis] >= g.dims[axis] {
+			return false
+		}
+	}
+	return true
+}
+
+func neighbors(cell [3]int) [6][3]int {
+	x, y, z := cell[0], cell[1], cell[2]
+	return [6][3]int{{x - 1, y, z}, {x + 1, y, z}, {x, y - 1, z}, {x, y + 1, z}, {x, y, z - 1}, {x, y, z + 1}}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,99 @@
+package decomposition_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/collision/decomposition"
+)
+
+func boxTriangles(min, max mgl64.Vec3) []collider.Triangle {
+	v := func(x, y, z int) mgl64.Vec3 {
+		return mgl64.Vec3{[]float64{min[0], max[0]}[x], []float64{min[1], max[1]}[y], []float64{min[2], max[2]}[z]}
+	}
+	quads := [][4]mgl64.Vec3{
+		{v(0, 0, 0), v(0, 0, 1), v(0, 1, 1), v(0, 1, 0)},
+		{v(1, 0, 0), v(1, 1, 0), v(1, 1, 1), v(1, 0, 1)},
+		{v(0, 0, 0), v(1, 0, 0), v(1, 0, 1), v(0, 0, 1)},
+		{v(0, 1, 0), v(0, 1, 1), v(1, 1, 1), v(1, 1, 0)},
+		{v(0, 0, 0), v(0, 1, 0), v(1, 1, 0), v(1, 0, 0)},
+		{v(0, 0, 1), v(1, 0, 1), v(1, 1, 1), v(0, 1, 1)},
+	}
+
+	var triangles []collider.Triangle
+	for _, q := range quads {
+		triangles = append(triangles, collider.NewTriangle([3]mgl64.Vec3{q[0], q[1], q[2]}))
+		triangles = append(triangles, collider.NewTriangle([3]mgl64.Vec3{q[0], q[2], q[3]}))
+	}
+	return triangles
+}
+
+func totalVolume(hulls []collider.ConvexHull) float64 {
+	var volume float64
+	for _, hull := range hulls {
+		volume += hull.Volume()
+	}
+	return volume
+}
+
+func TestDecomposeBox(t *testing.T) {
+	mesh := collider.TriMesh{Triangles: boxTriangles(mgl64.Vec3{0, 0, 0}, mgl64.Vec3{2, 1, 1})}
+	settings := decomposition.DefaultSettings()
+	settings.Resolution = 16
+
+	hulls := decomposition.Decompose(mesh, settings)
+	if len(hulls) != 1 {
+		t.Fatalf("expected a box to already be convex but got %d hulls", len(hulls))
+	}
+	if len(hulls[0].Points) != 8 {
+		t.Errorf("expected the hull to be the box's 8 corners but got %d points", len(hulls[0].Points))
+	}
+	if volume := hulls[0].Volume(); math.Abs(volume-2) > 1e-6 {
+		t.Errorf("expected a volume of 2 but got %f", volume)
+	}
+}
+
+func TestDecomposeLShape(t *testing.T) {
+	var triangles []collider.Triangle
+	triangles = append(triangles, boxTriangles(mgl64.Vec3{0, 0, 0}, mgl64.Vec3{2, 1, 1})...)
+	triangles = append(triangles, boxTriangles(mgl64.Vec3{0, 0, 0}, mgl64.Vec3{1, 2, 1})...)
+	settings := decomposition.DefaultSettings()
+	settings.Resolution = 16
+
+	hulls := decomposition.Decompose(collider.TriMesh{Triangles: triangles}, settings)
+	if len(hulls) < 2 {
+		t.Fatalf("expected the L to be split into at least 2 hulls but got %d", len(hulls))
+	}
+	if len(hulls) > settings.MaxHulls {
+		t.Errorf("expected at most %d hulls but got %d", settings.MaxHulls, len(hulls))
+	}
+	// the L's own hull would be 3.5
+	if volume := totalVolume(hulls); math.Abs(volume-3) > 0.15 {
+		t.Errorf("expected the hulls to have about the L's volume of 3 but got %f", volume)
+	}
+	for i, hull := range hulls {
+		if len(hull.Points) > settings.MaxVerticesPerHull {
+			t.Errorf("expected hull %d to have at most %d vertices but got %d", i, settings.MaxVerticesPerHull, len(hull.Points))
+		}
+	}
+}
+
+func TestDecomposeRespectsMaxHulls(t *testing.T) {
+	var triangles []collider.Triangle
+	// a comb with four teeth
+	triangles = append(triangles, boxTriangles(mgl64.Vec3{0, 0, 0}, mgl64.Vec3{7, 1, 1})...)
+	for i := 0; i < 4; i++ {
+		x := float64(i * 2)
+		triangles = append(triangles, boxTriangles(mgl64.Vec3{x, 0, 0}, mgl64.Vec3{x + 1, 3, 1})...)
+	}
+	settings := decomposition.DefaultSettings()
+	settings.Resolution = 14
+	settings.MaxHulls = 3
+
+	hulls := decomposition.Decompose(collider.TriMesh{Triangles: triangles}, settings)
+	if len(hulls) != 3 {
+		t.Errorf("expected the comb to be split into exactly 3 hulls but got %d", len(hulls))
+	}
+}
@@ -0,0 +1,172 @@
+package collision
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+// CheckCollision picks the check for a pair of colliders. the contact follows the usual
+// convention where a is collider A and b is collider B. false is returned when the colliders
+// don't touch or when the pair isn't supported, like two rays.
+func CheckCollision(a, b collider.Collider) (Contact, bool) {
+	a, b = normalizeCollider(a), normalizeCollider(b)
+	if colliderRank(a) > colliderRank(b) {
+		contact, collided := checkCollisionOrdered(b, a)
+		return contact.Flip(), collided
+	}
+	return checkCollisionOrdered(a, b)
+}
+
+// ClosestPoints returns the closest points between two colliders, the first on a and the
+// second on b. the distance is negative when they overlap, in which case it's the
+// penetration depth. rays aren't supported.
+func ClosestPoints(a, b collider.Collider) ([2]mgl64.Vec3, float64, bool) {
+	coreA, ok := convexCoreFor(normalizeCollider(a))
+	if !ok {
+		return [2]mgl64.Vec3{}, 0, false
+	}
+	coreB, ok := convexCoreFor(normalizeCollider(b))
+	if !ok {
+		return [2]mgl64.Vec3{}, 0, false
+	}
+
+	closestPoints, _, distance, _ := closestPointsConvex(coreA, coreB)
+	return closestPoints, distance, true
+}
+
+func checkCollisionOrdered(a, b collider.Collider) (Contact, bool) {
+	switch a := a.(type) {
+	case collider.Sphere:
+		switch b := b.(type) {
+		case collider.Sphere:
+			return CheckCollisionSphereSphere(a, b)
+		case collider.Capsule:
+			return CheckCollisionSphereCapsule(a, b)
+		case collider.BoundingBox:
+			return CheckCollisionSphereAABB(a, &b)
+		case collider.OrientedBoundingBox:
+			return CheckCollisionSphereOBB(a, b)
+		case collider.Triangle:
+			return CheckCollisionSphereTriangle(a, b)
+		case collider.Ray:
+			return flipped(CheckCollisionRaySphere(b, a))
+		}
+	case collider.Capsule:
+		switch b := b.(type) {
+		case collider.Capsule:
+			return CheckCollisionCapsuleCapsule(a, b)
+		case collider.BoundingBox:
+			return CheckCollisionCapsuleAABB(a, &b)
+		case collider.OrientedBoundingBox:
+			return CheckCollisionCapsuleOBB(a, b)
+		case collider.Triangle:
+			return CheckCollisionCapsuleTriangle(a, b)
+		case collider.Ray:
+			return flipped(CheckCollisionRayCapsule(b, a))
+		}
+	case collider.BoundingBox:
+		switch b := b.(type) {
+		case collider.BoundingBox:
+			return CheckCollisionAABBAABB(&a, &b)
+		case collider.OrientedBoundingBox:
+			return CheckCollisionAABBOBB(&a, b)
+		case collider.Triangle:
+			return CheckCollisionAABBTriangle(&a, b)
+		case collider.Ray:
+			return flipped(CheckCollisionRayAABB(b, &a))
+		}
+	case collider.OrientedBoundingBox:
+		switch b := b.(type) {
+		case collider.OrientedBoundingBox:
+			return CheckCollisionOBBOBB(a, b)
+		case collider.Triangle:
+			return CheckCollisionOBBTriangle(a, b)
+		case collider.Ray:
+			return flipped(CheckCollisionRayOBB(b, a))
+		}
+	case collider.Triangle:
+		switch b := b.(type) {
+		case collider.Triangle:
+			return CheckCollisionTriangleTriangle(a, b)
+		case collider.Ray:
+			return flipped(CheckCollisionRayTriangle(b, a))
+		}
+	case collider.ConvexHull:
+		if b, ok := b.(collider.Ray); ok {
+			return flipped(CheckCollisionRayConvexHull(b, a))
+		}
+	}
+
+	// the rest of the pairs are between a hull and another convex shape
+	coreA, okA := convexCoreFor(a)
+	coreB, okB := convexCoreFor(b)
+	if okA && okB {
+		return convexContact(coreA, coreB)
+	}
+	return Contact{}, false
+}
+
+func flipped(contact Contact, collided bool) (Contact, bool) {
+	return contact.Flip(), collided
+}
+
+// normalizeCollider turns pointers into values so that the type switches only deal with values
+func normalizeCollider(c collider.Collider) collider.Collider {
+	switch c := c.(type) {
+	case *collider.Sphere:
+		return *c
+	case *collider.Capsule:
+		return *c
+	case *collider.BoundingBox:
+		return *c
+	case *collider.OrientedBoundingBox:
+		return *c
+	case *collider.Triangle:
+		return *c
+	case *collider.Ray:
+		return *c
+	case *collider.ConvexHull:
+		return *c
+	}
+	return c
+}
+
+// colliderRank is the order colliders are passed to the pairwise checks in, a pair is checked
+// with the lower ranked collider first and flipped if needed
+func colliderRank(c collider.Collider) int {
+	switch c.(type) {
+	case collider.Sphere:
+		return 0
+	case collider.Capsule:
+		return 1
+	case collider.BoundingBox:
+		return 2
+	case collider.OrientedBoundingBox:
+		return 3
+	case collider.Triangle:
+		return 4
+	case collider.ConvexHull:
+		return 5
+	case collider.Ray:
+		return 6
+	}
+	return -1
+}
+
+func convexCoreFor(c collider.Collider) (convexCore, bool) {
+	switch c := c.(type) {
+	case collider.Sphere:
+		return sphereCore(c), true
+	case collider.Capsule:
+		return capsuleCore(c), true
+	case collider.BoundingBox:
+		return obbCore(collider.OrientedBoundingBoxFromAABB(c)), true
+	case collider.OrientedBoundingBox:
+		return obbCore(c), true
+	case collider.Triangle:
+		return triangleCore(c), true
+	case collider.ConvexHull:
+		return convexHullCore(c), true
+	}
+	return convexCore{}, false
+}
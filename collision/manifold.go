@@ -0,0 +1,74 @@
+package collision
+
+import "github.com/go-gl/mathgl/mgl64"
+
+// MaxManifoldPoints is the maximum number of contact points a single Contact can carry
+const MaxManifoldPoints = 4
+
+type FeatureType uint8
+
+const (
+	FeatureTypeVertex FeatureType = iota
+	FeatureTypeEdge
+	FeatureTypeFace
+)
+
+// FeatureID identifies the pair of features (vertex, edge, face) that produced a contact
+// point. a solver can match feature ids across frames to carry over accumulated impulses
+// for warm starting.
+type FeatureID uint32
+
+func NewFeatureID(typeA FeatureType, indexA uint8, typeB FeatureType, indexB uint8) FeatureID {
+	return FeatureID(uint32(typeA)<<24 | uint32(indexA)<<16 | uint32(typeB)<<8 | uint32(indexB))
+}
+
+func (f FeatureID) FeatureA() (FeatureType, uint8) {
+	return FeatureType(f >> 24), uint8(f >> 16)
+}
+
+func (f FeatureID) FeatureB() (FeatureType, uint8) {
+	return FeatureType(f >> 8), uint8(f)
+}
+
+// Flip swaps the A and B features
+func (f FeatureID) Flip() FeatureID {
+	typeA, indexA := f.FeatureA()
+	typeB, indexB := f.FeatureB()
+	return NewFeatureID(typeB, indexB, typeA, indexA)
+}
+
+// ContactPoint is a single point in a contact manifold. Point lies on the surface of
+// collider B and Penetration is how far collider A is pushed into B at that point. box
+// manifolds can include points that are just short of touching with a small negative
+// Penetration.
+type ContactPoint struct {
+	Point       mgl64.Vec3
+	Penetration float64
+	FeatureID   FeatureID
+}
+
+func (c *Contact) addPoint(point mgl64.Vec3, penetration float64, featureID FeatureID) {
+	if c.PointCount >= MaxManifoldPoints {
+		return
+	}
+	c.Points[c.PointCount] = ContactPoint{Point: point, Penetration: penetration, FeatureID: featureID}
+	c.PointCount++
+}
+
+// ManifoldPoints returns the valid contact points of the manifold
+func (c *Contact) ManifoldPoints() []ContactPoint {
+	return c.Points[:c.PointCount]
+}
+
+// Flip returns the contact from the perspective of collider B. the normal and separating
+// vector are negated and the packed indices and features are swapped. contact points stay
+// where they are.
+func (c Contact) Flip() Contact {
+	c.PackedIndexA, c.PackedIndexB = c.PackedIndexB, c.PackedIndexA
+	c.Normal = c.Normal.Mul(-1)
+	c.SeparatingVector = c.SeparatingVector.Mul(-1)
+	for i := 0; i < c.PointCount; i++ {
+		c.Points[i].FeatureID = c.Points[i].FeatureID.Flip()
+	}
+	return c
+}
@@ -0,0 +1,159 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/checks"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+func CheckCollisionSphereSphere(sphere1 collider.Sphere, sphere2 collider.Sphere) (Contact, bool) {
+	return convexContact(sphereCore(sphere1), sphereCore(sphere2))
+}
+
+func CheckCollisionSphereCapsule(sphere collider.Sphere, capsule collider.Capsule) (Contact, bool) {
+	return convexContact(sphereCore(sphere), capsuleCore(capsule))
+}
+
+func CheckCollisionSphereOBB(sphere collider.Sphere, obb collider.OrientedBoundingBox) (Contact, bool) {
+	return convexContact(sphereCore(sphere), obbCore(obb))
+}
+
+func CheckCollisionSphereTriangle(sphere collider.Sphere, triangle collider.Triangle) (Contact, bool) {
+	return convexContact(sphereCore(sphere), triangleCore(triangle))
+}
+
+func CheckCollisionCapsuleAABB(capsule collider.Capsule, aabb *collider.BoundingBox) (Contact, bool) {
+	return CheckCollisionCapsuleOBB(capsule, collider.OrientedBoundingBoxFromAABB(*aabb))
+}
+
+func CheckCollisionCapsuleOBB(capsule collider.Capsule, obb collider.OrientedBoundingBox) (Contact, bool) {
+	contact, collided := convexContact(capsuleCore(capsule), obbCore(obb))
+	if collided {
+		capsuleBoxFaceManifold(&contact, capsule, obb)
+	}
+	return contact, collided
+}
+
+func CheckCollisionAABBOBB(aabb *collider.BoundingBox, obb collider.OrientedBoundingBox) (Contact, bool) {
+	return boxContact(collider.OrientedBoundingBoxFromAABB(*aabb), obb)
+}
+
+func CheckCollisionAABBTriangle(aabb *collider.BoundingBox, triangle collider.Triangle) (Contact, bool) {
+	return convexContact(obbCore(collider.OrientedBoundingBoxFromAABB(*aabb)), triangleCore(triangle))
+}
+
+func CheckCollisionOBBOBB(obb1 collider.OrientedBoundingBox, obb2 collider.OrientedBoundingBox) (Contact, bool) {
+	return boxContact(obb1, obb2)
+}
+
+func CheckCollisionOBBTriangle(obb collider.OrientedBoundingBox, triangle collider.Triangle) (Contact, bool) {
+	return convexContact(obbCore(obb), triangleCore(triangle))
+}
+
+func CheckCollisionTriangleTriangle(triangle1 collider.Triangle, triangle2 collider.Triangle) (Contact, bool) {
+	return convexContact(triangleCore(triangle1), triangleCore(triangle2))
+}
+
+func CheckCollisionConvexHullConvexHull(hull1 collider.ConvexHull, hull2 collider.ConvexHull) (Contact, bool) {
+	return convexContact(convexHullCore(hull1), convexHullCore(hull2))
+}
+
+// rays don't penetrate, so ray contacts have Point set to where the ray enters the collider
+// and Normal set to the collider's surface normal there
+
+func CheckCollisionRaySphere(ray collider.Ray, sphere collider.Sphere) (Contact, bool) {
+	point, hit := checks.IntersectRaySphere(ray, sphere)
+	if !hit {
+		return Contact{}, false
+	}
+	return rayContact(ray, point, point.Sub(sphere.Center)), true
+}
+
+func CheckCollisionRayCapsule(ray collider.Ray, capsule collider.Capsule) (Contact, bool) {
+	point, hit := checks.IntersectRayCapsule(ray, capsule)
+	if !hit {
+		return Contact{}, false
+	}
+	return rayContact(ray, point, point.Sub(checks.ClosestPointOnLineToPoint(capsule.Top, capsule.Bottom, point))), true
+}
+
+func CheckCollisionRayAABB(ray collider.Ray, aabb *collider.BoundingBox) (Contact, bool) {
+	return CheckCollisionRayOBB(ray, collider.OrientedBoundingBoxFromAABB(*aabb))
+}
+
+func CheckCollisionRayOBB(ray collider.Ray, obb collider.OrientedBoundingBox) (Contact, bool) {
+	point, hit := checks.IntersectRayOBB(ray, obb)
+	if !hit {
+		return Contact{}, false
+	}
+
+	// the face the point is closest to
+	delta := point.Sub(obb.Center)
+	var normal mgl64.Vec3
+	minDistance := math.MaxFloat64
+	for i, axis := range obb.Axes {
+		dot := delta.Dot(axis)
+		if distance := obb.HalfExtents[i] - math.Abs(dot); distance < minDistance {
+			minDistance = distance
+			normal = axis
+			if dot < 0 {
+				normal = axis.Mul(-1)
+			}
+		}
+	}
+	return rayContact(ray, point, normal), true
+}
+
+func CheckCollisionRayTriangle(ray collider.Ray, triangle collider.Triangle) (Contact, bool) {
+	point, hit := checks.IntersectRayTriangle(ray, triangle)
+	if !hit {
+		return Contact{}, false
+	}
+
+	// face the ray
+	normal := triangle.Normal
+	if normal.Dot(ray.Direction) > 0 {
+		normal = normal.Mul(-1)
+	}
+	return rayContact(ray, point, normal), true
+}
+
+func CheckCollisionRayConvexHull(ray collider.Ray, hull collider.ConvexHull) (Contact, bool) {
+	if hull.ContainsPoint(ray.Origin, 0) {
+		return rayContact(ray, ray.Origin, mgl64.Vec3{}), true
+	}
+
+	var closest Contact
+	var hit bool
+	minDistance := math.MaxFloat64
+	for _, triangle := range hull.Triangles() {
+		// only faces pointing towards the ray can be where it enters
+		if triangle.Normal.Dot(ray.Direction) >= 0 {
+			continue
+		}
+		if contact, collided := CheckCollisionRayTriangle(ray, triangle); collided {
+			if distance := contact.Point.Sub(ray.Origin).LenSqr(); distance < minDistance {
+				closest, hit, minDistance = contact, true, distance
+			}
+		}
+	}
+	return closest, hit
+}
+
+func rayContact(ray collider.Ray, point mgl64.Vec3, normal mgl64.Vec3) Contact {
+	if normal.LenSqr() < epsilon*epsilon {
+		// the ray started inside the collider
+		normal = ray.Direction.Mul(-1)
+	}
+	normal = normal.Normalize()
+
+	contact := Contact{
+		Point:  point,
+		Normal: normal,
+		Type:   ContactTypeRay,
+	}
+	contact.addPoint(point, 0, NewFeatureID(FeatureTypeVertex, 0, FeatureTypeFace, 0))
+	return contact
+}
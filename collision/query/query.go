@@ -0,0 +1,434 @@
+// Package query answers questions about what's where in a scene, such as what a ray hits
+// or what a capsule would run into if it moved. objects are indexed in a broadphase so only
+// the ones near a query are tested.
+package query
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/spatialpartition"
+)
+
+const AllLayers uint32 = math.MaxUint32
+
+// Object is something in the scene that queries can hit. the shape is in world space and
+// is either Collider or TriMesh.
+type Object struct {
+	ID       int
+	Layer    uint32
+	Collider collider.Collider
+	TriMesh  *collider.TriMesh
+}
+
+// Filter decides which objects a query considers. a zero Mask matches every layer and a nil
+// Predicate accepts everything.
+type Filter struct {
+	Mask      uint32
+	Predicate func(object *Object) bool
+}
+
+func (f Filter) accepts(object *Object) bool {
+	if f.Mask != 0 && object.Layer&f.Mask == 0 {
+		return false
+	}
+	return f.Predicate == nil || f.Predicate(object)
+}
+
+// Hit is where a query touched an object. for raycasts and sweeps Distance is how far along
+// the query the hit happened and Normal points away from the object. for overlaps Distance
+// is the penetration depth and Normal points from the object towards the query shape.
+type Hit struct {
+	ID       int
+	Point    mgl64.Vec3
+	Normal   mgl64.Vec3
+	Distance float64
+}
+
+type Scene struct {
+	partition spatialpartition.Broadphase
+	objects   map[int]*Object
+	// bounds covers every object that's been added, it isn't shrunk when objects are removed
+	bounds collider.BoundingBox
+
+	// guards objects, bounds and the partition
+	mu sync.RWMutex
+}
+
+// NewScene creates a scene whose objects are indexed in the partition, which should be empty
+// and not used by anything else afterwards
+func NewScene(partition spatialpartition.Broadphase) *Scene {
+	return &Scene{
+		partition: partition,
+		objects:   map[int]*Object{},
+	}
+}
+
+// Add adds the object to the scene, replacing the object with the same ID if there is one.
+// objects that move should be added again with their new shape.
+func (s *Scene) Add(object Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	box := objectBoundingBox(&object)
+	if len(s.objects) == 0 {
+		s.bounds = box
+	} else {
+		s.bounds = collider.BoundingBoxFromVertices([]mgl64.Vec3{s.bounds.MinVertex, s.bounds.MaxVertex, box.MinVertex, box.MaxVertex})
+	}
+
+	s.partition.DeleteEntity(object.ID)
+	s.objects[object.ID] = &object
+	s.partition.IndexEntities([]spatialpartition.Entity{objectEntity{object: &object, box: box}})
+}
+
+func (s *Scene) Remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.partition.DeleteEntity(id)
+	delete(s.objects, id)
+}
+
+func (s *Scene) Object(id int) (*Object, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	object, ok := s.objects[id]
+	return object, ok
+}
+
+// RaycastClosest returns the closest hit along the ray within maxDistance
+func (s *Scene) RaycastClosest(ray collider.Ray, maxDistance float64, filter Filter) (Hit, bool) {
+	ray.Direction = ray.Direction.Normalize()
+	var closest Hit
+	var hit bool
+	for _, object := range s.candidates(s.rayBoundingBox(ray, maxDistance), filter) {
+		if h, ok := raycastObject(ray, maxDistance, object); ok && (!hit || h.Distance < closest.Distance) {
+			closest, hit = h, true
+		}
+	}
+	return closest, hit
+}
+
+// RaycastAny returns the first hit that's found along the ray, which isn't necessarily the
+// closest. it's cheaper than RaycastClosest for line of sight checks.
+func (s *Scene) RaycastAny(ray collider.Ray, maxDistance float64, filter Filter) (Hit, bool) {
+	ray.Direction = ray.Direction.Normalize()
+	for _, object := range s.candidates(s.rayBoundingBox(ray, maxDistance), filter) {
+		if h, ok := raycastObject(ray, maxDistance, object); ok {
+			return h, true
+		}
+	}
+	return Hit{}, false
+}
+
+// RaycastAll returns every object the ray hits within maxDistance sorted from closest to
+// furthest. objects are only hit once, where the ray first enters them.
+func (s *Scene) RaycastAll(ray collider.Ray, maxDistance float64, filter Filter) []Hit {
+	ray.Direction = ray.Direction.Normalize()
+	var hits []Hit
+	for _, object := range s.candidates(s.rayBoundingBox(ray, maxDistance), filter) {
+		if h, ok := raycastObject(ray, maxDistance, object); ok {
+			hits = append(hits, h)
+		}
+	}
+	sortHits(hits)
+	return hits
+}
+
+// SweepSphere returns the first object the sphere runs into when moved along delta
+func (s *Scene) SweepSphere(sphere collider.Sphere, delta mgl64.Vec3, filter Filter) (Hit, bool) {
+	return s.SweepCapsule(collider.NewCapsule(sphere.Center, sphere.Center, sphere.Radius), delta, filter)
+}
+
+// SweepCapsule returns the first object the capsule runs into when moved along delta
+func (s *Scene) SweepCapsule(capsule collider.Capsule, delta mgl64.Vec3, filter Filter) (Hit, bool) {
+	box := *collider.BoundingBoxFromCapsule(capsule)
+	moved := *collider.BoundingBoxFromCapsule(collider.NewCapsule(capsule.Top.Add(delta), capsule.Bottom.Add(delta), capsule.Radius))
+	sweptBox := collider.BoundingBoxFromVertices([]mgl64.Vec3{box.MinVertex, box.MaxVertex, moved.MinVertex, moved.MaxVertex})
+
+	length := delta.Len()
+	var closest Hit
+	var hit bool
+	for _, object := range s.candidates(sweptBox, filter) {
+		var sweepHit collision.SweepHit
+		var ok bool
+		if object.TriMesh != nil {
+			sweepHit, ok = collision.SweepCapsuleTriMesh(capsule, delta, *object.TriMesh)
+		} else {
+			sweepHit, ok = collision.SweepCapsuleCollider(capsule, delta, object.Collider)
+		}
+		if !ok {
+			continue
+		}
+
+		h := Hit{ID: object.ID, Point: sweepHit.Point, Normal: sweepHit.Normal, Distance: sweepHit.TimeOfImpact * length}
+		if !hit || h.Distance < closest.Distance {
+			closest, hit = h, true
+		}
+	}
+	return closest, hit
+}
+
+// Overlap returns every object that overlaps the collider sorted by ID
+func (s *Scene) Overlap(c collider.Collider, filter Filter) []Hit {
+	var hits []Hit
+	for _, object := range s.candidates(colliderBoundingBox(c), filter) {
+		if h, ok := overlapObject(c, object); ok {
+			hits = append(hits, h)
+		}
+	}
+	return hits
+}
+
+type QueryType int
+
+const (
+	QueryTypeRaycastClosest QueryType = iota
+	QueryTypeRaycastAny
+	QueryTypeRaycastAll
+	QueryTypeSweepSphere
+	QueryTypeSweepCapsule
+	QueryTypeOverlap
+)
+
+// Query is one query in a batch. only the fields used by its type need to be set:
+// raycasts use Ray and MaxDistance, sweeps use Sphere or Capsule and Delta and overlaps
+// use Collider.
+type Query struct {
+	Type   QueryType
+	Filter Filter
+
+	Ray         collider.Ray
+	MaxDistance float64
+
+	Sphere  collider.Sphere
+	Capsule collider.Capsule
+	Delta   mgl64.Vec3
+
+	Collider collider.Collider
+}
+
+// Batch runs the queries across multiple goroutines and returns the hits for each query in
+// the same order. queries that return a single hit have at most one.
+func (s *Scene) Batch(queries []Query) [][]Hit {
+	results := make([][]Hit, len(queries))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(queries) {
+		workers = len(queries)
+	}
+
+	var wg sync.WaitGroup
+	indices := make(chan int, len(queries))
+	for i := range queries {
+		indices <- i
+	}
+	close(indices)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = s.run(queries[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (s *Scene) run(q Query) []Hit {
+	single := func(hit Hit, ok bool) []Hit {
+		if !ok {
+			return nil
+		}
+		return []Hit{hit}
+	}
+
+	switch q.Type {
+	case QueryTypeRaycastClosest:
+		return single(s.RaycastClosest(q.Ray, q.MaxDistance, q.Filter))
+	case QueryTypeRaycastAny:
+		return single(s.RaycastAny(q.Ray, q.MaxDistance, q.Filter))
+	case QueryTypeRaycastAll:
+		return s.RaycastAll(q.Ray, q.MaxDistance, q.Filter)
+	case QueryTypeSweepSphere:
+		return single(s.SweepSphere(q.Sphere, q.Delta, q.Filter))
+	case QueryTypeSweepCapsule:
+		return single(s.SweepCapsule(q.Capsule, q.Delta, q.Filter))
+	case QueryTypeOverlap:
+		return s.Overlap(q.Collider, q.Filter)
+	}
+	return nil
+}
+
+// candidates returns the objects near the bounding box that pass the filter sorted by ID so
+// that results don't depend on how the partition stores them
+func (s *Scene) candidates(box collider.BoundingBox, filter Filter) []*Object {
+	s.mu.RLock()
+	entities := s.partition.QueryEntities(box)
+	s.mu.RUnlock()
+
+	var objects []*Object
+	for _, entity := range entities {
+		e := entity.(objectEntity)
+		if !collision.CheckOverlapAABBAABB(&e.box, &box) || !filter.accepts(e.object) {
+			continue
+		}
+		objects = append(objects, e.object)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].ID < objects[j].ID })
+	return objects
+}
+
+func raycastObject(ray collider.Ray, maxDistance float64, object *Object) (Hit, bool) {
+	var contact collision.Contact
+	var ok bool
+	if object.TriMesh != nil {
+		contact, ok = raycastTriMesh(ray, *object.TriMesh)
+	} else {
+		contact, ok = collision.CheckCollision(ray, object.Collider)
+	}
+	if !ok {
+		return Hit{}, false
+	}
+
+	distance := contact.Point.Sub(ray.Origin).Dot(ray.Direction)
+	if distance > maxDistance {
+		return Hit{}, false
+	}
+	return Hit{ID: object.ID, Point: contact.Point, Normal: contact.Normal, Distance: distance}, true
+}
+
+func raycastTriMesh(ray collider.Ray, triMesh collider.TriMesh) (collision.Contact, bool) {
+	var closest collision.Contact
+	var hit bool
+	minDistance := math.MaxFloat64
+	for _, triangle := range triMesh.Triangles {
+		if contact, ok := collision.CheckCollisionRayTriangle(ray, triangle); ok {
+			if distance := contact.Point.Sub(ray.Origin).LenSqr(); distance < minDistance {
+				closest, hit, minDistance = contact, true, distance
+			}
+		}
+	}
+	return closest, hit
+}
+
+func overlapObject(c collider.Collider, object *Object) (Hit, bool) {
+	if object.TriMesh == nil {
+		contact, ok := collision.CheckCollision(c, object.Collider)
+		if !ok {
+			return Hit{}, false
+		}
+		return Hit{ID: object.ID, Point: contact.Point, Normal: contact.Normal, Distance: contact.SeparatingDistance}, true
+	}
+
+	// report the deepest triangle
+	var deepest Hit
+	var hit bool
+	for _, triangle := range object.TriMesh.Triangles {
+		contact, ok := collision.CheckCollision(c, triangle)
+		if !ok {
+			continue
+		}
+		if !hit || contact.SeparatingDistance > deepest.Distance {
+			deepest = Hit{ID: object.ID, Point: contact.Point, Normal: contact.Normal, Distance: contact.SeparatingDistance}
+			hit = true
+		}
+	}
+	return deepest, hit
+}
+
+func sortHits(hits []Hit) {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Distance != hits[j].Distance {
+			return hits[i].Distance < hits[j].Distance
+		}
+		return hits[i].ID < hits[j].ID
+	})
+}
+
+// rayBoundingBox bounds the ray up to maxDistance. the ray is cut off where it leaves the
+// scene's bounds, so rays with an infinite maxDistance still give a finite box.
+func (s *Scene) rayBoundingBox(ray collider.Ray, maxDistance float64) collider.BoundingBox {
+	s.mu.RLock()
+	bounds := s.bounds
+	s.mu.RUnlock()
+
+	// no point in the scene is further away from the origin than the furthest corner
+	var furthest mgl64.Vec3
+	for i := 0; i < 3; i++ {
+		furthest[i] = math.Max(math.Abs(ray.Origin[i]-bounds.MinVertex[i]), math.Abs(ray.Origin[i]-bounds.MaxVertex[i]))
+	}
+	length := math.Min(maxDistance, furthest.Len())
+
+	return collider.BoundingBoxFromVertices([]mgl64.Vec3{ray.Origin, ray.Origin.Add(ray.Direction.Mul(length))})
+}
+
+func objectBoundingBox(object *Object) collider.BoundingBox {
+	if object.TriMesh != nil {
+		var vertices []mgl64.Vec3
+		for _, triangle := range object.TriMesh.Triangles {
+			vertices = append(vertices, triangle.Points[:]...)
+		}
+		return collider.BoundingBoxFromVertices(vertices)
+	}
+	return colliderBoundingBox(object.Collider)
+}
+
+func colliderBoundingBox(c collider.Collider) collider.BoundingBox {
+	switch c := c.(type) {
+	case collider.Sphere:
+		r := mgl64.Vec3{c.Radius, c.Radius, c.Radius}
+		return collider.BoundingBox{MinVertex: c.Center.Sub(r), MaxVertex: c.Center.Add(r)}
+	case *collider.Sphere:
+		return colliderBoundingBox(*c)
+	case collider.Capsule:
+		return *collider.BoundingBoxFromCapsule(c)
+	case *collider.Capsule:
+		return *collider.BoundingBoxFromCapsule(*c)
+	case collider.BoundingBox:
+		return c
+	case *collider.BoundingBox:
+		return *c
+	case collider.OrientedBoundingBox:
+		return c.BoundingBox()
+	case *collider.OrientedBoundingBox:
+		return c.BoundingBox()
+	case collider.Triangle:
+		return collider.BoundingBoxFromVertices(c.Points[:])
+	case *collider.Triangle:
+		return collider.BoundingBoxFromVertices(c.Points[:])
+	case collider.ConvexHull:
+		return c.BoundingBox()
+	case *collider.ConvexHull:
+		return c.BoundingBox()
+	}
+	return collider.BoundingBox{}
+}
+
+// objectEntity adapts an object to the broadphase
+type objectEntity struct {
+	object *Object
+	box    collider.BoundingBox
+}
+
+func (e objectEntity) GetID() int {
+	return e.object.ID
+}
+
+func (e objectEntity) Position() mgl64.Vec3 {
+	return e.box.MinVertex.Add(e.box.MaxVertex).Mul(0.5)
+}
+
+func (e objectEntity) BoundingBox() collider.BoundingBox {
+	return e.box
+}
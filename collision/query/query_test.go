@@ -0,0 +1,248 @@
+package query_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/collision/query"
+	"github.com/kkevinchou/kitolib/spatialpartition"
+)
+
+const (
+	layerWorld uint32 = 1 << iota
+	layerCharacter
+)
+
+// newScene builds a floor with a row of spheres and boxes standing on it
+func newScene() *query.Scene {
+	return newSceneIn(spatialpartition.NewSpatialPartition(10, 10))
+}
+
+func newSceneIn(partition spatialpartition.Broadphase) *query.Scene {
+	scene := query.NewScene(partition)
+
+	floor := collider.TriMesh{Triangles: []collider.Triangle{
+		collider.NewTriangle([3]mgl64.Vec3{{-20, 0, -20}, {-20, 0, 20}, {20, 0, 20}}),
+		collider.NewTriangle([3]mgl64.Vec3{{-20, 0, -20}, {20, 0, 20}, {20, 0, -20}}),
+	}}
+	scene.Add(query.Object{ID: 1, Layer: layerWorld, TriMesh: &floor})
+	scene.Add(query.Object{ID: 2, Layer: layerCharacter, Collider: collider.NewSphere(mgl64.Vec3{5, 1, 0}, 1)})
+	scene.Add(query.Object{ID: 3, Layer: layerWorld, Collider: *collider.NewBoundingBox(mgl64.Vec3{9, 0, -1}, mgl64.Vec3{11, 2, 1})})
+	scene.Add(query.Object{ID: 4, Layer: layerCharacter, Collider: collider.NewCapsule(mgl64.Vec3{15, 2, 0}, mgl64.Vec3{15, 0.5, 0}, 0.5)})
+	return scene
+}
+
+func TestRaycast(t *testing.T) {
+	scene := newScene()
+	ray := collider.Ray{Origin: mgl64.Vec3{0, 1, 0}, Direction: mgl64.Vec3{2, 0, 0}}
+
+	hit, ok := scene.RaycastClosest(ray, 100, query.Filter{})
+	if !ok {
+		t.Fatal("expected the ray to hit something")
+	}
+	if hit.ID != 2 || math.Abs(hit.Distance-4) > 1e-6 {
+		t.Errorf("expected the sphere to be hit at a distance of 4 but got object %d at %f", hit.ID, hit.Distance)
+	}
+	if !hit.Normal.ApproxEqualThreshold(mgl64.Vec3{-1, 0, 0}, 1e-6) {
+		t.Errorf("expected the sphere's normal to face the ray but got %v", hit.Normal)
+	}
+
+	hits := scene.RaycastAll(ray, 100, query.Filter{})
+	var ids []int
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	if len(ids) != 3 || ids[0] != 2 || ids[1] != 3 || ids[2] != 4 {
+		t.Errorf("expected to hit objects 2, 3 and 4 in order but got %v", ids)
+	}
+
+	if hits := scene.RaycastAll(ray, 9.5, query.Filter{}); len(hits) != 2 {
+		t.Errorf("expected the max distance to stop the ray before the capsule but got %d hits", len(hits))
+	}
+
+	if _, ok := scene.RaycastAny(collider.Ray{Origin: mgl64.Vec3{0, 5, 0}, Direction: mgl64.Vec3{0, 1, 0}}, 100, query.Filter{}); ok {
+		t.Error("expected a ray pointing at the sky to not hit anything")
+	}
+
+	hit, ok = scene.RaycastClosest(collider.Ray{Origin: mgl64.Vec3{-3, 5, 2}, Direction: mgl64.Vec3{0, -1, 0}}, 100, query.Filter{})
+	if !ok || hit.ID != 1 || !hit.Point.ApproxEqualThreshold(mgl64.Vec3{-3, 0, 2}, 1e-6) {
+		t.Errorf("expected a ray pointing down to hit the floor at (-3, 0, 2) but got object %d at %v", hit.ID, hit.Point)
+	}
+}
+
+func TestUnboundedRaycast(t *testing.T) {
+	broadphases := []spatialpartition.Broadphase{
+		spatialpartition.NewSpatialPartition(10, 10),
+		spatialpartition.NewAABBTree(0.5),
+		spatialpartition.NewHashGrid(4),
+		spatialpartition.NewOctree(mgl64.Vec3{}, 50, spatialpartition.DefaultOctreeSettings()),
+	}
+	for _, broadphase := range broadphases {
+		scene := newSceneIn(broadphase)
+		ray := collider.Ray{Origin: mgl64.Vec3{0, 1, 0}, Direction: mgl64.Vec3{1, 0, 0}}
+
+		hit, ok := scene.RaycastClosest(ray, math.Inf(1), query.Filter{})
+		if !ok || hit.ID != 2 || math.Abs(hit.Distance-4) > 1e-6 {
+			t.Errorf("%T: expected the sphere to be hit at a distance of 4 but got object %d at %f", broadphase, hit.ID, hit.Distance)
+		}
+		if hits := scene.RaycastAll(ray, math.Inf(1), query.Filter{}); len(hits) != 3 {
+			t.Errorf("%T: expected the unbounded ray to hit 3 objects but got %d", broadphase, len(hits))
+		}
+	}
+}
+
+func TestRaycastFilter(t *testing.T) {
+	scene := newScene()
+	ray := collider.Ray{Origin: mgl64.Vec3{0, 1, 0}, Direction: mgl64.Vec3{1, 0, 0}}
+
+	hit, ok := scene.RaycastClosest(ray, 100, query.Filter{Mask: layerWorld})
+	if !ok || hit.ID != 3 {
+		t.Errorf("expected the mask to skip the sphere and hit the box but got object %d", hit.ID)
+	}
+
+	ignoreBox := func(object *query.Object) bool { return object.ID != 3 }
+	hits := scene.RaycastAll(ray, 100, query.Filter{Predicate: ignoreBox})
+	if len(hits) != 2 || hits[0].ID != 2 || hits[1].ID != 4 {
+		t.Errorf("expected the predicate to skip the box but got %v", hits)
+	}
+}
+
+func TestSweeps(t *testing.T) {
+	scene := newScene()
+
+	hit, ok := scene.SweepSphere(collider.NewSphere(mgl64.Vec3{0, 1.5, 0}, 0.5), mgl64.Vec3{20, 0, 0}, query.Filter{})
+	if !ok {
+		t.Fatal("expected the sphere to hit something")
+	}
+	// the spheres touch when their centers are 1.5 apart
+	if hit.ID != 2 || math.Abs(hit.Distance-(5-math.Sqrt(1.5*1.5-0.5*0.5))) > 1e-3 {
+		t.Errorf("expected to run into the sphere but got object %d at %f", hit.ID, hit.Distance)
+	}
+
+	hit, ok = scene.SweepCapsule(collider.NewCapsule(mgl64.Vec3{7, 1.5, 0}, mgl64.Vec3{7, 1, 0}, 0.5), mgl64.Vec3{10, 0, 0}, query.Filter{Mask: layerWorld})
+	if !ok {
+		t.Fatal("expected the capsule to hit something")
+	}
+	if hit.ID != 3 || math.Abs(hit.Distance-1.5) > 1e-3 {
+		t.Errorf("expected to run into the box after 1.5 but got object %d at %f", hit.ID, hit.Distance)
+	}
+	if !hit.Normal.ApproxEqualThreshold(mgl64.Vec3{-1, 0, 0}, 1e-3) {
+		t.Errorf("expected the box's side to be hit but got normal %v", hit.Normal)
+	}
+
+	hit, ok = scene.SweepSphere(collider.NewSphere(mgl64.Vec3{-5, 3, 0}, 0.5), mgl64.Vec3{0, -10, 0}, query.Filter{})
+	if !ok || hit.ID != 1 || math.Abs(hit.Distance-2.5) > 1e-3 {
+		t.Errorf("expected to land on the floor after 2.5 but got object %d at %f", hit.ID, hit.Distance)
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	scene := newScene()
+
+	hits := scene.Overlap(collider.NewSphere(mgl64.Vec3{7.5, 1, 0}, 2), query.Filter{})
+	var ids []int
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Errorf("expected to overlap the floor, sphere and box but got %v", ids)
+	}
+	for _, h := range hits {
+		if h.Distance <= 0 {
+			t.Errorf("expected a positive penetration for object %d but got %f", h.ID, h.Distance)
+		}
+	}
+
+	hits = scene.Overlap(collider.NewSphere(mgl64.Vec3{7.5, 1, 0}, 2), query.Filter{Mask: layerCharacter})
+	if len(hits) != 1 || hits[0].ID != 2 {
+		t.Errorf("expected to only overlap the sphere but got %v", hits)
+	}
+}
+
+func TestAddReplacesAndRemove(t *testing.T) {
+	scene := newScene()
+	ray := collider.Ray{Origin: mgl64.Vec3{0, 1, 0}, Direction: mgl64.Vec3{1, 0, 0}}
+
+	// move the sphere behind the camera
+	scene.Add(query.Object{ID: 2, Layer: layerCharacter, Collider: collider.NewSphere(mgl64.Vec3{-5, 1, 0}, 1)})
+	if hit, _ := scene.RaycastClosest(ray, 100, query.Filter{}); hit.ID != 3 {
+		t.Errorf("expected the moved sphere to be out of the way but hit object %d", hit.ID)
+	}
+
+	scene.Remove(3)
+	if hit, _ := scene.RaycastClosest(ray, 100, query.Filter{}); hit.ID != 4 {
+		t.Errorf("expected the removed box to be out of the way but hit object %d", hit.ID)
+	}
+}
+
+func TestBatchMatchesSingleQueries(t *testing.T) {
+	scene := newScene()
+
+	var queries []query.Query
+	for i := 0; i < 200; i++ {
+		angle := float64(i) / 200 * 2 * math.Pi
+		direction := mgl64.Vec3{math.Cos(angle), -0.1, math.Sin(angle)}
+		queries = append(queries,
+			query.Query{Type: query.QueryTypeRaycastClosest, Ray: collider.Ray{Origin: mgl64.Vec3{5, 1, 5}, Direction: direction}, MaxDistance: 30},
+			query.Query{Type: query.QueryTypeRaycastAll, Ray: collider.Ray{Origin: mgl64.Vec3{-2, 1, 0.1 * float64(i%10)}, Direction: mgl64.Vec3{1, 0, 0}}, MaxDistance: 30},
+			query.Query{Type: query.QueryTypeSweepSphere, Sphere: collider.NewSphere(mgl64.Vec3{5, 1.5, 5}, 0.4), Delta: direction.Mul(20)},
+			query.Query{Type: query.QueryTypeOverlap, Collider: collider.NewSphere(mgl64.Vec3{float64(i % 20), 1, 0}, 1)},
+		)
+	}
+
+	results := scene.Batch(queries)
+	if len(results) != len(queries) {
+		t.Fatalf("expected %d results but got %d", len(queries), len(results))
+	}
+
+	for i, q := range queries {
+		var expected []query.Hit
+		switch q.Type {
+		case query.QueryTypeRaycastClosest:
+			if hit, ok := scene.RaycastClosest(q.Ray, q.MaxDistance, q.Filter); ok {
+				expected = []query.Hit{hit}
+			}
+		case query.QueryTypeRaycastAll:
+			expected = scene.RaycastAll(q.Ray, q.MaxDistance, q.Filter)
+		case query.QueryTypeSweepSphere:
+			if hit, ok := scene.SweepSphere(q.Sphere, q.Delta, q.Filter); ok {
+				expected = []query.Hit{hit}
+			}
+		case query.QueryTypeOverlap:
+			expected = scene.Overlap(q.Collider, q.Filter)
+		}
+
+		if len(results[i]) != len(expected) {
+			t.Errorf("query %d: expected %d hits but got %d", i, len(expected), len(results[i]))
+			continue
+		}
+		for j := range expected {
+			if results[i][j] != expected[j] {
+				t.Errorf("query %d: expected hit %v but got %v", i, expected[j], results[i][j])
+			}
+		}
+	}
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	scene := newScene()
+	ray := collider.Ray{Origin: mgl64.Vec3{0, 1, 0}, Direction: mgl64.Vec3{1, 0, 0}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if hit, ok := scene.RaycastClosest(ray, 100, query.Filter{}); !ok || hit.ID != 2 {
+					t.Errorf("expected the sphere to be hit but got object %d", hit.ID)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
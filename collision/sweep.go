@@ -0,0 +1,330 @@
+package collision
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/checks"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+const (
+	// max number of conservative advancement steps, a sweep that hasn't converged by then
+	// falls back to bisecting
+	maxSweepIterations = 64
+
+	// shapes within this distance of each other are considered touching
+	sweepTolerance float64 = 0.0001
+
+	// distance kept between the moving shape and whatever it hit so that the next
+	// sweep doesn't start out penetrating
+	skinWidth float64 = 0.001
+)
+
+// SweepHit describes the first thing a swept shape runs into. TimeOfImpact is the fraction
+// of the sweep's delta that can be travelled before touching, Point is the contact point on
+// the hit geometry and Normal points from the hit geometry towards the swept shape.
+type SweepHit struct {
+	TimeOfImpact float64
+	Point        mgl64.Vec3
+	Normal       mgl64.Vec3
+
+	// TriIndex is the index of the triangle that was hit for trimesh sweeps
+	TriIndex *int
+}
+
+// SweepCapsuleTriMesh sweeps the capsule along delta and returns the earliest hit against the
+// trimesh. unlike the discrete checks this can't tunnel through thin geometry.
+func SweepCapsuleTriMesh(capsule collider.Capsule, delta mgl64.Vec3, triMesh collider.TriMesh) (SweepHit, bool) {
+	var closestHit SweepHit
+	var hasHit bool
+
+	sweptBox := sweptCapsuleBoundingBox(capsule, delta)
+	for i, triangle := range triMesh.Triangles {
+		triangleBox := collider.BoundingBoxFromVertices(triangle.Points[:])
+		if !CheckOverlapAABBAABB(&sweptBox, &triangleBox) {
+			continue
+		}
+
+		hit, ok := SweepCapsuleTriangle(capsule, delta, triangle)
+		if !ok {
+			continue
+		}
+
+		if !hasHit || hit.TimeOfImpact < closestHit.TimeOfImpact {
+			index := i
+			hit.TriIndex = &index
+			closestHit = hit
+			hasHit = true
+		}
+	}
+
+	return closestHit, hasHit
+}
+
+func SweepCapsuleTriangle(capsule collider.Capsule, delta mgl64.Vec3, triangle collider.Triangle) (SweepHit, bool) {
+	t, closestPoints, distance, ok := conservativeAdvance(delta, capsule.Radius, func(offset mgl64.Vec3) ([2]mgl64.Vec3, float64) {
+		return checks.ClosestPointsLineVSTriangle(
+			collider.Line{P1: capsule.Top.Add(offset), P2: capsule.Bottom.Add(offset)},
+			triangle,
+		)
+	})
+	if !ok {
+		return SweepHit{}, false
+	}
+
+	normal := triangle.Normal
+	if distance > epsilon {
+		normal = closestPoints[0].Sub(closestPoints[1]).Normalize()
+	} else if normal.Dot(delta) > 0 {
+		normal = normal.Mul(-1)
+	}
+
+	if t == 0 && normal.Dot(delta) >= 0 {
+		// already touching but moving away
+		return SweepHit{}, false
+	}
+
+	return SweepHit{TimeOfImpact: t, Point: closestPoints[1], Normal: normal}, true
+}
+
+func SweepSphereTriMesh(sphere collider.Sphere, delta mgl64.Vec3, triMesh collider.TriMesh) (SweepHit, bool) {
+	return SweepCapsuleTriMesh(sphereToCapsule(sphere), delta, triMesh)
+}
+
+// SweepCapsuleCapsule sweeps capsule1 along delta against a stationary capsule2
+func SweepCapsuleCapsule(capsule1 collider.Capsule, delta mgl64.Vec3, capsule2 collider.Capsule) (SweepHit, bool) {
+	t, closestPoints, distance, ok := conservativeAdvance(delta, capsule1.Radius+capsule2.Radius, func(offset mgl64.Vec3) ([2]mgl64.Vec3, float64) {
+		return checks.ClosestPointsLineVSLine(
+			collider.Line{P1: capsule1.Top.Add(offset), P2: capsule1.Bottom.Add(offset)},
+			collider.Line{P1: capsule2.Top, P2: capsule2.Bottom},
+		)
+	})
+	if !ok {
+		return SweepHit{}, false
+	}
+
+	normal := delta.Normalize().Mul(-1)
+	if distance > epsilon {
+		normal = closestPoints[0].Sub(closestPoints[1]).Normalize()
+	}
+
+	if t == 0 && normal.Dot(delta) >= 0 {
+		return SweepHit{}, false
+	}
+
+	return SweepHit{
+		TimeOfImpact: t,
+		Point:        closestPoints[1].Add(normal.Mul(capsule2.Radius)),
+		Normal:       normal,
+	}, true
+}
+
+func SweepSphereCapsule(sphere collider.Sphere, delta mgl64.Vec3, capsule collider.Capsule) (SweepHit, bool) {
+	return SweepCapsuleCapsule(sphereToCapsule(sphere), delta, capsule)
+}
+
+// SweepCapsuleCollider sweeps the capsule along delta against a stationary convex collider.
+// rays can't be swept against and never hit.
+func SweepCapsuleCollider(capsule collider.Capsule, delta mgl64.Vec3, c collider.Collider) (SweepHit, bool) {
+	target, ok := convexCoreFor(normalizeCollider(c))
+	if !ok {
+		return SweepHit{}, false
+	}
+
+	t, closestPoints, distance, ok := conservativeAdvance(delta, capsule.Radius+target.radius, func(offset mgl64.Vec3) ([2]mgl64.Vec3, float64) {
+		moving := convexCore{vertices: []mgl64.Vec3{capsule.Top.Add(offset), capsule.Bottom.Add(offset)}}
+		return gjkClosestPoints(moving, target)
+	})
+	if !ok {
+		return SweepHit{}, false
+	}
+
+	normal := delta.Normalize().Mul(-1)
+	if distance > epsilon {
+		normal = closestPoints[0].Sub(closestPoints[1]).Normalize()
+	}
+
+	if t == 0 && normal.Dot(delta) >= 0 {
+		return SweepHit{}, false
+	}
+
+	return SweepHit{
+		TimeOfImpact: t,
+		Point:        closestPoints[1].Add(normal.Mul(target.radius)),
+		Normal:       normal,
+	}, true
+}
+
+func SweepSphereCollider(sphere collider.Sphere, delta mgl64.Vec3, c collider.Collider) (SweepHit, bool) {
+	return SweepCapsuleCollider(sphereToCapsule(sphere), delta, c)
+}
+
+// SlideNormalFunc picks the plane the rest of the movement slides along after a hit, given
+// the hit and the movement that's left. returning false stops the slide.
+type SlideNormalFunc func(hit SweepHit, remaining mgl64.Vec3) (mgl64.Vec3, bool)
+
+// MoveAndSlide moves the capsule along delta, sliding along any surfaces it runs into rather
+// than stopping dead. each hit uses up one iteration. the translation that was applied to the
+// capsule is returned along with the hits along the way.
+func MoveAndSlide(capsule collider.Capsule, delta mgl64.Vec3, triMesh collider.TriMesh, obstacles []collider.Capsule, maxIterations int) (mgl64.Vec3, []SweepHit) {
+	return MoveAndSlideWith(capsule, delta, triMesh, obstacles, maxIterations, nil)
+}
+
+// MoveAndSlideWith is MoveAndSlide with slideNormal deciding what each hit is slid along, nil
+// slides along the hit normal
+func MoveAndSlideWith(capsule collider.Capsule, delta mgl64.Vec3, triMesh collider.TriMesh, obstacles []collider.Capsule, maxIterations int, slideNormal SlideNormalFunc) (mgl64.Vec3, []SweepHit) {
+	var translation mgl64.Vec3
+	var hits []SweepHit
+	var prevNormal mgl64.Vec3
+
+	remaining := delta
+	for i := 0; i < maxIterations; i++ {
+		if remaining.Len() <= sweepTolerance {
+			break
+		}
+
+		moved := translateCapsule(capsule, translation)
+		hit, hasHit := SweepCapsuleTriMesh(moved, remaining, triMesh)
+		for _, obstacle := range obstacles {
+			if obstacleHit, ok := SweepCapsuleCapsule(moved, remaining, obstacle); ok {
+				if !hasHit || obstacleHit.TimeOfImpact < hit.TimeOfImpact {
+					hit = obstacleHit
+					hasHit = true
+				}
+			}
+		}
+
+		if !hasHit {
+			translation = translation.Add(remaining)
+			remaining = mgl64.Vec3{}
+			break
+		}
+		hits = append(hits, hit)
+
+		// move up to the hit, keeping a small gap to the surface
+		length := remaining.Len()
+		travel := length*hit.TimeOfImpact - skinWidth
+		if travel > 0 {
+			translation = translation.Add(remaining.Mul(travel / length))
+		}
+
+		// slide the rest of the movement along the surface
+		remaining = remaining.Mul(1 - hit.TimeOfImpact)
+		normal := hit.Normal
+		if slideNormal != nil {
+			var ok bool
+			if normal, ok = slideNormal(hit, remaining); !ok {
+				break
+			}
+		}
+		remaining = remaining.Sub(normal.Mul(remaining.Dot(normal)))
+
+		// sliding along the new surface pushes us back into the previous one, so we're in a
+		// crease and can only move along the line where the two surfaces meet
+		if i > 0 && remaining.Dot(prevNormal) < 0 {
+			crease := prevNormal.Cross(normal)
+			if crease.LenSqr() <= epsilon {
+				break
+			}
+			crease = crease.Normalize()
+			remaining = crease.Mul(remaining.Dot(crease))
+		}
+		prevNormal = normal
+	}
+
+	return translation, hits
+}
+
+// conservativeAdvance steps a shape moving along delta towards a static shape. distanceFunc
+// returns the closest points [moving, static] and their distance when the moving shape is
+// offset by the passed in vector. both shapes are convex and the motion is a pure
+// translation, so the distance is a convex function of t and can't drop faster than the rate
+// it's closing at along the line between the closest points. stepping by the gap over that
+// rate never overshoots and doesn't crawl when the shape skims along a surface. if the
+// iterations run out the rest of the sweep is bisected instead, see bisectAdvance.
+func conservativeAdvance(delta mgl64.Vec3, radius float64, distanceFunc func(offset mgl64.Vec3) ([2]mgl64.Vec3, float64)) (float64, [2]mgl64.Vec3, float64, bool) {
+	speed := delta.Len()
+
+	var t float64
+	var closestPoints [2]mgl64.Vec3
+	var distance float64
+	for i := 0; i < maxSweepIterations; i++ {
+		closestPoints, distance = distanceFunc(delta.Mul(t))
+		gap := distance - radius
+		if gap <= sweepTolerance {
+			return t, closestPoints, distance, true
+		}
+
+		if speed <= epsilon {
+			return 0, [2]mgl64.Vec3{}, 0, false
+		}
+
+		// how fast the gap is closing, when it isn't the shapes only get further apart
+		closing := speed
+		if distance > epsilon {
+			closing = -closestPoints[0].Sub(closestPoints[1]).Mul(1 / distance).Dot(delta)
+		}
+		if closing <= epsilon {
+			return 0, [2]mgl64.Vec3{}, 0, false
+		}
+
+		t += gap / closing
+		if t > 1 {
+			return 0, [2]mgl64.Vec3{}, 0, false
+		}
+	}
+
+	return bisectAdvance(t, delta, radius, distanceFunc)
+}
+
+// bisectAdvance finishes a sweep that conservative advancement couldn't, from a t that's known
+// to be clear of the static shape. the distance is convex so the closest the shapes get is
+// found by bisecting on whether they're still closing. if that's still clear there's no hit,
+// otherwise the first touch is bisected for between t and there.
+func bisectAdvance(t float64, delta mgl64.Vec3, radius float64, distanceFunc func(offset mgl64.Vec3) ([2]mgl64.Vec3, float64)) (float64, [2]mgl64.Vec3, float64, bool) {
+	low, high := t, 1.0
+	for i := 0; i < maxSweepIterations; i++ {
+		mid := (low + high) / 2
+		closestPoints, distance := distanceFunc(delta.Mul(mid))
+		if distance > epsilon && closestPoints[0].Sub(closestPoints[1]).Dot(delta) < 0 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	closestPoints, distance := distanceFunc(delta.Mul(high))
+	if distance-radius > sweepTolerance {
+		return 0, [2]mgl64.Vec3{}, 0, false
+	}
+
+	low = t
+	for i := 0; i < maxSweepIterations; i++ {
+		mid := (low + high) / 2
+		midPoints, midDistance := distanceFunc(delta.Mul(mid))
+		if midDistance-radius > sweepTolerance {
+			low = mid
+		} else {
+			high, closestPoints, distance = mid, midPoints, midDistance
+		}
+	}
+	return high, closestPoints, distance, true
+}
+
+func sweptCapsuleBoundingBox(capsule collider.Capsule, delta mgl64.Vec3) collider.BoundingBox {
+	box := collider.BoundingBoxFromVertices([]mgl64.Vec3{
+		capsule.Top,
+		capsule.Bottom,
+		capsule.Top.Add(delta),
+		capsule.Bottom.Add(delta),
+	})
+	r := mgl64.Vec3{capsule.Radius, capsule.Radius, capsule.Radius}
+	return collider.BoundingBox{MinVertex: box.MinVertex.Sub(r), MaxVertex: box.MaxVertex.Add(r)}
+}
+
+func translateCapsule(capsule collider.Capsule, translation mgl64.Vec3) collider.Capsule {
+	return collider.NewCapsule(capsule.Top.Add(translation), capsule.Bottom.Add(translation), capsule.Radius)
+}
+
+func sphereToCapsule(sphere collider.Sphere) collider.Capsule {
+	return collider.NewCapsule(sphere.Center, sphere.Center, sphere.Radius)
+}
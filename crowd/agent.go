@@ -0,0 +1,70 @@
+package crowd
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// Agent is a member of a crowd. it follows the path to its target and steers around the
+// other agents near it.
+type Agent struct {
+	Radius   float64
+	MaxSpeed float64
+
+	id       int
+	position mgl64.Vec3
+	velocity mgl64.Vec3
+
+	path []geometry.Point
+	// corner is the index of the point on the path the agent is heading to
+	corner  int
+	arrived bool
+
+	preferredVelocity mgl64.Vec3
+	newVelocity       mgl64.Vec3
+}
+
+// NewAgent makes an agent, the id has to be unique within the crowd and the partition it
+// uses
+func NewAgent(id int, position mgl64.Vec3, radius, maxSpeed float64) *Agent {
+	return &Agent{
+		Radius:   radius,
+		MaxSpeed: maxSpeed,
+		id:       id,
+		position: position,
+	}
+}
+
+func (a *Agent) GetID() int {
+	return a.id
+}
+
+func (a *Agent) Position() mgl64.Vec3 {
+	return a.position
+}
+
+func (a *Agent) Velocity() mgl64.Vec3 {
+	return a.velocity
+}
+
+func (a *Agent) BoundingBox() collider.BoundingBox {
+	extents := mgl64.Vec3{a.Radius, a.Radius, a.Radius}
+	return collider.BoundingBox{MinVertex: a.position.Sub(extents), MaxVertex: a.position.Add(extents)}
+}
+
+// Path returns the path the agent is following, nil when it has no target
+func (a *Agent) Path() []geometry.Point {
+	return a.path
+}
+
+// Arrived returns whether the agent reached its last target
+func (a *Agent) Arrived() bool {
+	return a.arrived
+}
+
+func (a *Agent) stop() {
+	a.path = nil
+	a.corner = 0
+	a.preferredVelocity = mgl64.Vec3{}
+}
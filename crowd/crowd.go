@@ -0,0 +1,245 @@
+package crowd
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/geometry"
+	"github.com/kkevinchou/kitolib/pathing"
+	"github.com/kkevinchou/kitolib/spatialpartition"
+)
+
+// Crowd moves agents along their paths at a fixed timestep. agents avoid each other with
+// optimal reciprocal collision avoidance (ORCA) and are kept on the navmesh. agents are
+// stepped in the order they were added and their neighbors are sorted, so the same inputs
+// always give the same results.
+type Crowd struct {
+	Timestep float64
+	// TimeHorizon is how many seconds ahead agents look for collisions with each other, higher
+	// values avoid earlier but make agents more timid
+	TimeHorizon float64
+	// NeighborRadius is how far from an agent other agents are avoided
+	NeighborRadius float64
+	MaxNeighbors   int
+	// ArrivalDistance is how close an agent has to get to its target to stop
+	ArrivalDistance float64
+	// LookaheadCorners is how many corners along its path an agent skips when it can already
+	// walk straight to the one after them
+	LookaheadCorners int
+	// Partition finds the neighbors, agent ids are used as entity ids
+	Partition spatialpartition.Broadphase
+	// SnapExtents is how far from an agent that's off the navmesh the navmesh is searched for
+	// the nearest point to put it back on
+	SnapExtents mgl64.Vec3
+
+	planner     *pathing.Planner
+	agents      []*Agent
+	accumulator float64
+}
+
+// NewCrowd makes a crowd that plans paths with the planner and keeps agents on its navmesh
+func NewCrowd(planner *pathing.Planner) *Crowd {
+	return &Crowd{
+		Timestep:         1.0 / 60,
+		TimeHorizon:      2,
+		NeighborRadius:   5,
+		MaxNeighbors:     10,
+		ArrivalDistance:  0.1,
+		LookaheadCorners: 2,
+		Partition:        spatialpartition.NewHashGrid(5),
+		SnapExtents:      mgl64.Vec3{2, 2, 2},
+		planner:          planner,
+	}
+}
+
+// AddAgent adds the agent to the crowd, snapping it onto the navmesh. false is returned and
+// the agent isn't added when there's no navmesh within SnapExtents of it.
+func (c *Crowd) AddAgent(agent *Agent) bool {
+	navmesh := c.planner.NavMesh()
+	position := geometry.Point(agent.position)
+	point, ok := navmesh.MoveAlongSurface(position, position)
+	if !ok {
+		point, ok = navmesh.FindNearestPoint(position, c.SnapExtents)
+	}
+	if !ok {
+		return false
+	}
+	agent.position = point.Vector3()
+	c.agents = append(c.agents, agent)
+	return true
+}
+
+func (c *Crowd) RemoveAgent(id int) {
+	for i, agent := range c.agents {
+		if agent.id == id {
+			c.agents = append(c.agents[:i], c.agents[i+1:]...)
+			c.Partition.DeleteEntity(id)
+			return
+		}
+	}
+}
+
+func (c *Crowd) Agents() []*Agent {
+	return c.agents
+}
+
+// SetTarget finds a path for the agent to the target. false is returned and the agent stops
+// when there's no path.
+func (c *Crowd) SetTarget(agent *Agent, target mgl64.Vec3) bool {
+	path := c.planner.FindPath(geometry.Point(agent.position), geometry.Point(target))
+	agent.arrived = false
+	if path == nil {
+		agent.stop()
+		return false
+	}
+	agent.path = path
+	agent.corner = 0
+	return true
+}
+
+// Stop clears the agent's target
+func (c *Crowd) Stop(agent *Agent) {
+	agent.stop()
+}
+
+// Update advances the crowd by delta in fixed timesteps, any leftover time is carried over to
+// the next call. the number of steps taken is returned.
+func (c *Crowd) Update(delta time.Duration) int {
+	c.accumulator += delta.Seconds()
+
+	steps := 0
+	for c.accumulator >= c.Timestep {
+		c.Step()
+		c.accumulator -= c.Timestep
+		steps++
+	}
+	return steps
+}
+
+// Step advances the crowd by a single timestep
+func (c *Crowd) Step() {
+	entities := make([]spatialpartition.Entity, len(c.agents))
+	for i, agent := range c.agents {
+		entities[i] = agent
+	}
+	c.Partition.IndexEntities(entities)
+
+	for _, agent := range c.agents {
+		c.steer(agent)
+	}
+
+	// every agent picks its velocity from the velocities of the last step before any of them
+	// move
+	for _, agent := range c.agents {
+		lines := orcaLines(agent, c.neighbors(agent), c.TimeHorizon, c.Timestep)
+		velocity := newVelocity(lines, agent.MaxSpeed, xz(agent.preferredVelocity))
+		agent.newVelocity = mgl64.Vec3{velocity[0], 0, velocity[1]}
+	}
+
+	for _, agent := range c.agents {
+		start := agent.position
+		end := c.move(start, start.Add(agent.newVelocity.Mul(c.Timestep)))
+		agent.position = end
+		// what's left of the velocity after sliding along the edges of the navmesh
+		moved := end.Sub(start)
+		agent.velocity = mgl64.Vec3{moved[0], 0, moved[2]}.Mul(1 / c.Timestep)
+	}
+}
+
+// move moves from start toward end along the surface of the navmesh. a start that's off the
+// navmesh, like when the navmesh changed under an agent, is snapped back onto it first, and
+// one that's too far from the navmesh to snap doesn't move at all.
+func (c *Crowd) move(start, end mgl64.Vec3) mgl64.Vec3 {
+	navmesh := c.planner.NavMesh()
+	if point, ok := navmesh.MoveAlongSurface(geometry.Point(start), geometry.Point(end)); ok {
+		return point.Vector3()
+	}
+	nearest, ok := navmesh.FindNearestPoint(geometry.Point(start), c.SnapExtents)
+	if !ok {
+		return start
+	}
+	if point, ok := navmesh.MoveAlongSurface(nearest, geometry.Point(nearest.Vector3().Add(end.Sub(start)))); ok {
+		return point.Vector3()
+	}
+	return nearest.Vector3()
+}
+
+// steer sets the velocity the agent wants to take to follow its path
+func (c *Crowd) steer(agent *Agent) {
+	if agent.path == nil {
+		agent.preferredVelocity = mgl64.Vec3{}
+		return
+	}
+
+	last := len(agent.path) - 1
+	position := geometry.Point(agent.position)
+	for agent.corner < last && horizontalDistance(agent.position, agent.path[agent.corner].Vector3()) <= c.ArrivalDistance {
+		agent.corner++
+	}
+	// cut corners that are no longer in the way
+	for i := 0; i < c.LookaheadCorners && agent.corner < last; i++ {
+		if !c.planner.ValidatePath([]geometry.Point{position, agent.path[agent.corner+1]}) {
+			break
+		}
+		agent.corner++
+	}
+
+	target := agent.path[agent.corner].Vector3()
+	distance := horizontalDistance(agent.position, target)
+	if agent.corner == last && distance <= c.ArrivalDistance {
+		agent.stop()
+		agent.arrived = true
+		return
+	}
+
+	speed := agent.MaxSpeed
+	if agent.corner == last {
+		// slow down so as not to overshoot the target
+		speed = math.Min(speed, distance/c.Timestep)
+	}
+	direction := mgl64.Vec3{target[0] - agent.position[0], 0, target[2] - agent.position[2]}.Mul(1 / distance)
+	agent.preferredVelocity = direction.Mul(speed)
+}
+
+// neighbors returns the closest agents within NeighborRadius, at most MaxNeighbors of them
+func (c *Crowd) neighbors(agent *Agent) []*Agent {
+	extents := mgl64.Vec3{c.NeighborRadius, c.NeighborRadius, c.NeighborRadius}
+	box := collider.BoundingBox{MinVertex: agent.position.Sub(extents), MaxVertex: agent.position.Add(extents)}
+
+	type neighbor struct {
+		agent    *Agent
+		distance float64
+	}
+	var found []neighbor
+	for _, entity := range c.Partition.QueryEntities(box) {
+		other, ok := entity.(*Agent)
+		if !ok || other == agent {
+			continue
+		}
+		if distance := horizontalDistance(agent.position, other.position); distance <= c.NeighborRadius {
+			found = append(found, neighbor{agent: other, distance: distance})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].distance != found[j].distance {
+			return found[i].distance < found[j].distance
+		}
+		return found[i].agent.id < found[j].agent.id
+	})
+	if len(found) > c.MaxNeighbors {
+		found = found[:c.MaxNeighbors]
+	}
+
+	neighbors := make([]*Agent, len(found))
+	for i, n := range found {
+		neighbors[i] = n.agent
+	}
+	return neighbors
+}
+
+func horizontalDistance(a, b mgl64.Vec3) float64 {
+	return math.Hypot(a[0]-b[0], a[2]-b[2])
+}
@@ -0,0 +1,199 @@
+package crowd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/geometry"
+	"github.com/kkevinchou/kitolib/pathing"
+)
+
+func square(size, x, z float64) *geometry.Polygon {
+	return geometry.NewPolygon([]geometry.Point{
+		{x * size, 0, z * size},
+		{x * size, 0, z*size + size},
+		{x*size + size, 0, z*size + size},
+		{x*size + size, 0, z * size},
+	})
+}
+
+func newCrowd(squares ...[2]float64) *Crowd {
+	var polygons []*geometry.Polygon
+	for _, s := range squares {
+		polygons = append(polygons, square(10, s[0], s[1]))
+	}
+	p := &pathing.Planner{}
+	p.SetNavMesh(pathing.ConstructNavMesh(polygons))
+	return NewCrowd(p)
+}
+
+// run steps the crowd until every agent arrived or the steps run out
+func run(c *Crowd, steps int, each func()) {
+	for i := 0; i < steps; i++ {
+		c.Step()
+		if each != nil {
+			each()
+		}
+		arrived := true
+		for _, agent := range c.Agents() {
+			arrived = arrived && agent.Arrived()
+		}
+		if arrived {
+			return
+		}
+	}
+}
+
+func assertOnMesh(t *testing.T, c *Crowd) {
+	t.Helper()
+	for _, agent := range c.Agents() {
+		onMesh := false
+		for _, polygon := range c.planner.NavMesh().Polygons() {
+			onMesh = onMesh || polygon.ContainsPoint(geometry.Point(agent.Position()))
+		}
+		if !onMesh {
+			t.Fatalf("expected agent %d to stay on the navmesh but it's at %v", agent.GetID(), agent.Position())
+		}
+	}
+}
+
+func TestAgentFollowsPath(t *testing.T) {
+	// X X
+	//   X
+	c := newCrowd([2]float64{0, 0}, [2]float64{1, 0}, [2]float64{1, 1})
+	agent := NewAgent(0, mgl64.Vec3{2, 0, 2}, 0.5, 5)
+	c.AddAgent(agent)
+
+	target := mgl64.Vec3{18, 0, 18}
+	if !c.SetTarget(agent, target) {
+		t.Fatal("expected a path to the target")
+	}
+	run(c, 600, func() { assertOnMesh(t, c) })
+
+	if !agent.Arrived() {
+		t.Fatalf("expected the agent to arrive but it's at %v", agent.Position())
+	}
+	if horizontalDistance(agent.Position(), target) > c.ArrivalDistance {
+		t.Errorf("expected the agent to be at %v but it's at %v", target, agent.Position())
+	}
+	if agent.Path() != nil || agent.Velocity().Len() > 0.5 {
+		t.Errorf("expected the agent to stop but it's moving at %v", agent.Velocity())
+	}
+
+	if c.SetTarget(agent, mgl64.Vec3{0, 0, 18}) {
+		t.Error("expected no path to a target off the navmesh")
+	}
+}
+
+func TestAgentsAvoidEachOther(t *testing.T) {
+	c := newCrowd([2]float64{0, 0}, [2]float64{1, 0}, [2]float64{2, 0})
+	a := NewAgent(0, mgl64.Vec3{2, 0, 5}, 0.5, 3)
+	b := NewAgent(1, mgl64.Vec3{28, 0, 5}, 0.5, 3)
+	c.AddAgent(a)
+	c.AddAgent(b)
+	c.SetTarget(a, b.Position())
+	c.SetTarget(b, a.Position())
+
+	closest := 100.0
+	run(c, 1200, func() {
+		assertOnMesh(t, c)
+		if distance := horizontalDistance(a.Position(), b.Position()); distance < closest {
+			closest = distance
+		}
+	})
+
+	if closest < a.Radius+b.Radius-0.05 {
+		t.Errorf("expected the agents not to overlap but they got %f apart", closest)
+	}
+	if !a.Arrived() || !b.Arrived() {
+		t.Errorf("expected both agents to arrive but they're at %v and %v", a.Position(), b.Position())
+	}
+}
+
+func TestCrowdDeterministic(t *testing.T) {
+	simulate := func() []mgl64.Vec3 {
+		c := newCrowd([2]float64{0, 0}, [2]float64{1, 0}, [2]float64{0, 1}, [2]float64{1, 1})
+		for i := 0; i < 12; i++ {
+			agent := NewAgent(i, mgl64.Vec3{2 + float64(i%4)*1.5, 0, 2 + float64(i/4)*1.5}, 0.4, 4)
+			c.AddAgent(agent)
+			c.SetTarget(agent, mgl64.Vec3{17 - float64(i%4)*1.5, 0, 17 - float64(i/4)*1.5})
+		}
+		c.Update(3 * time.Second)
+
+		var positions []mgl64.Vec3
+		for _, agent := range c.Agents() {
+			positions = append(positions, agent.Position())
+		}
+		return positions
+	}
+
+	first, second := simulate(), simulate()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("expected agent %d to end up in the same place but got %v and %v", i, first[i], second[i])
+		}
+	}
+}
+
+func TestCrowdStaysOnMesh(t *testing.T) {
+	// agents pushing into each other in a corridor mustn't be pushed off of it
+	c := newCrowd([2]float64{0, 0}, [2]float64{1, 0}, [2]float64{2, 0})
+	for i := 0; i < 20; i++ {
+		x := 1 + float64(i%10)*0.9
+		z := 1 + float64(i/10)*8
+		agent := NewAgent(i, mgl64.Vec3{x, 0, z}, 0.4, 4)
+		c.AddAgent(agent)
+		c.SetTarget(agent, mgl64.Vec3{25, 0, 5})
+	}
+
+	run(c, 600, func() { assertOnMesh(t, c) })
+	for _, agent := range c.Agents() {
+		if horizontalDistance(agent.Position(), mgl64.Vec3{25, 0, 5}) > 6 {
+			t.Errorf("expected agent %d to make it to the target but it's at %v", agent.GetID(), agent.Position())
+		}
+	}
+}
+
+func TestRemoveAgent(t *testing.T) {
+	c := newCrowd([2]float64{0, 0})
+	for i := 0; i < 3; i++ {
+		c.AddAgent(NewAgent(i, mgl64.Vec3{float64(i) * 2, 0, 1}, 0.5, 1))
+	}
+	c.Step()
+	c.RemoveAgent(1)
+
+	var ids []int
+	for _, agent := range c.Agents() {
+		ids = append(ids, agent.GetID())
+	}
+	if fmt.Sprint(ids) != "[0 2]" {
+		t.Errorf("expected agents [0 2] but got %v", ids)
+	}
+}
+
+func TestAgentsSnapOntoMesh(t *testing.T) {
+	c := newCrowd([2]float64{0, 0}, [2]float64{1, 0})
+	agent := NewAgent(0, mgl64.Vec3{-1, 0, 5}, 0.5, 1)
+	if !c.AddAgent(agent) {
+		t.Fatal("expected the agent next to the navmesh to be added")
+	}
+	if agent.Position() != (mgl64.Vec3{0, 0, 5}) {
+		t.Errorf("expected the agent to be snapped onto the edge of the navmesh but it's at %v", agent.Position())
+	}
+	if c.AddAgent(NewAgent(1, mgl64.Vec3{50, 0, 50}, 0.5, 1)) || len(c.Agents()) != 1 {
+		t.Error("expected the agent far from the navmesh not to be added")
+	}
+
+	// the navmesh changes under an agent
+	other := NewAgent(2, mgl64.Vec3{11, 0, 5}, 0.5, 1)
+	c.AddAgent(other)
+	navmesh := c.planner.NavMesh()
+	navmesh.RemovePolygon(navmesh.Polygons()[1])
+	c.Step()
+	assertOnMesh(t, c)
+	if other.Position() != (mgl64.Vec3{10, 0, 5}) {
+		t.Errorf("expected the agent to be snapped back onto the navmesh but it's at %v", other.Position())
+	}
+}
@@ -0,0 +1,220 @@
+package crowd
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+const orcaEpsilon = 0.00001
+
+// orcaLine is a half plane of velocities, the ones to the left of the line are allowed
+type orcaLine struct {
+	point     mgl64.Vec2
+	direction mgl64.Vec2
+}
+
+func det(a, b mgl64.Vec2) float64 {
+	return a[0]*b[1] - a[1]*b[0]
+}
+
+// xz returns the vector seen from above
+func xz(v mgl64.Vec3) mgl64.Vec2 {
+	return mgl64.Vec2{v[0], v[2]}
+}
+
+// orcaLines returns the velocities the agent can take so that it doesn't run into any of
+// its neighbors within timeHorizon, assuming they each do half of the avoiding
+func orcaLines(agent *Agent, neighbors []*Agent, timeHorizon, timestep float64) []orcaLine {
+	lines := make([]orcaLine, 0, len(neighbors))
+	invTimeHorizon := 1 / timeHorizon
+	velocity := xz(agent.velocity)
+
+	for _, other := range neighbors {
+		relativePosition := xz(other.position.Sub(agent.position))
+		relativeVelocity := velocity.Sub(xz(other.velocity))
+		distanceSq := relativePosition.Dot(relativePosition)
+		combinedRadius := agent.Radius + other.Radius
+		combinedRadiusSq := combinedRadius * combinedRadius
+
+		var line orcaLine
+		var u mgl64.Vec2
+		if distanceSq > combinedRadiusSq {
+			// from the relative velocity to the cut off center of the velocity obstacle
+			w := relativeVelocity.Sub(relativePosition.Mul(invTimeHorizon))
+			wLengthSq := w.Dot(w)
+			dot := w.Dot(relativePosition)
+
+			if dot < 0 && dot*dot > combinedRadiusSq*wLengthSq {
+				// closest to the cut off circle
+				wLength := math.Sqrt(wLengthSq)
+				unitW := w.Mul(1 / wLength)
+				line.direction = mgl64.Vec2{unitW[1], -unitW[0]}
+				u = unitW.Mul(combinedRadius*invTimeHorizon - wLength)
+			} else {
+				// closest to one of the legs
+				leg := math.Sqrt(distanceSq - combinedRadiusSq)
+				if det(relativePosition, w) > 0 {
+					line.direction = mgl64.Vec2{
+						relativePosition[0]*leg - relativePosition[1]*combinedRadius,
+						relativePosition[0]*combinedRadius + relativePosition[1]*leg,
+					}.Mul(1 / distanceSq)
+				} else {
+					line.direction = mgl64.Vec2{
+						relativePosition[0]*leg + relativePosition[1]*combinedRadius,
+						-relativePosition[0]*combinedRadius + relativePosition[1]*leg,
+					}.Mul(-1 / distanceSq)
+				}
+				u = line.direction.Mul(relativeVelocity.Dot(line.direction)).Sub(relativeVelocity)
+			}
+		} else {
+			// already overlapping, get apart within one step
+			invTimestep := 1 / timestep
+			w := relativeVelocity.Sub(relativePosition.Mul(invTimestep))
+			wLength := w.Len()
+			if wLength < orcaEpsilon {
+				// exactly on top of each other, the one with the lower id goes left
+				w = mgl64.Vec2{1, 0}
+				if agent.id > other.id {
+					w = mgl64.Vec2{-1, 0}
+				}
+				wLength = 0
+			}
+			unitW := w.Normalize()
+			line.direction = mgl64.Vec2{unitW[1], -unitW[0]}
+			u = unitW.Mul(combinedRadius*invTimestep - wLength)
+		}
+
+		line.point = velocity.Add(u.Mul(0.5))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// newVelocity returns the velocity closest to preferred that's allowed by every line and
+// no faster than maxSpeed. when no velocity is allowed by all of them the one that breaks
+// them the least is returned.
+func newVelocity(lines []orcaLine, maxSpeed float64, preferred mgl64.Vec2) mgl64.Vec2 {
+	result, failed := linearProgram2(lines, maxSpeed, preferred, false)
+	if failed < len(lines) {
+		result = linearProgram3(lines, failed, maxSpeed, result)
+	}
+	return result
+}
+
+// linearProgram1 finds the best velocity on the line lineNo that's allowed by the lines
+// before it
+func linearProgram1(lines []orcaLine, lineNo int, radius float64, optimal mgl64.Vec2, directionOpt bool) (mgl64.Vec2, bool) {
+	line := lines[lineNo]
+	dot := line.point.Dot(line.direction)
+	discriminant := dot*dot + radius*radius - line.point.Dot(line.point)
+	if discriminant < 0 {
+		// the max speed circle doesn't reach the line
+		return mgl64.Vec2{}, false
+	}
+
+	sqrtDiscriminant := math.Sqrt(discriminant)
+	tLeft := -dot - sqrtDiscriminant
+	tRight := -dot + sqrtDiscriminant
+
+	for i := 0; i < lineNo; i++ {
+		denominator := det(line.direction, lines[i].direction)
+		numerator := det(lines[i].direction, line.point.Sub(lines[i].point))
+
+		if math.Abs(denominator) <= orcaEpsilon {
+			// parallel lines
+			if numerator < 0 {
+				return mgl64.Vec2{}, false
+			}
+			continue
+		}
+
+		t := numerator / denominator
+		if denominator >= 0 {
+			tRight = math.Min(tRight, t)
+		} else {
+			tLeft = math.Max(tLeft, t)
+		}
+		if tLeft > tRight {
+			return mgl64.Vec2{}, false
+		}
+	}
+
+	if directionOpt {
+		if optimal.Dot(line.direction) > 0 {
+			return line.point.Add(line.direction.Mul(tRight)), true
+		}
+		return line.point.Add(line.direction.Mul(tLeft)), true
+	}
+
+	t := line.direction.Dot(optimal.Sub(line.point))
+	t = math.Max(tLeft, math.Min(tRight, t))
+	return line.point.Add(line.direction.Mul(t)), true
+}
+
+// linearProgram2 finds the velocity closest to optimal within radius that's allowed by all
+// of the lines. it returns the index of the line it failed on, or len(lines) on success.
+func linearProgram2(lines []orcaLine, radius float64, optimal mgl64.Vec2, directionOpt bool) (mgl64.Vec2, int) {
+	var result mgl64.Vec2
+	if directionOpt {
+		// optimal is a unit direction here
+		result = optimal.Mul(radius)
+	} else if optimal.Dot(optimal) > radius*radius {
+		result = optimal.Normalize().Mul(radius)
+	} else {
+		result = optimal
+	}
+
+	for i, line := range lines {
+		if det(line.direction, line.point.Sub(result)) > 0 {
+			// the result breaks this line
+			next, ok := linearProgram1(lines, i, radius, optimal, directionOpt)
+			if !ok {
+				return result, i
+			}
+			result = next
+		}
+	}
+	return result, len(lines)
+}
+
+// linearProgram3 finds the velocity that breaks the lines from beginLine on by the least
+func linearProgram3(lines []orcaLine, beginLine int, radius float64, result mgl64.Vec2) mgl64.Vec2 {
+	distance := 0.0
+	for i := beginLine; i < len(lines); i++ {
+		if det(lines[i].direction, lines[i].point.Sub(result)) <= distance {
+			continue
+		}
+
+		var projected []orcaLine
+		for j := 0; j < i; j++ {
+			var line orcaLine
+			determinant := det(lines[i].direction, lines[j].direction)
+			if math.Abs(determinant) <= orcaEpsilon {
+				if lines[i].direction.Dot(lines[j].direction) > 0 {
+					// same direction
+					continue
+				}
+				// opposite directions
+				line.point = lines[i].point.Add(lines[j].point).Mul(0.5)
+			} else {
+				t := det(lines[j].direction, lines[i].point.Sub(lines[j].point)) / determinant
+				line.point = lines[i].point.Add(lines[i].direction.Mul(t))
+			}
+			line.direction = lines[j].direction.Sub(lines[i].direction).Normalize()
+			projected = append(projected, line)
+		}
+
+		previous := result
+		optimal := mgl64.Vec2{-lines[i].direction[1], lines[i].direction[0]}
+		next, failed := linearProgram2(projected, radius, optimal, true)
+		if failed < len(projected) {
+			// can only happen from rounding errors, keep the last result
+			result = previous
+		} else {
+			result = next
+		}
+		distance = det(lines[i].direction, lines[i].point.Sub(result))
+	}
+	return result
+}
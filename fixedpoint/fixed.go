@@ -0,0 +1,209 @@
+// Package fixedpoint provides 32.32 fixed point numbers along with vector, matrix and
+// quaternion types that mirror mgl64. all of the math is done with integers so results
+// are identical across machines and compilers, which floats don't guarantee.
+package fixedpoint
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const fractionBits = 32
+
+// Fixed is a signed 32.32 fixed point number
+type Fixed int64
+
+const (
+	Zero   Fixed = 0
+	One    Fixed = 1 << fractionBits
+	Half   Fixed = One / 2
+	Pi     Fixed = 13493037705
+	TwoPi  Fixed = 26986075409
+	HalfPi Fixed = 6746518852
+
+	MaxValue Fixed = math.MaxInt64
+	MinValue Fixed = math.MinInt64
+
+	// Epsilon is the smallest positive value
+	Epsilon Fixed = 1
+)
+
+// FromInt converts an integer to a fixed point number
+func FromInt(i int) Fixed {
+	return Fixed(i) << fractionBits
+}
+
+// FromFloat converts a float to the nearest fixed point number. the conversion is exact
+// for the same input, so it's safe to use for constants and initial state.
+func FromFloat(f float64) Fixed {
+	return Fixed(math.Round(f * (1 << fractionBits)))
+}
+
+// FromRatio returns numerator / denominator
+func FromRatio(numerator, denominator int) Fixed {
+	return FromInt(numerator).Div(FromInt(denominator))
+}
+
+// Float converts to a float, which should only be used for display and debugging
+func (f Fixed) Float() float64 {
+	return float64(f) / (1 << fractionBits)
+}
+
+// Int returns the integer part, rounded towards negative infinity
+func (f Fixed) Int() int {
+	return int(f >> fractionBits)
+}
+
+func (f Fixed) String() string {
+	return fmt.Sprintf("%f", f.Float())
+}
+
+// Mul multiplies f by g, saturating to MaxValue or MinValue if the result doesn't fit
+func (f Fixed) Mul(g Fixed) Fixed {
+	negative := (f < 0) != (g < 0)
+	hi, lo := bits.Mul64(abs64(f), abs64(g))
+
+	// round to nearest
+	lo, carry := bits.Add64(lo, 1<<(fractionBits-1), 0)
+	hi += carry
+
+	// the result is the middle 64 bits of the product, anything above them or in the sign
+	// bit is too big
+	if hi>>(fractionBits-1) != 0 {
+		return saturate(negative)
+	}
+
+	result := Fixed(hi<<(64-fractionBits) | lo>>fractionBits)
+	if negative {
+		return -result
+	}
+	return result
+}
+
+// Div divides f by g, saturating if the result doesn't fit. dividing by zero panics like
+// integer division does.
+func (f Fixed) Div(g Fixed) Fixed {
+	if g == 0 {
+		panic("fixedpoint: division by zero")
+	}
+
+	negative := (f < 0) != (g < 0)
+	numerator := abs64(f)
+	hi, lo := numerator>>(64-fractionBits), numerator<<fractionBits
+	denominator := abs64(g)
+
+	// the quotient has to fit in 64 bits to divide and then in 63 to keep the sign
+	if hi >= denominator {
+		return saturate(negative)
+	}
+	quotient, _ := bits.Div64(hi, lo, denominator)
+	if quotient > math.MaxInt64 {
+		return saturate(negative)
+	}
+
+	result := Fixed(quotient)
+	if negative {
+		return -result
+	}
+	return result
+}
+
+func (f Fixed) Abs() Fixed {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+func (f Fixed) Sign() int {
+	if f < 0 {
+		return -1
+	} else if f > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Sqrt returns the square root, negative numbers return zero
+func (f Fixed) Sqrt() Fixed {
+	if f <= 0 {
+		return 0
+	}
+
+	// newton's method starting from a power of two close to the root
+	guess := Fixed(1) << ((bits.Len64(uint64(f)) + fractionBits) / 2)
+	for i := 0; i < 64; i++ {
+		next := (guess + f.Div(guess)) / 2
+		if next == guess || next == guess+1 {
+			break
+		}
+		guess = next
+	}
+	return guess
+}
+
+// Mod returns f modulo g with the sign of g
+func (f Fixed) Mod(g Fixed) Fixed {
+	m := f % g
+	if m != 0 && (m < 0) != (g < 0) {
+		m += g
+	}
+	return m
+}
+
+func Min(a, b Fixed) Fixed {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func Max(a, b Fixed) Fixed {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func Clamp(f, low, high Fixed) Fixed {
+	return Max(low, Min(f, high))
+}
+
+// Sin is computed with a taylor series after reducing the angle to [-pi/2, pi/2]
+func Sin(angle Fixed) Fixed {
+	x := (angle + Pi).Mod(TwoPi) - Pi
+	if x > HalfPi {
+		x = Pi - x
+	} else if x < -HalfPi {
+		x = -Pi - x
+	}
+
+	x2 := x.Mul(x)
+	term := x
+	sum := x
+	for n := 1; n <= 7; n++ {
+		term = -term.Mul(x2).Div(FromInt((2 * n) * (2*n + 1)))
+		sum += term
+	}
+	return sum
+}
+
+func Cos(angle Fixed) Fixed {
+	return Sin(angle + HalfPi)
+}
+
+// saturate returns the value a result that's too big clamps to
+func saturate(negative bool) Fixed {
+	if negative {
+		return MinValue
+	}
+	return MaxValue
+}
+
+func abs64(f Fixed) uint64 {
+	if f < 0 {
+		return uint64(-f)
+	}
+	return uint64(f)
+}
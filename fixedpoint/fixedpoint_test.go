@@ -0,0 +1,115 @@
+package fixedpoint_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/fixedpoint"
+)
+
+const tolerance = 1e-6
+
+func TestArithmetic(t *testing.T) {
+	values := []float64{0, 1, -1, 0.5, -2.25, 3.14159, 1000.125, -0.001, 12345.678}
+	for _, a := range values {
+		for _, b := range values {
+			fa, fb := fixedpoint.FromFloat(a), fixedpoint.FromFloat(b)
+
+			if got := (fa + fb).Float(); math.Abs(got-(a+b)) > tolerance {
+				t.Errorf("%f + %f = %f", a, b, got)
+			}
+			if got := fa.Mul(fb).Float(); math.Abs(got-a*b) > tolerance*math.Max(1, math.Abs(a*b)) {
+				t.Errorf("%f * %f = %f", a, b, got)
+			}
+			if b != 0 {
+				if got := fa.Div(fb).Float(); math.Abs(got-a/b) > tolerance*math.Max(1, math.Abs(a/b)) {
+					t.Errorf("%f / %f = %f", a, b, got)
+				}
+			}
+		}
+	}
+}
+
+func TestOverflowSaturates(t *testing.T) {
+	big := fixedpoint.FromInt(1 << 20)
+	cases := []struct {
+		name     string
+		got      fixedpoint.Fixed
+		expected fixedpoint.Fixed
+	}{
+		{"big * big", big.Mul(big), fixedpoint.MaxValue},
+		{"big * -big", big.Mul(-big), fixedpoint.MinValue},
+		{"-big * -big", (-big).Mul(-big), fixedpoint.MaxValue},
+		{"max * 2", fixedpoint.MaxValue.Mul(fixedpoint.FromInt(2)), fixedpoint.MaxValue},
+		{"min * min", fixedpoint.MinValue.Mul(fixedpoint.MinValue), fixedpoint.MaxValue},
+		{"big / epsilon", big.Div(fixedpoint.Epsilon), fixedpoint.MaxValue},
+		{"-big / epsilon", (-big).Div(fixedpoint.Epsilon), fixedpoint.MinValue},
+		{"max / half", fixedpoint.MaxValue.Div(fixedpoint.Half), fixedpoint.MaxValue},
+		{"min / -half", fixedpoint.MinValue.Div(-fixedpoint.Half), fixedpoint.MaxValue},
+		// the largest products that still fit aren't clamped
+		{"max * 1", fixedpoint.MaxValue.Mul(fixedpoint.One), fixedpoint.MaxValue},
+		{"min * 1", fixedpoint.MinValue.Mul(fixedpoint.One), fixedpoint.MinValue},
+		{"2^15 * 2^15", fixedpoint.FromInt(1 << 15).Mul(fixedpoint.FromInt(1 << 15)), fixedpoint.FromInt(1 << 30)},
+	}
+	for _, c := range cases {
+		if c.got != c.expected {
+			t.Errorf("expected %s to be %v but got %v", c.name, c.expected, c.got)
+		}
+	}
+}
+
+func TestSqrt(t *testing.T) {
+	for _, v := range []float64{0, 0.0001, 0.25, 1, 2, 9, 100.5, 123456.789} {
+		if got := fixedpoint.FromFloat(v).Sqrt().Float(); math.Abs(got-math.Sqrt(v)) > tolerance {
+			t.Errorf("expected sqrt(%f) = %f but got %f", v, math.Sqrt(v), got)
+		}
+	}
+}
+
+func TestTrig(t *testing.T) {
+	for angle := -10.0; angle <= 10; angle += 0.1 {
+		fixedAngle := fixedpoint.FromFloat(angle)
+		if got := fixedpoint.Sin(fixedAngle).Float(); math.Abs(got-math.Sin(angle)) > tolerance {
+			t.Errorf("expected sin(%f) = %f but got %f", angle, math.Sin(angle), got)
+		}
+		if got := fixedpoint.Cos(fixedAngle).Float(); math.Abs(got-math.Cos(angle)) > tolerance {
+			t.Errorf("expected cos(%f) = %f but got %f", angle, math.Cos(angle), got)
+		}
+	}
+}
+
+func TestMatchesMgl(t *testing.T) {
+	a := mgl64.Vec3{1.5, -2, 0.25}
+	b := mgl64.Vec3{-0.5, 3, 4}
+	fa, fb := fixedpoint.Vec3FromMgl(a), fixedpoint.Vec3FromMgl(b)
+
+	if got := fa.Cross(fb).Mgl(); !got.ApproxEqualThreshold(a.Cross(b), tolerance) {
+		t.Errorf("expected cross product %v but got %v", a.Cross(b), got)
+	}
+	if got := fa.Normalize().Mgl(); !got.ApproxEqualThreshold(a.Normalize(), tolerance) {
+		t.Errorf("expected normalized vector %v but got %v", a.Normalize(), got)
+	}
+
+	axis := mgl64.Vec3{1, 2, 3}.Normalize()
+	q := mgl64.QuatRotate(0.7, axis)
+	fq := fixedpoint.QuatRotate(fixedpoint.FromFloat(0.7), fixedpoint.Vec3FromMgl(axis))
+	if got := fq.Rotate(fa).Mgl(); !got.ApproxEqualThreshold(q.Rotate(a), tolerance) {
+		t.Errorf("expected rotated vector %v but got %v", q.Rotate(a), got)
+	}
+	if got := fq.Mat3().Mul3x1(fa).Mgl(); !got.ApproxEqualThreshold(q.Rotate(a), tolerance) {
+		t.Errorf("expected the rotation matrix to rotate to %v but got %v", q.Rotate(a), got)
+	}
+
+	r := mgl64.QuatRotate(-1.2, mgl64.Vec3{0, 1, 0})
+	fr := fixedpoint.QuatRotate(fixedpoint.FromFloat(-1.2), fixedpoint.Vec3{0, fixedpoint.One, 0})
+	if got, expected := fq.Mul(fr).Rotate(fa).Mgl(), q.Mul(r).Rotate(a); !got.ApproxEqualThreshold(expected, tolerance) {
+		t.Errorf("expected composed rotation %v but got %v", expected, got)
+	}
+
+	m := mgl64.Mat3FromRows(mgl64.Vec3{1, 2, 3}, mgl64.Vec3{4, 5, 6}, mgl64.Vec3{7, 8, 10})
+	fm := fixedpoint.Mat3FromRows(fixedpoint.Vec3FromFloats(1, 2, 3), fixedpoint.Vec3FromFloats(4, 5, 6), fixedpoint.Vec3FromFloats(7, 8, 10))
+	if got := fm.Mul3(fm.Transpose()).Mul3x1(fa).Mgl(); !got.ApproxEqualThreshold(m.Mul3(m.Transpose()).Mul3x1(a), tolerance) {
+		t.Errorf("expected %v but got %v", m.Mul3(m.Transpose()).Mul3x1(a), got)
+	}
+}
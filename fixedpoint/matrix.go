@@ -0,0 +1,74 @@
+package fixedpoint
+
+// Mat3 mirrors mgl64.Mat3 and is stored in column major order
+type Mat3 [9]Fixed
+
+func Ident3() Mat3 {
+	return Mat3{One, 0, 0, 0, One, 0, 0, 0, One}
+}
+
+func Diag3(v Vec3) Mat3 {
+	return Mat3{v[0], 0, 0, 0, v[1], 0, 0, 0, v[2]}
+}
+
+func Mat3FromCols(col0, col1, col2 Vec3) Mat3 {
+	return Mat3{col0[0], col0[1], col0[2], col1[0], col1[1], col1[2], col2[0], col2[1], col2[2]}
+}
+
+func Mat3FromRows(row0, row1, row2 Vec3) Mat3 {
+	return Mat3{row0[0], row1[0], row2[0], row0[1], row1[1], row2[1], row0[2], row1[2], row2[2]}
+}
+
+func (m Mat3) At(row, col int) Fixed {
+	return m[col*3+row]
+}
+
+func (m Mat3) Row(row int) Vec3 {
+	return Vec3{m[row], m[row+3], m[row+6]}
+}
+
+func (m Mat3) Col(col int) Vec3 {
+	return Vec3{m[col*3], m[col*3+1], m[col*3+2]}
+}
+
+func (m Mat3) Add(n Mat3) Mat3 {
+	var result Mat3
+	for i := range m {
+		result[i] = m[i] + n[i]
+	}
+	return result
+}
+
+func (m Mat3) Sub(n Mat3) Mat3 {
+	var result Mat3
+	for i := range m {
+		result[i] = m[i] - n[i]
+	}
+	return result
+}
+
+func (m Mat3) Mul(c Fixed) Mat3 {
+	var result Mat3
+	for i := range m {
+		result[i] = m[i].Mul(c)
+	}
+	return result
+}
+
+func (m Mat3) Mul3(n Mat3) Mat3 {
+	var result Mat3
+	for col := 0; col < 3; col++ {
+		for row := 0; row < 3; row++ {
+			result[col*3+row] = m.Row(row).Dot(n.Col(col))
+		}
+	}
+	return result
+}
+
+func (m Mat3) Mul3x1(v Vec3) Vec3 {
+	return Vec3{m.Row(0).Dot(v), m.Row(1).Dot(v), m.Row(2).Dot(v)}
+}
+
+func (m Mat3) Transpose() Mat3 {
+	return Mat3FromRows(m.Col(0), m.Col(1), m.Col(2))
+}
@@ -0,0 +1,86 @@
+package fixedpoint
+
+// Quat mirrors mgl64.Quat
+type Quat struct {
+	W Fixed
+	V Vec3
+}
+
+func QuatIdent() Quat {
+	return Quat{W: One}
+}
+
+// QuatRotate creates a rotation of angle radians about axis, axis should be normalized
+func QuatRotate(angle Fixed, axis Vec3) Quat {
+	half := angle / 2
+	return Quat{W: Cos(half), V: axis.Mul(Sin(half))}
+}
+
+func (q Quat) X() Fixed {
+	return q.V[0]
+}
+
+func (q Quat) Y() Fixed {
+	return q.V[1]
+}
+
+func (q Quat) Z() Fixed {
+	return q.V[2]
+}
+
+func (q Quat) Add(r Quat) Quat {
+	return Quat{W: q.W + r.W, V: q.V.Add(r.V)}
+}
+
+func (q Quat) Sub(r Quat) Quat {
+	return Quat{W: q.W - r.W, V: q.V.Sub(r.V)}
+}
+
+func (q Quat) Scale(c Fixed) Quat {
+	return Quat{W: q.W.Mul(c), V: q.V.Mul(c)}
+}
+
+func (q Quat) Mul(r Quat) Quat {
+	return Quat{
+		W: q.W.Mul(r.W) - q.V.Dot(r.V),
+		V: q.V.Cross(r.V).Add(r.V.Mul(q.W)).Add(q.V.Mul(r.W)),
+	}
+}
+
+func (q Quat) Conjugate() Quat {
+	return Quat{W: q.W, V: q.V.Mul(-One)}
+}
+
+func (q Quat) Dot(r Quat) Fixed {
+	return q.W.Mul(r.W) + q.V.Dot(r.V)
+}
+
+func (q Quat) Len() Fixed {
+	return q.Dot(q).Sqrt()
+}
+
+// Normalize returns the unit quaternion, a zero quaternion becomes the identity
+func (q Quat) Normalize() Quat {
+	l := q.Len()
+	if l == 0 {
+		return QuatIdent()
+	}
+	return Quat{W: q.W.Div(l), V: Vec3{q.V[0].Div(l), q.V[1].Div(l), q.V[2].Div(l)}}
+}
+
+// Rotate rotates v by the unit quaternion q
+func (q Quat) Rotate(v Vec3) Vec3 {
+	cross := q.V.Cross(v)
+	return v.Add(cross.Mul(2 * q.W)).Add(q.V.Cross(cross).Mul(2 * One))
+}
+
+// Mat3 returns the rotation matrix of the unit quaternion q
+func (q Quat) Mat3() Mat3 {
+	x, y, z, w := q.V[0], q.V[1], q.V[2], q.W
+	two := 2 * One
+	return Mat3FromRows(
+		Vec3{One - two.Mul(y.Mul(y)+z.Mul(z)), two.Mul(x.Mul(y) - w.Mul(z)), two.Mul(x.Mul(z) + w.Mul(y))},
+		Vec3{two.Mul(x.Mul(y) + w.Mul(z)), One - two.Mul(x.Mul(x)+z.Mul(z)), two.Mul(y.Mul(z) - w.Mul(x))},
+		Vec3{two.Mul(x.Mul(z) - w.Mul(y)), two.Mul(y.Mul(z) + w.Mul(x)), One - two.Mul(x.Mul(x)+y.Mul(y))},
+	)
+}
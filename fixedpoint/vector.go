@@ -0,0 +1,83 @@
+package fixedpoint
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Vec3 mirrors mgl64.Vec3
+type Vec3 [3]Fixed
+
+func Vec3FromFloats(x, y, z float64) Vec3 {
+	return Vec3{FromFloat(x), FromFloat(y), FromFloat(z)}
+}
+
+func Vec3FromMgl(v mgl64.Vec3) Vec3 {
+	return Vec3FromFloats(v.X(), v.Y(), v.Z())
+}
+
+// Mgl converts to a float vector, which should only be used for rendering and debugging
+func (v Vec3) Mgl() mgl64.Vec3 {
+	return mgl64.Vec3{v[0].Float(), v[1].Float(), v[2].Float()}
+}
+
+func (v Vec3) X() Fixed {
+	return v[0]
+}
+
+func (v Vec3) Y() Fixed {
+	return v[1]
+}
+
+func (v Vec3) Z() Fixed {
+	return v[2]
+}
+
+func (v Vec3) Add(u Vec3) Vec3 {
+	return Vec3{v[0] + u[0], v[1] + u[1], v[2] + u[2]}
+}
+
+func (v Vec3) Sub(u Vec3) Vec3 {
+	return Vec3{v[0] - u[0], v[1] - u[1], v[2] - u[2]}
+}
+
+func (v Vec3) Mul(c Fixed) Vec3 {
+	return Vec3{v[0].Mul(c), v[1].Mul(c), v[2].Mul(c)}
+}
+
+func (v Vec3) Dot(u Vec3) Fixed {
+	return v[0].Mul(u[0]) + v[1].Mul(u[1]) + v[2].Mul(u[2])
+}
+
+func (v Vec3) Cross(u Vec3) Vec3 {
+	return Vec3{
+		v[1].Mul(u[2]) - v[2].Mul(u[1]),
+		v[2].Mul(u[0]) - v[0].Mul(u[2]),
+		v[0].Mul(u[1]) - v[1].Mul(u[0]),
+	}
+}
+
+func (v Vec3) LenSqr() Fixed {
+	return v.Dot(v)
+}
+
+func (v Vec3) Len() Fixed {
+	return v.LenSqr().Sqrt()
+}
+
+// Normalize returns the unit vector in the direction of v, the zero vector is returned as is
+func (v Vec3) Normalize() Vec3 {
+	l := v.Len()
+	if l == 0 {
+		return v
+	}
+	return Vec3{v[0].Div(l), v[1].Div(l), v[2].Div(l)}
+}
+
+func (v Vec3) ApproxEqualThreshold(u Vec3, threshold Fixed) bool {
+	for i := range v {
+		if (v[i] - u[i]).Abs() > threshold {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package pathing
+
+import (
+	"math"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// AreaType is what kind of ground a polygon is, like road, grass or water. what the types
+// mean is up to the game, polygons are DefaultArea unless set otherwise.
+type AreaType uint8
+
+const (
+	DefaultArea AreaType = 0
+	// MaxAreaTypes is how many area types fit in an AreaMask
+	MaxAreaTypes = 64
+)
+
+// AreaMask is a set of area types
+type AreaMask uint64
+
+func NewAreaMask(areas ...AreaType) AreaMask {
+	var mask AreaMask
+	for _, area := range areas {
+		mask |= 1 << area
+	}
+	return mask
+}
+
+func (m AreaMask) Has(area AreaType) bool {
+	return m&(1<<area) != 0
+}
+
+// AreaFilter is how an agent sees the areas of the navmesh. walking through an area costs the
+// distance times its multiplier in Costs, areas without one cost 1. excluded areas are never
+// entered.
+type AreaFilter struct {
+	Costs    map[AreaType]float64
+	Excluded AreaMask
+}
+
+func (f AreaFilter) cost(area AreaType) float64 {
+	if multiplier, ok := f.Costs[area]; ok {
+		return multiplier
+	}
+	return 1
+}
+
+// minCost is the smallest multiplier, the A* heuristic is scaled by it so that it never
+// overestimates the cost of walking
+func (f AreaFilter) minCost() float64 {
+	min := 1.0
+	for area, multiplier := range f.Costs {
+		if !f.Excluded.Has(area) {
+			min = math.Min(min, multiplier)
+		}
+	}
+	return min
+}
+
+// SetArea sets the area type of a polygon on the navmesh
+func (nm *NavMesh) SetArea(polygon *geometry.Polygon, area AreaType) {
+	nm.changed()
+	if area == DefaultArea {
+		delete(nm.areas, polygon)
+		return
+	}
+	nm.areas[polygon] = area
+}
+
+func (nm *NavMesh) Area(polygon *geometry.Polygon) AreaType {
+	return nm.areas[polygon]
+}
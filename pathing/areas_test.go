@@ -0,0 +1,96 @@
+package pathing
+
+import (
+	"testing"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+const (
+	roadArea AreaType = iota + 1
+	grassArea
+	waterArea
+)
+
+// pond is two rows of squares where the middle of the bottom row is water and the top row is
+// a road
+func pond() (*NavMesh, *geometry.Polygon) {
+	var polygons []*geometry.Polygon
+	for _, offset := range [][2]float64{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {1, 1}, {2, 1}} {
+		polygons = append(polygons, sqWithOffset(10, offset[0], offset[1]))
+	}
+	navmesh := ConstructNavMesh(polygons)
+	navmesh.SetArea(polygons[1], waterArea)
+	for _, polygon := range polygons[3:] {
+		navmesh.SetArea(polygon, roadArea)
+	}
+	return navmesh, polygons[1]
+}
+
+// assertAvoids fails if the path goes through the middle of the polygon
+func assertAvoids(t *testing.T, start geometry.Point, path []geometry.Point, polygon *geometry.Polygon) {
+	t.Helper()
+	if len(path) == 0 {
+		t.Fatal("expected a path")
+	}
+	previous := start.Vector3()
+	for _, point := range path {
+		for s := 0.0; s <= 1; s += 0.01 {
+			position := previous.Add(point.Vector3().Sub(previous).Mul(s))
+			if position[0] > 10.01 && position[0] < 19.99 && position[2] < 9.99 && polygon.ContainsPoint(geometry.Point(position)) {
+				t.Fatalf("path %v goes through %v", path, polygon.Points())
+			}
+		}
+		previous = point.Vector3()
+	}
+}
+
+func TestAreaFilterExcludes(t *testing.T) {
+	navmesh, water := pond()
+	start, goal := geometry.Point{5, 0, 5}, geometry.Point{25, 0, 5}
+
+	p := Planner{}
+	p.SetNavMesh(navmesh)
+	assertPathEq(t, []geometry.Point{start, goal}, p.FindPath(start, goal))
+
+	// infantry walk around the water
+	p.SetAreaFilter(AreaFilter{Excluded: NewAreaMask(waterArea)})
+	path := p.FindPath(start, goal)
+	assertPathEq(t, []geometry.Point{start, {10, 0, 10}, {20, 0, 10}, goal}, path)
+	assertAvoids(t, start, path, water)
+
+	if path := p.FindPath(start, geometry.Point{15, 0, 5}); path != nil {
+		t.Errorf("expected no path into the water but got %v", path)
+	}
+}
+
+func TestAreaFilterCosts(t *testing.T) {
+	navmesh, water := pond()
+	navmesh.SetArea(water, grassArea)
+	start, goal := geometry.Point{5, 0, 5}, geometry.Point{25, 0, 5}
+
+	p := Planner{}
+	p.SetNavMesh(navmesh)
+
+	assertPathEq(t, []geometry.Point{start, goal}, p.FindPath(start, goal))
+
+	// vehicles are slow on grass and fast on roads
+	p.SetAreaFilter(AreaFilter{Costs: map[AreaType]float64{grassArea: 5, roadArea: 0.5}})
+	path := p.FindPath(start, goal)
+	assertPathEq(t, []geometry.Point{start, {10, 0, 10}, {20, 0, 10}, goal}, path)
+	assertAvoids(t, start, path, water)
+}
+
+func TestReplacePolygonKeepsArea(t *testing.T) {
+	navmesh, water := pond()
+	left := geometry.NewPolygon([]geometry.Point{{10, 0, 0}, {10, 0, 10}, {15, 0, 10}, {15, 0, 0}})
+	right := geometry.NewPolygon([]geometry.Point{{15, 0, 0}, {15, 0, 10}, {20, 0, 10}, {20, 0, 0}})
+	navmesh.ReplacePolygon(water, left, right)
+
+	if navmesh.Area(water) != DefaultArea {
+		t.Error("expected the removed polygon to lose its area")
+	}
+	if navmesh.Area(left) != waterArea || navmesh.Area(right) != waterArea {
+		t.Error("expected the replacements to be water")
+	}
+}
@@ -0,0 +1,994 @@
+package pathing
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/geometry"
+	"github.com/kkevinchou/kitolib/modelspec"
+	"github.com/kkevinchou/kitolib/utils"
+)
+
+// BuildSettings describe the voxels a navmesh is built from and the agent that walks on it
+type BuildSettings struct {
+	// CellSize is the width and depth of a voxel
+	CellSize float64
+	// CellHeight is how far apart two surfaces in the same column need to be to stay separate
+	CellHeight float64
+
+	AgentRadius float64
+	AgentHeight float64
+	// AgentMaxSlope is the steepest slope the agent can walk on, in degrees
+	AgentMaxSlope float64
+	// AgentMaxClimb is the tallest step the agent can walk up
+	AgentMaxClimb float64
+
+	// MaxEdgeError is how far the outline of a wall can stray from the voxels when it's
+	// simplified, zero keeps it on them
+	MaxEdgeError float64
+}
+
+func DefaultBuildSettings() BuildSettings {
+	return BuildSettings{
+		CellSize:      0.3,
+		CellHeight:    0.2,
+		AgentRadius:   0.6,
+		AgentHeight:   2,
+		AgentMaxSlope: 45,
+		AgentMaxClimb: 0.9,
+		MaxEdgeError:  0.4,
+	}
+}
+
+// BuildResult is what the navmesh is made from. Steps are the indexes of the polygons at the
+// bottom and top of each step, which have to be joined with NavMesh.ConnectStep. Contours are
+// the outlines of the walkable regions and are mostly useful for debugging.
+type BuildResult struct {
+	Polygons []*geometry.Polygon
+	Steps    [][2]int
+	Contours [][]geometry.Point
+}
+
+// BuildNavMesh builds a navmesh covering everywhere the agent can stand on the mesh
+func BuildNavMesh(mesh collider.TriMesh, settings BuildSettings) *NavMesh {
+	result := BuildPolygons(mesh, settings)
+	navmesh := ConstructNavMesh(result.Polygons)
+	result.connectSteps(navmesh)
+	return navmesh
+}
+
+// connectSteps joins the polygons on either side of each step
+func (r BuildResult) connectSteps(navmesh *NavMesh) {
+	for _, s := range r.Steps {
+		navmesh.ConnectStep(r.Polygons[s[0]], r.Polygons[s[1]])
+	}
+}
+
+func BuildNavMeshFromPrimitives(primitives []*modelspec.PrimitiveSpecification, settings BuildSettings) *NavMesh {
+	return BuildNavMesh(*collider.CreateTriMeshFromPrimitives(primitives), settings)
+}
+
+// BuildPolygons is a simplified take on Recast. the mesh is voxelized into columns of spans,
+// the tops of spans the agent can stand on are joined into regions that are shrunk by the
+// agent's radius, and the outlines of each region are traced, simplified, triangulated and
+// merged back into convex polygons. a region whose outlines can't be triangulated is split
+// into rectangles instead. the polygons wind the same way as the rest of the navmesh and
+// neighboring polygons share their vertices exactly so that AddPolygon finds the portals
+// between them. where regions meet at a step the polygons on either side have vertices right
+// above each other instead, and are listed in Steps.
+//
+// walls are simplified to within MaxEdgeError of the voxels. with zero the polygons follow the
+// voxels so diagonal walls come out as stairs, a smaller CellSize follows them more closely.
+func BuildPolygons(mesh collider.TriMesh, settings BuildSettings) BuildResult {
+	return buildPolygons(mesh, settings, nil, nil)
+}
+
+// tileBounds are the cells [min, max) of a tile
+type tileBounds struct {
+	min, max [2]int
+}
+
+// buildPolygons builds the polygons for the whole mesh, or only the ones inside the tile
+// when there is one. tiles are built with a border around them so that the edges of the
+// walkable area are in the same places as they would be without tiles, and the polygons have
+// a vertex at every cell on the tile's sides to line up with the tiles next to them.
+func buildPolygons(mesh collider.TriMesh, settings BuildSettings, tile *tileBounds, obstacles []Obstacle) BuildResult {
+	if settings.CellSize <= 0 {
+		return BuildResult{}
+	}
+
+	hf, ok := rasterize(mesh, settings, tile)
+	if !ok {
+		return BuildResult{}
+	}
+
+	field := newOpenField(hf, settings)
+	field.tile = tile
+	field.carve(obstacles, settings.AgentHeight)
+	field.erode(settings.AgentRadius / settings.CellSize)
+	field.clipToTile()
+	regions := field.buildRegions()
+
+	vertices := newVertexCache(settings.CellHeight / 2)
+	steps := field.steps(vertices)
+	stepEnds := map[[2]int]bool{}
+	for _, s := range steps {
+		stepEnds[s.corner(s.from)], stepEnds[s.corner(s.to)] = true, true
+	}
+
+	var result BuildResult
+	var polygons []cellPolygon
+	for _, region := range regions {
+		var outlines [][][2]int
+		for _, contour := range field.traceContours(region) {
+			outline := field.simplifyContour(contour, stepEnds, settings.MaxEdgeError/settings.CellSize)
+			outlines = append(outlines, outline)
+			result.Contours = append(result.Contours, field.outlinePoints(outline, region[0]))
+		}
+		triangulated, ok := triangulateRegion(outlines)
+		if !ok {
+			polygons = append(polygons, field.fallbackRectangles(region)...)
+			continue
+		}
+		for _, corners := range triangulated {
+			polygons = append(polygons, cellPolygon{corners: corners, span: region[0]})
+		}
+	}
+
+	// every vertex has to be known before any edge can be split at its neighbors' vertices
+	for _, p := range polygons {
+		for _, corner := range p.corners {
+			vertices.height(corner[0], corner[1], field.surfaceHeight(p.span, corner[0], corner[1]))
+		}
+	}
+
+	// the vertices along a step get a height on both sides of it so that the polygons on
+	// either side can be joined where they overlap
+	for _, s := range steps {
+		for _, p := range polygons {
+			for _, corner := range p.corners {
+				if s.on(corner) {
+					vertices.height(corner[0], corner[1], field.surfaceHeight(s.lower, corner[0], corner[1]))
+					vertices.height(corner[0], corner[1], field.surfaceHeight(s.upper, corner[0], corner[1]))
+				}
+			}
+		}
+	}
+
+	for _, p := range polygons {
+		result.Polygons = append(result.Polygons, geometry.NewPolygon(field.polygonPoints(p, vertices)))
+	}
+	result.Steps = field.stepPolygons(steps, polygons)
+	return result
+}
+
+const flatEpsilon = 1e-6
+
+// direction order is -x, +z, +x, -z
+var (
+	directionX = [4]int{-1, 0, 1, 0}
+	directionZ = [4]int{0, 1, 0, -1}
+)
+
+type span struct {
+	min, max float64
+	walkable bool
+
+	// the plane of the walkable surface on top of the span and the surface's height, which
+	// is below max when the span was merged with a step
+	normal  mgl64.Vec3
+	offset  float64
+	surface float64
+}
+
+// heightfield is a grid of columns of solid spans
+type heightfield struct {
+	// originX and originZ are the first column's cell, cells are counted from the world's
+	// origin so that tiles agree on where the cells are
+	originX  int
+	originZ  int
+	cellSize float64
+	width    int
+	depth    int
+	columns  [][]span
+}
+
+func rasterize(mesh collider.TriMesh, settings BuildSettings, tile *tileBounds) (*heightfield, bool) {
+	var vertices []mgl64.Vec3
+	for _, triangle := range mesh.Triangles {
+		vertices = append(vertices, triangle.Points[:]...)
+	}
+	if len(vertices) == 0 {
+		return nil, false
+	}
+
+	cs := settings.CellSize
+	hf := &heightfield{cellSize: cs}
+	if tile != nil {
+		// enough padding that the edges outside the tile erode it the same way
+		padding := int(math.Ceil(settings.AgentRadius/cs)) + 2
+		hf.originX, hf.originZ = tile.min[0]-padding, tile.min[1]-padding
+		hf.width = tile.max[0] - tile.min[0] + 2*padding
+		hf.depth = tile.max[1] - tile.min[1] + 2*padding
+	} else {
+		// a column of padding on every side so that walls on the bounds have somewhere to go
+		bounds := collider.BoundingBoxFromVertices(vertices)
+		hf.originX = int(math.Floor(bounds.MinVertex[0]/cs)) - 1
+		hf.originZ = int(math.Floor(bounds.MinVertex[2]/cs)) - 1
+		hf.width = int(math.Ceil(bounds.MaxVertex[0]/cs)) + 1 - hf.originX
+		hf.depth = int(math.Ceil(bounds.MaxVertex[2]/cs)) + 1 - hf.originZ
+	}
+	hf.columns = make([][]span, hf.width*hf.depth)
+
+	minNormalY := math.Cos(mgl64.DegToRad(settings.AgentMaxSlope))
+	minArea := cs * cs * 1e-6
+	surfaces := make([][]surface, len(hf.columns))
+	for _, triangle := range mesh.Triangles {
+		normal := triangle.Points[1].Sub(triangle.Points[0]).Cross(triangle.Points[2].Sub(triangle.Points[0]))
+		if normal.Len() == 0 {
+			continue
+		}
+		normal = normal.Normalize()
+
+		box := collider.BoundingBoxFromVertices(triangle.Points[:])
+		minX, minZ := hf.cell(box.MinVertex)
+		maxX, maxZ := hf.cell(box.MaxVertex)
+		for z := minZ; z <= maxZ; z++ {
+			for x := minX; x <= maxX; x++ {
+				x0 := float64(hf.originX+x) * cs
+				z0 := float64(hf.originZ+z) * cs
+
+				clipped := triangle.Points[:]
+				clipped = clipPolygon(clipped, 0, x0, true)
+				clipped = clipPolygon(clipped, 0, x0+cs, false)
+				clipped = clipPolygon(clipped, 2, z0, true)
+				clipped = clipPolygon(clipped, 2, z0+cs, false)
+				// only touching the side of the column, vertical triangles still have an area
+				// so walls on a column's side end up in the columns on both sides
+				if len(clipped) < 3 || polygonArea(clipped) < minArea {
+					continue
+				}
+
+				s := span{
+					min:      math.MaxFloat64,
+					max:      -math.MaxFloat64,
+					walkable: normal.Y() >= minNormalY,
+					normal:   normal,
+					offset:   normal.Dot(triangle.Points[0]),
+				}
+				for _, point := range clipped {
+					s.min = math.Min(s.min, point.Y())
+					s.max = math.Max(s.max, point.Y())
+				}
+				s.surface = s.max
+				hf.addSpan(x+z*hf.width, s, settings.CellHeight, settings.AgentMaxClimb)
+
+				if normal.Y() > flatEpsilon {
+					surfaces[x+z*hf.width] = append(surfaces[x+z*hf.width], surface{y: s.max, up: true})
+				} else if normal.Y() < -flatEpsilon {
+					surfaces[x+z*hf.width] = append(surfaces[x+z*hf.width], surface{y: s.min})
+				}
+			}
+		}
+	}
+
+	for index, column := range surfaces {
+		hf.fillSolid(index, column, settings)
+	}
+	return hf, true
+}
+
+// surface is where a column passes through a triangle that isn't vertical
+type surface struct {
+	y  float64
+	up bool
+}
+
+// fillSolid fills the inside of closed meshes. going up the column, a surface facing down is
+// where the column goes into a mesh and the next one facing up is where it comes out. without
+// this the floor inside a box would be walkable if the box is tall enough.
+func (hf *heightfield) fillSolid(index int, column []surface, settings BuildSettings) {
+	// a mesh sitting on the floor has its bottom at the same height as the floor, the floor
+	// has to come first
+	key := func(s surface) float64 {
+		if s.up {
+			return s.y
+		}
+		return s.y + settings.CellHeight
+	}
+	sort.Slice(column, func(i, j int) bool { return key(column[i]) < key(column[j]) })
+
+	depth := 0
+	var bottom float64
+	for i, s := range column {
+		// triangles from the same face that share the column
+		if i > 0 && column[i-1].up == s.up && math.Abs(column[i-1].y-s.y) <= settings.CellHeight {
+			continue
+		}
+		if !s.up {
+			if depth == 0 {
+				bottom = s.y
+			}
+			depth++
+			continue
+		}
+		if depth == 0 {
+			// the surface isn't part of a closed mesh
+			continue
+		}
+		depth--
+		if depth == 0 {
+			hf.addSpan(index, span{min: bottom, max: s.y}, settings.CellHeight, settings.AgentMaxClimb)
+		}
+	}
+}
+
+// cell returns the column that contains the point, clamped to the grid
+func (hf *heightfield) cell(point mgl64.Vec3) (int, int) {
+	x := int(math.Floor(point[0]/hf.cellSize)) - hf.originX
+	z := int(math.Floor(point[2]/hf.cellSize)) - hf.originZ
+	return clampInt(x, 0, hf.width-1), clampInt(z, 0, hf.depth-1)
+}
+
+// addSpan merges s with every span in the column it overlaps
+func (hf *heightfield) addSpan(index int, s span, tolerance float64, climb float64) {
+	var kept []span
+	for _, existing := range hf.columns[index] {
+		if existing.min > s.max+tolerance || existing.max < s.min-tolerance {
+			kept = append(kept, existing)
+			continue
+		}
+		s = mergeSpans(s, existing, climb)
+	}
+	kept = append(kept, s)
+	sort.Slice(kept, func(i, j int) bool { return kept[i].min < kept[j].min })
+	hf.columns[index] = kept
+}
+
+// mergeSpans keeps the top surface of the two spans. when the tops are close enough to step
+// between, the merged span is walkable if either of them is, which lets the agent walk over
+// the edges of steps and low obstacles.
+func mergeSpans(a, b span, climb float64) span {
+	top, other := a, b
+	if b.max > a.max || (b.max == a.max && b.walkable && (!a.walkable || b.surface > a.surface)) {
+		top, other = b, a
+	}
+
+	merged := top
+	merged.min = math.Min(a.min, b.min)
+	if math.Abs(a.max-b.max) <= climb && other.walkable && (!top.walkable || other.surface > top.surface) {
+		merged.walkable = true
+		merged.normal, merged.offset, merged.surface = other.normal, other.offset, other.surface
+	}
+	return merged
+}
+
+// openSpan is the open space above a walkable span
+type openSpan struct {
+	x, z      int
+	floor     float64
+	ceiling   float64
+	normal    mgl64.Vec3
+	offset    float64
+	neighbors [4]int
+	distance  float64
+	removed   bool
+	region    int
+}
+
+type openField struct {
+	originX  int
+	originZ  int
+	cellSize float64
+	width    int
+	depth    int
+	spans    []openSpan
+	tile     *tileBounds
+}
+
+// newOpenField keeps the spans the agent fits on top of and connects the ones it can step
+// between
+func newOpenField(hf *heightfield, settings BuildSettings) *openField {
+	field := &openField{originX: hf.originX, originZ: hf.originZ, cellSize: hf.cellSize, width: hf.width, depth: hf.depth}
+	columns := make([][]int, len(hf.columns))
+	for index, column := range hf.columns {
+		for i, s := range column {
+			if !s.walkable {
+				continue
+			}
+			ceiling := math.Inf(1)
+			if i+1 < len(column) {
+				ceiling = column[i+1].min
+			}
+			if ceiling-s.max < settings.AgentHeight {
+				continue
+			}
+			columns[index] = append(columns[index], len(field.spans))
+			field.spans = append(field.spans, openSpan{
+				x:         index % hf.width,
+				z:         index / hf.width,
+				floor:     s.max,
+				ceiling:   ceiling,
+				normal:    s.normal,
+				offset:    s.offset,
+				neighbors: [4]int{-1, -1, -1, -1},
+				region:    -1,
+			})
+		}
+	}
+
+	for i := range field.spans {
+		s := &field.spans[i]
+		for d := 0; d < 4; d++ {
+			x, z := s.x+directionX[d], s.z+directionZ[d]
+			if x < 0 || x >= field.width || z < 0 || z >= field.depth {
+				continue
+			}
+			bestStep := math.MaxFloat64
+			for _, j := range columns[x+z*field.width] {
+				other := field.spans[j]
+				step := math.Abs(other.floor - s.floor)
+				clearance := math.Min(other.ceiling, s.ceiling) - math.Max(other.floor, s.floor)
+				if step <= settings.AgentMaxClimb && clearance >= settings.AgentHeight && step < bestStep {
+					s.neighbors[d], bestStep = j, step
+				}
+			}
+		}
+	}
+	return field
+}
+
+// neighbor returns the span in direction d if it's still connected
+func (f *openField) neighbor(i int, d int) (int, bool) {
+	j := f.spans[i].neighbors[d]
+	if j == -1 || f.spans[j].removed {
+		return -1, false
+	}
+	return j, true
+}
+
+// carve removes the spans an agent standing on would overlap the obstacles from
+func (f *openField) carve(obstacles []Obstacle, agentHeight float64) {
+	for i, s := range f.spans {
+		x, z := f.cornerPosition(s.x, s.z)
+		column := collider.BoundingBox{
+			MinVertex: mgl64.Vec3{x, s.floor, z},
+			MaxVertex: mgl64.Vec3{x + f.cellSize, s.floor + agentHeight, z + f.cellSize},
+		}
+		for _, obstacle := range obstacles {
+			if obstacle.blocks(column) {
+				f.spans[i].removed = true
+				break
+			}
+		}
+	}
+}
+
+// clipToTile removes the spans in the tile's border, they were only there for the erosion
+func (f *openField) clipToTile() {
+	if f.tile == nil {
+		return
+	}
+	for i, s := range f.spans {
+		x, z := f.originX+s.x, f.originZ+s.z
+		if x < f.tile.min[0] || x >= f.tile.max[0] || z < f.tile.min[1] || z >= f.tile.max[1] {
+			f.spans[i].removed = true
+		}
+	}
+}
+
+// onTileSide returns whether the corner is on the side of the tile being built
+func (f *openField) onTileSide(x, z int) bool {
+	if f.tile == nil {
+		return false
+	}
+	x, z = f.originX+x, f.originZ+z
+	insideX := x >= f.tile.min[0] && x <= f.tile.max[0]
+	insideZ := z >= f.tile.min[1] && z <= f.tile.max[1]
+	return (insideZ && (x == f.tile.min[0] || x == f.tile.max[0])) || (insideX && (z == f.tile.min[1] || z == f.tile.max[1]))
+}
+
+// erode removes the spans closer than radius cells to an edge. distances are measured from
+// the centers of the spans on the edge, which are half a cell in from the edge themselves.
+func (f *openField) erode(radius float64) {
+	if radius <= 0 {
+		return
+	}
+
+	queue := utils.NewPriorityQueue()
+	for i := range f.spans {
+		f.spans[i].distance = math.MaxFloat64
+		if f.onEdge(i) {
+			f.spans[i].distance = 0
+			queue.Push(i, 0)
+		}
+	}
+
+	for !queue.Empty() {
+		i := queue.Pop().(int)
+		for d := 0; d < 4; d++ {
+			j, ok := f.neighbor(i, d)
+			if !ok {
+				continue
+			}
+			f.relax(queue, i, j, 1)
+			// diagonals go through a straight neighbor
+			if k, ok := f.neighbor(j, (d+1)%4); ok {
+				f.relax(queue, i, k, math.Sqrt2)
+			}
+		}
+	}
+
+	for i := range f.spans {
+		if f.spans[i].distance < radius-1e-9 {
+			f.spans[i].removed = true
+		}
+	}
+}
+
+// onEdge returns whether any of the eight spans around the span is missing. the diagonals
+// count so that the inside corners of obstacles are eroded as much as their sides.
+func (f *openField) onEdge(i int) bool {
+	for d := 0; d < 4; d++ {
+		j, ok := f.neighbor(i, d)
+		if !ok {
+			return true
+		}
+		if _, ok := f.neighbor(j, (d+1)%4); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *openField) relax(queue *utils.PriorityQueue, from, to int, cost float64) {
+	if distance := f.spans[from].distance + cost; distance < f.spans[to].distance {
+		f.spans[to].distance = distance
+		queue.Push(to, distance)
+	}
+}
+
+// buildRegions flood fills connected spans that lie on the same plane so that every region
+// can be covered with flat polygons
+func (f *openField) buildRegions() [][]int {
+	var regions [][]int
+	for i := range f.spans {
+		if f.spans[i].removed || f.spans[i].region != -1 {
+			continue
+		}
+
+		id := len(regions)
+		key := planeKey(f.spans[i])
+		f.spans[i].region = id
+		region := []int{i}
+		stack := []int{i}
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for d := 0; d < 4; d++ {
+				j, ok := f.neighbor(current, d)
+				if !ok || f.spans[j].region != -1 || planeKey(f.spans[j]) != key {
+					continue
+				}
+				f.spans[j].region = id
+				region = append(region, j)
+				stack = append(stack, j)
+			}
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+func planeKey(s openSpan) [4]int64 {
+	return [4]int64{
+		int64(math.Round(s.normal[0] * 1e4)),
+		int64(math.Round(s.normal[1] * 1e4)),
+		int64(math.Round(s.normal[2] * 1e4)),
+		int64(math.Round(s.offset * 1e4)),
+	}
+}
+
+// cellPolygon is a convex polygon with its vertices on corners of the grid, span is any span
+// in its region for looking up the plane
+type cellPolygon struct {
+	corners [][2]int
+	span    int
+}
+
+// side returns the ranges along the line that the polygon's edges facing direction d cover
+func (p cellPolygon) side(d int, line int) [][2]int {
+	from, to := cellEdge(0, 0, d)
+	var ranges [][2]int
+	for k, a := range p.corners {
+		b := p.corners[(k+1)%len(p.corners)]
+		if d == 0 || d == 2 {
+			if a[0] == line && b[0] == line && (b[1]-a[1])*(to[1]-from[1]) > 0 {
+				ranges = append(ranges, [2]int{minInt(a[1], b[1]), maxInt(a[1], b[1])})
+			}
+		} else if a[1] == line && b[1] == line && (b[0]-a[0])*(to[0]-from[0]) > 0 {
+			ranges = append(ranges, [2]int{minInt(a[0], b[0]), maxInt(a[0], b[0])})
+		}
+	}
+	return ranges
+}
+
+// rect covers the cells [x0, x1) x [z0, z1), span is any span in it for looking up the plane
+type rect struct {
+	x0, z0, x1, z1 int
+	span           int
+}
+
+func (r rect) corners() [4][2]int {
+	return [4][2]int{{r.x0, r.z0}, {r.x0, r.z1}, {r.x1, r.z1}, {r.x1, r.z0}}
+}
+
+// fallbackRectangles greedily covers the region with rectangles, growing each one along x and
+// then z. it's only used for regions whose outline can't be triangulated.
+func (f *openField) fallbackRectangles(region []int) []cellPolygon {
+	cells := map[[2]int]int{}
+	for _, i := range region {
+		cells[[2]int{f.spans[i].x, f.spans[i].z}] = i
+	}
+	order := append([]int{}, region...)
+	sort.Slice(order, func(a, b int) bool {
+		sa, sb := f.spans[order[a]], f.spans[order[b]]
+		if sa.z != sb.z {
+			return sa.z < sb.z
+		}
+		return sa.x < sb.x
+	})
+
+	used := map[[2]int]bool{}
+	free := func(x, z int) bool {
+		_, ok := cells[[2]int{x, z}]
+		return ok && !used[[2]int{x, z}]
+	}
+
+	var polygons []cellPolygon
+	for _, i := range order {
+		s := f.spans[i]
+		if used[[2]int{s.x, s.z}] {
+			continue
+		}
+		x1 := s.x + 1
+		for free(x1, s.z) {
+			x1++
+		}
+		z1 := s.z + 1
+		for {
+			row := true
+			for x := s.x; x < x1; x++ {
+				if !free(x, z1) {
+					row = false
+					break
+				}
+			}
+			if !row {
+				break
+			}
+			z1++
+		}
+
+		for z := s.z; z < z1; z++ {
+			for x := s.x; x < x1; x++ {
+				used[[2]int{x, z}] = true
+			}
+		}
+		r := rect{x0: s.x, z0: s.z, x1: x1, z1: z1, span: i}
+		corners := r.corners()
+		polygons = append(polygons, cellPolygon{corners: corners[:], span: i})
+	}
+	return polygons
+}
+
+// surfaceHeight is the height of the span's surface at a corner of the grid
+func (f *openField) surfaceHeight(i int, x, z int) float64 {
+	s := f.spans[i]
+	worldX, worldZ := f.cornerPosition(x, z)
+	return (s.offset - s.normal[0]*worldX - s.normal[2]*worldZ) / s.normal[1]
+}
+
+func (f *openField) cornerPosition(x, z int) (float64, float64) {
+	return float64(f.originX+x) * f.cellSize, float64(f.originZ+z) * f.cellSize
+}
+
+// polygonPoints walks around the polygon, adding the vertices of neighboring polygons that
+// touch its straight sides so that the two share an edge. sides on the tile's sides get a
+// vertex at every cell since the tile next to it can put its vertices anywhere.
+func (f *openField) polygonPoints(p cellPolygon, vertices *vertexCache) []geometry.Point {
+	var points []geometry.Point
+	visit := func(x, z int, corner bool) {
+		y := f.surfaceHeight(p.span, x, z)
+		if corner || f.onTileSide(x, z) {
+			y = vertices.height(x, z, y)
+		} else if shared, ok := vertices.find(x, z, y); ok {
+			y = shared
+		} else {
+			return
+		}
+		worldX, worldZ := f.cornerPosition(x, z)
+		points = append(points, geometry.Point{worldX, y, worldZ})
+	}
+
+	for k, a := range p.corners {
+		b := p.corners[(k+1)%len(p.corners)]
+		visit(a[0], a[1], true)
+		if a[0] != b[0] && a[1] != b[1] {
+			continue
+		}
+		dx, dz := sign(b[0]-a[0]), sign(b[1]-a[1])
+		for x, z := a[0]+dx, a[1]+dz; x != b[0] || z != b[1]; x, z = x+dx, z+dz {
+			visit(x, z, false)
+		}
+	}
+	return startAtCorner(points)
+}
+
+// outlinePoints places the corners of an outline on the region's surface
+func (f *openField) outlinePoints(outline [][2]int, span int) []geometry.Point {
+	var points []geometry.Point
+	for _, corner := range outline {
+		x, z := f.cornerPosition(corner[0], corner[1])
+		points = append(points, geometry.Point{x, f.surfaceHeight(span, corner[0], corner[1]), z})
+	}
+	return points
+}
+
+// startAtCorner rotates the points so that the first three aren't in a line, polygons find
+// their plane from them
+func startAtCorner(points []geometry.Point) []geometry.Point {
+	n := len(points)
+	for i := range points {
+		a, b, c := points[i].Vector3(), points[(i+1)%n].Vector3(), points[(i+2)%n].Vector3()
+		if math.Abs(utils.Cross2D(b.Sub(a), c.Sub(b))) > 1e-9 {
+			return append(append([]geometry.Point{}, points[i:]...), points[:i]...)
+		}
+	}
+	return points
+}
+
+// cellEdge returns the corners of the cell's side in direction d, in the same order as the
+// polygons wind
+func cellEdge(x, z int, d int) ([2]int, [2]int) {
+	switch d {
+	case 0:
+		return [2]int{x, z}, [2]int{x, z + 1}
+	case 1:
+		return [2]int{x, z + 1}, [2]int{x + 1, z + 1}
+	case 2:
+		return [2]int{x + 1, z + 1}, [2]int{x + 1, z}
+	}
+	return [2]int{x + 1, z}, [2]int{x, z}
+}
+
+// step is where two regions the agent can step between meet at different heights. lower
+// and upper are spans in the two regions, the side of lower's cells in direction faces
+// upper, and the side runs from from to to along the line. lower is only the first region
+// found, it isn't always the lower one.
+type step struct {
+	lower, upper int
+	direction    int
+	line         int
+	from, to     int
+}
+
+func (s step) corner(u int) [2]int {
+	if s.direction == 0 || s.direction == 2 {
+		return [2]int{s.line, u}
+	}
+	return [2]int{u, s.line}
+}
+
+// on returns whether the corner is on the step
+func (s step) on(corner [2]int) bool {
+	line, u := corner[0], corner[1]
+	if s.direction == 1 || s.direction == 3 {
+		line, u = corner[1], corner[0]
+	}
+	return line == s.line && u >= s.from && u <= s.to
+}
+
+// stepPolygons returns the pairs of polygons that face each other across a step
+func (f *openField) stepPolygons(steps []step, polygons []cellPolygon) [][2]int {
+	var pairs [][2]int
+	seen := map[[2]int]bool{}
+	for _, s := range steps {
+		for i, a := range polygons {
+			if f.spans[a.span].region != f.spans[s.lower].region {
+				continue
+			}
+			for _, sideA := range a.side(s.direction, s.line) {
+				for j, b := range polygons {
+					if f.spans[b.span].region != f.spans[s.upper].region {
+						continue
+					}
+					for _, sideB := range b.side((s.direction+2)%4, s.line) {
+						from := maxInt(s.from, maxInt(sideA[0], sideB[0]))
+						to := minInt(s.to, minInt(sideA[1], sideB[1]))
+						if from < to && !seen[[2]int{i, j}] {
+							seen[[2]int{i, j}] = true
+							pairs = append(pairs, [2]int{i, j})
+						}
+					}
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// steps finds the sides shared by connected regions that don't line up. regions on
+// different planes can only share vertices where the planes meet, everywhere else the
+// polygons on either side are joined across the gap with NavMesh.ConnectStep.
+func (f *openField) steps(vertices *vertexCache) []step {
+	type stepKey struct {
+		regionA, regionB int
+		direction, line  int
+	}
+	runs := map[stepKey][]int{}
+	first := map[stepKey][2]int{}
+	var keys []stepKey
+	for i, s := range f.spans {
+		if s.removed {
+			continue
+		}
+		for d := 0; d < 4; d++ {
+			j, ok := f.neighbor(i, d)
+			if !ok || f.spans[j].region <= s.region {
+				continue
+			}
+			from, to := cellEdge(s.x, s.z, d)
+			if f.sharedCorner(i, j, from, vertices) && f.sharedCorner(i, j, to, vertices) {
+				continue
+			}
+
+			key := stepKey{regionA: s.region, regionB: f.spans[j].region, direction: d, line: from[0]}
+			u := s.z
+			if d == 1 || d == 3 {
+				key.line, u = from[1], s.x
+			}
+			if _, ok := runs[key]; !ok {
+				keys = append(keys, key)
+				first[key] = [2]int{i, j}
+			}
+			runs[key] = append(runs[key], u)
+		}
+	}
+
+	var steps []step
+	for _, key := range keys {
+		cells := runs[key]
+		sort.Ints(cells)
+		for start := 0; start < len(cells); {
+			end := start
+			for end+1 < len(cells) && cells[end+1] == cells[end]+1 {
+				end++
+			}
+			steps = append(steps, step{
+				lower:     first[key][0],
+				upper:     first[key][1],
+				direction: key.direction,
+				line:      key.line,
+				from:      cells[start],
+				to:        cells[end] + 1,
+			})
+			start = end + 1
+		}
+	}
+	return steps
+}
+
+// sharedCorner returns whether the surfaces of both spans end up at the same vertex at corner
+func (f *openField) sharedCorner(i, j int, corner [2]int, vertices *vertexCache) bool {
+	a := f.surfaceHeight(i, corner[0], corner[1])
+	b := f.surfaceHeight(j, corner[0], corner[1])
+	return math.Abs(a-b) <= vertices.tolerance
+}
+
+// vertexCache hands out the same height for every polygon that has a vertex at a corner, two
+// surfaces that meet at a corner can disagree by a rounding error
+type vertexCache struct {
+	tolerance float64
+	heights   map[[2]int][]float64
+}
+
+func newVertexCache(tolerance float64) *vertexCache {
+	return &vertexCache{tolerance: tolerance, heights: map[[2]int][]float64{}}
+}
+
+func (c *vertexCache) find(x, z int, y float64) (float64, bool) {
+	for _, height := range c.heights[[2]int{x, z}] {
+		if math.Abs(height-y) <= c.tolerance {
+			return height, true
+		}
+	}
+	return 0, false
+}
+
+func (c *vertexCache) height(x, z int, y float64) float64 {
+	if height, ok := c.find(x, z, y); ok {
+		return height
+	}
+	c.heights[[2]int{x, z}] = append(c.heights[[2]int{x, z}], y)
+	return y
+}
+
+// clipPolygon keeps the part of the polygon on one side of the plane where axis equals value
+func clipPolygon(points []mgl64.Vec3, axis int, value float64, keepAbove bool) []mgl64.Vec3 {
+	side := func(point mgl64.Vec3) float64 {
+		if keepAbove {
+			return point[axis] - value
+		}
+		return value - point[axis]
+	}
+
+	var clipped []mgl64.Vec3
+	for i, current := range points {
+		next := points[(i+1)%len(points)]
+		d1, d2 := side(current), side(next)
+		if d1 >= 0 {
+			clipped = append(clipped, current)
+		}
+		if (d1 < 0 && d2 > 0) || (d1 > 0 && d2 < 0) {
+			t := d1 / (d1 - d2)
+			clipped = append(clipped, current.Add(next.Sub(current).Mul(t)))
+		}
+	}
+	return clipped
+}
+
+// polygonArea is the area of a flat polygon however it's tilted, vertical ones included
+func polygonArea(points []mgl64.Vec3) float64 {
+	var sum mgl64.Vec3
+	for i := 1; i+1 < len(points); i++ {
+		sum = sum.Add(points[i].Sub(points[0]).Cross(points[i+1].Sub(points[0])))
+	}
+	return sum.Len() / 2
+}
+
+// polygonAreaXZ is the area of the polygon seen from above, which is how much of the ground
+// it covers
+func polygonAreaXZ(points []mgl64.Vec3) float64 {
+	var sum float64
+	for i := 1; i+1 < len(points); i++ {
+		sum += utils.Cross2D(points[i].Sub(points[0]), points[i+1].Sub(points[0]))
+	}
+	return math.Abs(sum) / 2
+}
+
+func clampInt(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+func sign(value int) int {
+	if value < 0 {
+		return -1
+	}
+	if value > 0 {
+		return 1
+	}
+	return 0
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
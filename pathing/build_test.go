@@ -0,0 +1,342 @@
+package pathing
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/geometry"
+	"github.com/kkevinchou/kitolib/utils"
+)
+
+func testBuildSettings() BuildSettings {
+	return BuildSettings{
+		CellSize:      0.25,
+		CellHeight:    0.1,
+		AgentRadius:   0.5,
+		AgentHeight:   2,
+		AgentMaxSlope: 45,
+		AgentMaxClimb: 0.4,
+	}
+}
+
+// addQuad adds a quad whose corners wind the same way as the navmesh polygons, so that flat
+// quads face up
+func addQuad(mesh *collider.TriMesh, a, b, c, d mgl64.Vec3) {
+	mesh.Triangles = append(mesh.Triangles,
+		collider.NewTriangle([3]mgl64.Vec3{a, b, c}),
+		collider.NewTriangle([3]mgl64.Vec3{a, c, d}),
+	)
+}
+
+func addFloor(mesh *collider.TriMesh, minX, minZ, maxX, maxZ, y float64) {
+	addQuad(mesh, mgl64.Vec3{minX, y, minZ}, mgl64.Vec3{minX, y, maxZ}, mgl64.Vec3{maxX, y, maxZ}, mgl64.Vec3{maxX, y, minZ})
+}
+
+// addRamp adds a ramp along x from y0 at minX to y1 at maxX
+func addRamp(mesh *collider.TriMesh, minX, minZ, maxX, maxZ, y0, y1 float64) {
+	addQuad(mesh, mgl64.Vec3{minX, y0, minZ}, mgl64.Vec3{minX, y0, maxZ}, mgl64.Vec3{maxX, y1, maxZ}, mgl64.Vec3{maxX, y1, minZ})
+}
+
+func addBox(mesh *collider.TriMesh, min, max mgl64.Vec3) {
+	p := func(x, y, z int) mgl64.Vec3 {
+		corner := min
+		if x == 1 {
+			corner[0] = max[0]
+		}
+		if y == 1 {
+			corner[1] = max[1]
+		}
+		if z == 1 {
+			corner[2] = max[2]
+		}
+		return corner
+	}
+	addQuad(mesh, p(0, 1, 0), p(0, 1, 1), p(1, 1, 1), p(1, 1, 0))
+	addQuad(mesh, p(0, 0, 0), p(1, 0, 0), p(1, 0, 1), p(0, 0, 1))
+	addQuad(mesh, p(0, 0, 0), p(0, 0, 1), p(0, 1, 1), p(0, 1, 0))
+	addQuad(mesh, p(1, 0, 0), p(1, 1, 0), p(1, 1, 1), p(1, 0, 1))
+	addQuad(mesh, p(0, 0, 0), p(0, 1, 0), p(1, 1, 0), p(1, 0, 0))
+	addQuad(mesh, p(0, 0, 1), p(1, 0, 1), p(1, 1, 1), p(0, 1, 1))
+}
+
+// areaAt is the area covered by the polygons at height y
+func areaAt(polygons []*geometry.Polygon, y float64) float64 {
+	var area float64
+	for _, polygon := range polygons {
+		points := polygon.Points()
+		if math.Abs(points[0][1]-y) > 1e-6 {
+			continue
+		}
+		var sum float64
+		for i, point := range points {
+			next := points[(i+1)%len(points)]
+			sum += point[0]*next[2] - next[0]*point[2]
+		}
+		area += math.Abs(sum) / 2
+	}
+	return area
+}
+
+func pathLength(start geometry.Point, path []geometry.Point) float64 {
+	var length float64
+	previous := start
+	for _, point := range path {
+		length += point.Vector3().Sub(previous.Vector3()).Len()
+		previous = point
+	}
+	return length
+}
+
+func buildPlanner(mesh collider.TriMesh, settings BuildSettings) *Planner {
+	planner := &Planner{}
+	planner.SetNavMesh(BuildNavMesh(mesh, settings))
+	return planner
+}
+
+func TestBuildFloor(t *testing.T) {
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 10, 10, 0)
+
+	result := BuildPolygons(mesh, testBuildSettings())
+	if len(result.Polygons) == 0 {
+		t.Fatal("expected polygons")
+	}
+	if area := areaAt(result.Polygons, 0); math.Abs(area-81) > 1e-6 {
+		t.Errorf("expected the floor shrunk by the agent radius to have an area of 81 but got %f", area)
+	}
+	for _, polygon := range result.Polygons {
+		for _, point := range polygon.Points() {
+			if point[0] < 0.5-1e-9 || point[0] > 9.5+1e-9 || point[2] < 0.5-1e-9 || point[2] > 9.5+1e-9 || point[1] != 0 {
+				t.Fatalf("point %v is closer than the agent radius to the edge", point)
+			}
+		}
+	}
+	if len(result.Contours) != 1 || len(result.Contours[0]) != 4 {
+		t.Errorf("expected the floor's outline to be a square but got %v", result.Contours)
+	}
+
+	planner := &Planner{}
+	planner.SetNavMesh(ConstructNavMesh(result.Polygons))
+	start, goal := geometry.Point{1, 0, 1}, geometry.Point{9, 0, 9}
+	path := planner.FindPath(start, goal)
+	if len(path) == 0 || path[len(path)-1] != goal {
+		t.Fatalf("expected a path to the goal but got %v", path)
+	}
+	if length := pathLength(start, path); math.Abs(length-start.Vector3().Sub(goal.Vector3()).Len()) > 1e-6 {
+		t.Errorf("expected a straight path but got %v", path)
+	}
+}
+
+func TestBuildAroundObstacle(t *testing.T) {
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 10, 10, 0)
+	addBox(&mesh, mgl64.Vec3{4, 0, 4}, mgl64.Vec3{6, 2, 6})
+
+	result := BuildPolygons(mesh, testBuildSettings())
+	// the box and the radius around it take roughly 3x3 out of the floor
+	if area := areaAt(result.Polygons, 0); area < 67 || area > 72.5 {
+		t.Errorf("unexpected floor area %f", area)
+	}
+	if area := areaAt(result.Polygons, 2); area <= 0 || area > 1+1e-6 {
+		t.Errorf("expected a small walkable area on top of the box but got %f", area)
+	}
+	for _, polygon := range result.Polygons {
+		points := polygon.Points()
+		if points[0][1] != 0 {
+			continue
+		}
+		for _, point := range points {
+			if point[0] > 3.6 && point[0] < 6.4 && point[2] > 3.6 && point[2] < 6.4 {
+				t.Fatalf("point %v is too close to the box", point)
+			}
+		}
+	}
+
+	planner := buildPlanner(mesh, testBuildSettings())
+	start, goal := geometry.Point{1, 0, 5}, geometry.Point{9, 0, 5}
+	path := planner.FindPath(start, goal)
+	if len(path) == 0 || path[len(path)-1] != goal {
+		t.Fatalf("expected a path around the box but got %v", path)
+	}
+	previous := start.Vector3()
+	for _, point := range path {
+		for s := 0.0; s <= 1; s += 0.01 {
+			p := previous.Add(point.Vector3().Sub(previous).Mul(s))
+			if p[0] > 4 && p[0] < 6 && p[2] > 4 && p[2] < 6 {
+				t.Fatalf("path %v goes through the box", path)
+			}
+		}
+		previous = point.Vector3()
+	}
+}
+
+func TestBuildRamp(t *testing.T) {
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 4, 4, 0)
+	addRamp(&mesh, 4, 0, 8, 4, 0, 2)
+	addFloor(&mesh, 8, 0, 12, 4, 2)
+
+	result := BuildPolygons(mesh, testBuildSettings())
+	onRamp := false
+	for _, polygon := range result.Polygons {
+		if polygon.ContainsPoint(geometry.Point{6, 1, 2}) {
+			onRamp = true
+		}
+	}
+	if !onRamp {
+		t.Error("expected a polygon on the ramp")
+	}
+
+	planner := buildPlanner(mesh, testBuildSettings())
+	start, goal := geometry.Point{1, 0, 2}, geometry.Point{11, 2, 2}
+	path := planner.FindPath(start, goal)
+	if len(path) == 0 || path[len(path)-1] != goal {
+		t.Fatalf("expected a path up the ramp but got %v", path)
+	}
+}
+
+func TestBuildSteepRamp(t *testing.T) {
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 4, 4, 0)
+	addRamp(&mesh, 4, 0, 5, 4, 0, 2)
+	addFloor(&mesh, 5, 0, 9, 4, 2)
+
+	planner := buildPlanner(mesh, testBuildSettings())
+	if path := planner.FindPath(geometry.Point{1, 0, 2}, geometry.Point{8, 2, 2}); path != nil {
+		t.Errorf("expected the ramp to be too steep but got %v", path)
+	}
+}
+
+func TestBuildLowCeiling(t *testing.T) {
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 10, 4, 0)
+	addBox(&mesh, mgl64.Vec3{4, 1, -1}, mgl64.Vec3{6, 1.2, 5})
+
+	planner := buildPlanner(mesh, testBuildSettings())
+	if path := planner.FindPath(geometry.Point{1, 0, 2}, geometry.Point{9, 0, 2}); path != nil {
+		t.Errorf("expected the agent not to fit under the ceiling but got %v", path)
+	}
+
+	settings := testBuildSettings()
+	settings.AgentHeight = 0.9
+	planner = buildPlanner(mesh, settings)
+	if path := planner.FindPath(geometry.Point{1, 0, 2}, geometry.Point{9, 0, 2}); path == nil {
+		t.Error("expected a short agent to fit under the ceiling")
+	}
+}
+
+func TestBuildStep(t *testing.T) {
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 10, 4, 0)
+	addBox(&mesh, mgl64.Vec3{5, 0, -1}, mgl64.Vec3{11, 0.3, 5})
+
+	planner := buildPlanner(mesh, testBuildSettings())
+	if path := planner.FindPath(geometry.Point{1, 0, 2}, geometry.Point{9, 0.3, 2}); len(path) != 2 {
+		t.Errorf("expected a straight path up the step but got %v", path)
+	}
+
+	settings := testBuildSettings()
+	settings.AgentMaxClimb = 0.2
+	planner = buildPlanner(mesh, settings)
+	if path := planner.FindPath(geometry.Point{1, 0, 2}, geometry.Point{9, 0.3, 2}); path != nil {
+		t.Errorf("expected the step to be too tall but got %v", path)
+	}
+}
+
+func TestBuildStepHasNoRisers(t *testing.T) {
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 10, 4, 0)
+	addBox(&mesh, mgl64.Vec3{5, 0, -1}, mgl64.Vec3{11, 0.8, 5})
+	settings := testBuildSettings()
+	settings.AgentMaxClimb = 1
+
+	result := BuildPolygons(mesh, settings)
+	for _, polygon := range result.Polygons {
+		if polygonAreaXZ(vertices(polygon)) < 1e-6 {
+			t.Fatalf("expected every polygon to be walkable but got %v", polygon.Points())
+		}
+	}
+	if len(result.Steps) == 0 {
+		t.Fatal("expected the polygons either side of the step to be joined")
+	}
+
+	navmesh := BuildNavMesh(mesh, settings)
+	planner := &Planner{}
+	planner.SetNavMesh(navmesh)
+	start, goal := geometry.Point{1, 0, 2}, geometry.Point{9, 0.8, 2}
+	path := planner.FindPath(start, goal)
+	if len(path) != 2 {
+		t.Fatalf("expected a straight path up the step but got %v", path)
+	}
+	if !planner.ValidatePath(path) {
+		t.Error("expected the path up the step to stay on the navmesh")
+	}
+	if point, _ := navmesh.MoveAlongSurface(geometry.Point{4, 0, 2}, geometry.Point{6, 0, 2}); math.Abs(point[0]-6) > 1e-6 || math.Abs(point[1]-0.8) > 1e-6 {
+		t.Errorf("expected to move onto the top of the step but got %v", point)
+	}
+
+	// the edges along the step aren't the edge of the navmesh, so a wide agent can still go up
+	// it anywhere
+	planner.SetAgentRadius(0.5)
+	if path := planner.FindPath(geometry.Point{1, 0, 1}, geometry.Point{9, 0.8, 1}); len(path) != 2 {
+		t.Errorf("expected a wide agent to go straight up the step but got %v", path)
+	}
+
+	// the step is saved along with the polygons
+	var buffer bytes.Buffer
+	if err := navmesh.WriteBinary(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadNavMeshBinary(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	planner.SetNavMesh(loaded)
+	if path := planner.FindPath(start, goal); len(path) != 2 {
+		t.Errorf("expected the loaded navmesh to keep the step but got %v", path)
+	}
+
+	hierarchical := NewHierarchicalPlanner(planner, 2)
+	if path := hierarchical.FindPath(start, goal); len(path) != 2 {
+		t.Errorf("expected the hierarchical planner to cross the step but got %v", path)
+	}
+}
+
+func TestBuildSimplifiesDiagonalWalls(t *testing.T) {
+	var mesh collider.TriMesh
+	mesh.Triangles = append(mesh.Triangles, collider.NewTriangle([3]mgl64.Vec3{{0, 0, 0}, {0, 0, 10}, {10, 0, 10}}))
+
+	stairs := BuildPolygons(mesh, testBuildSettings())
+	settings := testBuildSettings()
+	settings.MaxEdgeError = 0.3
+	result := BuildPolygons(mesh, settings)
+	if len(result.Contours) != 1 || len(result.Contours[0]) >= len(stairs.Contours[0]) {
+		t.Fatalf("expected the diagonal wall to be simplified but got %d vertices instead of %d", len(result.Contours[0]), len(stairs.Contours[0]))
+	}
+	if len(result.Contours[0]) > 8 {
+		t.Errorf("expected the triangle's outline to need only a few vertices but got %v", result.Contours[0])
+	}
+	if wanted, area := areaAt(stairs.Polygons, 0), areaAt(result.Polygons, 0); math.Abs(area-wanted) > 0.3*10*math.Sqrt2 {
+		t.Errorf("expected the simplified polygons to cover about %f but got %f", wanted, area)
+	}
+
+	for _, polygon := range result.Polygons {
+		points := polygon.Points()
+		for i := range points {
+			a, b, c := points[i].Vector3(), points[(i+1)%len(points)].Vector3(), points[(i+2)%len(points)].Vector3()
+			if utils.Cross2D(b.Sub(a), c.Sub(b)) > 1e-9 {
+				t.Fatalf("expected a convex polygon but got %v", points)
+			}
+		}
+	}
+
+	planner := &Planner{}
+	planner.SetNavMesh(ConstructNavMesh(result.Polygons))
+	start, goal := geometry.Point{1, 0, 9}, geometry.Point{8, 0, 9.2}
+	if path := planner.FindPath(start, goal); len(path) != 2 {
+		t.Errorf("expected a straight path along the triangle but got %v", path)
+	}
+}
@@ -0,0 +1,432 @@
+package pathing
+
+import (
+	"math"
+	"sort"
+)
+
+// contourVertex is a corner on the outline of a region, neighbor is the region on the other
+// side of the edge that starts at it or -1 when that edge is a wall
+type contourVertex struct {
+	corner   [2]int
+	neighbor int
+}
+
+// traceContours traces the outlines of the region, the outer outline and one for every hole.
+// the outlines wind the same way as the polygons and have a vertex at every corner along
+// them. where the region touches itself at a corner the outline keeps turning into the
+// region, which splits it there so that every outline is simple.
+func (f *openField) traceContours(region []int) [][]contourVertex {
+	edges := map[[2]int][]contourVertex{}
+	var starts [][2]int
+	for _, i := range region {
+		s := f.spans[i]
+		for d := 0; d < 4; d++ {
+			neighbor := -1
+			if j, ok := f.neighbor(i, d); ok {
+				if f.spans[j].region == s.region {
+					continue
+				}
+				neighbor = f.spans[j].region
+			}
+			from, to := cellEdge(s.x, s.z, d)
+			edges[from] = append(edges[from], contourVertex{corner: to, neighbor: neighbor})
+			starts = append(starts, from)
+		}
+	}
+
+	var contours [][]contourVertex
+	for _, start := range starts {
+		// outlines can't start where the region touches itself since there's no telling
+		// which way to go yet
+		if len(edges[start]) != 1 {
+			continue
+		}
+		var contour []contourVertex
+		previous, current := start, start
+		for len(edges[current]) > 0 {
+			outgoing := edges[current]
+			pick := 0
+			for k := range outgoing {
+				if turn(previous, current, outgoing[k].corner) < turn(previous, current, outgoing[pick].corner) {
+					pick = k
+				}
+			}
+			edge := outgoing[pick]
+			edges[current] = append(outgoing[:pick:pick], outgoing[pick+1:]...)
+			contour = append(contour, contourVertex{corner: current, neighbor: edge.neighbor})
+			previous, current = current, edge.corner
+			if current == start {
+				break
+			}
+		}
+		contours = append(contours, contour)
+	}
+	return contours
+}
+
+// simplifyContour keeps the vertices other polygons have to line up with, which are where
+// the outline meets another region, a step or the tile's side, and where it turns away from a
+// wall. walls in between are simplified to within maxError cells of the voxels.
+func (f *openField) simplifyContour(contour []contourVertex, stepEnds map[[2]int]bool, maxError float64) [][2]int {
+	n := len(contour)
+	var keep []int
+	for i, v := range contour {
+		previous, next := contour[(i+n-1)%n], contour[(i+1)%n]
+		switch {
+		case previous.neighbor != v.neighbor, stepEnds[v.corner], f.onTileSide(v.corner[0], v.corner[1]):
+			keep = append(keep, i)
+		case v.neighbor != -1 && turn(previous.corner, v.corner, next.corner) != 0:
+			keep = append(keep, i)
+		}
+	}
+
+	// an outline that's all wall is simplified from its lowest corner to its highest
+	if len(keep) == 0 {
+		lowest, highest := 0, 0
+		for i, v := range contour {
+			if lessCorner(v.corner, contour[lowest].corner) {
+				lowest = i
+			}
+			if lessCorner(contour[highest].corner, v.corner) {
+				highest = i
+			}
+		}
+		keep = []int{lowest, highest}
+		if highest < lowest {
+			keep = []int{highest, lowest}
+		}
+	}
+
+	var outline [][2]int
+	for k, from := range keep {
+		outline = append(outline, contour[from].corner)
+		if contour[from].neighbor != -1 {
+			continue
+		}
+		to := keep[(k+1)%len(keep)]
+		wall := [][2]int{contour[from].corner}
+		for i := (from + 1) % n; ; i = (i + 1) % n {
+			wall = append(wall, contour[i].corner)
+			if i == to {
+				break
+			}
+		}
+		outline = append(outline, simplifyWall(wall, maxError)...)
+	}
+	return outline
+}
+
+// simplifyWall returns the corners between the ends of the wall that keep it within maxError
+// of the rest of them
+func simplifyWall(wall [][2]int, maxError float64) [][2]int {
+	if len(wall) < 3 {
+		return nil
+	}
+	first, last := wall[0], wall[len(wall)-1]
+	worst, worstDistance := -1, maxError+flatEpsilon
+	for i := 1; i < len(wall)-1; i++ {
+		if distance := segmentDistance(wall[i], first, last); distance > worstDistance {
+			worst, worstDistance = i, distance
+		}
+	}
+	if worst == -1 {
+		return nil
+	}
+	simplified := append(simplifyWall(wall[:worst+1], maxError), wall[worst])
+	return append(simplified, simplifyWall(wall[worst:], maxError)...)
+}
+
+// segmentDistance is the distance from p to the segment from a to b
+func segmentDistance(p, a, b [2]int) float64 {
+	dx, dz := float64(b[0]-a[0]), float64(b[1]-a[1])
+	px, pz := float64(p[0]-a[0]), float64(p[1]-a[1])
+	var t float64
+	if length := dx*dx + dz*dz; length > 0 {
+		t = math.Max(0, math.Min(1, (px*dx+pz*dz)/length))
+	}
+	return math.Hypot(px-t*dx, pz-t*dz)
+}
+
+// triangulateRegion joins the holes to the region's outer outline, triangulates it and merges
+// the triangles back into convex polygons. false is returned when the outlines can't be
+// triangulated, like when simplifying a wall made it cross another one.
+func triangulateRegion(outlines [][][2]int) ([][][2]int, bool) {
+	var outer [][2]int
+	var holes [][][2]int
+	for _, outline := range outlines {
+		area := signedArea(outline)
+		switch {
+		case len(outline) < 3 || area == 0:
+			continue
+		case area > 0:
+			holes = append(holes, outline)
+		case outer != nil:
+			return nil, false
+		default:
+			outer = outline
+		}
+	}
+	if outer == nil {
+		return nil, false
+	}
+
+	outer, ok := mergeHoles(outer, holes)
+	if !ok {
+		return nil, false
+	}
+	triangles, ok := triangulate(outer)
+	if !ok {
+		return nil, false
+	}
+	return mergePolygons(triangles), true
+}
+
+// mergeHoles cuts a pair of edges from every hole to the outer outline so that the outline
+// goes around the holes too. holes are joined from left to right, each one to the closest
+// corner it can see.
+func mergeHoles(outer [][2]int, holes [][][2]int) ([][2]int, bool) {
+	leftmost := func(hole [][2]int) int {
+		left := 0
+		for i, corner := range hole {
+			if lessCorner(corner, hole[left]) {
+				left = i
+			}
+		}
+		return left
+	}
+	sort.Slice(holes, func(a, b int) bool {
+		return lessCorner(holes[a][leftmost(holes[a])], holes[b][leftmost(holes[b])])
+	})
+
+	for h, hole := range holes {
+		order := make([]int, len(hole))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return lessCorner(hole[order[a]], hole[order[b]]) })
+
+		bridged := false
+		for _, hi := range order {
+			corner := hole[hi]
+			candidates := make([]int, len(outer))
+			for i := range candidates {
+				candidates[i] = i
+			}
+			sort.Slice(candidates, func(a, b int) bool {
+				return distanceSquared(outer[candidates[a]], corner) < distanceSquared(outer[candidates[b]], corner)
+			})
+
+			for _, oi := range candidates {
+				if !inCone(outer, oi, corner) || crossesRing(outer, outer[oi], corner) {
+					continue
+				}
+				blocked := false
+				for _, other := range holes[h:] {
+					if crossesRing(other, outer[oi], corner) {
+						blocked = true
+						break
+					}
+				}
+				if blocked {
+					continue
+				}
+
+				merged := append([][2]int{}, outer[:oi+1]...)
+				merged = append(merged, hole[hi:]...)
+				merged = append(merged, hole[:hi+1]...)
+				outer = append(merged, outer[oi:]...)
+				bridged = true
+				break
+			}
+			if bridged {
+				break
+			}
+		}
+		if !bridged {
+			return nil, false
+		}
+	}
+	return outer, true
+}
+
+// triangulate clips ears off the outline, the one with the shortest new edge first so that
+// the triangles don't end up long and thin
+func triangulate(outline [][2]int) ([][][2]int, bool) {
+	points := append([][2]int{}, outline...)
+	isEar := func(i int) bool {
+		n := len(points)
+		previous, next := (i+n-1)%n, (i+1)%n
+		return turn(points[previous], points[i], points[next]) < 0 && diagonal(points, previous, next)
+	}
+	ears := make([]bool, len(points))
+	for i := range points {
+		ears[i] = isEar(i)
+	}
+
+	var triangles [][][2]int
+	for len(points) > 3 {
+		n := len(points)
+		best, bestLength := -1, 0
+		for i, ear := range ears {
+			if !ear {
+				continue
+			}
+			if length := distanceSquared(points[(i+n-1)%n], points[(i+1)%n]); best == -1 || length < bestLength {
+				best, bestLength = i, length
+			}
+		}
+		if best == -1 {
+			return nil, false
+		}
+
+		triangles = append(triangles, [][2]int{points[(best+n-1)%n], points[best], points[(best+1)%n]})
+		points = append(points[:best], points[best+1:]...)
+		ears = append(ears[:best], ears[best+1:]...)
+		n--
+		// the corners either side of the ear have new neighbors
+		previous, next := (best+n-1)%n, best%n
+		ears[previous], ears[next] = isEar(previous), isEar(next)
+	}
+	if turn(points[0], points[1], points[2]) >= 0 {
+		return nil, false
+	}
+	return append(triangles, points), true
+}
+
+// mergePolygons merges neighboring polygons for as long as the result stays convex, the ones
+// sharing the longest edge first. there's no limit on how many vertices a polygon can have.
+func mergePolygons(polygons [][][2]int) [][][2]int {
+	for {
+		edges := map[[2][2]int][2]int{}
+		for i, polygon := range polygons {
+			for k := range polygon {
+				edges[[2][2]int{polygon[k], polygon[(k+1)%len(polygon)]}] = [2]int{i, k}
+			}
+		}
+
+		var merged [][2]int
+		var a, b, bestLength int
+		for i, polygon := range polygons {
+			for k := range polygon {
+				from, to := polygon[k], polygon[(k+1)%len(polygon)]
+				other, ok := edges[[2][2]int{to, from}]
+				if !ok || other[0] <= i {
+					continue
+				}
+				length := distanceSquared(from, to)
+				if length <= bestLength {
+					continue
+				}
+				if m, ok := mergeConvex(polygon, k, polygons[other[0]], other[1]); ok {
+					merged, a, b, bestLength = m, i, other[0], length
+				}
+			}
+		}
+		if merged == nil {
+			return polygons
+		}
+		polygons[a] = merged
+		polygons = append(polygons[:b], polygons[b+1:]...)
+	}
+}
+
+// mergeConvex merges the polygons across the edge that starts at corner i of a and corner j
+// of b, false is returned when the result wouldn't be convex
+func mergeConvex(a [][2]int, i int, b [][2]int, j int) ([][2]int, bool) {
+	na, nb := len(a), len(b)
+	p, q := a[i], a[(i+1)%na]
+	if !convexJoin(a[(i+na-1)%na], p, b[(j+2)%nb]) || !convexJoin(b[(j+nb-1)%nb], q, a[(i+2)%na]) {
+		return nil, false
+	}
+
+	merged := make([][2]int, 0, na+nb-2)
+	for k := 1; k <= na; k++ {
+		merged = append(merged, a[(i+k)%na])
+	}
+	for k := 2; k < nb; k++ {
+		merged = append(merged, b[(j+k)%nb])
+	}
+	return merged, true
+}
+
+// convexJoin returns whether the outline doesn't turn outward at b. going straight is fine
+// since the vertex can be shared with the polygon on the other side.
+func convexJoin(a, b, c [2]int) bool {
+	t := turn(a, b, c)
+	return t < 0 || (t == 0 && (b[0]-a[0])*(c[0]-b[0])+(b[1]-a[1])*(c[1]-b[1]) > 0)
+}
+
+// diagonal returns whether the segment between corners i and j stays inside the outline
+func diagonal(points [][2]int, i, j int) bool {
+	return inCone(points, i, points[j]) && inCone(points, j, points[i]) && !crossesRing(points, points[i], points[j])
+}
+
+// inCone returns whether a segment from corner i to p starts off inside the outline
+func inCone(points [][2]int, i int, p [2]int) bool {
+	n := len(points)
+	previous, current, next := points[(i+n-1)%n], points[i], points[(i+1)%n]
+	if turn(previous, current, next) <= 0 {
+		return turn(current, p, previous) < 0 && turn(p, current, next) < 0
+	}
+	return !(turn(current, p, next) <= 0 && turn(p, current, previous) <= 0)
+}
+
+// crossesRing returns whether the segment from a to b touches any edge of the ring that
+// doesn't end at a or b
+func crossesRing(ring [][2]int, a, b [2]int) bool {
+	for k, c := range ring {
+		d := ring[(k+1)%len(ring)]
+		if c == a || c == b || d == a || d == b {
+			continue
+		}
+		if segmentsIntersect(a, b, c, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentsIntersect returns whether the segments cross or touch
+func segmentsIntersect(a, b, c, d [2]int) bool {
+	abc, abd, cda, cdb := turn(a, b, c), turn(a, b, d), turn(c, d, a), turn(c, d, b)
+	if ((abc > 0 && abd < 0) || (abc < 0 && abd > 0)) && ((cda > 0 && cdb < 0) || (cda < 0 && cdb > 0)) {
+		return true
+	}
+	return (abc == 0 && onSegment(c, a, b)) || (abd == 0 && onSegment(d, a, b)) ||
+		(cda == 0 && onSegment(a, c, d)) || (cdb == 0 && onSegment(b, c, d))
+}
+
+// onSegment returns whether p, which is in line with the segment, is on it
+func onSegment(p, a, b [2]int) bool {
+	return p[0] >= minInt(a[0], b[0]) && p[0] <= maxInt(a[0], b[0]) &&
+		p[1] >= minInt(a[1], b[1]) && p[1] <= maxInt(a[1], b[1])
+}
+
+// turn is negative when going from a to b to c turns the same way the polygons wind, zero
+// when they're in line and positive when it turns the other way
+func turn(a, b, c [2]int) int {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// signedArea is twice the outline's area, negative when it winds the same way as the polygons
+func signedArea(outline [][2]int) int {
+	var sum int
+	for i, a := range outline {
+		b := outline[(i+1)%len(outline)]
+		sum += a[0]*b[1] - a[1]*b[0]
+	}
+	return sum
+}
+
+func distanceSquared(a, b [2]int) int {
+	dx, dz := b[0]-a[0], b[1]-a[1]
+	return dx*dx + dz*dz
+}
+
+func lessCorner(a, b [2]int) bool {
+	if a[0] != b[0] {
+		return a[0] < b[0]
+	}
+	return a[1] < b[1]
+}
@@ -0,0 +1,165 @@
+package pathing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// svgSize is how many pixels across the longer side of an SVG export is
+const svgSize = 1024
+
+// areaColors are the fills of the area types in SVG exports, the types past the end of the
+// list wrap around
+var areaColors = []string{"#9ecae1", "#a1d99b", "#fdae6b", "#bcbddc", "#fdd0a2", "#c7e9c0", "#d9d9d9", "#fcbba1"}
+
+// WriteOBJ exports the navmesh as a Wavefront OBJ for looking at in a modeling tool. polygons
+// are grouped by area type, off-mesh links and the paths given, like ones from
+// Planner.FindPath, are added as lines.
+func (nm *NavMesh) WriteOBJ(w io.Writer, paths ...[]geometry.Point) error {
+	data := nm.data()
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "o navmesh")
+	for _, vertex := range data.Vertices {
+		fmt.Fprintf(bw, "v %g %g %g\n", vertex[0], vertex[1], vertex[2])
+	}
+	vertexCount := len(data.Vertices)
+
+	var areas []AreaType
+	seen := map[AreaType]bool{}
+	for _, polygon := range data.Polygons {
+		if !seen[polygon.Area] {
+			seen[polygon.Area] = true
+			areas = append(areas, polygon.Area)
+		}
+	}
+	for _, area := range areas {
+		fmt.Fprintf(bw, "g area%d\n", area)
+		for _, polygon := range data.Polygons {
+			if polygon.Area != area {
+				continue
+			}
+			fmt.Fprint(bw, "f")
+			for _, index := range polygon.Vertices {
+				// obj indexes start at 1
+				fmt.Fprintf(bw, " %d", index+1)
+			}
+			fmt.Fprintln(bw)
+		}
+	}
+
+	writeLine := func(points [][3]float64) {
+		for _, point := range points {
+			fmt.Fprintf(bw, "v %g %g %g\n", point[0], point[1], point[2])
+		}
+		fmt.Fprint(bw, "l")
+		for i := range points {
+			fmt.Fprintf(bw, " %d", vertexCount+i+1)
+		}
+		fmt.Fprintln(bw)
+		vertexCount += len(points)
+	}
+
+	if len(data.Links) > 0 {
+		fmt.Fprintln(bw, "o links")
+		for _, link := range data.Links {
+			writeLine([][3]float64{link.Start, link.End})
+		}
+	}
+	for i, path := range paths {
+		if len(path) < 2 {
+			continue
+		}
+		fmt.Fprintf(bw, "o path%d\n", i)
+		points := make([][3]float64, len(path))
+		for j, point := range path {
+			points[j] = point
+		}
+		writeLine(points)
+	}
+
+	return bw.Flush()
+}
+
+// WriteSVG exports the navmesh seen from above, with x to the right and z down. polygons are
+// filled by area type, off-mesh links are dashed and the paths given, like ones from
+// Planner.FindPath, are drawn over the top.
+func (nm *NavMesh) WriteSVG(w io.Writer, paths ...[]geometry.Point) error {
+	data := nm.data()
+	bw := bufio.NewWriter(w)
+
+	minX, minZ := math.MaxFloat64, math.MaxFloat64
+	maxX, maxZ := -math.MaxFloat64, -math.MaxFloat64
+	grow := func(point [3]float64) {
+		minX, maxX = math.Min(minX, point[0]), math.Max(maxX, point[0])
+		minZ, maxZ = math.Min(minZ, point[2]), math.Max(maxZ, point[2])
+	}
+	for _, vertex := range data.Vertices {
+		grow(vertex)
+	}
+	for _, link := range data.Links {
+		grow(link.Start)
+		grow(link.End)
+	}
+	for _, path := range paths {
+		for _, point := range path {
+			grow(point)
+		}
+	}
+	if minX > maxX {
+		minX, maxX, minZ, maxZ = 0, 1, 0, 1
+	}
+
+	width, height := math.Max(maxX-minX, floatEpsilon), math.Max(maxZ-minZ, floatEpsilon)
+	padding := math.Max(width, height) * 0.02
+	scale := svgSize / math.Max(width, height)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="%g %g %g %g">`+"\n",
+		int(math.Ceil((width+2*padding)*scale)), int(math.Ceil((height+2*padding)*scale)),
+		minX-padding, minZ-padding, width+2*padding, height+2*padding)
+
+	// strokes stay the same width in pixels however big the navmesh is
+	fmt.Fprintln(bw, `<g id="polygons" stroke="#525252" stroke-width="1" stroke-linejoin="round">`)
+	for i, polygon := range data.Polygons {
+		fmt.Fprintf(bw, `<polygon id="polygon%d" fill="%s" vector-effect="non-scaling-stroke" points="`, i, areaColors[int(polygon.Area)%len(areaColors)])
+		for j, index := range polygon.Vertices {
+			if j > 0 {
+				fmt.Fprint(bw, " ")
+			}
+			fmt.Fprintf(bw, "%g,%g", data.Vertices[index][0], data.Vertices[index][2])
+		}
+		fmt.Fprintln(bw, `"/>`)
+	}
+	fmt.Fprintln(bw, "</g>")
+
+	if len(data.Links) > 0 {
+		fmt.Fprintln(bw, `<g id="links" stroke="#6a51a3" stroke-width="2" stroke-dasharray="6 4">`)
+		for _, link := range data.Links {
+			fmt.Fprintf(bw, `<line vector-effect="non-scaling-stroke" x1="%g" y1="%g" x2="%g" y2="%g"/>`+"\n", link.Start[0], link.Start[2], link.End[0], link.End[2])
+		}
+		fmt.Fprintln(bw, "</g>")
+	}
+
+	radius := 3 / scale
+	for i, path := range paths {
+		fmt.Fprintf(bw, `<g id="path%d" stroke="#de2d26" fill="#de2d26">`+"\n", i)
+		fmt.Fprint(bw, `<polyline fill="none" stroke-width="2" vector-effect="non-scaling-stroke" points="`)
+		for j, point := range path {
+			if j > 0 {
+				fmt.Fprint(bw, " ")
+			}
+			fmt.Fprintf(bw, "%g,%g", point[0], point[2])
+		}
+		fmt.Fprintln(bw, `"/>`)
+		for _, point := range path {
+			fmt.Fprintf(bw, `<circle stroke="none" cx="%g" cy="%g" r="%g"/>`+"\n", point[0], point[2], radius)
+		}
+		fmt.Fprintln(bw, "</g>")
+	}
+
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
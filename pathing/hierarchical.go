@@ -0,0 +1,404 @@
+package pathing
+
+import (
+	"math"
+	"sort"
+
+	"github.com/kkevinchou/kitolib/geometry"
+	"github.com/kkevinchou/kitolib/utils"
+)
+
+const defaultPathCacheSize = 256
+
+// entrance is a point where paths can leave or enter a region, either the middle of a border
+// with another region or the end of a link
+type entrance struct {
+	point  geometry.Point
+	region int
+}
+
+type entranceEdge struct {
+	to   entrance
+	cost float64
+}
+
+type pathKey struct {
+	start, goal geometry.Point
+}
+
+// HierarchicalPlanner plans long paths on big navmeshes. polygons are grouped into regions of
+// connected polygons in the same square cluster, and the cost of getting between the
+// entrances of each region is found up front. a path is planned over the entrances first and
+// then found in detail only through the regions that route goes through. paths are cached
+// and both the regions and the cache are rebuilt when the navmesh changes.
+type HierarchicalPlanner struct {
+	planner     *Planner
+	clusterSize float64
+
+	// version is the navmesh version the regions were built for, -1 when they need building
+	version        int
+	regions        map[*geometry.Polygon]int
+	regionPolygons []map[*geometry.Polygon]bool
+	entrances      [][]entrance
+	edges          map[entrance][]entranceEdge
+
+	cache map[pathKey][]PathSegment
+	// cacheOrder is a ring of the cached keys in the order they were stored, cacheNext is
+	// where the next one goes once it's full
+	cacheOrder []pathKey
+	cacheNext  int
+	cacheSize  int
+}
+
+// NewHierarchicalPlanner plans with the planner's navmesh, agent radius and area filter.
+// Invalidate has to be called after changing any of those on the planner.
+func NewHierarchicalPlanner(planner *Planner, clusterSize float64) *HierarchicalPlanner {
+	return &HierarchicalPlanner{
+		planner:     planner,
+		clusterSize: clusterSize,
+		version:     -1,
+		cache:       map[pathKey][]PathSegment{},
+		cacheSize:   defaultPathCacheSize,
+	}
+}
+
+// SetCacheSize sets how many paths are remembered. paths are dropped first in first out, using
+// a cached path doesn't keep it around for longer. zero turns the cache off.
+func (h *HierarchicalPlanner) SetCacheSize(size int) {
+	h.cacheSize = size
+	h.clearCache()
+}
+
+// Invalidate drops the regions and the cached paths
+func (h *HierarchicalPlanner) Invalidate() {
+	h.version = -1
+	h.clearCache()
+}
+
+// FindPath finds the same kind of path as Planner.FindPath
+func (h *HierarchicalPlanner) FindPath(start geometry.Point, goal geometry.Point) []geometry.Point {
+	return segmentPoints(h.FindPathSegments(start, goal))
+}
+
+// FindPathSegments finds the same kind of path as Planner.FindPathSegments
+func (h *HierarchicalPlanner) FindPathSegments(start geometry.Point, goal geometry.Point) []PathSegment {
+	if h.version != h.planner.navmesh.Version() {
+		h.build()
+	}
+
+	key := pathKey{start: start, goal: goal}
+	segments, ok := h.cache[key]
+	if !ok {
+		segments = h.findSegments(start, goal)
+		h.store(key, segments)
+	}
+	if segments == nil {
+		return nil
+	}
+	return append([]PathSegment(nil), segments...)
+}
+
+func (h *HierarchicalPlanner) findSegments(start, goal geometry.Point) []PathSegment {
+	nm := h.planner.navmesh
+	startPolygon, goalPolygon := nm.polygonAt(start), nm.polygonAt(goal)
+	if startPolygon == nil || goalPolygon == nil {
+		return nil
+	}
+
+	links := nm.links()
+	route := h.route(links, entrance{point: start, region: h.regions[startPolygon]}, entrance{point: goal, region: h.regions[goalPolygon]})
+	if route == nil {
+		return nil
+	}
+	if start == goal {
+		return []PathSegment{{Start: start, End: goal}}
+	}
+
+	// each leg of the route inside a region is found in detail on its own, then the whole path
+	// is smoothed at once
+	var roughPath []NavNode
+	for i := 0; i+1 < len(route); i++ {
+		from, to := route[i], route[i+1]
+		if from.region != to.region {
+			// crossing a border or taking a link, the legs on either side end and start there
+			continue
+		}
+		leg, _ := h.planner.findPath(from.point, to.point, h.regionPolygons[from.region])
+		if leg == nil {
+			return nil
+		}
+		if len(roughPath) > 0 && roughPath[len(roughPath)-1] == leg[0] {
+			leg = leg[1:]
+		}
+		roughPath = append(roughPath, leg...)
+	}
+	return h.planner.segments(links, roughPath)
+}
+
+// route returns the cheapest path over the entrances from start to goal
+func (h *HierarchicalPlanner) route(links *linkGraph, start, goal entrance) []entrance {
+	frontier := utils.NewPriorityQueue()
+	cameFrom := map[entrance]entrance{}
+	costSoFar := map[entrance]float64{start: 0}
+	explored := map[entrance]bool{}
+	heuristicScale := h.planner.heuristicScale(links)
+
+	frontier.Push(start, 0)
+	for !frontier.Empty() {
+		current := frontier.Pop().(entrance)
+		if current == goal {
+			break
+		}
+		if explored[current] {
+			continue
+		}
+		explored[current] = true
+
+		edges := append([]entranceEdge(nil), h.edges[current]...)
+		if current == start {
+			edges = append(edges, h.localEdges(start, h.entrances[start.region])...)
+		}
+		if current.region == goal.region {
+			edges = append(edges, h.localEdges(current, []entrance{goal})...)
+		}
+
+		for _, edge := range edges {
+			if explored[edge.to] {
+				continue
+			}
+			newCost := costSoFar[current] + edge.cost
+			if cost, ok := costSoFar[edge.to]; !ok || newCost < cost {
+				costSoFar[edge.to] = newCost
+				frontier.Push(edge.to, newCost+h.planner.navmesh.Cost(goal.point, edge.to.point)*heuristicScale)
+				cameFrom[edge.to] = current
+			}
+		}
+	}
+
+	if _, ok := costSoFar[goal]; !ok {
+		return nil
+	}
+
+	route := []entrance{goal}
+	for node := goal; node != start; {
+		node = cameFrom[node]
+		route = append(route, node)
+	}
+	for i, j := 0, len(route)-1; i < j; i, j = i+1, j-1 {
+		route[i], route[j] = route[j], route[i]
+	}
+	return route
+}
+
+// localEdges returns the cost of getting from the entrance to each of the others through its
+// region
+func (h *HierarchicalPlanner) localEdges(from entrance, others []entrance) []entranceEdge {
+	var edges []entranceEdge
+	for _, other := range others {
+		if other == from {
+			continue
+		}
+		path, cost := h.planner.findPath(from.point, other.point, h.regionPolygons[from.region])
+		if path != nil {
+			edges = append(edges, entranceEdge{to: other, cost: cost})
+		}
+	}
+	return edges
+}
+
+// build groups the polygons into regions and finds the cost of getting between the entrances
+// of each one
+func (h *HierarchicalPlanner) build() {
+	nm := h.planner.navmesh
+	h.version = nm.Version()
+	h.clearCache()
+
+	h.regions = map[*geometry.Polygon]int{}
+	h.regionPolygons = nil
+	for _, polygon := range nm.Polygons() {
+		if _, ok := h.regions[polygon]; ok {
+			continue
+		}
+
+		region := len(h.regionPolygons)
+		cluster := h.cluster(polygon)
+		members := map[*geometry.Polygon]bool{polygon: true}
+		h.regions[polygon] = region
+		queue := []*geometry.Polygon{polygon}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for neighbor := range nm.polyPairToPortal[current] {
+				if _, ok := h.regions[neighbor]; ok || h.cluster(neighbor) != cluster {
+					continue
+				}
+				h.regions[neighbor] = region
+				members[neighbor] = true
+				queue = append(queue, neighbor)
+			}
+		}
+		h.regionPolygons = append(h.regionPolygons, members)
+	}
+
+	h.entrances = make([][]entrance, len(h.regionPolygons))
+	h.edges = map[entrance][]entranceEdge{}
+	added := map[entrance]bool{}
+	addEntrance := func(e entrance) {
+		if !added[e] {
+			added[e] = true
+			h.entrances[e.region] = append(h.entrances[e.region], e)
+		}
+	}
+	connect := func(from, to entrance, cost float64) {
+		addEntrance(from)
+		addEntrance(to)
+		h.edges[from] = append(h.edges[from], entranceEdge{to: to, cost: cost})
+	}
+
+	for _, border := range h.borders() {
+		a, b := entrance{point: border.points[0], region: border.regions[0]}, entrance{point: border.points[1], region: border.regions[1]}
+		cost := nm.Cost(a.point, b.point)
+		connect(a, b, cost)
+		connect(b, a, cost)
+	}
+	for _, link := range nm.OffMeshLinks() {
+		startPolygon, endPolygon := nm.polygonAt(link.Start), nm.polygonAt(link.End)
+		if startPolygon == nil || endPolygon == nil {
+			continue
+		}
+		a, b := entrance{point: link.Start, region: h.regions[startPolygon]}, entrance{point: link.End, region: h.regions[endPolygon]}
+		connect(a, b, link.cost())
+		if link.Bidirectional {
+			connect(b, a, link.cost())
+		}
+	}
+
+	for _, entrances := range h.entrances {
+		for _, e := range entrances {
+			h.edges[e] = append(h.edges[e], h.localEdges(e, entrances)...)
+		}
+	}
+}
+
+// border is where two regions meet, points are on the polygons in each of the regions and
+// are only different at a step
+type border struct {
+	points  [2]geometry.Point
+	regions [2]int
+}
+
+// borders returns one entrance point for each stretch of portals between two regions that the
+// agent fits through, in the middle of the widest portal of the stretch
+func (h *HierarchicalPlanner) borders() []border {
+	nm := h.planner.navmesh
+
+	index := map[*geometry.Polygon]int{}
+	for i, polygon := range nm.Polygons() {
+		index[polygon] = i
+	}
+
+	type regionPair [2]int
+	portals := map[regionPair][]Portal{}
+	// sides are the polygons on either side of each portal
+	sides := map[regionPair][][2]*geometry.Polygon{}
+	var pairs []regionPair
+	for _, polygon := range nm.Polygons() {
+		// in the order of the polygons so that the entrances are the same every build
+		var neighbors []*geometry.Polygon
+		for neighbor := range nm.polyPairToPortal[polygon] {
+			neighbors = append(neighbors, neighbor)
+		}
+		sort.Slice(neighbors, func(i, j int) bool { return index[neighbors[i]] < index[neighbors[j]] })
+
+		for _, neighbor := range neighbors {
+			portal := nm.polyPairToPortal[polygon][neighbor]
+			pair := regionPair{h.regions[polygon], h.regions[neighbor]}
+			if pair[0] >= pair[1] || !h.planner.fits(polygon, neighbor) {
+				continue
+			}
+			if _, ok := portals[pair]; !ok {
+				pairs = append(pairs, pair)
+			}
+			portals[pair] = append(portals[pair], portal)
+			sides[pair] = append(sides[pair], [2]*geometry.Polygon{polygon, neighbor})
+		}
+	}
+
+	var borders []border
+	for _, pair := range pairs {
+		// portals that share an end are part of the same stretch
+		group := make([]int, len(portals[pair]))
+		var find func(i int) int
+		find = func(i int) int {
+			if group[i] != i {
+				group[i] = find(group[i])
+			}
+			return group[i]
+		}
+		for i := range group {
+			group[i] = i
+		}
+		for i, a := range portals[pair] {
+			for j, b := range portals[pair][:i] {
+				if a.Point1 == b.Point1 || a.Point1 == b.Point2 || a.Point2 == b.Point1 || a.Point2 == b.Point2 {
+					group[find(i)] = find(j)
+				}
+			}
+		}
+
+		widest := map[int]int{}
+		var roots []int
+		for i, portal := range portals[pair] {
+			root := find(i)
+			w, ok := widest[root]
+			if !ok {
+				roots = append(roots, root)
+			}
+			if !ok || portal.Width() > portals[pair][w].Width() {
+				widest[root] = i
+			}
+		}
+		for _, root := range roots {
+			portal := portals[pair][widest[root]]
+			middle := geometry.Point(portal.Point1.Vector3().Add(portal.Point2.Vector3()).Mul(0.5))
+			b := border{points: [2]geometry.Point{middle, middle}, regions: pair}
+			if side := sides[pair][widest[root]]; nm.isStep(side[0], side[1]) {
+				b.points = [2]geometry.Point{surfacePoint(side[0], middle), surfacePoint(side[1], middle)}
+			}
+			borders = append(borders, b)
+		}
+	}
+	return borders
+}
+
+// cluster returns the square of the grid the middle of the polygon is in
+func (h *HierarchicalPlanner) cluster(polygon *geometry.Polygon) [2]int {
+	var x, z float64
+	points := polygon.Points()
+	for _, point := range points {
+		x += point[0]
+		z += point[2]
+	}
+	n := float64(len(points))
+	return [2]int{int(math.Floor(x / n / h.clusterSize)), int(math.Floor(z / n / h.clusterSize))}
+}
+
+func (h *HierarchicalPlanner) store(key pathKey, segments []PathSegment) {
+	if h.cacheSize <= 0 {
+		return
+	}
+	h.cache[key] = segments
+	if len(h.cacheOrder) < h.cacheSize {
+		h.cacheOrder = append(h.cacheOrder, key)
+		return
+	}
+	delete(h.cache, h.cacheOrder[h.cacheNext])
+	h.cacheOrder[h.cacheNext] = key
+	h.cacheNext = (h.cacheNext + 1) % h.cacheSize
+}
+
+func (h *HierarchicalPlanner) clearCache() {
+	h.cache = map[pathKey][]PathSegment{}
+	h.cacheOrder = nil
+	h.cacheNext = 0
+}
@@ -0,0 +1,146 @@
+package pathing
+
+import (
+	"testing"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// maze is a size by size grid of unit squares with a wall on every fourth column that has a
+// gap at alternating ends
+func maze(size int) *NavMesh {
+	var polygons []*geometry.Polygon
+	for x := 0; x < size; x++ {
+		for z := 0; z < size; z++ {
+			if x%4 == 2 {
+				if (x/4)%2 == 0 && z != size-1 || (x/4)%2 == 1 && z != 0 {
+					continue
+				}
+			}
+			polygons = append(polygons, sqWithOffset(1, float64(x), float64(z)))
+		}
+	}
+	return ConstructNavMesh(polygons)
+}
+
+func TestHierarchicalPlannerMatchesFlat(t *testing.T) {
+	p := &Planner{}
+	p.SetNavMesh(maze(24))
+	h := NewHierarchicalPlanner(p, 6)
+
+	for _, query := range [][2]geometry.Point{
+		{{0.5, 0, 0.5}, {23.5, 0, 0.5}},
+		{{0.3, 0, 12.2}, {20.7, 0, 3.1}},
+		{{1.5, 0, 1.5}, {1.2, 0, 4.6}},
+		{{23.5, 0, 23.5}, {0.5, 0, 0.5}},
+	} {
+		start, goal := query[0], query[1]
+		flat, hierarchical := p.FindPath(start, goal), h.FindPath(start, goal)
+		if len(hierarchical) == 0 || hierarchical[len(hierarchical)-1] != goal {
+			t.Fatalf("expected a path from %v to %v but got %v", start, goal, hierarchical)
+		}
+		if !p.ValidatePath(hierarchical) {
+			t.Errorf("expected the path %v to stay on the navmesh", hierarchical)
+		}
+		flatLength, length := pathLength(start, flat), pathLength(start, hierarchical)
+		if length > flatLength*1.05 {
+			t.Errorf("expected a path close to %f long from %v to %v but got %f", flatLength, start, goal, length)
+		}
+	}
+}
+
+func TestHierarchicalPlannerCache(t *testing.T) {
+	navmesh := ConstructNavMesh([]*geometry.Polygon{sqWithXOffset(0), sqWithXOffset(6), sqWithXOffset(12)})
+	p := &Planner{}
+	p.SetNavMesh(navmesh)
+	h := NewHierarchicalPlanner(p, 6)
+
+	start, goal := geometry.Point{1, 0, 3}, geometry.Point{17, 0, 3}
+	assertPathEq(t, []geometry.Point{start, goal}, h.FindPath(start, goal))
+	if len(h.cache) != 1 {
+		t.Fatalf("expected the path to be cached")
+	}
+	h.FindPath(start, goal)
+	if len(h.cache) != 1 {
+		t.Errorf("expected the cached path to be reused")
+	}
+
+	navmesh.RemovePolygon(navmesh.Polygons()[1])
+	if path := h.FindPath(start, goal); path != nil {
+		t.Errorf("expected the cache to be invalidated when the navmesh changed but got %v", path)
+	}
+
+	h.SetCacheSize(2)
+	for i := 0; i < 5; i++ {
+		h.FindPath(geometry.Point{1, 0, float64(i + 1)}, geometry.Point{5, 0, 3})
+	}
+	if len(h.cache) != 2 || len(h.cacheOrder) != 2 {
+		t.Errorf("expected 2 cached paths but got %d", len(h.cache))
+	}
+	// first in first out, the two newest paths are the ones kept
+	for i := 3; i < 5; i++ {
+		if _, ok := h.cache[pathKey{start: geometry.Point{1, 0, float64(i + 1)}, goal: geometry.Point{5, 0, 3}}]; !ok {
+			t.Errorf("expected path %d to still be cached", i)
+		}
+	}
+}
+
+func TestHierarchicalPlannerLinks(t *testing.T) {
+	navmesh := ConstructNavMesh([]*geometry.Polygon{sqWithXOffset(0), sqWithXOffset(20)})
+	navmesh.AddOffMeshLink(OffMeshLink{Start: geometry.Point{5, 0, 3}, End: geometry.Point{21, 0, 3}, Tag: "jump"})
+	p := &Planner{}
+	p.SetNavMesh(navmesh)
+	h := NewHierarchicalPlanner(p, 6)
+
+	segments := h.FindPathSegments(geometry.Point{1, 0, 3}, geometry.Point{25, 0, 3})
+	if len(segments) != 3 || segments[1].Link == nil || segments[1].Link.Tag != "jump" {
+		t.Errorf("expected the path to take the jump but got %v", segments)
+	}
+}
+
+func benchmarkQueries() [][2]geometry.Point {
+	return [][2]geometry.Point{
+		{{0.5, 0, 0.5}, {63.5, 0, 63.5}},
+		{{63.5, 0, 0.5}, {0.5, 0, 63.5}},
+		{{0.5, 0, 32.5}, {63.5, 0, 30.5}},
+		{{10.5, 0, 60.5}, {50.5, 0, 5.5}},
+	}
+}
+
+func BenchmarkFindPathFlat(b *testing.B) {
+	p := &Planner{}
+	p.SetNavMesh(maze(64))
+	queries := benchmarkQueries()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		query := queries[i%len(queries)]
+		p.FindPath(query[0], query[1])
+	}
+}
+
+func BenchmarkFindPathHierarchical(b *testing.B) {
+	p := &Planner{}
+	p.SetNavMesh(maze(64))
+	h := NewHierarchicalPlanner(p, 8)
+	h.SetCacheSize(0)
+	queries := benchmarkQueries()
+	h.FindPath(queries[0][0], queries[0][1])
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		query := queries[i%len(queries)]
+		h.FindPath(query[0], query[1])
+	}
+}
+
+func BenchmarkFindPathHierarchicalCached(b *testing.B) {
+	p := &Planner{}
+	p.SetNavMesh(maze(64))
+	h := NewHierarchicalPlanner(p, 8)
+	queries := benchmarkQueries()
+	h.FindPath(queries[0][0], queries[0][1])
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		query := queries[i%len(queries)]
+		h.FindPath(query[0], query[1])
+	}
+}
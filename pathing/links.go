@@ -0,0 +1,155 @@
+package pathing
+
+import (
+	"math"
+	"sort"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// OffMeshLink connects two points on the navmesh that don't share an edge, like the top and
+// bottom of a ladder or the two ends of a jump
+type OffMeshLink struct {
+	ID            int
+	Start         geometry.Point
+	End           geometry.Point
+	Bidirectional bool
+	// Cost is what taking the link costs in the same units as distance, the distance between
+	// the ends is used when it's zero
+	Cost float64
+	// Tag is for gameplay to tell what kind of link it is, like "jump" or "ladder"
+	Tag string
+}
+
+func (l OffMeshLink) cost() float64 {
+	if l.Cost > 0 {
+		return l.Cost
+	}
+	return l.Start.Vector3().Sub(l.End.Vector3()).Len()
+}
+
+type linkEdge struct {
+	to   NavNode
+	link int
+}
+
+// linkGraph is where the links' ends are on the navmesh. it's built when it's needed since
+// polygons come and go, and isn't changed once it's built so searches take it once and read
+// it without locking
+type linkGraph struct {
+	links map[int]OffMeshLink
+	edges map[NavNode][]linkEdge
+	// ends are the link ends in each polygon
+	ends  map[*geometry.Polygon][]NavNode
+	isEnd map[NavNode]bool
+	// minCostRatio is the smallest cost per distance of any link, at most 1. a link that costs
+	// less than its length gets closer to the goal than its cost, so the A* heuristic has to
+	// be scaled down by it.
+	minCostRatio float64
+}
+
+// AddOffMeshLink adds the link and returns its id. the link is only used while both of its
+// ends are on a polygon, so links survive their polygons being rebuilt.
+func (nm *NavMesh) AddOffMeshLink(link OffMeshLink) int {
+	link.ID = nm.nextLinkID
+	nm.nextLinkID++
+	nm.offMeshLinks[link.ID] = link
+	nm.changed()
+	return link.ID
+}
+
+func (nm *NavMesh) RemoveOffMeshLink(id int) {
+	delete(nm.offMeshLinks, id)
+	nm.changed()
+}
+
+// OffMeshLinks returns the links ordered by id
+func (nm *NavMesh) OffMeshLinks() []OffMeshLink {
+	var links []OffMeshLink
+	for _, link := range nm.offMeshLinks {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	return links
+}
+
+func (nm *NavMesh) OffMeshLink(id int) (OffMeshLink, bool) {
+	link, ok := nm.offMeshLinks[id]
+	return link, ok
+}
+
+// links returns the link graph for the navmesh as it is now, it's rebuilt after the navmesh
+// changes
+func (nm *NavMesh) links() *linkGraph {
+	nm.linkGraphLock.Lock()
+	defer nm.linkGraphLock.Unlock()
+	if nm.linkGraph != nil {
+		return nm.linkGraph
+	}
+
+	graph := &linkGraph{
+		links:        map[int]OffMeshLink{},
+		edges:        map[NavNode][]linkEdge{},
+		ends:         map[*geometry.Polygon][]NavNode{},
+		isEnd:        map[NavNode]bool{},
+		minCostRatio: 1,
+	}
+	addEnd := func(node NavNode) {
+		if !graph.isEnd[node] {
+			graph.isEnd[node] = true
+			graph.ends[node.Polygon] = append(graph.ends[node.Polygon], node)
+		}
+	}
+
+	for _, link := range nm.OffMeshLinks() {
+		startPolygon, endPolygon := nm.polygonAt(link.Start), nm.polygonAt(link.End)
+		if startPolygon == nil || endPolygon == nil {
+			continue
+		}
+		start := NavNode{Point: link.Start, Polygon: startPolygon}
+		end := NavNode{Point: link.End, Polygon: endPolygon}
+		graph.links[link.ID] = link
+		addEnd(start)
+		addEnd(end)
+		graph.edges[start] = append(graph.edges[start], linkEdge{to: end, link: link.ID})
+		if link.Bidirectional {
+			graph.edges[end] = append(graph.edges[end], linkEdge{to: start, link: link.ID})
+		}
+		if length := nm.Cost(link.Start, link.End); length > floatEpsilon {
+			graph.minCostRatio = math.Min(graph.minCostRatio, link.cost()/length)
+		}
+	}
+
+	nm.linkGraph = graph
+	return graph
+}
+
+// between returns the link that goes straight from one node to the other
+func (graph *linkGraph) between(from, to NavNode) (OffMeshLink, bool) {
+	for _, edge := range graph.edges[from] {
+		if edge.to == to {
+			return graph.links[edge.link], true
+		}
+	}
+	return OffMeshLink{}, false
+}
+
+// neighbors returns the nodes reachable from node because of the links. the ends of links
+// can be walked to from anywhere in their polygon and the other way around.
+func (graph *linkGraph) neighbors(node NavNode) []NavNode {
+	var neighbors []NavNode
+	for _, end := range graph.ends[node.Polygon] {
+		if end != node {
+			neighbors = append(neighbors, end)
+		}
+	}
+	if graph.isEnd[node] {
+		for _, point := range node.Polygon.Points() {
+			neighbors = append(neighbors, NavNode{Point: point, Polygon: node.Polygon})
+		}
+	}
+	for _, edge := range graph.edges[node] {
+		neighbors = append(neighbors, edge.to)
+	}
+	return neighbors
+}
@@ -0,0 +1,102 @@
+package pathing
+
+import (
+	"testing"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+func TestOffMeshLink(t *testing.T) {
+	// two platforms with a gap between them
+	navmesh := ConstructNavMesh([]*geometry.Polygon{sqWithXOffset(0), sqWithXOffset(20)})
+	p := Planner{}
+	p.SetNavMesh(navmesh)
+
+	start, goal := geometry.Point{1, 0, 3}, geometry.Point{25, 0, 3}
+	if path := p.FindPath(start, goal); path != nil {
+		t.Fatalf("expected no path across the gap but got %v", path)
+	}
+
+	id := navmesh.AddOffMeshLink(OffMeshLink{Start: geometry.Point{5, 0, 3}, End: geometry.Point{21, 0, 3}, Tag: "jump"})
+	expectedPath := []geometry.Point{start, {5, 0, 3}, {21, 0, 3}, goal}
+	assertPathEq(t, expectedPath, p.FindPath(start, goal))
+
+	segments := p.FindPathSegments(start, goal)
+	if len(segments) != 3 {
+		t.Fatalf("expected 3 segments but got %v", segments)
+	}
+	for i, segment := range segments {
+		if segment.Start != expectedPath[i] || segment.End != expectedPath[i+1] {
+			t.Errorf("expected segment %d to go from %v to %v but got %v", i, expectedPath[i], expectedPath[i+1], segment)
+		}
+		if (segment.Link != nil) != (i == 1) {
+			t.Errorf("expected only the middle segment to be a link but segment %d is %v", i, segment)
+		}
+	}
+	if link := segments[1].Link; link.ID != id || link.Tag != "jump" {
+		t.Errorf("expected the jump link but got %v", link)
+	}
+
+	if path := p.FindPath(goal, start); path != nil {
+		t.Errorf("expected the one way link not to be taken backwards but got %v", path)
+	}
+
+	navmesh.RemoveOffMeshLink(id)
+	navmesh.AddOffMeshLink(OffMeshLink{Start: geometry.Point{5, 0, 3}, End: geometry.Point{21, 0, 3}, Bidirectional: true})
+	assertPathEq(t, []geometry.Point{goal, {21, 0, 3}, {5, 0, 3}, start}, p.FindPath(goal, start))
+}
+
+func TestOffMeshLinkCost(t *testing.T) {
+	navmesh := ConstructNavMesh([]*geometry.Polygon{sqWithXOffset(0), sqWithXOffset(6), sqWithXOffset(12)})
+	p := Planner{}
+	p.SetNavMesh(navmesh)
+	start, goal := geometry.Point{1, 0, 3}, geometry.Point{17, 0, 3}
+
+	// a teleporter is cheaper than walking
+	id := navmesh.AddOffMeshLink(OffMeshLink{Start: geometry.Point{2, 0, 2}, End: geometry.Point{16, 0, 2}, Cost: 1, Tag: "teleporter"})
+	segments := p.FindPathSegments(start, goal)
+	if len(segments) != 3 || segments[1].Link == nil || segments[1].Link.Tag != "teleporter" {
+		t.Fatalf("expected the path to take the teleporter but got %v", segments)
+	}
+
+	// a slow ladder is not
+	navmesh.RemoveOffMeshLink(id)
+	navmesh.AddOffMeshLink(OffMeshLink{Start: geometry.Point{2, 0, 2}, End: geometry.Point{16, 0, 2}, Cost: 100, Tag: "ladder"})
+	assertPathEq(t, []geometry.Point{start, goal}, p.FindPath(start, goal))
+}
+
+func TestOffMeshLinkSurvivesRebuild(t *testing.T) {
+	a, b := sqWithXOffset(0), sqWithXOffset(20)
+	navmesh := ConstructNavMesh([]*geometry.Polygon{a})
+	p := Planner{}
+	p.SetNavMesh(navmesh)
+	navmesh.AddOffMeshLink(OffMeshLink{Start: geometry.Point{5, 0, 3}, End: geometry.Point{21, 0, 3}})
+
+	start, goal := geometry.Point{1, 0, 3}, geometry.Point{25, 0, 3}
+	if path := p.FindPath(start, goal); path != nil {
+		t.Fatalf("expected no path while the link's end is off the navmesh but got %v", path)
+	}
+
+	navmesh.AddPolygon(b)
+	if path := p.FindPath(start, goal); len(path) != 4 {
+		t.Errorf("expected the link to be used once its end is on the navmesh but got %v", path)
+	}
+}
+
+// a link that costs much less than its length can make a path that starts out going away from
+// the goal the cheapest, which the search only finds if its heuristic takes the link into account
+func TestCheapLongLink(t *testing.T) {
+	var polygons []*geometry.Polygon
+	for x := -48.0; x <= 54; x += 6 {
+		polygons = append(polygons, sqWithXOffset(x))
+	}
+	navmesh := ConstructNavMesh(polygons)
+	navmesh.AddOffMeshLink(OffMeshLink{Start: geometry.Point{-40, 0, 3}, End: geometry.Point{50, 0, 3}, Cost: 1, Tag: "teleporter"})
+	p := &Planner{}
+	p.SetNavMesh(navmesh)
+
+	start, goal := geometry.Point{1, 0, 3}, geometry.Point{49, 0, 3}
+	expected := []geometry.Point{start, {-40, 0, 3}, {50, 0, 3}, goal}
+	assertPathEq(t, expected, p.FindPath(start, goal))
+	assertPathEq(t, expected, NewHierarchicalPlanner(p, 6).FindPath(start, goal))
+}
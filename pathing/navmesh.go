@@ -2,8 +2,15 @@ package pathing
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"sync"
 
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
 	"github.com/kkevinchou/kitolib/geometry"
+	"github.com/kkevinchou/kitolib/spatialpartition"
+	"github.com/kkevinchou/kitolib/utils"
 )
 
 type NavNode struct {
@@ -21,12 +28,58 @@ func (p Portal) String() string {
 	return fmt.Sprintf("P{%v, %v}", p.Point1, p.Point2)
 }
 
+func (p Portal) Width() float64 {
+	return p.Point1.Vector3().Sub(p.Point2.Vector3()).Len()
+}
+
+// newPortal makes a deterministically ordered Portal for consistent lookups
+func newPortal(point1, point2 geometry.Point) Portal {
+	portal := Portal{Point1: point1, Point2: point2}
+	if point1[0] != point2[0] {
+		if point1[0] > point2[0] {
+			portal = Portal{Point1: point1, Point2: point2}
+		} else {
+			portal = Portal{Point1: point2, Point2: point1}
+		}
+	} else if point1[1] != point2[1] {
+		if point1[1] > point2[1] {
+			portal = Portal{Point1: point1, Point2: point2}
+		} else {
+			portal = Portal{Point1: point2, Point2: point1}
+		}
+	} else if point1[2] != point2[2] {
+		if point1[2] > point2[2] {
+			portal = Portal{Point1: point1, Point2: point2}
+		} else {
+			portal = Portal{Point1: point2, Point2: point1}
+		}
+	}
+	return portal
+}
+
 type NavMesh struct {
 	neighbors        map[NavNode][]NavNode
 	costs            map[NavNode]map[NavNode]float64
 	polygons         []*geometry.Polygon
 	portalToPolygons map[Portal][]*geometry.Polygon
 	polyPairToPortal map[*geometry.Polygon]map[*geometry.Polygon]Portal
+	pointToPolygons  map[geometry.Point][]*geometry.Polygon
+
+	// polygonIndex finds the polygons near a point without going through all of them. ids go
+	// up in the order polygons are added so lookups can keep that order.
+	polygonIndex  *spatialpartition.AABBTree
+	polygonIDs    map[*geometry.Polygon]int
+	nextPolygonID int
+
+	areas        map[*geometry.Polygon]AreaType
+	offMeshLinks map[int]OffMeshLink
+	nextLinkID   int
+	// linkGraph is nil when it needs to be rebuilt, it's built by whichever search needs it
+	// first so it's behind a lock
+	linkGraph     *linkGraph
+	linkGraphLock sync.Mutex
+	// version goes up every time the polygons, their areas or the links change
+	version int
 
 	*RenderComponent
 }
@@ -37,6 +90,11 @@ func ConstructNavMesh(polygons []*geometry.Polygon) *NavMesh {
 		costs:            map[NavNode]map[NavNode]float64{},
 		portalToPolygons: map[Portal][]*geometry.Polygon{},
 		polyPairToPortal: map[*geometry.Polygon]map[*geometry.Polygon]Portal{},
+		pointToPolygons:  map[geometry.Point][]*geometry.Polygon{},
+		areas:            map[*geometry.Polygon]AreaType{},
+		offMeshLinks:     map[int]OffMeshLink{},
+		polygonIndex:     spatialpartition.NewAABBTree(0),
+		polygonIDs:       map[*geometry.Polygon]int{},
 	}
 
 	for _, polygon := range polygons {
@@ -52,12 +110,33 @@ func ConstructNavMesh(polygons []*geometry.Polygon) *NavMesh {
 
 	return navmesh
 }
+
+// Version is a number that changes whenever the navmesh does, for anything that keeps data
+// derived from it
+func (nm *NavMesh) Version() int {
+	return nm.version
+}
+
+func (nm *NavMesh) changed() {
+	nm.version++
+	nm.linkGraphLock.Lock()
+	nm.linkGraph = nil
+	nm.linkGraphLock.Unlock()
+}
+
 func (nm *NavMesh) Polygons() []*geometry.Polygon {
 	return nm.polygons
 }
 
 func (nm *NavMesh) AddPolygon(polygon *geometry.Polygon) {
 	nm.polygons = append(nm.polygons, polygon)
+	nm.changed()
+	nm.polygonIDs[polygon] = nm.nextPolygonID
+	nm.polygonIndex.IndexEntities([]spatialpartition.Entity{polygonEntity{id: nm.nextPolygonID, polygon: polygon}})
+	nm.nextPolygonID++
+	for _, point := range polygon.Points() {
+		nm.pointToPolygons[point] = append(nm.pointToPolygons[point], polygon)
+	}
 	for i, point1 := range polygon.Points() {
 		for j, point2 := range polygon.Points() {
 			// Avoid processing the same two pairs of indicies
@@ -72,27 +151,7 @@ func (nm *NavMesh) AddPolygon(polygon *geometry.Polygon) {
 			nm.addEdge(navNode1, navNode2)
 			nm.addEdge(navNode2, navNode1)
 
-			// Make a deterministically ordered Portal for consistent lookups
-			portal := Portal{Point1: point1, Point2: point2}
-			if point1[0] != point2[0] {
-				if point1[0] > point2[0] {
-					portal = Portal{Point1: point1, Point2: point2}
-				} else {
-					portal = Portal{Point1: point2, Point2: point1}
-				}
-			} else if point1[1] != point2[1] {
-				if point1[1] > point2[1] {
-					portal = Portal{Point1: point1, Point2: point2}
-				} else {
-					portal = Portal{Point1: point2, Point2: point1}
-				}
-			} else if point1[2] != point2[2] {
-				if point1[2] > point2[2] {
-					portal = Portal{Point1: point1, Point2: point2}
-				} else {
-					portal = Portal{Point1: point2, Point2: point1}
-				}
-			}
+			portal := newPortal(point1, point2)
 
 			// Found one half of the portal, complete the other half
 			if len(nm.portalToPolygons[portal]) == 1 {
@@ -106,8 +165,12 @@ func (nm *NavMesh) AddPolygon(polygon *geometry.Polygon) {
 						nm.polyPairToPortal[polyWithSharedPortal] = map[*geometry.Polygon]Portal{}
 					}
 
-					nm.polyPairToPortal[polygon][polyWithSharedPortal] = portal
-					nm.polyPairToPortal[polyWithSharedPortal][polygon] = portal
+					// polygons can share more than two points along an edge, keep the widest
+					// portal between them
+					if existing, ok := nm.polyPairToPortal[polygon][polyWithSharedPortal]; !ok || existing.Width() < portal.Width() {
+						nm.polyPairToPortal[polygon][polyWithSharedPortal] = portal
+						nm.polyPairToPortal[polyWithSharedPortal][polygon] = portal
+					}
 
 					// Set points that lie on the same portal to be neighbors to one another
 
@@ -143,6 +206,461 @@ func (nm *NavMesh) AddPolygon(polygon *geometry.Polygon) {
 	}
 }
 
+// ConnectStep joins two polygons whose edges line up seen from above but at different
+// heights, like the polygons at the bottom and top of a step. the vertices along the part of
+// the edges they share are joined to the ones right above or below them, and the portal
+// between the polygons is the lower polygon's side of it. false is returned when the polygons
+// are already joined or don't share part of an edge.
+func (nm *NavMesh) ConnectStep(a, b *geometry.Polygon) bool {
+	if a == b {
+		return false
+	}
+	if _, ok := nm.polyPairToPortal[a][b]; ok {
+		return false
+	}
+
+	// vertices of a with a vertex of b at the same place seen from above
+	var pairs [][2]geometry.Point
+	for _, pointA := range a.Points() {
+		for _, pointB := range b.Points() {
+			if pointA != pointB && sameXZ(pointA, pointB) {
+				pairs = append(pairs, [2]geometry.Point{pointA, pointB})
+			}
+		}
+	}
+
+	// the widest two that are along an edge of both, with the polygons on either side of it
+	first, second := -1, -1
+	var width float64
+	for i := range pairs {
+		for j := i + 1; j < len(pairs); j++ {
+			p, q := pairs[i][0].Vector3(), pairs[j][0].Vector3()
+			sideA, sideB := sideXZ(a, p, q), sideXZ(b, p, q)
+			if sideA == 0 || sideB == 0 || sideA == sideB {
+				continue
+			}
+			if w := math.Hypot(q[0]-p[0], q[2]-p[2]); w > width {
+				first, second, width = i, j, w
+			}
+		}
+	}
+	if first == -1 {
+		return false
+	}
+
+	lower := 0
+	if pairs[first][1][1] < pairs[first][0][1] {
+		lower = 1
+	}
+	portal := newPortal(pairs[first][lower], pairs[second][lower])
+	if _, ok := nm.polyPairToPortal[a]; !ok {
+		nm.polyPairToPortal[a] = map[*geometry.Polygon]Portal{}
+	}
+	if _, ok := nm.polyPairToPortal[b]; !ok {
+		nm.polyPairToPortal[b] = map[*geometry.Polygon]Portal{}
+	}
+	nm.polyPairToPortal[a][b] = portal
+	nm.polyPairToPortal[b][a] = portal
+
+	p, q := pairs[first][0].Vector3(), pairs[second][0].Vector3()
+	for _, pair := range pairs {
+		if !betweenXZ(pair[0].Vector3(), p, q) {
+			continue
+		}
+		nodeA := NavNode{Point: pair[0], Polygon: a}
+		nodeB := NavNode{Point: pair[1], Polygon: b}
+		nm.neighbors[nodeA] = append(nm.neighbors[nodeA], nodeB)
+		nm.neighbors[nodeB] = append(nm.neighbors[nodeB], nodeA)
+	}
+	nm.changed()
+	return true
+}
+
+// isStep returns whether the polygons are joined by a step rather than a shared edge
+func (nm *NavMesh) isStep(a, b *geometry.Polygon) bool {
+	portal, ok := nm.polyPairToPortal[a][b]
+	if !ok {
+		return false
+	}
+	// only the lower polygon has the portal's vertices
+	polygons := nm.portalToPolygons[portal]
+	return !containsPolygon(polygons, a) || !containsPolygon(polygons, b)
+}
+
+// sideXZ returns which side of the line through p and q the polygon is on seen from above, 1
+// for the left, -1 for the right and 0 when the line goes through it
+func sideXZ(polygon *geometry.Polygon, p, q mgl64.Vec3) int {
+	direction := q.Sub(p)
+	side := 0
+	for _, point := range polygon.Points() {
+		cross := utils.Cross2D(direction, point.Vector3().Sub(p))
+		if math.Abs(cross) <= floatEpsilon {
+			continue
+		}
+		s := 1
+		if cross > 0 {
+			s = -1
+		}
+		if side != 0 && s != side {
+			return 0
+		}
+		side = s
+	}
+	return side
+}
+
+// betweenXZ returns whether the point is on the segment from p to q seen from above
+func betweenXZ(point, p, q mgl64.Vec3) bool {
+	direction := q.Sub(p)
+	offset := point.Sub(p)
+	if math.Abs(utils.Cross2D(direction, offset)) > floatEpsilon {
+		return false
+	}
+	along := direction[0]*offset[0] + direction[2]*offset[2]
+	return along >= -floatEpsilon && along <= direction[0]*direction[0]+direction[2]*direction[2]+floatEpsilon
+}
+
+func sameXZ(a, b geometry.Point) bool {
+	return a[0] == b[0] && a[2] == b[2]
+}
+
+// RemovePolygon removes the polygon along with its portals and the links to its vertices
+func (nm *NavMesh) RemovePolygon(polygon *geometry.Polygon) {
+	index := -1
+	for i, p := range nm.polygons {
+		if p == polygon {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return
+	}
+	polygons := make([]*geometry.Polygon, 0, len(nm.polygons)-1)
+	polygons = append(polygons, nm.polygons[:index]...)
+	nm.polygons = append(polygons, nm.polygons[index+1:]...)
+	delete(nm.areas, polygon)
+	nm.changed()
+	nm.polygonIndex.DeleteEntity(nm.polygonIDs[polygon])
+	delete(nm.polygonIDs, polygon)
+
+	points := polygon.Points()
+	for i, point := range points {
+		node := NavNode{Point: point, Polygon: polygon}
+		for _, neighbor := range nm.neighbors[node] {
+			if neighbor.Polygon != polygon {
+				nm.neighbors[neighbor] = removeNavNode(nm.neighbors[neighbor], node)
+			}
+		}
+		delete(nm.neighbors, node)
+		delete(nm.costs, node)
+
+		nm.pointToPolygons[point] = removePolygon(nm.pointToPolygons[point], polygon)
+		if len(nm.pointToPolygons[point]) == 0 {
+			delete(nm.pointToPolygons, point)
+		}
+
+		for _, other := range points[i+1:] {
+			portal := newPortal(point, other)
+			nm.portalToPolygons[portal] = removePolygon(nm.portalToPolygons[portal], polygon)
+			if len(nm.portalToPolygons[portal]) == 0 {
+				delete(nm.portalToPolygons, portal)
+			}
+		}
+	}
+
+	for other := range nm.polyPairToPortal[polygon] {
+		delete(nm.polyPairToPortal[other], polygon)
+		if len(nm.polyPairToPortal[other]) == 0 {
+			delete(nm.polyPairToPortal, other)
+		}
+	}
+	delete(nm.polyPairToPortal, polygon)
+}
+
+// ReplacePolygon removes the polygon and adds the polygons that take its place, which keep
+// its area type
+func (nm *NavMesh) ReplacePolygon(polygon *geometry.Polygon, replacements ...*geometry.Polygon) {
+	area := nm.Area(polygon)
+	nm.RemovePolygon(polygon)
+	for _, replacement := range replacements {
+		nm.AddPolygon(replacement)
+		nm.SetArea(replacement, area)
+	}
+}
+
+// OnBoundary returns whether the point is a vertex on the edge of the navmesh, where one of
+// the polygon edges it's on isn't shared with another polygon or joined to one by a step
+func (nm *NavMesh) OnBoundary(point geometry.Point) bool {
+	for _, polygon := range nm.pointToPolygons[point] {
+		points := polygon.Points()
+		n := len(points)
+		for i, p := range points {
+			if p != point {
+				continue
+			}
+			for _, other := range []geometry.Point{points[(i+n-1)%n], points[(i+1)%n]} {
+				if len(nm.portalToPolygons[newPortal(point, other)]) < 2 && !nm.onStep(polygon, point, other) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// onStep returns whether the polygon's edge from p to q is part of a step to another polygon
+func (nm *NavMesh) onStep(polygon *geometry.Polygon, p, q geometry.Point) bool {
+	for other, portal := range nm.polyPairToPortal[polygon] {
+		if !nm.isStep(polygon, other) {
+			continue
+		}
+		a, b := portal.Point1.Vector3(), portal.Point2.Vector3()
+		if betweenXZ(p.Vector3(), a, b) && betweenXZ(q.Vector3(), a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// polygonAt returns the first polygon that contains the point
+func (nm *NavMesh) polygonAt(point geometry.Point) *geometry.Polygon {
+	for _, polygon := range nm.polygonsNear(point.Vector3(), point.Vector3()) {
+		if polygon.ContainsPoint(point) {
+			return polygon
+		}
+	}
+	return nil
+}
+
+// polygonEntity is a polygon in the polygon index
+type polygonEntity struct {
+	id      int
+	polygon *geometry.Polygon
+}
+
+func (e polygonEntity) GetID() int {
+	return e.id
+}
+
+func (e polygonEntity) Position() mgl64.Vec3 {
+	box := e.BoundingBox()
+	return box.MinVertex.Add(box.MaxVertex).Mul(0.5)
+}
+
+// BoundingBox is grown a little so that points that are just off of the polygon, which
+// ContainsPoint and containsXZ allow, are still found
+func (e polygonEntity) BoundingBox() collider.BoundingBox {
+	var vertices []mgl64.Vec3
+	for _, point := range e.polygon.Points() {
+		vertices = append(vertices, point.Vector3())
+	}
+	box := collider.BoundingBoxFromVertices(vertices)
+	margin := mgl64.Vec3{polygonIndexMargin, polygonIndexMargin, polygonIndexMargin}
+	return collider.BoundingBox{MinVertex: box.MinVertex.Sub(margin), MaxVertex: box.MaxVertex.Add(margin)}
+}
+
+// polygonIndexMargin is how far points can be off of a polygon and still be looked up in it
+const polygonIndexMargin = 0.1
+
+// polygonsNear returns the polygons whose bounds overlap the box from min to max, in the order
+// they were added
+func (nm *NavMesh) polygonsNear(min, max mgl64.Vec3) []*geometry.Polygon {
+	entities := nm.polygonIndex.QueryEntities(collider.BoundingBox{MinVertex: min, MaxVertex: max})
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].GetID() < entities[j].GetID()
+	})
+	polygons := make([]*geometry.Polygon, len(entities))
+	for i, entity := range entities {
+		polygons[i] = entity.(polygonEntity).polygon
+	}
+	return polygons
+}
+
+// segmentOnMesh walks the segment from above, from polygon to polygon through the portals
+// between them, and returns whether it stays on the navmesh all the way to end
+func (nm *NavMesh) segmentOnMesh(start, end geometry.Point) bool {
+	current := nm.startPolygon(start, end)
+	if current == nil {
+		return false
+	}
+
+	a := start.Vector3()
+	delta := end.Vector3().Sub(a)
+	length := math.Hypot(delta[0], delta[2])
+	if length < floatEpsilon {
+		return nm.polygonAt(end) != nil
+	}
+
+	visited := map[*geometry.Polygon]bool{current: true}
+	for {
+		t := exitDistance(current, a, delta)
+		if t >= 1 {
+			return true
+		}
+
+		exit := geometry.Point(a.Add(delta.Mul(t)))
+		ahead := geometry.Point(a.Add(delta.Mul(math.Min(1, t+segmentStep/length))))
+		current = nm.nextPolygon(current, exit, ahead, visited)
+		if current == nil {
+			return false
+		}
+	}
+}
+
+// MoveAlongSurface moves from start toward end on the navmesh. when the way is blocked by the
+// edge of the navmesh the rest of the move slides along that edge. the point reached is
+// returned with its height on the navmesh, or false when start isn't on the navmesh.
+func (nm *NavMesh) MoveAlongSurface(start, end geometry.Point) (geometry.Point, bool) {
+	current := nm.startPolygon(start, end)
+	if current == nil {
+		return start, false
+	}
+
+	a := start.Vector3()
+	delta := end.Vector3().Sub(a)
+	length := math.Hypot(delta[0], delta[2])
+	if length < floatEpsilon {
+		return surfacePoint(current, start), true
+	}
+
+	visited := map[*geometry.Polygon]bool{current: true}
+	for {
+		t, edgeIndex := exitEdge(current, a, delta, floatEpsilon)
+		if t >= 1 {
+			return surfacePoint(current, end), true
+		}
+
+		exit := geometry.Point(a.Add(delta.Mul(t)))
+		ahead := geometry.Point(a.Add(delta.Mul(math.Min(1, t+segmentStep/length))))
+		next := nm.nextPolygon(current, exit, ahead, visited)
+		if next != nil {
+			current = next
+			continue
+		}
+
+		// stop just inside the edge and slide along it for the rest of the way
+		stopAt, _ := exitEdge(current, a, delta, -floatEpsilon)
+		stop := a.Add(delta.Mul(math.Min(1, stopAt)))
+		points := current.Points()
+		edgeStart, edgeEnd := points[edgeIndex].Vector3(), points[(edgeIndex+1)%len(points)].Vector3()
+		edge := mgl64.Vec3{edgeEnd[0] - edgeStart[0], 0, edgeEnd[2] - edgeStart[2]}
+		edgeLength := edge.Len()
+		if edgeLength < floatEpsilon {
+			return surfacePoint(current, geometry.Point(stop)), true
+		}
+		direction := edge.Mul(1 / edgeLength)
+
+		remaining := delta.Mul(1 - math.Min(1, stopAt))
+		along := mgl64.Vec3{stop[0] - edgeStart[0], 0, stop[2] - edgeStart[2]}.Dot(direction)
+		slide := math.Max(-along, math.Min(edgeLength-along, mgl64.Vec3{remaining[0], 0, remaining[2]}.Dot(direction)))
+		return surfacePoint(current, geometry.Point(stop.Add(direction.Mul(slide)))), true
+	}
+}
+
+// startPolygon returns the polygon the segment from start to end starts in. starting on a
+// vertex or an edge it's the polygon the segment heads into.
+func (nm *NavMesh) startPolygon(start, end geometry.Point) *geometry.Polygon {
+	current := nm.polygonAt(start)
+	if current == nil {
+		return nil
+	}
+
+	a := start.Vector3()
+	delta := end.Vector3().Sub(a)
+	length := math.Hypot(delta[0], delta[2])
+	if length < floatEpsilon {
+		return current
+	}
+
+	ahead := geometry.Point(a.Add(delta.Mul(math.Min(1, segmentStep/length))))
+	for _, polygon := range nm.polygonsNear(start.Vector3(), start.Vector3()) {
+		if polygon.ContainsPoint(start) && containsXZ(polygon, ahead) {
+			return polygon
+		}
+	}
+	return current
+}
+
+// surfacePoint returns the point moved up or down onto the polygon's plane
+func surfacePoint(polygon *geometry.Polygon, point geometry.Point) geometry.Point {
+	points := polygon.Points()
+	origin := points[0].Vector3()
+	normal := points[1].Vector3().Sub(origin).Cross(points[2].Vector3().Sub(origin))
+	if math.Abs(normal[1]) < floatEpsilon {
+		return point
+	}
+	point[1] = origin[1] - (normal[0]*(point[0]-origin[0])+normal[2]*(point[2]-origin[2]))/normal[1]
+	return point
+}
+
+// segmentStep is how far past the edge of a polygon the next polygon has to reach
+const segmentStep = 1e-4
+
+// nextPolygon finds the polygon past exit that the segment continues into. polygons that only
+// touch exit, like when the segment goes through a corner that several polygons share, are
+// passed through.
+func (nm *NavMesh) nextPolygon(current *geometry.Polygon, exit, ahead geometry.Point, visited map[*geometry.Polygon]bool) *geometry.Polygon {
+	queue := []*geometry.Polygon{current}
+	for len(queue) > 0 {
+		polygon := queue[0]
+		queue = queue[1:]
+
+		for neighbor := range nm.polyPairToPortal[polygon] {
+			if visited[neighbor] || !containsXZ(neighbor, exit) {
+				continue
+			}
+			visited[neighbor] = true
+			if containsXZ(neighbor, ahead) {
+				return neighbor
+			}
+			queue = append(queue, neighbor)
+		}
+	}
+	return nil
+}
+
+// exitDistance returns how far along delta from a the segment leaves the polygon, seen from
+// above, as a fraction of delta. 1 or more means it doesn't leave.
+func exitDistance(polygon *geometry.Polygon, a, delta mgl64.Vec3) float64 {
+	t, _ := exitEdge(polygon, a, delta, floatEpsilon)
+	return t
+}
+
+// exitEdge returns how far along delta the segment gets to margin outside of the polygon and
+// the index of the edge it goes out through
+func exitEdge(polygon *geometry.Polygon, a, delta mgl64.Vec3, margin float64) (float64, int) {
+	t := math.MaxFloat64
+	index := -1
+	points := polygon.Points()
+	for i, point := range points {
+		edge := points[(i+1)%len(points)].Vector3().Sub(point.Vector3())
+		// heading out of the edge
+		rate := utils.Cross2D(edge, delta)
+		if rate <= 0 {
+			continue
+		}
+		offset := utils.Cross2D(edge, a.Sub(point.Vector3()))
+		if edgeT := (margin - offset) / rate; edgeT < t {
+			t, index = edgeT, i
+		}
+	}
+	return math.Max(0, t), index
+}
+
+// containsXZ returns whether the point is inside the polygon seen from above. exits are found
+// floatEpsilon outside of polygons so the tolerance is a bit bigger than that.
+func containsXZ(polygon *geometry.Polygon, point geometry.Point) bool {
+	points := polygon.Points()
+	for i, p := range points {
+		edge := points[(i+1)%len(points)].Vector3().Sub(p.Vector3())
+		if utils.Cross2D(edge, point.Vector3().Sub(p.Vector3())) > 2*floatEpsilon {
+			return false
+		}
+	}
+	return true
+}
+
 func (nm *NavMesh) addEdge(from, to NavNode) {
 	if _, ok := nm.neighbors[from]; !ok {
 		nm.neighbors[from] = []NavNode{}
@@ -151,10 +669,11 @@ func (nm *NavMesh) addEdge(from, to NavNode) {
 }
 
 func (nm *NavMesh) Neighbors(point NavNode) []NavNode {
-	if neighbors, ok := nm.neighbors[point]; ok {
-		return copyPointList(neighbors)
+	neighbors := []NavNode{}
+	if n, ok := nm.neighbors[point]; ok {
+		neighbors = copyPointList(n)
 	}
-	return []NavNode{}
+	return append(neighbors, nm.links().neighbors(point)...)
 }
 
 func (nm *NavMesh) Cost(from, to geometry.Point) float64 {
@@ -176,3 +695,32 @@ func (nm *NavMesh) Cost(from, to geometry.Point) float64 {
 func copyPointList(points []NavNode) []NavNode {
 	return append([]NavNode{}, points...)
 }
+
+func removeNavNode(nodes []NavNode, node NavNode) []NavNode {
+	var kept []NavNode
+	for _, n := range nodes {
+		if n != node {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+func containsPolygon(polygons []*geometry.Polygon, polygon *geometry.Polygon) bool {
+	for _, p := range polygons {
+		if p == polygon {
+			return true
+		}
+	}
+	return false
+}
+
+func removePolygon(polygons []*geometry.Polygon, polygon *geometry.Polygon) []*geometry.Polygon {
+	var kept []*geometry.Polygon
+	for _, p := range polygons {
+		if p != polygon {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
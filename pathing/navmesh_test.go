@@ -0,0 +1,42 @@
+package pathing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+func TestMoveAlongSurface(t *testing.T) {
+	navmesh := ConstructNavMesh([]*geometry.Polygon{
+		sqWithOffset(10, 0, 0),
+		sqWithOffset(10, 1, 0),
+		sqWithOffset(10, 1, 1),
+	})
+
+	if point, ok := navmesh.MoveAlongSurface(geometry.Point{5, 0, 5}, geometry.Point{15, 0, 15}); !ok || point != (geometry.Point{15, 0, 15}) {
+		t.Errorf("expected to reach the end through the corner but got %v", point)
+	}
+
+	// into the wall above the first square, sliding along it to the right
+	point, ok := navmesh.MoveAlongSurface(geometry.Point{5, 0, 8}, geometry.Point{8, 0, 12})
+	if !ok || math.Abs(point[0]-8) > 1e-3 || point[2] > 10 || point[2] < 10-1e-3 {
+		t.Errorf("expected to slide along the edge to about (8, 0, 10) but got %v", point)
+	}
+
+	// the slide stops at the corner
+	point, _ = navmesh.MoveAlongSurface(geometry.Point{5, 0, 9}, geometry.Point{-5, 0, 12})
+	if math.Abs(point[0]) > 1e-3 || math.Abs(point[2]-10) > 1e-3 {
+		t.Errorf("expected to stop in the corner but got %v", point)
+	}
+
+	if _, ok := navmesh.MoveAlongSurface(geometry.Point{5, 0, 25}, geometry.Point{5, 0, 5}); ok {
+		t.Error("expected a start off the navmesh to fail")
+	}
+
+	// the height follows a ramp
+	ramp := ConstructNavMesh([]*geometry.Polygon{geometry.NewPolygon([]geometry.Point{{0, 0, 0}, {0, 0, 10}, {10, 5, 10}, {10, 5, 0}})})
+	if point, _ := ramp.MoveAlongSurface(geometry.Point{1, 0.5, 5}, geometry.Point{5, 0, 5}); math.Abs(point[1]-2.5) > 1e-9 {
+		t.Errorf("expected to be halfway up the ramp but got %v", point)
+	}
+}
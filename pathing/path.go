@@ -1,6 +1,8 @@
 package pathing
 
 import (
+	"math"
+
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/kkevinchou/kitolib/geometry"
 	"github.com/kkevinchou/kitolib/utils"
@@ -19,30 +21,125 @@ const (
 )
 
 type Planner struct {
-	navmesh *NavMesh
+	navmesh     *NavMesh
+	agentRadius float64
+	areaFilter  AreaFilter
 }
 
 func (p *Planner) SetNavMesh(navmesh *NavMesh) {
 	p.navmesh = navmesh
 }
 
+func (p *Planner) NavMesh() *NavMesh {
+	return p.navmesh
+}
+
+// SetAgentRadius has paths skip portals the agent doesn't fit through and stay radius away
+// from the corners of the navmesh they go around. zero plans for a point.
+func (p *Planner) SetAgentRadius(radius float64) {
+	p.agentRadius = radius
+}
+
+// PathSegment is a straight part of a path. Link is set when the segment is an off-mesh link
+// rather than walking, so gameplay can jump or climb along it instead.
+type PathSegment struct {
+	Start geometry.Point
+	End   geometry.Point
+	Link  *OffMeshLink
+}
+
+// SetAreaFilter sets how much each area costs the agent to walk through and which areas it
+// can't enter, so that paths take the cheapest way it's allowed
+func (p *Planner) SetAreaFilter(filter AreaFilter) {
+	p.areaFilter = filter
+}
+
 // FindPath finds a path from start to goal. The path returned does not include the start node.
 func (p *Planner) FindPath(start geometry.Point, goal geometry.Point) []geometry.Point {
+	return segmentPoints(p.FindPathSegments(start, goal))
+}
+
+// segmentPoints returns the points the segments go through
+func segmentPoints(segments []PathSegment) []geometry.Point {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	path := []geometry.Point{segments[0].Start}
+	for _, segment := range segments {
+		path = append(path, segment.End)
+	}
+	return path
+}
+
+// FindPathSegments finds the same path as FindPath split into the segments between its
+// points, with the off-mesh links it takes marked
+func (p *Planner) FindPathSegments(start geometry.Point, goal geometry.Point) []PathSegment {
 	if start == goal {
-		return []geometry.Point{start, goal}
+		return []PathSegment{{Start: start, End: goal}}
 	}
 
-	roughPath := p.findPath(start, goal)
+	search := p.newSearch(start, goal, nil)
+	search.step(math.MaxInt)
+	roughPath, _ := search.path()
 	if roughPath == nil {
 		return nil
 	}
+	return p.segments(search.links, roughPath)
+}
 
-	if len(roughPath) >= 3 {
-		portals := p.findPortals(roughPath)
+// segments smooths the nodes of a path into straight segments, links is the link graph the
+// path was found with
+func (p *Planner) segments(links *linkGraph, roughPath []NavNode) []PathSegment {
+	// the walking parts between links are smoothed on their own
+	var segments []PathSegment
+	partStart := 0
+	for i := range roughPath {
+		var link OffMeshLink
+		taken := false
+		if i+1 < len(roughPath) {
+			link, taken = links.between(roughPath[i], roughPath[i+1])
+		}
+		if !taken && i+1 < len(roughPath) {
+			continue
+		}
+
+		points := p.smoothPart(roughPath[partStart : i+1])
+		for j := 0; j+1 < len(points); j++ {
+			segments = append(segments, PathSegment{Start: points[j], End: points[j+1]})
+		}
+		if taken {
+			segments = append(segments, PathSegment{Start: roughPath[i].Point, End: roughPath[i+1].Point, Link: &link})
+		}
+		partStart = i + 1
+	}
+	return segments
+}
+
+func (p *Planner) smoothPart(path []NavNode) []geometry.Point {
+	if len(path) >= 3 {
+		portals := p.findPortals(path)
 		return smoothPath(portals)
 	}
+	return getPoints(path)
+}
 
-	return getPoints(roughPath)
+// ValidatePath returns whether the path is still on the navmesh. paths found before the
+// navmesh changed, like after an obstacle was added to a TiledNavMesh, should be checked and
+// found again when they aren't.
+func (p *Planner) ValidatePath(path []geometry.Point) bool {
+	if p.navmesh == nil || len(path) == 0 {
+		return false
+	}
+	if len(path) == 1 {
+		return p.navmesh.polygonAt(path[0]) != nil
+	}
+	for i := 0; i+1 < len(path); i++ {
+		if !p.navmesh.segmentOnMesh(path[i], path[i+1]) {
+			return false
+		}
+	}
+	return true
 }
 
 func (p *Planner) findPortals(path []NavNode) []Portal {
@@ -53,11 +150,35 @@ func (p *Planner) findPortals(path []NavNode) []Portal {
 	portals := []Portal{Portal{Point1: path[0].Point, Point2: path[0].Point}}
 	prevPolygon := path[0].Polygon
 
-	for _, node := range path {
-		if node.Polygon != prevPolygon {
-			portals = append(portals, p.navmesh.polyPairToPortal[prevPolygon][node.Polygon])
+	for i := 0; i < len(path); i++ {
+		node := path[i]
+		if node.Polygon == prevPolygon {
+			continue
+		}
+
+		// a path that only touches a more costly polygon at a vertex on its way to the next
+		// one has to go through that vertex, otherwise the funnel could cut across the polygon
+		last := i
+		for last+1 < len(path) && path[last+1].Point == node.Point && path[last+1].Polygon != path[last].Polygon {
+			last++
+		}
+		if last > i && p.touchesCostlier(prevPolygon, path[i:last+1]) {
+			portals = append(portals, Portal{Point1: node.Point, Point2: node.Point})
+			i = last
+			prevPolygon = path[last].Polygon
+			continue
+		}
+
+		// a start on the edge of its polygon is in the polygon on the other side too, and a
+		// funnel starting on a portal can't tell which side of it the path goes
+		portal := p.navmesh.polyPairToPortal[prevPolygon][node.Polygon]
+		if len(portals) == 1 && betweenXZ(path[0].Point.Vector3(), portal.Point1.Vector3(), portal.Point2.Vector3()) {
 			prevPolygon = node.Polygon
+			continue
 		}
+
+		portals = append(portals, p.shrinkPortal(portal))
+		prevPolygon = node.Polygon
 	}
 
 	finalPoint := path[len(path)-1].Point
@@ -66,119 +187,83 @@ func (p *Planner) findPortals(path []NavNode) []Portal {
 	return portals
 }
 
-func (p *Planner) findPath(start geometry.Point, goal geometry.Point) []NavNode {
-	// Initialize
-	frontier := utils.NewPriorityQueue()
-	cameFrom := map[NavNode]NavNode{}
-	costSoFar := map[NavNode]float64{}
-
-	startPolygonFound := false
-	goalPolygonFound := false
-
-	var startNode, goalNode NavNode
-
-	// Find which polygon our start node lies in
-	for _, polygon := range p.navmesh.Polygons() {
-		if startPolygonFound && goalPolygonFound {
-			break
-		}
-
-		if !startPolygonFound && polygon.ContainsPoint(start) {
-			startNode = NavNode{Point: start, Polygon: polygon}
-			startPolygonFound = true
-			for _, point := range polygon.Points() {
-				node := NavNode{Point: point, Polygon: polygon}
-				cost := point.Vector3().Sub(start.Vector3()).Len()
-				cameFrom[node] = startNode
-				costSoFar[node] = cost
-
-				// Initialize the frontier with each of the neighbors
-				// of the start node within the polygon
-				frontier.Push(node, cost)
-			}
-		}
-
-		if !goalPolygonFound && polygon.ContainsPoint(goal) {
-			goalNode = NavNode{Point: goal, Polygon: polygon}
-			goalPolygonFound = true
+// touchesCostlier returns whether any of the polygons passed through at a single point cost
+// more to walk through than the polygons before and after them
+func (p *Planner) touchesCostlier(from *geometry.Polygon, run []NavNode) bool {
+	to := run[len(run)-1].Polygon
+	limit := math.Max(p.areaFilter.cost(p.navmesh.Area(from)), p.areaFilter.cost(p.navmesh.Area(to)))
+	for _, node := range run[:len(run)-1] {
+		if p.areaFilter.cost(p.navmesh.Area(node.Polygon)) > limit {
+			return true
 		}
 	}
+	return false
+}
 
-	// If we couldn't find the start or goal polygon, abort
-	if !startPolygonFound || !goalPolygonFound {
-		return nil
+// shrinkPortal moves the ends of the portal that are on the edge of the navmesh in by the
+// agent's radius, ends shared with other polygons are open on both sides and are left alone.
+// the funnel then keeps the path the radius away from corners.
+func (p *Planner) shrinkPortal(portal Portal) Portal {
+	if p.agentRadius <= 0 || portal.Width() == 0 {
+		return portal
 	}
 
-	// If we have a direct path from start to goal, return it
-	if startNode.Polygon == goalNode.Polygon {
-		return []NavNode{startNode, goalNode}
+	direction := portal.Point2.Vector3().Sub(portal.Point1.Vector3()).Normalize()
+	shrunk := portal
+	if p.navmesh.OnBoundary(portal.Point1) {
+		shrunk.Point1 = geometry.Point(portal.Point1.Vector3().Add(direction.Mul(p.agentRadius)))
 	}
-
-	// Set the goal node as the neighbor of each node in the goal polygon
-	goalNeighbors := map[NavNode]bool{}
-	for _, point := range goalNode.Polygon.Points() {
-		node := NavNode{Point: point, Polygon: goalNode.Polygon}
-		goalNeighbors[node] = true
+	if p.navmesh.OnBoundary(portal.Point2) {
+		shrunk.Point2 = geometry.Point(portal.Point2.Vector3().Sub(direction.Mul(p.agentRadius)))
 	}
+	return shrunk
+}
 
-	explored := map[NavNode]bool{}
-
-	// Start searching for a path!
-	for !frontier.Empty() {
-		current := frontier.Pop().(NavNode)
-
-		if current == goalNode {
-			break
-		}
-
-		explored[current] = true
-
-		neighbors := p.navmesh.Neighbors(current)
-
-		// Append the goal to the list of neighbors if the current point is a neighbor
-		// of the goal point
-		if _, ok := goalNeighbors[current]; ok {
-			neighbors = append(neighbors, goalNode)
-		}
-
-		for _, neighbor := range neighbors {
-			if _, ok := explored[neighbor]; ok {
-				continue
-			}
-
-			// Overwrite the cost to reach the neighbor if the cost is better than
-			// what we previously recorded (or if we haven't recorded a cost yet)
-			newCost := costSoFar[current] + p.navmesh.Cost(current.Point, neighbor.Point)
-			if cost, ok := costSoFar[neighbor]; !ok || newCost < cost {
-				costSoFar[neighbor] = newCost
-				frontier.Push(neighbor, newCost+p.navmesh.Cost(goalNode.Point, neighbor.Point))
-				cameFrom[neighbor] = current
-			}
-		}
+// fits returns whether the agent fits through the portal between the two polygons
+func (p *Planner) fits(from, to *geometry.Polygon) bool {
+	if p.agentRadius <= 0 {
+		return true
 	}
-
-	if _, ok := cameFrom[goalNode]; !ok {
-		// Could not find a path to the goal node
-		return nil
+	portal, ok := p.navmesh.polyPairToPortal[from][to]
+	if !ok {
+		return true
 	}
 
-	path := []NavNode{}
-	pathNode := goalNode
-
-	for {
-		path = append(path, pathNode)
-		if pathNode == startNode {
-			break
+	var required float64
+	for _, point := range []geometry.Point{portal.Point1, portal.Point2} {
+		if p.navmesh.OnBoundary(point) {
+			required += p.agentRadius
 		}
-		pathNode = cameFrom[pathNode]
 	}
+	return portal.Width() >= required-floatEpsilon
+}
 
-	reversePath := make([]NavNode, len(path))
-	for i := 0; i < len(path); i++ {
-		reversePath[len(path)-1-i] = path[i]
+// heuristicScale is what the straight line distance to the goal is scaled by so that it's
+// never more than the cheapest way there, through the cheapest area or the cheapest link
+func (p *Planner) heuristicScale(links *linkGraph) float64 {
+	return math.Min(p.areaFilter.minCost(), links.minCostRatio)
+}
+
+// allowed returns whether the agent can enter the polygon
+func (p *Planner) allowed(polygon *geometry.Polygon) bool {
+	return !p.areaFilter.Excluded.Has(p.navmesh.Area(polygon))
+}
+
+// cost is the cost of going straight from one node to the other, either by walking through
+// the polygon they're in or by taking a link
+func (p *Planner) cost(links *linkGraph, from, to NavNode) float64 {
+	if link, ok := links.between(from, to); ok {
+		return link.cost()
 	}
+	return p.navmesh.Cost(from.Point, to.Point) * p.areaFilter.cost(p.navmesh.Area(to.Polygon))
+}
 
-	return reversePath
+// findPath returns the nodes of the cheapest path through the polygons in within, or through
+// all of them when within is nil, along with its cost
+func (p *Planner) findPath(start geometry.Point, goal geometry.Point, within map[*geometry.Polygon]bool) ([]NavNode, float64) {
+	search := p.newSearch(start, goal, within)
+	search.step(math.MaxInt)
+	return search.path()
 }
 
 func orderPortalPoints(portals []Portal) []geometry.Point {
@@ -0,0 +1,242 @@
+package pathing
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/geometry"
+	"github.com/kkevinchou/kitolib/utils"
+)
+
+// randomPointAttempts is how many points RandomPointInRadius tries before settling for the
+// closest point of a polygon
+const randomPointAttempts = 16
+
+// RaycastHit is where a ray along the navmesh was stopped. Fraction is how far along the ray
+// that is and Normal points away from the edge of the navmesh it ran into.
+type RaycastHit struct {
+	Point    geometry.Point
+	Normal   mgl64.Vec3
+	Fraction float64
+}
+
+// FindNearestPoint returns the closest point on the navmesh to point out of the walkable
+// polygons within extents of it, for snapping points that are just off the navmesh onto it
+// before finding a path
+func (nm *NavMesh) FindNearestPoint(point geometry.Point, extents mgl64.Vec3) (geometry.Point, bool) {
+	p := point.Vector3()
+	var nearest mgl64.Vec3
+	nearestDistance := math.MaxFloat64
+	for _, polygon := range nm.polygonsNear(p.Sub(extents), p.Add(extents)) {
+		if !walkable(polygon) {
+			continue
+		}
+		closest := closestPointOnPolygon(polygon, p)
+		if distance := closest.Sub(p).Len(); distance < nearestDistance {
+			nearest, nearestDistance = closest, distance
+		}
+	}
+	if nearestDistance == math.MaxFloat64 {
+		return point, false
+	}
+	return geometry.Point(nearest), true
+}
+
+// Raycast walks the straight line from start to end along the surface of the navmesh and
+// returns where it runs into the edge of the navmesh, or false when it gets to end. a start
+// that's off the navmesh is a hit right at the start.
+func (nm *NavMesh) Raycast(start, end geometry.Point) (RaycastHit, bool) {
+	current := nm.startPolygon(start, end)
+	if current == nil {
+		return RaycastHit{Point: start}, true
+	}
+
+	a := start.Vector3()
+	delta := end.Vector3().Sub(a)
+	length := math.Hypot(delta[0], delta[2])
+	if length < floatEpsilon {
+		return RaycastHit{Point: surfacePoint(current, end), Fraction: 1}, false
+	}
+
+	visited := map[*geometry.Polygon]bool{current: true}
+	for {
+		t := exitDistance(current, a, delta)
+		if t >= 1 {
+			return RaycastHit{Point: surfacePoint(current, end), Fraction: 1}, false
+		}
+
+		exit := geometry.Point(a.Add(delta.Mul(t)))
+		ahead := geometry.Point(a.Add(delta.Mul(math.Min(1, t+segmentStep/length))))
+		next := nm.nextPolygon(current, exit, ahead, visited)
+		if next != nil {
+			current = next
+			continue
+		}
+
+		t, edgeIndex := exitEdge(current, a, delta, 0)
+		points := current.Points()
+		edge := points[(edgeIndex+1)%len(points)].Vector3().Sub(points[edgeIndex].Vector3())
+		normal := mgl64.Vec3{-edge[2], 0, edge[0]}
+		if normal.Len() > floatEpsilon {
+			normal = normal.Normalize()
+		}
+		return RaycastHit{
+			Point:    surfacePoint(current, geometry.Point(a.Add(delta.Mul(t)))),
+			Normal:   normal,
+			Fraction: t,
+		}, true
+	}
+}
+
+// RandomPointInRadius returns a random point on the navmesh within radius of center that can
+// be walked to from center without off-mesh links. points are spread evenly by the area of
+// the walkable polygons seen from above and the same r gives the same points. false is
+// returned when center isn't on the navmesh.
+func (nm *NavMesh) RandomPointInRadius(center geometry.Point, radius float64, r *rand.Rand) (geometry.Point, bool) {
+	start := nm.polygonAt(center)
+	if start == nil {
+		return center, false
+	}
+	c := center.Vector3()
+
+	// the polygons reachable from start that come within radius of center
+	polygons := []*geometry.Polygon{start}
+	visited := map[*geometry.Polygon]bool{start: true}
+	for i := 0; i < len(polygons); i++ {
+		var neighbors []*geometry.Polygon
+		for neighbor := range nm.polyPairToPortal[polygons[i]] {
+			if !visited[neighbor] {
+				neighbors = append(neighbors, neighbor)
+			}
+		}
+		sort.Slice(neighbors, func(a, b int) bool {
+			return nm.polygonIDs[neighbors[a]] < nm.polygonIDs[neighbors[b]]
+		})
+		for _, neighbor := range neighbors {
+			visited[neighbor] = true
+			if closestPointOnPolygon(neighbor, c).Sub(c).Len() <= radius {
+				polygons = append(polygons, neighbor)
+			}
+		}
+	}
+
+	// walls are walked through to get to the polygons past them but never picked
+	var candidates []*geometry.Polygon
+	var areas []float64
+	total := 0.0
+	for _, polygon := range polygons {
+		if area := polygonAreaXZ(vertices(polygon)); area > floatEpsilon {
+			candidates = append(candidates, polygon)
+			areas = append(areas, area)
+			total += area
+		}
+	}
+	if len(candidates) == 0 {
+		return center, false
+	}
+
+	var polygon *geometry.Polygon
+	for attempt := 0; attempt < randomPointAttempts; attempt++ {
+		polygon = candidates[len(candidates)-1]
+		pick := r.Float64() * total
+		for i, area := range areas {
+			if pick < area {
+				polygon = candidates[i]
+				break
+			}
+			pick -= area
+		}
+
+		point := randomPointInPolygon(polygon, r)
+		if point.Sub(c).Len() <= radius {
+			return geometry.Point(point), true
+		}
+	}
+	// most of the polygons are outside of the radius, settle for the edge of it
+	return geometry.Point(closestPointOnPolygon(polygon, c)), true
+}
+
+// walkable returns whether the polygon covers any ground, a wall seen from above has no area
+func walkable(polygon *geometry.Polygon) bool {
+	return polygonAreaXZ(vertices(polygon)) > floatEpsilon
+}
+
+// closestPointOnPolygon returns the point on the polygon closest to p
+func closestPointOnPolygon(polygon *geometry.Polygon, p mgl64.Vec3) mgl64.Vec3 {
+	points := polygon.Points()
+	origin := points[0].Vector3()
+	normal := points[1].Vector3().Sub(origin).Cross(points[2].Vector3().Sub(origin))
+	if normal.Len() > floatEpsilon {
+		normal = normal.Normalize()
+		projected := p.Sub(normal.Mul(normal.Dot(p.Sub(origin))))
+
+		inside := true
+		for i, point := range points {
+			edge := points[(i+1)%len(points)].Vector3().Sub(point.Vector3())
+			if edge.Cross(projected.Sub(point.Vector3())).Dot(normal) < -floatEpsilon {
+				inside = false
+				break
+			}
+		}
+		if inside {
+			return projected
+		}
+	}
+
+	var closest mgl64.Vec3
+	closestDistance := math.MaxFloat64
+	for i, point := range points {
+		a, b := point.Vector3(), points[(i+1)%len(points)].Vector3()
+		onEdge := closestPointOnSegment(a, b, p)
+		if distance := onEdge.Sub(p).Len(); distance < closestDistance {
+			closest, closestDistance = onEdge, distance
+		}
+	}
+	return closest
+}
+
+func closestPointOnSegment(a, b, p mgl64.Vec3) mgl64.Vec3 {
+	ab := b.Sub(a)
+	lengthSq := ab.Dot(ab)
+	if lengthSq < floatEpsilon {
+		return a
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/lengthSq))
+	return a.Add(ab.Mul(t))
+}
+
+func vertices(polygon *geometry.Polygon) []mgl64.Vec3 {
+	points := polygon.Points()
+	vertices := make([]mgl64.Vec3, len(points))
+	for i, point := range points {
+		vertices[i] = point.Vector3()
+	}
+	return vertices
+}
+
+// randomPointInPolygon picks a triangle of the polygon's fan by area seen from above and a
+// point in it
+func randomPointInPolygon(polygon *geometry.Polygon, r *rand.Rand) mgl64.Vec3 {
+	points := polygon.Points()
+	origin := points[0].Vector3()
+
+	pick := r.Float64() * polygonAreaXZ(vertices(polygon))
+	b, c := points[1].Vector3(), points[2].Vector3()
+	for i := 1; i+1 < len(points); i++ {
+		b, c = points[i].Vector3(), points[i+1].Vector3()
+		area := math.Abs(utils.Cross2D(b.Sub(origin), c.Sub(origin))) / 2
+		if pick < area {
+			break
+		}
+		pick -= area
+	}
+
+	// folding the square of (s, t) in half keeps the points evenly spread over the triangle
+	s, t := r.Float64(), r.Float64()
+	if s+t > 1 {
+		s, t = 1-s, 1-t
+	}
+	return origin.Add(b.Sub(origin).Mul(s)).Add(c.Sub(origin).Mul(t))
+}
@@ -0,0 +1,217 @@
+package pathing
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// lShape is three squares seen from above:
+//
+//	X X
+//	  X
+func lShape() *NavMesh {
+	return ConstructNavMesh([]*geometry.Polygon{
+		sqWithOffset(10, 0, 0),
+		sqWithOffset(10, 1, 0),
+		sqWithOffset(10, 1, 1),
+	})
+}
+
+func assertPointNear(t *testing.T, expected, actual geometry.Point) {
+	t.Helper()
+	if actual.Vector3().Sub(expected.Vector3()).Len() > 1e-6 {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
+
+func TestFindNearestPoint(t *testing.T) {
+	navmesh := lShape()
+	extents := mgl64.Vec3{2, 2, 2}
+
+	point, ok := navmesh.FindNearestPoint(geometry.Point{5, 1, 5}, extents)
+	if !ok {
+		t.Fatal("expected to find a point below")
+	}
+	assertPointNear(t, geometry.Point{5, 0, 5}, point)
+
+	// off the side of the navmesh and in the missing corner of the L
+	point, _ = navmesh.FindNearestPoint(geometry.Point{-1, 0, 5}, extents)
+	assertPointNear(t, geometry.Point{0, 0, 5}, point)
+	point, _ = navmesh.FindNearestPoint(geometry.Point{5, 0, 11}, extents)
+	assertPointNear(t, geometry.Point{5, 0, 10}, point)
+	point, _ = navmesh.FindNearestPoint(geometry.Point{9.8, 0, 10.5}, extents)
+	assertPointNear(t, geometry.Point{10, 0, 10.5}, point)
+
+	if _, ok := navmesh.FindNearestPoint(geometry.Point{5, 0, 15}, extents); ok {
+		t.Error("expected nothing within the extents")
+	}
+
+	p := Planner{}
+	p.SetNavMesh(navmesh)
+	start, _ := navmesh.FindNearestPoint(geometry.Point{-0.5, 0.5, 5}, extents)
+	if path := p.FindPath(start, geometry.Point{15, 0, 15}); path == nil {
+		t.Error("expected a path from the snapped start")
+	}
+}
+
+func TestRaycast(t *testing.T) {
+	navmesh := lShape()
+
+	hit, blocked := navmesh.Raycast(geometry.Point{5, 0, 5}, geometry.Point{15, 0, 15})
+	if blocked || hit.Fraction != 1 {
+		t.Errorf("expected a clear line through the corner but got %v", hit)
+	}
+	assertPointNear(t, geometry.Point{15, 0, 15}, hit.Point)
+
+	hit, blocked = navmesh.Raycast(geometry.Point{5, 0, 5}, geometry.Point{5, 0, 15})
+	if !blocked {
+		t.Fatal("expected the ray to hit the edge of the navmesh")
+	}
+	assertPointNear(t, geometry.Point{5, 0, 10}, hit.Point)
+	if math.Abs(hit.Fraction-0.5) > 1e-6 || hit.Normal.Sub(mgl64.Vec3{0, 0, 1}).Len() > 1e-6 {
+		t.Errorf("expected to hit halfway with a normal of (0, 0, 1) but got %v", hit)
+	}
+
+	hit, blocked = navmesh.Raycast(geometry.Point{15, 0, 15}, geometry.Point{5, 0, 15})
+	if !blocked || hit.Normal.Sub(mgl64.Vec3{-1, 0, 0}).Len() > 1e-6 {
+		t.Errorf("expected to hit the left edge of the last square but got %v", hit)
+	}
+	assertPointNear(t, geometry.Point{10, 0, 15}, hit.Point)
+
+	if hit, blocked := navmesh.Raycast(geometry.Point{5, 0, 15}, geometry.Point{5, 0, 5}); !blocked || hit.Fraction != 0 {
+		t.Errorf("expected a start off the navmesh to be blocked right away but got %v", hit)
+	}
+
+	// the hit follows the height of a ramp
+	ramp := ConstructNavMesh([]*geometry.Polygon{geometry.NewPolygon([]geometry.Point{{0, 0, 0}, {0, 0, 10}, {10, 5, 10}, {10, 5, 0}})})
+	if hit, _ := ramp.Raycast(geometry.Point{1, 0.5, 5}, geometry.Point{20, 0, 5}); math.Abs(hit.Point[1]-5) > 1e-6 {
+		t.Errorf("expected to hit the top of the ramp but got %v", hit)
+	}
+}
+
+func TestRandomPointInRadius(t *testing.T) {
+	navmesh := lShape()
+	// an island within the radius that can't be walked to
+	navmesh.AddPolygon(geometry.NewPolygon([]geometry.Point{{0, 0, 11}, {0, 0, 20}, {9, 0, 20}, {9, 0, 11}}))
+
+	center := geometry.Point{12, 0, 12}
+	r := rand.New(rand.NewSource(1))
+	var points []geometry.Point
+	sawOther := false
+	for i := 0; i < 200; i++ {
+		point, ok := navmesh.RandomPointInRadius(center, 8, r)
+		if !ok {
+			t.Fatal("expected a point")
+		}
+		if point.Vector3().Sub(center.Vector3()).Len() > 8+1e-9 {
+			t.Fatalf("expected %v to be within the radius", point)
+		}
+		if polygon := navmesh.polygonAt(point); polygon == nil || polygon == navmesh.Polygons()[3] {
+			t.Fatalf("expected %v to be on the walkable part of the navmesh", point)
+		}
+		if point[2] < 10 {
+			sawOther = true
+		}
+		points = append(points, point)
+	}
+	if !sawOther {
+		t.Error("expected points in the neighboring square as well")
+	}
+
+	r = rand.New(rand.NewSource(1))
+	for _, expected := range points {
+		if point, _ := navmesh.RandomPointInRadius(center, 8, r); point != expected {
+			t.Fatalf("expected the same points from the same seed, %v != %v", point, expected)
+		}
+	}
+
+	if _, ok := navmesh.RandomPointInRadius(geometry.Point{5, 0, 25}, 8, r); ok {
+		t.Error("expected no point around a center off the navmesh")
+	}
+}
+
+func TestPolygonIndex(t *testing.T) {
+	navmesh := maze(16)
+	for _, polygon := range navmesh.Polygons() {
+		center := polygonCenter(polygon)
+		if found := navmesh.polygonAt(center); found != polygon {
+			t.Fatalf("expected to find the polygon at %v", center)
+		}
+	}
+
+	polygon := navmesh.Polygons()[0]
+	center := polygonCenter(polygon)
+	navmesh.RemovePolygon(polygon)
+	if found := navmesh.polygonAt(center); found != nil {
+		t.Errorf("expected the removed polygon not to be found but got %v", found)
+	}
+}
+
+func polygonCenter(polygon *geometry.Polygon) geometry.Point {
+	var sum mgl64.Vec3
+	for _, point := range polygon.Points() {
+		sum = sum.Add(point.Vector3())
+	}
+	return geometry.Point(sum.Mul(1 / float64(len(polygon.Points()))))
+}
+
+func BenchmarkPolygonAt(b *testing.B) {
+	navmesh := maze(64)
+	point := geometry.Point{40.5, 0, 40.5}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		navmesh.polygonAt(point)
+	}
+}
+
+// onStepSurface returns whether the point is on the floor or the top of a 0.8 step rather
+// than somewhere up the side of it
+func onStepSurface(point geometry.Point) bool {
+	return math.Abs(point[1]) < 1e-6 || math.Abs(point[1]-0.8) < 1e-6
+}
+
+func TestQueriesSkipWalls(t *testing.T) {
+	// a wall joining the floor to the top of the step
+	walled := ConstructNavMesh([]*geometry.Polygon{
+		geometry.NewPolygon([]geometry.Point{{0, 0, 0}, {0, 0, 4}, {5, 0, 4}, {5, 0, 0}}),
+		geometry.NewPolygon([]geometry.Point{{5, 0, 0}, {5, 0, 4}, {5, 0.8, 4}, {5, 0.8, 0}}),
+		geometry.NewPolygon([]geometry.Point{{5, 0.8, 0}, {5, 0.8, 4}, {10, 0.8, 4}, {10, 0.8, 0}}),
+	})
+
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 10, 4, 0)
+	addBox(&mesh, mgl64.Vec3{5, 0, -1}, mgl64.Vec3{11, 0.8, 5})
+	settings := testBuildSettings()
+	settings.AgentMaxClimb = 1
+	built := BuildNavMesh(mesh, settings)
+
+	for _, test := range []struct {
+		name    string
+		navmesh *NavMesh
+	}{{"walled", walled}, {"built", built}} {
+		name, navmesh := test.name, test.navmesh
+		r := rand.New(rand.NewSource(1))
+		sawTop := false
+		for i := 0; i < 200; i++ {
+			point, ok := navmesh.RandomPointInRadius(geometry.Point{4.8, 0, 2}, 1, r)
+			if !ok || !onStepSurface(point) {
+				t.Fatalf("%s: expected a point on the floor or the top of the step but got %v", name, point)
+			}
+			if point[1] > 0.4 {
+				sawTop = true
+			}
+		}
+		if !sawTop {
+			t.Errorf("%s: expected points on the top of the step as well", name)
+		}
+
+		if point, ok := navmesh.FindNearestPoint(geometry.Point{5, 0.4, 2}, mgl64.Vec3{1, 1, 1}); !ok || !onStepSurface(point) {
+			t.Errorf("%s: expected the nearest point to be on the floor or the top of the step but got %v", name, point)
+		}
+	}
+}
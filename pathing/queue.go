@@ -0,0 +1,314 @@
+package pathing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// timeSliceIterations is how many nodes UpdateFor expands between checking the time
+const timeSliceIterations = 32
+
+// workerIterations is how many nodes a worker expands between checking for cancellation
+const workerIterations = 256
+
+type PathStatus int
+
+const (
+	// PathQueued hasn't been worked on yet
+	PathQueued PathStatus = iota
+	// PathSearching has been started, Path returns the best path so far
+	PathSearching
+	PathFound
+	// PathPartial hit the queue's iteration limit, Path returns the path to the point closest
+	// to the goal that was found
+	PathPartial
+	PathNotFound
+	PathCanceled
+)
+
+// PathHandle is a path requested from a PathQueue
+type PathHandle struct {
+	start    geometry.Point
+	goal     geometry.Point
+	priority int
+	id       int
+	queue    *PathQueue
+
+	// lock guards everything below, workers hold it while they step the search
+	lock       sync.Mutex
+	status     PathStatus
+	search     *search
+	iterations int
+	segments   []PathSegment
+	done       chan struct{}
+}
+
+// PathQueue spreads path requests out over time, either on worker goroutines or a budget at a
+// time from the game loop. requests with a higher priority are worked on first.
+type PathQueue struct {
+	planner       *Planner
+	maxIterations int
+
+	lock    sync.Mutex
+	wake    *sync.Cond
+	pending []*PathHandle
+	nextID  int
+	stopped bool
+	workers sync.WaitGroup
+}
+
+func NewPathQueue(planner *Planner) *PathQueue {
+	q := &PathQueue{planner: planner}
+	q.wake = sync.NewCond(&q.lock)
+	return q
+}
+
+// SetMaxIterations caps how many nodes a single request can expand. requests that hit the cap
+// end as PathPartial. zero means no cap.
+func (q *PathQueue) SetMaxIterations(iterations int) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.maxIterations = iterations
+}
+
+// Request queues a path from start to goal
+func (q *PathQueue) Request(start, goal geometry.Point, priority int) *PathHandle {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	h := &PathHandle{
+		start:    start,
+		goal:     goal,
+		priority: priority,
+		id:       q.nextID,
+		queue:    q,
+		done:     make(chan struct{}),
+	}
+	q.nextID++
+	q.pending = append(q.pending, h)
+	q.wake.Signal()
+	return h
+}
+
+// Pending returns how many requests haven't finished
+func (q *PathQueue) Pending() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.pending)
+}
+
+// Update works on the requests until iterations nodes have been expanded. a request that's
+// still searching when the budget runs out is picked up again on the next update.
+func (q *PathQueue) Update(iterations int) {
+	for iterations > 0 {
+		h := q.peek()
+		if h == nil {
+			return
+		}
+		used, finished := q.work(h, iterations)
+		if !finished {
+			return
+		}
+		q.remove(h)
+		iterations -= used
+	}
+}
+
+// UpdateFor works on the requests until the budget has passed
+func (q *PathQueue) UpdateFor(budget time.Duration) {
+	deadline := time.Now().Add(budget)
+	for time.Now().Before(deadline) {
+		h := q.peek()
+		if h == nil {
+			return
+		}
+		if _, finished := q.work(h, timeSliceIterations); finished {
+			q.remove(h)
+		}
+	}
+}
+
+// Start starts workers that find the paths as they're requested. Update shouldn't be called
+// and the navmesh shouldn't change until Stop.
+func (q *PathQueue) Start(workers int) {
+	q.lock.Lock()
+	q.stopped = false
+	q.lock.Unlock()
+
+	for i := 0; i < workers; i++ {
+		q.workers.Add(1)
+		go func() {
+			defer q.workers.Done()
+			for {
+				h := q.take()
+				if h == nil {
+					return
+				}
+				for {
+					if _, finished := q.work(h, workerIterations); finished {
+						break
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Stop waits for the workers to finish the requests they're on and stops them. requests that
+// weren't started stay queued.
+func (q *PathQueue) Stop() {
+	q.lock.Lock()
+	q.stopped = true
+	q.wake.Broadcast()
+	q.lock.Unlock()
+	q.workers.Wait()
+}
+
+// peek returns the request to work on next, the one with the highest priority that came first
+func (q *PathQueue) peek() *PathHandle {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.best()
+}
+
+// take waits for a request and removes it from the queue, it returns nil once stopped
+func (q *PathQueue) take() *PathHandle {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for {
+		if q.stopped {
+			return nil
+		}
+		if h := q.best(); h != nil {
+			q.removeLocked(h)
+			return h
+		}
+		q.wake.Wait()
+	}
+}
+
+func (q *PathQueue) best() *PathHandle {
+	var best *PathHandle
+	for _, h := range q.pending {
+		if best == nil || h.priority > best.priority || h.priority == best.priority && h.id < best.id {
+			best = h
+		}
+	}
+	return best
+}
+
+func (q *PathQueue) remove(h *PathHandle) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.removeLocked(h)
+}
+
+func (q *PathQueue) removeLocked(h *PathHandle) {
+	for i, pending := range q.pending {
+		if pending == h {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// work expands up to iterations nodes of the request's search and returns how many it
+// expanded and whether the request is finished
+func (q *PathQueue) work(h *PathHandle, iterations int) (int, bool) {
+	q.lock.Lock()
+	maxIterations := q.maxIterations
+	q.lock.Unlock()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.status == PathCanceled {
+		return 0, true
+	}
+	if h.search == nil {
+		if h.start == h.goal {
+			h.finish(PathFound, []PathSegment{{Start: h.start, End: h.goal}})
+			return 0, true
+		}
+		h.search = q.planner.newSearch(h.start, h.goal, nil)
+		h.status = PathSearching
+	}
+
+	if maxIterations > 0 && maxIterations-h.iterations < iterations {
+		iterations = maxIterations - h.iterations
+	}
+	used := h.search.step(iterations)
+	h.iterations += used
+
+	switch {
+	case h.search.found:
+		path, _ := h.search.path()
+		h.finish(PathFound, q.planner.segments(h.search.links, path))
+	case h.search.done:
+		h.finish(PathNotFound, nil)
+	case maxIterations > 0 && h.iterations >= maxIterations:
+		h.finish(PathPartial, q.planner.segments(h.search.links, h.search.partialPath()))
+	default:
+		return used, false
+	}
+	return used, true
+}
+
+// finish is called with the handle's lock held
+func (h *PathHandle) finish(status PathStatus, segments []PathSegment) {
+	h.status = status
+	h.segments = segments
+	h.search = nil
+	close(h.done)
+}
+
+func (h *PathHandle) Status() PathStatus {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.status
+}
+
+// Done is closed when the request finishes or is canceled
+func (h *PathHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Segments returns the path once it's found, or the best path so far while it's searching or
+// when it ended as PathPartial
+func (h *PathHandle) Segments() []PathSegment {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.status == PathSearching {
+		return h.queue.planner.segments(h.search.links, h.search.partialPath())
+	}
+	return append([]PathSegment(nil), h.segments...)
+}
+
+// Path returns the points of Segments, a path that hasn't gone anywhere yet is just the start
+func (h *PathHandle) Path() []geometry.Point {
+	segments := h.Segments()
+	if len(segments) == 0 {
+		switch h.Status() {
+		case PathSearching, PathPartial:
+			return []geometry.Point{h.start}
+		}
+		return nil
+	}
+	return segmentPoints(segments)
+}
+
+// Cancel stops the request from being worked on any further
+func (h *PathHandle) Cancel() {
+	h.lock.Lock()
+	if h.status == PathFound || h.status == PathPartial || h.status == PathNotFound || h.status == PathCanceled {
+		h.lock.Unlock()
+		return
+	}
+	h.finish(PathCanceled, nil)
+	h.lock.Unlock()
+
+	h.queue.remove(h)
+}
@@ -0,0 +1,168 @@
+package pathing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+func TestPathQueueTimeSliced(t *testing.T) {
+	p := &Planner{}
+	p.SetNavMesh(maze(24))
+	q := NewPathQueue(p)
+
+	start, goal := geometry.Point{0.5, 0, 0.5}, geometry.Point{23.5, 0, 0.5}
+	h := q.Request(start, goal, 0)
+	if h.Status() != PathQueued || h.Path() != nil {
+		t.Fatalf("expected the request to wait for an update but got %v", h.Path())
+	}
+
+	q.Update(10)
+	if h.Status() != PathSearching {
+		t.Fatalf("expected the request to still be searching but got %v", h.Status())
+	}
+	if path := h.Path(); len(path) == 0 || path[0] != start {
+		t.Fatalf("expected a partial path from the start but got %v", path)
+	}
+
+	updates := 1
+	for h.Status() == PathSearching {
+		q.Update(10)
+		updates++
+	}
+	if updates < 10 {
+		t.Errorf("expected the search to be spread over many updates but it took %d", updates)
+	}
+	if h.Status() != PathFound {
+		t.Fatalf("expected the path to be found but got %v", h.Status())
+	}
+	assertPathEq(t, p.FindPath(start, goal), h.Path())
+	if q.Pending() != 0 {
+		t.Errorf("expected no pending requests but got %d", q.Pending())
+	}
+	select {
+	case <-h.Done():
+	default:
+		t.Error("expected the request to be done")
+	}
+}
+
+func TestPathQueuePriority(t *testing.T) {
+	p := &Planner{}
+	p.SetNavMesh(maze(24))
+	q := NewPathQueue(p)
+
+	start, goal := geometry.Point{0.5, 0, 0.5}, geometry.Point{23.5, 0, 0.5}
+	low := q.Request(start, goal, 0)
+	high := q.Request(start, goal, 10)
+	later := q.Request(start, goal, 10)
+
+	q.Update(10)
+	if high.Status() != PathSearching || low.Status() != PathQueued || later.Status() != PathQueued {
+		t.Fatalf("expected only the first high priority request to be started but got %v %v %v", low.Status(), high.Status(), later.Status())
+	}
+
+	q.Update(1000000)
+	for _, h := range []*PathHandle{low, high, later} {
+		if h.Status() != PathFound {
+			t.Errorf("expected every path to be found but got %v", h.Status())
+		}
+	}
+}
+
+func TestPathQueueCancel(t *testing.T) {
+	p := &Planner{}
+	p.SetNavMesh(maze(24))
+	q := NewPathQueue(p)
+
+	start, goal := geometry.Point{0.5, 0, 0.5}, geometry.Point{23.5, 0, 0.5}
+	searching := q.Request(start, goal, 1)
+	queued := q.Request(start, goal, 0)
+	q.Update(10)
+
+	searching.Cancel()
+	queued.Cancel()
+	if searching.Status() != PathCanceled || queued.Status() != PathCanceled {
+		t.Fatalf("expected both requests to be canceled but got %v %v", searching.Status(), queued.Status())
+	}
+	if searching.Path() != nil {
+		t.Errorf("expected a canceled request to have no path but got %v", searching.Path())
+	}
+	if q.Pending() != 0 {
+		t.Errorf("expected no pending requests but got %d", q.Pending())
+	}
+}
+
+func TestPathQueuePartial(t *testing.T) {
+	p := &Planner{}
+	p.SetNavMesh(maze(24))
+	q := NewPathQueue(p)
+	q.SetMaxIterations(50)
+
+	start, goal := geometry.Point{0.5, 0, 0.5}, geometry.Point{23.5, 0, 0.5}
+	h := q.Request(start, goal, 0)
+	q.Update(1000)
+	if h.Status() != PathPartial {
+		t.Fatalf("expected the search to run out of iterations but got %v", h.Status())
+	}
+	path := h.Path()
+	if len(path) < 2 || path[0] != start {
+		t.Fatalf("expected a partial path from the start but got %v", path)
+	}
+	end := path[len(path)-1]
+	if p.navmesh.Cost(end, goal) >= p.navmesh.Cost(start, goal) {
+		t.Errorf("expected the partial path to get closer to the goal but it ends at %v", end)
+	}
+	if !p.ValidatePath(path) {
+		t.Errorf("expected the partial path %v to stay on the navmesh", path)
+	}
+
+	offMesh := q.Request(start, geometry.Point{100, 0, 100}, 0)
+	q.Update(1000)
+	if offMesh.Status() != PathNotFound {
+		t.Errorf("expected no path off the navmesh but got %v", offMesh.Status())
+	}
+}
+
+func TestPathQueueUpdateFor(t *testing.T) {
+	p := &Planner{}
+	p.SetNavMesh(maze(24))
+	q := NewPathQueue(p)
+
+	h := q.Request(geometry.Point{0.5, 0, 0.5}, geometry.Point{23.5, 0, 0.5}, 0)
+	for i := 0; i < 1000 && h.Status() != PathFound; i++ {
+		q.UpdateFor(time.Millisecond)
+	}
+	if h.Status() != PathFound {
+		t.Errorf("expected the path to be found but got %v", h.Status())
+	}
+}
+
+func TestPathQueueWorkers(t *testing.T) {
+	p := &Planner{}
+	p.SetNavMesh(maze(24))
+	q := NewPathQueue(p)
+	q.Start(4)
+	defer q.Stop()
+
+	var handles []*PathHandle
+	var goals []geometry.Point
+	for i := 0; i < 16; i++ {
+		goal := geometry.Point{23.5, 0, float64(i) + 0.5}
+		goals = append(goals, goal)
+		handles = append(handles, q.Request(geometry.Point{0.5, 0, 0.5}, goal, i%3))
+	}
+
+	for i, h := range handles {
+		select {
+		case <-h.Done():
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the workers")
+		}
+		if h.Status() != PathFound {
+			t.Fatalf("expected the path to be found but got %v", h.Status())
+		}
+		assertPathEq(t, p.FindPath(geometry.Point{0.5, 0, 0.5}, goals[i]), h.Path())
+	}
+}
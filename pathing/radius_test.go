@@ -0,0 +1,88 @@
+package pathing
+
+import (
+	"testing"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// corridor joins two 10x10 rooms with a 10 long corridor of the given width
+func corridor(width float64) *NavMesh {
+	low, high := 5-width/2, 5+width/2
+	roomA := geometry.NewPolygon([]geometry.Point{
+		{0, 0, 0}, {0, 0, 10}, {10, 0, 10}, {10, 0, high}, {10, 0, low}, {10, 0, 0},
+	})
+	hall := geometry.NewPolygon([]geometry.Point{
+		{10, 0, low}, {10, 0, high}, {20, 0, high}, {20, 0, low},
+	})
+	roomB := geometry.NewPolygon([]geometry.Point{
+		{20, 0, 0}, {20, 0, low}, {20, 0, high}, {20, 0, 10}, {30, 0, 10}, {30, 0, 0},
+	})
+	return ConstructNavMesh([]*geometry.Polygon{roomA, hall, roomB})
+}
+
+func TestAgentFitsThroughWideCorridor(t *testing.T) {
+	p := Planner{}
+	p.SetNavMesh(corridor(4))
+	p.SetAgentRadius(1.5)
+
+	path := p.FindPath(geometry.Point{2, 0, 5}, geometry.Point{28, 0, 5})
+	expectedPath := []geometry.Point{{2, 0, 5}, {28, 0, 5}}
+	assertPathEq(t, expectedPath, path)
+}
+
+func TestAgentDoesNotFitThroughNarrowCorridor(t *testing.T) {
+	p := Planner{}
+	p.SetNavMesh(corridor(2))
+	p.SetAgentRadius(1.5)
+
+	if path := p.FindPath(geometry.Point{2, 0, 5}, geometry.Point{28, 0, 5}); path != nil {
+		t.Fatalf("expected no path but got %v", path)
+	}
+
+	p.SetAgentRadius(1)
+	if path := p.FindPath(geometry.Point{2, 0, 5}, geometry.Point{28, 0, 5}); path == nil {
+		t.Fatal("expected an agent exactly as wide as the corridor to fit")
+	}
+}
+
+func TestAgentKeepsClearanceFromCorners(t *testing.T) {
+	polygons := []*geometry.Polygon{
+		sqWithOffset(10, 0, 0),
+		sqWithOffset(10, 1, 0),
+		sqWithOffset(10, 1, 1),
+	}
+	start, goal := geometry.Point{5, 0, 5}, geometry.Point{15, 0, 18}
+
+	p := Planner{}
+	p.SetNavMesh(ConstructNavMesh(polygons))
+	assertPathEq(t, []geometry.Point{start, {10, 0, 10}, goal}, p.FindPath(start, goal))
+
+	p.SetAgentRadius(1)
+	path := p.FindPath(start, goal)
+	assertPathEq(t, []geometry.Point{start, {10, 0, 9}, {11, 0, 10}, goal}, path)
+
+	corner := geometry.Point{10, 0, 10}
+	for _, point := range path {
+		if distance := point.Vector3().Sub(corner.Vector3()).Len(); distance < 1-floatEpsilon {
+			t.Fatalf("path point %v is %f from the corner", point, distance)
+		}
+	}
+}
+
+func TestSharedPortalEndsAreNotShrunk(t *testing.T) {
+	// the middle of the row of squares is open on both sides so the path stays straight
+	polygons := []*geometry.Polygon{
+		sqWithOffset(10, 0, 0),
+		sqWithOffset(10, 1, 0),
+		sqWithOffset(10, 0, 1),
+		sqWithOffset(10, 1, 1),
+	}
+
+	p := Planner{}
+	p.SetNavMesh(ConstructNavMesh(polygons))
+	p.SetAgentRadius(2)
+
+	start, goal := geometry.Point{4, 0, 8}, geometry.Point{15, 0, 9}
+	assertPathEq(t, []geometry.Point{start, goal}, p.FindPath(start, goal))
+}
@@ -0,0 +1,200 @@
+package pathing
+
+import (
+	"github.com/kkevinchou/kitolib/geometry"
+	"github.com/kkevinchou/kitolib/utils"
+)
+
+// search is an A* search over the nodes of the navmesh that can be run a few iterations at a
+// time
+type search struct {
+	planner *Planner
+	within  map[*geometry.Polygon]bool
+	// links is the link graph when the search started, it's read without locking the navmesh
+	links *linkGraph
+
+	startNode, goalNode NavNode
+	frontier            *utils.PriorityQueue
+	cameFrom            map[NavNode]NavNode
+	costSoFar           map[NavNode]float64
+	explored            map[NavNode]bool
+	goalNeighbors       map[NavNode]bool
+	heuristicScale      float64
+
+	// closest is the explored node nearest to the goal, for partial paths
+	closest         NavNode
+	closestDistance float64
+
+	done  bool
+	found bool
+}
+
+func (p *Planner) newSearch(start geometry.Point, goal geometry.Point, within map[*geometry.Polygon]bool) *search {
+	// Initialize
+	links := p.navmesh.links()
+	s := &search{
+		planner:        p,
+		within:         within,
+		links:          links,
+		frontier:       utils.NewPriorityQueue(),
+		cameFrom:       map[NavNode]NavNode{},
+		costSoFar:      map[NavNode]float64{},
+		explored:       map[NavNode]bool{},
+		goalNeighbors:  map[NavNode]bool{},
+		heuristicScale: p.heuristicScale(links),
+	}
+
+	startPolygonFound := false
+	goalPolygonFound := false
+
+	// Find which polygon our start node lies in
+	for _, polygon := range p.navmesh.polygonsNear(start.Vector3(), start.Vector3()) {
+		if (within == nil || within[polygon]) && polygon.ContainsPoint(start) {
+			s.startNode = NavNode{Point: start, Polygon: polygon}
+			startPolygonFound = true
+			break
+		}
+	}
+	for _, polygon := range p.navmesh.polygonsNear(goal.Vector3(), goal.Vector3()) {
+		if (within == nil || within[polygon]) && polygon.ContainsPoint(goal) {
+			s.goalNode = NavNode{Point: goal, Polygon: polygon}
+			goalPolygonFound = true
+			break
+		}
+	}
+
+	// If we couldn't find the start or goal polygon, abort
+	if !startPolygonFound || !goalPolygonFound {
+		s.done = true
+		return s
+	}
+	if !p.allowed(s.startNode.Polygon) || !p.allowed(s.goalNode.Polygon) {
+		s.done = true
+		return s
+	}
+	s.closest = s.startNode
+	s.closestDistance = p.navmesh.Cost(start, goal)
+
+	// If we have a direct path from start to goal, return it
+	if s.startNode.Polygon == s.goalNode.Polygon {
+		s.cameFrom[s.goalNode] = s.startNode
+		s.costSoFar[s.goalNode] = p.cost(links, s.startNode, s.goalNode)
+		s.done, s.found = true, true
+		return s
+	}
+
+	// Initialize the frontier with each of the neighbors of the start node within the polygon
+	startNeighbors := []NavNode{}
+	for _, point := range s.startNode.Polygon.Points() {
+		startNeighbors = append(startNeighbors, NavNode{Point: point, Polygon: s.startNode.Polygon})
+	}
+	startNeighbors = append(startNeighbors, links.ends[s.startNode.Polygon]...)
+	for _, node := range startNeighbors {
+		cost := p.cost(links, s.startNode, node)
+		s.cameFrom[node] = s.startNode
+		s.costSoFar[node] = cost
+		s.frontier.Push(node, cost)
+	}
+
+	// Set the goal node as the neighbor of each node in the goal polygon
+	for _, point := range s.goalNode.Polygon.Points() {
+		s.goalNeighbors[NavNode{Point: point, Polygon: s.goalNode.Polygon}] = true
+	}
+	for _, node := range links.ends[s.goalNode.Polygon] {
+		s.goalNeighbors[node] = true
+	}
+
+	return s
+}
+
+// step expands up to iterations nodes and returns how many it expanded
+func (s *search) step(iterations int) int {
+	p := s.planner
+	expanded := 0
+	for !s.done && expanded < iterations {
+		if s.frontier.Empty() {
+			s.done = true
+			break
+		}
+
+		current := s.frontier.Pop().(NavNode)
+		if current == s.goalNode {
+			s.done, s.found = true, true
+			break
+		}
+		if s.explored[current] {
+			continue
+		}
+		s.explored[current] = true
+		expanded++
+
+		if distance := p.navmesh.Cost(current.Point, s.goalNode.Point); distance < s.closestDistance {
+			s.closest, s.closestDistance = current, distance
+		}
+
+		neighbors := append(copyPointList(p.navmesh.neighbors[current]), s.links.neighbors(current)...)
+
+		// Append the goal to the list of neighbors if the current point is a neighbor
+		// of the goal point
+		if s.goalNeighbors[current] {
+			neighbors = append(neighbors, s.goalNode)
+		}
+
+		for _, neighbor := range neighbors {
+			if s.explored[neighbor] {
+				continue
+			}
+			if neighbor.Polygon != current.Polygon && (!p.allowed(neighbor.Polygon) || (s.within != nil && !s.within[neighbor.Polygon]) || !p.fits(current.Polygon, neighbor.Polygon)) {
+				continue
+			}
+
+			// Overwrite the cost to reach the neighbor if the cost is better than
+			// what we previously recorded (or if we haven't recorded a cost yet)
+			newCost := s.costSoFar[current] + p.cost(s.links, current, neighbor)
+			if cost, ok := s.costSoFar[neighbor]; !ok || newCost < cost {
+				s.costSoFar[neighbor] = newCost
+				s.frontier.Push(neighbor, newCost+p.navmesh.Cost(s.goalNode.Point, neighbor.Point)*s.heuristicScale)
+				s.cameFrom[neighbor] = current
+			}
+		}
+	}
+	return expanded
+}
+
+// path returns the path and its cost once the search found the goal
+func (s *search) path() ([]NavNode, float64) {
+	if !s.found {
+		return nil, 0
+	}
+	return s.pathTo(s.goalNode), s.costSoFar[s.goalNode]
+}
+
+// partialPath returns the path to the explored node closest to the goal, or nil when the
+// search couldn't start
+func (s *search) partialPath() []NavNode {
+	if s.found {
+		path, _ := s.path()
+		return path
+	}
+	if s.closest.Polygon == nil {
+		return nil
+	}
+	return s.pathTo(s.closest)
+}
+
+func (s *search) pathTo(node NavNode) []NavNode {
+	path := []NavNode{}
+	for {
+		path = append(path, node)
+		if node == s.startNode {
+			break
+		}
+		node = s.cameFrom[node]
+	}
+
+	reversePath := make([]NavNode, len(path))
+	for i := 0; i < len(path); i++ {
+		reversePath[len(path)-1-i] = path[i]
+	}
+	return reversePath
+}
@@ -0,0 +1,351 @@
+package pathing
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// navMeshFormatVersion goes up whenever the saved form of a navmesh changes. older versions
+// can't be read.
+const navMeshFormatVersion = 1
+
+var navMeshMagic = [4]byte{'N', 'A', 'V', 'M'}
+
+// navMeshData is the saved form of a navmesh. polygons refer to the shared vertices by index
+// and portals refer to polygons and vertices by index.
+type navMeshData struct {
+	Version    int           `json:"version"`
+	Vertices   [][3]float64  `json:"vertices"`
+	Polygons   []polygonData `json:"polygons"`
+	Portals    []portalData  `json:"portals"`
+	Links      []linkData    `json:"links"`
+	NextLinkID int           `json:"nextLinkID"`
+}
+
+type polygonData struct {
+	Vertices []int    `json:"vertices"`
+	Area     AreaType `json:"area"`
+}
+
+// portalData is the widest edge two polygons share
+type portalData struct {
+	Polygons [2]int `json:"polygons"`
+	Vertices [2]int `json:"vertices"`
+}
+
+type linkData struct {
+	ID            int        `json:"id"`
+	Start         [3]float64 `json:"start"`
+	End           [3]float64 `json:"end"`
+	Bidirectional bool       `json:"bidirectional"`
+	Cost          float64    `json:"cost"`
+	Tag           string     `json:"tag"`
+}
+
+// WriteBinary saves the navmesh in a compact versioned form that ReadNavMeshBinary loads
+func (nm *NavMesh) WriteBinary(w io.Writer) error {
+	data := nm.data()
+	bw := &binaryWriter{w: bufio.NewWriter(w)}
+
+	bw.write(navMeshMagic)
+	bw.uint32(navMeshFormatVersion)
+
+	bw.uint32(len(data.Vertices))
+	for _, vertex := range data.Vertices {
+		bw.write(vertex)
+	}
+
+	bw.uint32(len(data.Polygons))
+	for _, polygon := range data.Polygons {
+		bw.uint32(len(polygon.Vertices))
+		for _, index := range polygon.Vertices {
+			bw.uint32(index)
+		}
+		bw.write(polygon.Area)
+	}
+
+	bw.uint32(len(data.Portals))
+	for _, portal := range data.Portals {
+		bw.uint32(portal.Polygons[0])
+		bw.uint32(portal.Polygons[1])
+		bw.uint32(portal.Vertices[0])
+		bw.uint32(portal.Vertices[1])
+	}
+
+	bw.uint32(len(data.Links))
+	for _, link := range data.Links {
+		bw.uint32(link.ID)
+		bw.write(link.Start)
+		bw.write(link.End)
+		bw.write(link.Bidirectional)
+		bw.write(link.Cost)
+		bw.uint32(len(link.Tag))
+		bw.write([]byte(link.Tag))
+	}
+	bw.uint32(data.NextLinkID)
+
+	if bw.err != nil {
+		return bw.err
+	}
+	return bw.w.Flush()
+}
+
+// ReadNavMeshBinary loads a navmesh saved with WriteBinary
+func ReadNavMeshBinary(r io.Reader) (*NavMesh, error) {
+	br := &binaryReader{r: bufio.NewReader(r)}
+
+	var magic [4]byte
+	br.read(&magic)
+	if br.err == nil && magic != navMeshMagic {
+		return nil, errors.New("pathing: not a navmesh")
+	}
+	data := navMeshData{Version: br.uint32()}
+	if br.err == nil && data.Version != navMeshFormatVersion {
+		return nil, fmt.Errorf("pathing: unsupported navmesh version %d", data.Version)
+	}
+
+	data.Vertices = make([][3]float64, br.count())
+	for i := range data.Vertices {
+		br.read(&data.Vertices[i])
+	}
+
+	data.Polygons = make([]polygonData, br.count())
+	for i := range data.Polygons {
+		data.Polygons[i].Vertices = make([]int, br.count())
+		for j := range data.Polygons[i].Vertices {
+			data.Polygons[i].Vertices[j] = br.uint32()
+		}
+		br.read(&data.Polygons[i].Area)
+	}
+
+	data.Portals = make([]portalData, br.count())
+	for i := range data.Portals {
+		data.Portals[i].Polygons = [2]int{br.uint32(), br.uint32()}
+		data.Portals[i].Vertices = [2]int{br.uint32(), br.uint32()}
+	}
+
+	data.Links = make([]linkData, br.count())
+	for i := range data.Links {
+		link := &data.Links[i]
+		link.ID = br.uint32()
+		br.read(&link.Start)
+		br.read(&link.End)
+		br.read(&link.Bidirectional)
+		br.read(&link.Cost)
+		tag := make([]byte, br.count())
+		br.read(tag)
+		link.Tag = string(tag)
+	}
+	data.NextLinkID = br.uint32()
+
+	if br.err != nil {
+		if br.err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, br.err
+	}
+	return data.navMesh()
+}
+
+// WriteJSON saves the navmesh in the same form as WriteBinary but readable, for tools and
+// diffing
+func (nm *NavMesh) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(nm.data())
+}
+
+// ReadNavMeshJSON loads a navmesh saved with WriteJSON
+func ReadNavMeshJSON(r io.Reader) (*NavMesh, error) {
+	var data navMeshData
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	if data.Version != navMeshFormatVersion {
+		return nil, fmt.Errorf("pathing: unsupported navmesh version %d", data.Version)
+	}
+	return data.navMesh()
+}
+
+// data returns the saved form of the navmesh, vertices are numbered in the order the polygons
+// first use them
+func (nm *NavMesh) data() navMeshData {
+	data := navMeshData{Version: navMeshFormatVersion, NextLinkID: nm.nextLinkID}
+
+	vertexIndexes := map[geometry.Point]int{}
+	polygonIndexes := map[*geometry.Polygon]int{}
+	for i, polygon := range nm.polygons {
+		polygonIndexes[polygon] = i
+		var indexes []int
+		for _, point := range polygon.Points() {
+			index, ok := vertexIndexes[point]
+			if !ok {
+				index = len(data.Vertices)
+				vertexIndexes[point] = index
+				data.Vertices = append(data.Vertices, point)
+			}
+			indexes = append(indexes, index)
+		}
+		data.Polygons = append(data.Polygons, polygonData{Vertices: indexes, Area: nm.Area(polygon)})
+	}
+
+	for i, polygon := range nm.polygons {
+		for other, portal := range nm.polyPairToPortal[polygon] {
+			if j := polygonIndexes[other]; i < j {
+				data.Portals = append(data.Portals, portalData{
+					Polygons: [2]int{i, j},
+					Vertices: [2]int{vertexIndexes[portal.Point1], vertexIndexes[portal.Point2]},
+				})
+			}
+		}
+	}
+	sort.Slice(data.Portals, func(a, b int) bool {
+		if data.Portals[a].Polygons[0] != data.Portals[b].Polygons[0] {
+			return data.Portals[a].Polygons[0] < data.Portals[b].Polygons[0]
+		}
+		return data.Portals[a].Polygons[1] < data.Portals[b].Polygons[1]
+	})
+
+	for _, link := range nm.OffMeshLinks() {
+		data.Links = append(data.Links, linkData{
+			ID:            link.ID,
+			Start:         link.Start,
+			End:           link.End,
+			Bidirectional: link.Bidirectional,
+			Cost:          link.Cost,
+			Tag:           link.Tag,
+		})
+	}
+	return data
+}
+
+// navMesh builds the navmesh back up from its saved form. the adjacency is worked out again
+// from the shared vertices and the steps are joined again from the saved portals that aren't
+// between shared vertices, so the saved portals are checked against it to catch navmeshes
+// that were saved by a different version of the builder or edited by hand.
+func (data navMeshData) navMesh() (*NavMesh, error) {
+	var polygons []*geometry.Polygon
+	for i, polygon := range data.Polygons {
+		if len(polygon.Vertices) < 3 {
+			return nil, fmt.Errorf("pathing: polygon %d has %d vertices", i, len(polygon.Vertices))
+		}
+		var points []geometry.Point
+		for _, index := range polygon.Vertices {
+			if index < 0 || index >= len(data.Vertices) {
+				return nil, fmt.Errorf("pathing: polygon %d has vertex %d out of range", i, index)
+			}
+			points = append(points, geometry.Point(data.Vertices[index]))
+		}
+		polygons = append(polygons, geometry.NewPolygon(points))
+	}
+
+	nm := ConstructNavMesh(polygons)
+	for i, polygon := range data.Polygons {
+		if polygon.Area != DefaultArea {
+			nm.SetArea(polygons[i], polygon.Area)
+		}
+	}
+
+	for _, saved := range data.Portals {
+		a, b := saved.Polygons[0], saved.Polygons[1]
+		if a < 0 || a >= len(polygons) || b < 0 || b >= len(polygons) {
+			return nil, fmt.Errorf("pathing: portal between polygons %d and %d is out of range", a, b)
+		}
+		if _, ok := nm.polyPairToPortal[polygons[a]][polygons[b]]; !ok {
+			nm.ConnectStep(polygons[a], polygons[b])
+		}
+	}
+
+	portals := 0
+	for _, polygon := range polygons {
+		portals += len(nm.polyPairToPortal[polygon])
+	}
+	if portals != 2*len(data.Portals) {
+		return nil, fmt.Errorf("pathing: expected %d portals but the polygons make %d", len(data.Portals), portals/2)
+	}
+	for _, saved := range data.Portals {
+		a, b := saved.Polygons[0], saved.Polygons[1]
+		portal, ok := nm.polyPairToPortal[polygons[a]][polygons[b]]
+		if !ok || saved.Vertices[0] < 0 || saved.Vertices[0] >= len(data.Vertices) || saved.Vertices[1] < 0 || saved.Vertices[1] >= len(data.Vertices) ||
+			portal != newPortal(geometry.Point(data.Vertices[saved.Vertices[0]]), geometry.Point(data.Vertices[saved.Vertices[1]])) {
+			return nil, fmt.Errorf("pathing: portal between polygons %d and %d doesn't match the polygons", a, b)
+		}
+	}
+
+	for _, link := range data.Links {
+		nm.offMeshLinks[link.ID] = OffMeshLink{
+			ID:            link.ID,
+			Start:         link.Start,
+			End:           link.End,
+			Bidirectional: link.Bidirectional,
+			Cost:          link.Cost,
+			Tag:           link.Tag,
+		}
+	}
+	nm.nextLinkID = data.NextLinkID
+	nm.changed()
+	return nm, nil
+}
+
+// binaryWriter writes little endian values and keeps the first error so that it only has to
+// be checked at the end
+type binaryWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (bw *binaryWriter) write(value any) {
+	if bw.err == nil {
+		bw.err = binary.Write(bw.w, binary.LittleEndian, value)
+	}
+}
+
+func (bw *binaryWriter) uint32(value int) {
+	if value < 0 || value > math.MaxUint32 {
+		if bw.err == nil {
+			bw.err = fmt.Errorf("pathing: %d doesn't fit in the navmesh format", value)
+		}
+		return
+	}
+	bw.write(uint32(value))
+}
+
+type binaryReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (br *binaryReader) read(value any) {
+	if br.err == nil {
+		br.err = binary.Read(br.r, binary.LittleEndian, value)
+	}
+}
+
+func (br *binaryReader) uint32() int {
+	var value uint32
+	br.read(&value)
+	return int(value)
+}
+
+// maxSavedCount caps the lengths read so a bad file can't make us allocate too much
+const maxSavedCount = 1 << 24
+
+// count reads the length of a list
+func (br *binaryReader) count() int {
+	count := br.uint32()
+	if br.err == nil && count > maxSavedCount {
+		br.err = fmt.Errorf("pathing: list of %d is too long", count)
+	}
+	if br.err != nil {
+		return 0
+	}
+	return count
+}
@@ -0,0 +1,164 @@
+package pathing
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// savedMesh is a navmesh with every kind of data that gets saved
+func savedMesh() *NavMesh {
+	navmesh, _ := pond()
+	navmesh.AddOffMeshLink(OffMeshLink{Start: geometry.Point{1, 0, 1}, End: geometry.Point{29, 0, 1}, Bidirectional: true, Cost: 50, Tag: "jump"})
+	removed := navmesh.AddOffMeshLink(OffMeshLink{Start: geometry.Point{1, 0, 1}, End: geometry.Point{1, 0, 19}})
+	navmesh.RemoveOffMeshLink(removed)
+	return navmesh
+}
+
+func assertNavMeshEq(t *testing.T, expected, actual *NavMesh) {
+	t.Helper()
+	if len(expected.Polygons()) != len(actual.Polygons()) {
+		t.Fatalf("expected %d polygons but got %d", len(expected.Polygons()), len(actual.Polygons()))
+	}
+	for i, polygon := range expected.Polygons() {
+		loaded := actual.Polygons()[i]
+		assertPathEq(t, polygon.Points(), loaded.Points())
+		if expected.Area(polygon) != actual.Area(loaded) {
+			t.Errorf("expected polygon %d to be area %d but got %d", i, expected.Area(polygon), actual.Area(loaded))
+		}
+		if len(expected.polyPairToPortal[polygon]) != len(actual.polyPairToPortal[loaded]) {
+			t.Errorf("expected polygon %d to have %d neighbors but got %d", i, len(expected.polyPairToPortal[polygon]), len(actual.polyPairToPortal[loaded]))
+		}
+	}
+
+	expectedLinks, actualLinks := expected.OffMeshLinks(), actual.OffMeshLinks()
+	if len(expectedLinks) != len(actualLinks) {
+		t.Fatalf("expected links %v but got %v", expectedLinks, actualLinks)
+	}
+	for i := range expectedLinks {
+		if expectedLinks[i] != actualLinks[i] {
+			t.Errorf("expected link %v but got %v", expectedLinks[i], actualLinks[i])
+		}
+	}
+	if expected.nextLinkID != actual.nextLinkID {
+		t.Errorf("expected the next link id to be %d but got %d", expected.nextLinkID, actual.nextLinkID)
+	}
+
+	filter := AreaFilter{Costs: map[AreaType]float64{waterArea: 10}}
+	expectedPlanner, actualPlanner := Planner{}, Planner{}
+	expectedPlanner.SetNavMesh(expected)
+	expectedPlanner.SetAreaFilter(filter)
+	actualPlanner.SetNavMesh(actual)
+	actualPlanner.SetAreaFilter(filter)
+	start, goal := geometry.Point{1, 0, 5}, geometry.Point{29, 0, 5}
+	assertPathEq(t, expectedPlanner.FindPath(start, goal), actualPlanner.FindPath(start, goal))
+}
+
+func TestNavMeshBinary(t *testing.T) {
+	navmesh := savedMesh()
+	var buffer bytes.Buffer
+	if err := navmesh.WriteBinary(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	saved := buffer.Bytes()
+
+	loaded, err := ReadNavMeshBinary(bytes.NewReader(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNavMeshEq(t, navmesh, loaded)
+
+	// saving what was loaded gives back the same bytes
+	var again bytes.Buffer
+	if err := loaded.WriteBinary(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved, again.Bytes()) {
+		t.Error("expected saving the loaded navmesh to give the same bytes")
+	}
+
+	if _, err := ReadNavMeshBinary(bytes.NewReader(saved[:len(saved)/2])); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected a truncated navmesh to fail with %v but got %v", io.ErrUnexpectedEOF, err)
+	}
+	if _, err := ReadNavMeshBinary(strings.NewReader("not a navmesh")); err == nil {
+		t.Error("expected something that isn't a navmesh to fail")
+	}
+	newer := append([]byte{}, saved...)
+	newer[4] = navMeshFormatVersion + 1
+	if _, err := ReadNavMeshBinary(bytes.NewReader(newer)); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("expected an unsupported version to fail but got %v", err)
+	}
+}
+
+func TestNavMeshJSON(t *testing.T) {
+	navmesh := savedMesh()
+	var buffer bytes.Buffer
+	if err := navmesh.WriteJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	saved := buffer.String()
+
+	loaded, err := ReadNavMeshJSON(strings.NewReader(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNavMeshEq(t, navmesh, loaded)
+
+	// a portal that the polygons don't make
+	edited := strings.Replace(saved, `"portals": [`, `"portals": [
+		{"polygons": [0, 4], "vertices": [0, 1]},`, 1)
+	if _, err := ReadNavMeshJSON(strings.NewReader(edited)); err == nil {
+		t.Error("expected portals that don't match the polygons to fail")
+	}
+}
+
+func TestNavMeshExport(t *testing.T) {
+	navmesh := savedMesh()
+	p := Planner{}
+	p.SetNavMesh(navmesh)
+	path := p.FindPath(geometry.Point{1, 0, 5}, geometry.Point{29, 0, 5})
+
+	var obj bytes.Buffer
+	if err := navmesh.WriteOBJ(&obj, path); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for _, line := range strings.Split(obj.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			counts[fields[0]]++
+		}
+	}
+	if counts["f"] != len(navmesh.Polygons()) {
+		t.Errorf("expected a face for each of the %d polygons but got %d", len(navmesh.Polygons()), counts["f"])
+	}
+	// one for the link and one for the path
+	if counts["l"] != 2 {
+		t.Errorf("expected 2 lines but got %d", counts["l"])
+	}
+
+	var svg bytes.Buffer
+	if err := navmesh.WriteSVG(&svg, path); err != nil {
+		t.Fatal(err)
+	}
+	decoder := xml.NewDecoder(&svg)
+	elements := map[string]int{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("expected valid svg but got %v", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			elements[start.Name.Local]++
+		}
+	}
+	if elements["polygon"] != len(navmesh.Polygons()) || elements["polyline"] != 1 || elements["circle"] != len(path) || elements["line"] != 1 {
+		t.Errorf("expected a polygon for each polygon, the link and the path but got %v", elements)
+	}
+}
@@ -0,0 +1,223 @@
+package pathing
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+// Obstacle is something temporary in the way of agents, like a closed door or a crate, that's
+// carved out of a TiledNavMesh without changing its mesh
+type Obstacle interface {
+	BoundingBox() collider.BoundingBox
+	// blocks returns whether the obstacle overlaps the space above a cell
+	blocks(column collider.BoundingBox) bool
+}
+
+// CylinderObstacle is an upright cylinder standing on Position
+type CylinderObstacle struct {
+	Position mgl64.Vec3
+	Radius   float64
+	Height   float64
+}
+
+func (c CylinderObstacle) BoundingBox() collider.BoundingBox {
+	return collider.BoundingBox{
+		MinVertex: c.Position.Sub(mgl64.Vec3{c.Radius, 0, c.Radius}),
+		MaxVertex: c.Position.Add(mgl64.Vec3{c.Radius, c.Height, c.Radius}),
+	}
+}
+
+func (c CylinderObstacle) blocks(column collider.BoundingBox) bool {
+	if column.MaxVertex[1] <= c.Position[1] || column.MinVertex[1] >= c.Position[1]+c.Height {
+		return false
+	}
+	// the closest point of the column to the cylinder's axis
+	x := math.Max(column.MinVertex[0], math.Min(c.Position[0], column.MaxVertex[0]))
+	z := math.Max(column.MinVertex[2], math.Min(c.Position[2], column.MaxVertex[2]))
+	dx, dz := x-c.Position[0], z-c.Position[2]
+	return dx*dx+dz*dz < c.Radius*c.Radius
+}
+
+// BoxObstacle is an axis aligned box
+type BoxObstacle struct {
+	Min mgl64.Vec3
+	Max mgl64.Vec3
+}
+
+func (b BoxObstacle) BoundingBox() collider.BoundingBox {
+	return collider.BoundingBox{MinVertex: b.Min, MaxVertex: b.Max}
+}
+
+func (b BoxObstacle) blocks(column collider.BoundingBox) bool {
+	for i := 0; i < 3; i++ {
+		if column.MaxVertex[i] <= b.Min[i] || column.MinVertex[i] >= b.Max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type TileKey struct {
+	X, Z int
+}
+
+// TiledNavMesh builds the navmesh in square tiles so that changing the level or adding an
+// obstacle only rebuilds the tiles around it. the embedded NavMesh is updated in place, so a
+// Planner using it sees the changes right away and can check its old paths with ValidatePath.
+type TiledNavMesh struct {
+	*NavMesh
+
+	mesh      collider.TriMesh
+	settings  BuildSettings
+	tileCells int
+	tiles     map[TileKey][]*geometry.Polygon
+
+	obstacles      map[int]Obstacle
+	nextObstacleID int
+}
+
+// NewTiledNavMesh builds every tile the mesh covers. tiles are rounded to a whole number of
+// cells.
+func NewTiledNavMesh(mesh collider.TriMesh, tileSize float64, settings BuildSettings) *TiledNavMesh {
+	t := &TiledNavMesh{
+		NavMesh:   ConstructNavMesh(nil),
+		mesh:      mesh,
+		settings:  settings,
+		tileCells: int(math.Max(1, math.Round(tileSize/settings.CellSize))),
+		tiles:     map[TileKey][]*geometry.Polygon{},
+		obstacles: map[int]Obstacle{},
+	}
+
+	var vertices []mgl64.Vec3
+	for _, triangle := range mesh.Triangles {
+		vertices = append(vertices, triangle.Points[:]...)
+	}
+	if len(vertices) > 0 {
+		t.RebuildTiles(collider.BoundingBoxFromVertices(vertices))
+	}
+	return t
+}
+
+func (t *TiledNavMesh) TileSize() float64 {
+	return float64(t.tileCells) * t.settings.CellSize
+}
+
+// Tile returns the tile the point is in
+func (t *TiledNavMesh) Tile(point mgl64.Vec3) TileKey {
+	size := t.TileSize()
+	return TileKey{X: int(math.Floor(point[0] / size)), Z: int(math.Floor(point[2] / size))}
+}
+
+// Tiles returns the tiles that have been built, ordered by Z and then X
+func (t *TiledNavMesh) Tiles() []TileKey {
+	var keys []TileKey
+	for key := range t.tiles {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Z != keys[j].Z {
+			return keys[i].Z < keys[j].Z
+		}
+		return keys[i].X < keys[j].X
+	})
+	return keys
+}
+
+func (t *TiledNavMesh) TilePolygons(key TileKey) []*geometry.Polygon {
+	return t.tiles[key]
+}
+
+// SetMesh replaces the level geometry and rebuilds the tiles that changed overlaps
+func (t *TiledNavMesh) SetMesh(mesh collider.TriMesh, changed collider.BoundingBox) []TileKey {
+	t.mesh = mesh
+	return t.RebuildTiles(changed)
+}
+
+// AddObstacle carves the obstacle out of the tiles around it and returns its id
+func (t *TiledNavMesh) AddObstacle(obstacle Obstacle) int {
+	id := t.nextObstacleID
+	t.nextObstacleID++
+	t.obstacles[id] = obstacle
+	t.RebuildTiles(obstacle.BoundingBox())
+	return id
+}
+
+// RemoveObstacle removes the obstacle and rebuilds the tiles around where it was
+func (t *TiledNavMesh) RemoveObstacle(id int) {
+	obstacle, ok := t.obstacles[id]
+	if !ok {
+		return
+	}
+	delete(t.obstacles, id)
+	t.RebuildTiles(obstacle.BoundingBox())
+}
+
+// RebuildTiles rebuilds every tile that anything inside bounds can affect, which includes
+// tiles up to the agent's radius away. the rebuilt tiles are returned.
+func (t *TiledNavMesh) RebuildTiles(bounds collider.BoundingBox) []TileKey {
+	margin := t.settings.AgentRadius + 2*t.settings.CellSize
+	min := t.Tile(bounds.MinVertex.Sub(mgl64.Vec3{margin, 0, margin}))
+	max := t.Tile(bounds.MaxVertex.Add(mgl64.Vec3{margin, 0, margin}))
+
+	var rebuilt []TileKey
+	for z := min.Z; z <= max.Z; z++ {
+		for x := min.X; x <= max.X; x++ {
+			key := TileKey{X: x, Z: z}
+			t.RebuildTile(key)
+			rebuilt = append(rebuilt, key)
+		}
+	}
+	return rebuilt
+}
+
+// RebuildTile replaces the tile's polygons with ones built from the current mesh and
+// obstacles
+func (t *TiledNavMesh) RebuildTile(key TileKey) {
+	for _, polygon := range t.tiles[key] {
+		t.RemovePolygon(polygon)
+	}
+	delete(t.tiles, key)
+
+	bounds := &tileBounds{
+		min: [2]int{key.X * t.tileCells, key.Z * t.tileCells},
+		max: [2]int{(key.X + 1) * t.tileCells, (key.Z + 1) * t.tileCells},
+	}
+	result := buildPolygons(t.mesh, t.settings, bounds, t.tileObstacles(key))
+	if len(result.Polygons) == 0 {
+		return
+	}
+	for _, polygon := range result.Polygons {
+		t.AddPolygon(polygon)
+	}
+	result.connectSteps(t.NavMesh)
+	t.tiles[key] = result.Polygons
+}
+
+// tileObstacles returns the obstacles close enough to the tile to affect it, in the order
+// they were added
+func (t *TiledNavMesh) tileObstacles(key TileKey) []Obstacle {
+	size := t.TileSize()
+	margin := t.settings.AgentRadius + 2*t.settings.CellSize
+	minX, minZ := float64(key.X)*size-margin, float64(key.Z)*size-margin
+	maxX, maxZ := float64(key.X+1)*size+margin, float64(key.Z+1)*size+margin
+
+	var ids []int
+	for id, obstacle := range t.obstacles {
+		box := obstacle.BoundingBox()
+		if box.MaxVertex[0] < minX || box.MinVertex[0] > maxX || box.MaxVertex[2] < minZ || box.MinVertex[2] > maxZ {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	obstacles := make([]Obstacle, len(ids))
+	for i, id := range ids {
+		obstacles[i] = t.obstacles[id]
+	}
+	return obstacles
+}
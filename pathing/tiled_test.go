@@ -0,0 +1,163 @@
+package pathing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/geometry"
+)
+
+func TestRemovePolygon(t *testing.T) {
+	a, b, c := sqWithXOffset(0), sqWithXOffset(6), sqWithXOffset(12)
+	navmesh := ConstructNavMesh([]*geometry.Polygon{a, b, c})
+
+	p := Planner{}
+	p.SetNavMesh(navmesh)
+	start, goal := geometry.Point{1, 0, 3}, geometry.Point{17, 0, 3}
+	path := p.FindPath(start, goal)
+	if path == nil {
+		t.Fatal("expected a path")
+	}
+
+	navmesh.RemovePolygon(b)
+	if path := p.FindPath(start, goal); path != nil {
+		t.Fatalf("expected no path without the middle polygon but got %v", path)
+	}
+	if p.ValidatePath(path) {
+		t.Error("expected the old path to be invalid")
+	}
+
+	// nothing of the removed polygon should be left behind
+	expected := ConstructNavMesh([]*geometry.Polygon{a, c})
+	if len(navmesh.neighbors) != len(expected.neighbors) || len(navmesh.portalToPolygons) != len(expected.portalToPolygons) ||
+		len(navmesh.polyPairToPortal) != len(expected.polyPairToPortal) || len(navmesh.pointToPolygons) != len(expected.pointToPolygons) {
+		t.Errorf("expected the navmesh to match one built without the polygon")
+	}
+
+	navmesh.AddPolygon(b)
+	if !p.ValidatePath(path) {
+		t.Error("expected the old path to be valid again")
+	}
+
+	// the middle split in two
+	left := geometry.NewPolygon([]geometry.Point{{6, 0, 0}, {6, 0, 6}, {9, 0, 6}, {9, 0, 0}})
+	right := geometry.NewPolygon([]geometry.Point{{9, 0, 0}, {9, 0, 6}, {12, 0, 6}, {12, 0, 0}})
+	navmesh.ReplacePolygon(b, left, right)
+	if len(navmesh.Polygons()) != 4 {
+		t.Fatalf("expected 4 polygons but got %d", len(navmesh.Polygons()))
+	}
+	assertPathEq(t, []geometry.Point{start, goal}, p.FindPath(start, goal))
+}
+
+func TestValidatePath(t *testing.T) {
+	polygons := []*geometry.Polygon{
+		sqWithOffset(10, 0, 0),
+		sqWithOffset(10, 1, 0),
+		sqWithOffset(10, 1, 1),
+	}
+	p := Planner{}
+	p.SetNavMesh(ConstructNavMesh(polygons))
+
+	if !p.ValidatePath([]geometry.Point{{5, 0, 5}, {10, 0, 10}, {15, 0, 18}}) {
+		t.Error("expected the path around the corner to be valid")
+	}
+	if p.ValidatePath([]geometry.Point{{5, 0, 5}, {15, 0, 18}}) {
+		t.Error("expected the path cutting the corner to be invalid")
+	}
+	if p.ValidatePath([]geometry.Point{{5, 0, 5}, {25, 0, 5}}) {
+		t.Error("expected the path off the navmesh to be invalid")
+	}
+}
+
+func TestTiledNavMeshMatchesSingleBuild(t *testing.T) {
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 20, 20, 0)
+	settings := testBuildSettings()
+
+	tiled := NewTiledNavMesh(mesh, 5, settings)
+	if tiles := len(tiled.Tiles()); tiles != 16 {
+		t.Fatalf("expected 16 tiles but got %d", tiles)
+	}
+	whole := BuildPolygons(mesh, settings).Polygons
+	if tiledArea, wholeArea := areaAt(tiled.Polygons(), 0), areaAt(whole, 0); math.Abs(tiledArea-wholeArea) > 1e-6 {
+		t.Errorf("expected the tiles to cover %f but got %f", wholeArea, tiledArea)
+	}
+
+	p := Planner{}
+	p.SetNavMesh(tiled.NavMesh)
+	start, goal := geometry.Point{1, 0, 1}, geometry.Point{19, 0, 18}
+	assertPathEq(t, []geometry.Point{start, goal}, p.FindPath(start, goal))
+}
+
+func TestTiledNavMeshObstacles(t *testing.T) {
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 20, 20, 0)
+	tiled := NewTiledNavMesh(mesh, 5, testBuildSettings())
+
+	p := Planner{}
+	p.SetNavMesh(tiled.NavMesh)
+	start, goal := geometry.Point{2, 0, 10}, geometry.Point{18, 0, 10}
+	path := p.FindPath(start, goal)
+
+	farTile := tiled.TilePolygons(TileKey{X: 0, Z: 0})
+	id := tiled.AddObstacle(CylinderObstacle{Position: mgl64.Vec3{10, 0, 10}, Radius: 2, Height: 2})
+	if tiled.TilePolygons(TileKey{X: 0, Z: 0})[0] != farTile[0] {
+		t.Error("expected tiles away from the obstacle not to be rebuilt")
+	}
+	if p.ValidatePath(path) {
+		t.Error("expected the straight path through the obstacle to be invalid")
+	}
+
+	path = p.FindPath(start, goal)
+	if len(path) == 0 || path[len(path)-1] != goal {
+		t.Fatalf("expected a path around the obstacle but got %v", path)
+	}
+	if !p.ValidatePath(path) {
+		t.Error("expected the new path to be valid")
+	}
+	previous := start.Vector3()
+	for _, point := range path {
+		for s := 0.0; s <= 1; s += 0.01 {
+			position := previous.Add(point.Vector3().Sub(previous).Mul(s))
+			if distance := math.Hypot(position[0]-10, position[2]-10); distance < 2.4 {
+				t.Fatalf("path %v is %f from the obstacle's center", path, distance)
+			}
+		}
+		previous = point.Vector3()
+	}
+
+	// a closed door across the whole floor
+	door := tiled.AddObstacle(BoxObstacle{Min: mgl64.Vec3{14, 0, -1}, Max: mgl64.Vec3{15, 3, 21}})
+	if path := p.FindPath(start, goal); path != nil {
+		t.Fatalf("expected the door to block the way but got %v", path)
+	}
+
+	tiled.RemoveObstacle(door)
+	tiled.RemoveObstacle(id)
+	path = p.FindPath(start, goal)
+	if length := pathLength(start, path); math.Abs(length-16) > 1e-6 {
+		t.Errorf("expected a straight path once the obstacles are gone but got %v", path)
+	}
+}
+
+func TestTiledNavMeshSetMesh(t *testing.T) {
+	var mesh collider.TriMesh
+	addFloor(&mesh, 0, 0, 20, 20, 0)
+	tiled := NewTiledNavMesh(mesh, 5, testBuildSettings())
+
+	p := Planner{}
+	p.SetNavMesh(tiled.NavMesh)
+	start, goal := geometry.Point{2, 0, 10}, geometry.Point{18, 0, 10}
+
+	wall := collider.BoundingBox{MinVertex: mgl64.Vec3{9, 0, -1}, MaxVertex: mgl64.Vec3{11, 3, 21}}
+	addBox(&mesh, wall.MinVertex, wall.MaxVertex)
+	rebuilt := tiled.SetMesh(mesh, wall)
+	if len(rebuilt) == len(tiled.Tiles()) {
+		t.Error("expected only the tiles around the wall to be rebuilt")
+	}
+	if path := p.FindPath(start, goal); path != nil {
+		t.Fatalf("expected the wall to block the way but got %v", path)
+	}
+}
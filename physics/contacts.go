@@ -0,0 +1,72 @@
+package physics
+
+import (
+	"github.com/kkevinchou/kitolib/collision"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+// colliderOrder decides which body is passed first to the collision checks, the checks
+// are only written for one ordering of each pair of shapes
+var colliderOrder = map[ColliderType]int{
+	ColliderTypeSphere:  0,
+	ColliderTypeCapsule: 1,
+	ColliderTypeBox:     2,
+}
+
+// generateContact checks for a collision between two bodies. the contact normal points from
+// b towards a and the packed indices are the ids of a and b.
+func generateContact(a, b *RigidBody) (collision.Contact, bool) {
+	if colliderOrder[a.ColliderType] > colliderOrder[b.ColliderType] {
+		contact, collided := generateContact(b, a)
+		return contact.Flip(), collided
+	}
+
+	contact, collided := checkBodies(a, b)
+	contact.PackedIndexA, contact.PackedIndexB = a.ID, b.ID
+	return contact, collided
+}
+
+// checkBodies runs the collision check for the bodies' colliders, a has to come first in
+// colliderOrder
+func checkBodies(a, b *RigidBody) (collision.Contact, bool) {
+	switch a.ColliderType {
+	case ColliderTypeSphere, ColliderTypeCapsule:
+		switch b.ColliderType {
+		case ColliderTypeSphere, ColliderTypeCapsule:
+			return collision.CheckCollisionCapsuleCapsule(bodyCapsule(a), bodyCapsule(b))
+		case ColliderTypeBox:
+			if a.ColliderType == ColliderTypeSphere {
+				return collision.CheckCollisionSphereOBB(a.SphereCollider(), b.BoxCollider())
+			}
+			return collision.CheckCollisionCapsuleOBB(a.CapsuleCollider(), b.BoxCollider())
+		}
+	case ColliderTypeBox:
+		return collision.CheckCollisionOBBOBB(a.BoxCollider(), b.BoxCollider())
+	}
+
+	return collision.Contact{}, false
+}
+
+// generateTriMeshContacts checks for collisions between a body and static geometry. boxes
+// aren't supported since the box vs triangle check only finds a single point, which a box
+// can't rest on. the packed indices are the body's id and staticGeometryID, TriIndex says
+// which triangle was hit.
+func generateTriMeshContacts(body *RigidBody, triMesh *collider.TriMesh) []collision.Contact {
+	if body.ColliderType != ColliderTypeSphere && body.ColliderType != ColliderTypeCapsule {
+		return nil
+	}
+	contacts := collision.CheckCollisionCapsuleTriMesh(bodyCapsule(body), *triMesh)
+	for i := range contacts {
+		contacts[i].PackedIndexA, contacts[i].PackedIndexB = body.ID, staticGeometryID
+	}
+	return contacts
+}
+
+// bodyCapsule returns the collider of a sphere or capsule body as a capsule. spheres are
+// capsules with both ends at the same point.
+func bodyCapsule(body *RigidBody) collider.Capsule {
+	if body.ColliderType == ColliderTypeSphere {
+		return collider.NewCapsule(body.Position, body.Position, body.Sphere.Radius)
+	}
+	return body.CapsuleCollider()
+}
@@ -0,0 +1,42 @@
+package physics
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+func TestContactPackedIndices(t *testing.T) {
+	box := NewBoxBody(1, mgl64.Vec3{1, 1, 1}, 0)
+	sphere := NewSphereBody(2, 1, 1)
+	sphere.Position = mgl64.Vec3{0, 1.5, 0}
+
+	// the check runs with the sphere first and is flipped back so the box stays A
+	contact, collided := generateContact(box, sphere)
+	if !collided {
+		t.Fatal("expected the bodies to collide")
+	}
+	if contact.PackedIndexA != box.ID || contact.PackedIndexB != sphere.ID {
+		t.Errorf("expected packed indices %d and %d but got %d and %d", box.ID, sphere.ID, contact.PackedIndexA, contact.PackedIndexB)
+	}
+	if !contact.Normal.ApproxEqual(mgl64.Vec3{0, -1, 0}) {
+		t.Errorf("expected the normal to point from the sphere towards the box but got %v", contact.Normal)
+	}
+
+	contact, _ = generateContact(sphere, box)
+	if contact.PackedIndexA != sphere.ID || contact.PackedIndexB != box.ID {
+		t.Errorf("expected packed indices %d and %d but got %d and %d", sphere.ID, box.ID, contact.PackedIndexA, contact.PackedIndexB)
+	}
+
+	triMesh := &collider.TriMesh{Triangles: []collider.Triangle{
+		collider.NewTriangle([3]mgl64.Vec3{{-10, 0.6, 10}, {10, 0.6, 10}, {10, 0.6, -10}}),
+	}}
+	contacts := generateTriMeshContacts(sphere, triMesh)
+	if len(contacts) != 1 {
+		t.Fatalf("expected 1 contact but got %d", len(contacts))
+	}
+	if contacts[0].PackedIndexA != sphere.ID || contacts[0].PackedIndexB != staticGeometryID || *contacts[0].TriIndex != 0 {
+		t.Errorf("expected the sphere against triangle 0 of the static geometry but got %v", contacts[0])
+	}
+}
@@ -0,0 +1,92 @@
+package deterministic
+
+import (
+	"github.com/kkevinchou/kitolib/fixedpoint"
+)
+
+type Shape string
+
+const (
+	ShapeSphere Shape = "SPHERE"
+	ShapeBox    Shape = "BOX"
+)
+
+// Body is a rigid body with fixed point state. boxes are axis aligned and don't rotate, unlike
+// boxes in the float physics package.
+type Body struct {
+	ID          int
+	Shape       Shape
+	Radius      fixedpoint.Fixed
+	HalfExtents fixedpoint.Vec3
+
+	Position        fixedpoint.Vec3
+	Orientation     fixedpoint.Quat
+	LinearVelocity  fixedpoint.Vec3
+	AngularVelocity fixedpoint.Vec3
+
+	// a zero inverse mass makes the body static
+	InverseMass fixedpoint.Fixed
+	// InverseInertia is a scalar since spheres are the only shape that rotates
+	InverseInertia fixedpoint.Fixed
+
+	Restitution fixedpoint.Fixed
+	Friction    fixedpoint.Fixed
+}
+
+func newBody(id int, mass fixedpoint.Fixed) *Body {
+	body := &Body{
+		ID:          id,
+		Orientation: fixedpoint.QuatIdent(),
+		Friction:    fixedpoint.Half,
+	}
+	if mass > 0 {
+		body.InverseMass = fixedpoint.One.Div(mass)
+	}
+	return body
+}
+
+// NewSphereBody creates a sphere body, a mass of 0 creates a static body
+func NewSphereBody(id int, radius fixedpoint.Fixed, mass fixedpoint.Fixed) *Body {
+	body := newBody(id, mass)
+	body.Shape = ShapeSphere
+	body.Radius = radius
+	if mass > 0 {
+		// 2/5 * m * r^2
+		inertia := mass.Mul(radius).Mul(radius).Mul(fixedpoint.FromRatio(2, 5))
+		body.InverseInertia = fixedpoint.One.Div(inertia)
+	}
+	return body
+}
+
+// NewBoxBody creates an axis aligned box body, a mass of 0 creates a static body
+func NewBoxBody(id int, halfExtents fixedpoint.Vec3, mass fixedpoint.Fixed) *Body {
+	body := newBody(id, mass)
+	body.Shape = ShapeBox
+	body.HalfExtents = halfExtents
+	return body
+}
+
+func (b *Body) IsStatic() bool {
+	return b.InverseMass == 0
+}
+
+func (b *Body) ApplyImpulse(impulse fixedpoint.Vec3) {
+	b.LinearVelocity = b.LinearVelocity.Add(impulse.Mul(b.InverseMass))
+}
+
+func (b *Body) applyImpulse(impulse, r fixedpoint.Vec3) {
+	b.LinearVelocity = b.LinearVelocity.Add(impulse.Mul(b.InverseMass))
+	b.AngularVelocity = b.AngularVelocity.Add(r.Cross(impulse).Mul(b.InverseInertia))
+}
+
+func (b *Body) velocityAtPoint(r fixedpoint.Vec3) fixedpoint.Vec3 {
+	return b.LinearVelocity.Add(b.AngularVelocity.Cross(r))
+}
+
+func (b *Body) min() fixedpoint.Vec3 {
+	return b.Position.Sub(b.HalfExtents)
+}
+
+func (b *Body) max() fixedpoint.Vec3 {
+	return b.Position.Add(b.HalfExtents)
+}
@@ -0,0 +1,137 @@
+package deterministic
+
+import (
+	"github.com/kkevinchou/kitolib/fixedpoint"
+)
+
+// contact follows the float physics convention, the normal points from b to a and the point
+// is on b's surface
+type contact struct {
+	a           *Body
+	b           *Body
+	point       fixedpoint.Vec3
+	normal      fixedpoint.Vec3
+	penetration fixedpoint.Fixed
+}
+
+func generateContact(a, b *Body) (contact, bool) {
+	switch {
+	case a.Shape == ShapeSphere && b.Shape == ShapeSphere:
+		return sphereSphere(a, b)
+	case a.Shape == ShapeSphere && b.Shape == ShapeBox:
+		return sphereBox(a, b)
+	case a.Shape == ShapeBox && b.Shape == ShapeSphere:
+		c, ok := sphereBox(b, a)
+		return c.flip(), ok
+	case a.Shape == ShapeBox && b.Shape == ShapeBox:
+		return boxBox(a, b)
+	}
+	return contact{}, false
+}
+
+// flip swaps a and b, moving the point to the other body's surface
+func (c contact) flip() contact {
+	return contact{
+		a:           c.b,
+		b:           c.a,
+		point:       c.point.Sub(c.normal.Mul(c.penetration)),
+		normal:      c.normal.Mul(-fixedpoint.One),
+		penetration: c.penetration,
+	}
+}
+
+func sphereSphere(a, b *Body) (contact, bool) {
+	delta := a.Position.Sub(b.Position)
+	radii := a.Radius + b.Radius
+	if delta.LenSqr() >= radii.Mul(radii) {
+		return contact{}, false
+	}
+
+	distance := delta.Len()
+	normal := fixedpoint.Vec3{0, fixedpoint.One, 0}
+	if distance > 0 {
+		normal = delta.Mul(fixedpoint.One.Div(distance))
+	}
+
+	return contact{
+		a:           a,
+		b:           b,
+		point:       b.Position.Add(normal.Mul(b.Radius)),
+		normal:      normal,
+		penetration: radii - distance,
+	}, true
+}
+
+func sphereBox(sphere, box *Body) (contact, bool) {
+	boxMin, boxMax := box.min(), box.max()
+
+	var closest fixedpoint.Vec3
+	for i := 0; i < 3; i++ {
+		closest[i] = fixedpoint.Clamp(sphere.Position[i], boxMin[i], boxMax[i])
+	}
+
+	delta := sphere.Position.Sub(closest)
+	if delta.LenSqr() >= sphere.Radius.Mul(sphere.Radius) {
+		return contact{}, false
+	}
+
+	distance := delta.Len()
+	if distance > 0 {
+		normal := delta.Mul(fixedpoint.One.Div(distance))
+		return contact{a: sphere, b: box, point: closest, normal: normal, penetration: sphere.Radius - distance}, true
+	}
+
+	// the center is inside the box, push out through the closest face
+	axis, sign, depth := minimumOverlapAxis(sphere.Position, sphere.Position, boxMin, boxMax)
+	var normal fixedpoint.Vec3
+	normal[axis] = sign
+	closest[axis] = boxMin[axis]
+	if sign > 0 {
+		closest[axis] = boxMax[axis]
+	}
+	return contact{a: sphere, b: box, point: closest, normal: normal, penetration: depth + sphere.Radius}, true
+}
+
+func boxBox(a, b *Body) (contact, bool) {
+	aMin, aMax := a.min(), a.max()
+	bMin, bMax := b.min(), b.max()
+	for i := 0; i < 3; i++ {
+		if aMin[i] >= bMax[i] || bMin[i] >= aMax[i] {
+			return contact{}, false
+		}
+	}
+
+	axis, sign, depth := minimumOverlapAxis(aMin, aMax, bMin, bMax)
+	var normal fixedpoint.Vec3
+	normal[axis] = sign
+
+	// the center of the overlapping region, moved onto b's face
+	var point fixedpoint.Vec3
+	for i := 0; i < 3; i++ {
+		point[i] = (fixedpoint.Max(aMin[i], bMin[i]) + fixedpoint.Min(aMax[i], bMax[i])) / 2
+	}
+	point[axis] = bMin[axis]
+	if sign > 0 {
+		point[axis] = bMax[axis]
+	}
+
+	return contact{a: a, b: b, point: point, normal: normal, penetration: depth}, true
+}
+
+// minimumOverlapAxis returns the axis that separates box a from box b with the least
+// movement, along with the direction a should move and the overlap
+func minimumOverlapAxis(aMin, aMax, bMin, bMax fixedpoint.Vec3) (int, fixedpoint.Fixed, fixedpoint.Fixed) {
+	axis := 0
+	sign := fixedpoint.One
+	depth := fixedpoint.MaxValue
+	for i := 0; i < 3; i++ {
+		// moving a in the positive direction
+		if overlap := bMax[i] - aMin[i]; overlap < depth {
+			axis, sign, depth = i, fixedpoint.One, overlap
+		}
+		if overlap := aMax[i] - bMin[i]; overlap < depth {
+			axis, sign, depth = i, -fixedpoint.One, overlap
+		}
+	}
+	return axis, sign, depth
+}
@@ -0,0 +1,125 @@
+package deterministic_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/kkevinchou/kitolib/fixedpoint"
+	"github.com/kkevinchou/kitolib/physics/deterministic"
+)
+
+// newPile drops a pile of spheres and boxes onto the ground, bouncing off each other
+func newPile() *deterministic.World {
+	world := deterministic.NewWorld()
+
+	ground := deterministic.NewBoxBody(0, fixedpoint.Vec3FromFloats(10, 0.5, 10), 0)
+	ground.Position = fixedpoint.Vec3FromFloats(0, -0.5, 0)
+	world.AddBody(ground)
+
+	for i := 1; i <= 30; i++ {
+		position := fixedpoint.Vec3{fixedpoint.FromRatio(i%5, 2), fixedpoint.FromInt(i), fixedpoint.FromRatio(i%3, 3)}
+		var body *deterministic.Body
+		if i%2 == 0 {
+			body = deterministic.NewSphereBody(i, fixedpoint.FromRatio(1, 2), fixedpoint.One)
+		} else {
+			body = deterministic.NewBoxBody(i, fixedpoint.Vec3FromFloats(0.4, 0.4, 0.4), fixedpoint.FromInt(2))
+		}
+		body.Position = position
+		body.Restitution = fixedpoint.FromRatio(i%4, 10)
+		body.LinearVelocity = fixedpoint.Vec3{fixedpoint.FromRatio(i%7-3, 2), 0, 0}
+		world.AddBody(body)
+	}
+
+	return world
+}
+
+func simulate(steps int) []uint64 {
+	world := newPile()
+	hashes := make([]uint64, steps)
+	for i := 0; i < steps; i++ {
+		world.Step()
+		hashes[i] = world.StateHash()
+	}
+	return hashes
+}
+
+func TestRepeatedRunDeterminism(t *testing.T) {
+	expected := simulate(300)
+
+	for run := 0; run < 3; run++ {
+		hashes := simulate(300)
+		for i := range hashes {
+			if hashes[i] != expected[i] {
+				t.Fatalf("run %d desynced on step %d", run, i)
+			}
+		}
+	}
+}
+
+func TestConcurrentDeterminism(t *testing.T) {
+	expected := simulate(300)
+
+	results := make([][]uint64, 8)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = simulate(300)
+		}(i)
+	}
+	wg.Wait()
+
+	for run, hashes := range results {
+		if hashes[len(hashes)-1] != expected[len(expected)-1] {
+			t.Errorf("concurrent run %d ended with hash %x instead of %x", run, hashes[len(hashes)-1], expected[len(expected)-1])
+		}
+	}
+}
+
+// the hash after a fixed number of steps is pinned so that a code change that changes the
+// results is caught, as is a machine or compiler that gets different results when the tests
+// are run there. update it when the simulation is changed on purpose.
+func TestStateHashIsStable(t *testing.T) {
+	hashes := simulate(300)
+	const expected uint64 = 0xfe41067445626115
+	if hashes[len(hashes)-1] != expected {
+		t.Errorf("expected the pile to end with hash %#x but got %#x", expected, hashes[len(hashes)-1])
+	}
+}
+
+func TestStateHashDetectsDesync(t *testing.T) {
+	a := newPile()
+	b := newPile()
+	b.Bodies()[5].Position[0] += fixedpoint.Epsilon
+
+	if a.StateHash() == b.StateHash() {
+		t.Fatal("expected a one unit difference in position to change the hash")
+	}
+}
+
+func TestSphereRestsOnGround(t *testing.T) {
+	world := deterministic.NewWorld()
+	ground := deterministic.NewBoxBody(0, fixedpoint.Vec3FromFloats(10, 0.5, 10), 0)
+	ground.Position = fixedpoint.Vec3FromFloats(0, -0.5, 0)
+	ball := deterministic.NewSphereBody(1, fixedpoint.Half, fixedpoint.One)
+	ball.Position = fixedpoint.Vec3FromFloats(0, 3, 0)
+	ball.LinearVelocity = fixedpoint.Vec3FromFloats(2, 0, 0)
+	world.AddBody(ground)
+	world.AddBody(ball)
+
+	for i := 0; i < 60*5; i++ {
+		world.Step()
+	}
+
+	if y := ball.Position.Y().Float(); math.Abs(y-0.5) > 0.01 {
+		t.Errorf("expected the ball to rest on the ground at 0.5 but it's at %f", y)
+	}
+	// friction turns sliding into rolling, v = w * r
+	v := ball.LinearVelocity.X().Float()
+	w := ball.AngularVelocity.Z().Float()
+	if math.Abs(v+w*0.5) > 0.01 {
+		t.Errorf("expected the ball to roll without slipping but its velocity was %f with spin %f", v, w)
+	}
+}
@@ -0,0 +1,103 @@
+package deterministic
+
+import (
+	"github.com/kkevinchou/kitolib/fixedpoint"
+)
+
+type contactConstraint struct {
+	contact
+
+	rA       fixedpoint.Vec3
+	rB       fixedpoint.Vec3
+	tangents [2]fixedpoint.Vec3
+
+	normalMass  fixedpoint.Fixed
+	tangentMass [2]fixedpoint.Fixed
+	bias        fixedpoint.Fixed
+	friction    fixedpoint.Fixed
+
+	normalImpulse  fixedpoint.Fixed
+	tangentImpulse [2]fixedpoint.Fixed
+}
+
+func newContactConstraint(c contact, settings SolverSettings, dt fixedpoint.Fixed) *contactConstraint {
+	constraint := &contactConstraint{
+		contact:  c,
+		rA:       c.point.Sub(c.a.Position),
+		rB:       c.point.Sub(c.b.Position),
+		tangents: tangentBasis(c.normal),
+		friction: c.a.Friction.Mul(c.b.Friction).Sqrt(),
+	}
+
+	constraint.normalMass = constraint.mass(c.normal)
+	for i, tangent := range constraint.tangents {
+		constraint.tangentMass[i] = constraint.mass(tangent)
+	}
+
+	// push apart penetration beyond the slop
+	penetration := fixedpoint.Max(c.penetration-settings.PenetrationSlop, 0)
+	constraint.bias = -settings.Baumgarte.Mul(penetration).Div(dt)
+
+	// bounce if the bodies are closing fast enough
+	restitution := fixedpoint.Max(c.a.Restitution, c.b.Restitution)
+	closingVelocity := constraint.relativeVelocity().Dot(c.normal)
+	if closingVelocity < -settings.RestitutionThreshold {
+		constraint.bias = fixedpoint.Min(constraint.bias, restitution.Mul(closingVelocity))
+	}
+
+	return constraint
+}
+
+// mass returns the mass felt by an impulse along direction at the contact point
+func (c *contactConstraint) mass(direction fixedpoint.Vec3) fixedpoint.Fixed {
+	crossA := c.rA.Cross(direction)
+	crossB := c.rB.Cross(direction)
+	k := c.a.InverseMass + c.b.InverseMass +
+		crossA.LenSqr().Mul(c.a.InverseInertia) + crossB.LenSqr().Mul(c.b.InverseInertia)
+	if k == 0 {
+		return 0
+	}
+	return fixedpoint.One.Div(k)
+}
+
+// relativeVelocity returns the velocity of a relative to b at the contact point
+func (c *contactConstraint) relativeVelocity() fixedpoint.Vec3 {
+	return c.a.velocityAtPoint(c.rA).Sub(c.b.velocityAtPoint(c.rB))
+}
+
+func (c *contactConstraint) solve() {
+	// friction first so that the normal impulse has the final say on penetration
+	maxFriction := c.friction.Mul(c.normalImpulse)
+	for i, tangent := range c.tangents {
+		velocity := c.relativeVelocity().Dot(tangent)
+		lambda := -velocity.Mul(c.tangentMass[i])
+
+		oldImpulse := c.tangentImpulse[i]
+		c.tangentImpulse[i] = fixedpoint.Clamp(oldImpulse+lambda, -maxFriction, maxFriction)
+		c.apply(tangent.Mul(c.tangentImpulse[i] - oldImpulse))
+	}
+
+	velocity := c.relativeVelocity().Dot(c.normal)
+	lambda := -(velocity + c.bias).Mul(c.normalMass)
+
+	oldImpulse := c.normalImpulse
+	c.normalImpulse = fixedpoint.Max(oldImpulse+lambda, 0)
+	c.apply(c.normal.Mul(c.normalImpulse - oldImpulse))
+}
+
+// apply applies the impulse to a and the opposite impulse to b
+func (c *contactConstraint) apply(impulse fixedpoint.Vec3) {
+	c.a.applyImpulse(impulse, c.rA)
+	c.b.applyImpulse(impulse.Mul(-fixedpoint.One), c.rB)
+}
+
+// tangentBasis returns two unit vectors perpendicular to normal and each other
+func tangentBasis(normal fixedpoint.Vec3) [2]fixedpoint.Vec3 {
+	axis := fixedpoint.Vec3{fixedpoint.One, 0, 0}
+	if normal.X().Abs() > fixedpoint.FromFloat(0.57735) {
+		axis = fixedpoint.Vec3{0, fixedpoint.One, 0}
+	}
+	t1 := normal.Cross(axis).Normalize()
+	t2 := normal.Cross(t1)
+	return [2]fixedpoint.Vec3{t1, t2}
+}
@@ -0,0 +1,134 @@
+// Package deterministic is a fixed point physics world for lockstep networking. it only
+// simulates spheres and axis aligned boxes that don't rotate, there are no capsules,
+// triangle meshes or oriented boxes like in the float physics package. every client that
+// starts from the same state and steps the same number of times ends up with the same state
+// down to the bit, which can be checked with StateHash.
+// the tests only compare runs within one process against each other and against one pinned
+// hash, so they catch nondeterminism in the code but don't prove that other machines agree.
+package deterministic
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+
+	"github.com/kkevinchou/kitolib/fixedpoint"
+)
+
+type SolverSettings struct {
+	Iterations int
+	// Baumgarte is the fraction of the penetration that's resolved each step
+	Baumgarte fixedpoint.Fixed
+	// PenetrationSlop is the penetration allowed before bodies are pushed apart
+	PenetrationSlop fixedpoint.Fixed
+	// RestitutionThreshold is the closing speed below which collisions don't bounce
+	RestitutionThreshold fixedpoint.Fixed
+}
+
+// World steps bodies at a fixed timestep. bodies are stepped in the order they were added,
+// so clients need to add them in the same order.
+type World struct {
+	Gravity  fixedpoint.Vec3
+	Timestep fixedpoint.Fixed
+	Solver   SolverSettings
+
+	bodies []*Body
+}
+
+func NewWorld() *World {
+	return &World{
+		Gravity:  fixedpoint.Vec3FromFloats(0, -9.8, 0),
+		Timestep: fixedpoint.FromRatio(1, 60),
+		Solver: SolverSettings{
+			Iterations:           10,
+			Baumgarte:            fixedpoint.FromFloat(0.2),
+			PenetrationSlop:      fixedpoint.FromFloat(0.005),
+			RestitutionThreshold: fixedpoint.One,
+		},
+	}
+}
+
+func (w *World) AddBody(body *Body) {
+	w.bodies = append(w.bodies, body)
+}
+
+func (w *World) RemoveBody(id int) {
+	for i, body := range w.bodies {
+		if body.ID == id {
+			w.bodies = append(w.bodies[:i], w.bodies[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *World) Bodies() []*Body {
+	return w.bodies
+}
+
+// Step advances the simulation by a single timestep
+func (w *World) Step() {
+	dt := w.Timestep
+
+	for _, body := range w.bodies {
+		if body.IsStatic() {
+			continue
+		}
+		body.LinearVelocity = body.LinearVelocity.Add(w.Gravity.Mul(dt))
+	}
+
+	var constraints []*contactConstraint
+	for i := 0; i < len(w.bodies); i++ {
+		for j := i + 1; j < len(w.bodies); j++ {
+			a, b := w.bodies[i], w.bodies[j]
+			if a.IsStatic() && b.IsStatic() {
+				continue
+			}
+			if contact, ok := generateContact(a, b); ok {
+				constraints = append(constraints, newContactConstraint(contact, w.Solver, dt))
+			}
+		}
+	}
+
+	for i := 0; i < w.Solver.Iterations; i++ {
+		for _, constraint := range constraints {
+			constraint.solve()
+		}
+	}
+
+	for _, body := range w.bodies {
+		if body.IsStatic() {
+			continue
+		}
+		body.Position = body.Position.Add(body.LinearVelocity.Mul(dt))
+
+		halfDT := dt / 2
+		spin := fixedpoint.Quat{V: body.AngularVelocity.Mul(halfDT)}.Mul(body.Orientation)
+		body.Orientation = body.Orientation.Add(spin).Normalize()
+	}
+}
+
+// StateHash hashes the state of every body in the order they were added. clients in a
+// lockstep game can compare hashes after each step to detect when they've desynced.
+func (w *World) StateHash() uint64 {
+	hash := fnv.New64a()
+	var buffer [8]byte
+	write := func(value int64) {
+		binary.LittleEndian.PutUint64(buffer[:], uint64(value))
+		hash.Write(buffer[:])
+	}
+	writeVec3 := func(v fixedpoint.Vec3) {
+		for _, component := range v {
+			write(int64(component))
+		}
+	}
+
+	for _, body := range w.bodies {
+		write(int64(body.ID))
+		writeVec3(body.Position)
+		write(int64(body.Orientation.W))
+		writeVec3(body.Orientation.V)
+		writeVec3(body.LinearVelocity)
+		writeVec3(body.AngularVelocity)
+	}
+
+	return hash.Sum64()
+}
@@ -0,0 +1,185 @@
+package physics
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+const (
+	DefaultLayer uint32 = 1
+	AllLayers    uint32 = math.MaxUint32
+)
+
+// LayersCollide returns whether two bodies are allowed to touch. both bodies have to
+// include the other's layer in their mask, so a projectile layer that's left out of its
+// own mask won't collide with other projectiles.
+func LayersCollide(a, b *RigidBody) bool {
+	return a.Layer&b.Mask != 0 && b.Layer&a.Mask != 0
+}
+
+// TriggerEvent is reported when a body touches a trigger. when two triggers overlap an
+// event is reported for each of them.
+type TriggerEvent struct {
+	TriggerID int
+	OtherID   int
+}
+
+type TriggerEvents struct {
+	Enter []TriggerEvent
+	Stay  []TriggerEvent
+	Exit  []TriggerEvent
+}
+
+func (e *TriggerEvents) clear() {
+	e.Enter = nil
+	e.Stay = nil
+	e.Exit = nil
+}
+
+// updateTriggers diffs this step's trigger overlaps against the last step's
+func (w *World) updateTriggers(overlaps []TriggerEvent) {
+	w.triggerEvents.Exit = append(w.triggerEvents.Exit, w.removedOverlaps...)
+	w.removedOverlaps = nil
+
+	current := map[TriggerEvent]bool{}
+	for _, overlap := range overlaps {
+		current[overlap] = true
+		if w.triggerOverlaps[overlap] {
+			w.triggerEvents.Stay = append(w.triggerEvents.Stay, overlap)
+		} else {
+			w.triggerEvents.Enter = append(w.triggerEvents.Enter, overlap)
+		}
+	}
+
+	var exits []TriggerEvent
+	for overlap := range w.triggerOverlaps {
+		if !current[overlap] {
+			exits = append(exits, overlap)
+		}
+	}
+	sortTriggerEvents(exits)
+	w.triggerEvents.Exit = append(w.triggerEvents.Exit, exits...)
+
+	w.triggerOverlaps = current
+}
+
+// removeTriggerOverlaps holds on to the overlaps of a body that's removed from the world so that
+// they're reported as exits on the next step
+func (w *World) removeTriggerOverlaps(id int) {
+	var exits []TriggerEvent
+	for overlap := range w.triggerOverlaps {
+		if overlap.TriggerID == id || overlap.OtherID == id {
+			exits = append(exits, overlap)
+			delete(w.triggerOverlaps, overlap)
+		}
+	}
+	sortTriggerEvents(exits)
+	w.removedOverlaps = append(w.removedOverlaps, exits...)
+}
+
+func sortTriggerEvents(events []TriggerEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].TriggerID != events[j].TriggerID {
+			return events[i].TriggerID < events[j].TriggerID
+		}
+		return events[i].OtherID < events[j].OtherID
+	})
+}
+
+// triggerOverlaps returns the trigger events for a pair of overlapping bodies where at least one
+// is a trigger
+func triggerOverlaps(a, b *RigidBody) []TriggerEvent {
+	var overlaps []TriggerEvent
+	if a.IsTrigger {
+		overlaps = append(overlaps, TriggerEvent{TriggerID: a.ID, OtherID: b.ID})
+	}
+	if b.IsTrigger {
+		overlaps = append(overlaps, TriggerEvent{TriggerID: b.ID, OtherID: a.ID})
+	}
+	return overlaps
+}
+
+// bodyEntity lets a body be indexed by a spatial partition
+type bodyEntity struct {
+	body *RigidBody
+}
+
+func (e bodyEntity) GetID() int {
+	return e.body.ID
+}
+
+func (e bodyEntity) Position() mgl64.Vec3 {
+	return e.body.Position
+}
+
+func (e bodyEntity) BoundingBox() collider.BoundingBox {
+	return e.body.BoundingBox()
+}
+
+type bodyPair struct {
+	a *RigidBody
+	b *RigidBody
+}
+
+// candidatePairs returns the pairs of bodies with overlapping bounding boxes, in the order the
+// bodies were added to the world
+func (w *World) candidatePairs() []bodyPair {
+	var pairs []bodyPair
+
+	if w.Partition == nil {
+		for i := 0; i < len(w.bodies); i++ {
+			a := w.bodies[i]
+			boxA := a.BoundingBox()
+			for j := i + 1; j < len(w.bodies); j++ {
+				b := w.bodies[j]
+				boxB := b.BoundingBox()
+				if collision.CheckOverlapAABBAABB(&boxA, &boxB) {
+					pairs = append(pairs, bodyPair{a: a, b: b})
+				}
+			}
+		}
+		return pairs
+	}
+
+	if w.bodyIndices == nil {
+		w.bodyIndices = map[int]int{}
+	}
+	for id := range w.bodyIndices {
+		delete(w.bodyIndices, id)
+	}
+	w.bodyEntities = w.bodyEntities[:0]
+	for i, body := range w.bodies {
+		w.bodyIndices[body.ID] = i
+		w.bodyEntities = append(w.bodyEntities, bodyEntity{body: body})
+	}
+	w.Partition.IndexEntities(w.bodyEntities)
+
+	var candidates [][2]int
+	for _, pair := range w.Partition.Pairs() {
+		i, okA := w.bodyIndices[pair.A.GetID()]
+		j, okB := w.bodyIndices[pair.B.GetID()]
+		// the partition can be shared with entities that aren't bodies in this world
+		if !okA || !okB {
+			continue
+		}
+		if j < i {
+			i, j = j, i
+		}
+		candidates = append(candidates, [2]int{i, j})
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a][0] != candidates[b][0] {
+			return candidates[a][0] < candidates[b][0]
+		}
+		return candidates[a][1] < candidates[b][1]
+	})
+
+	for _, candidate := range candidates {
+		pairs = append(pairs, bodyPair{a: w.bodies[candidate[0]], b: w.bodies[candidate[1]]})
+	}
+	return pairs
+}
@@ -0,0 +1,175 @@
+package physics_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/physics"
+	"github.com/kkevinchou/kitolib/spatialpartition"
+)
+
+const projectileLayer uint32 = 1 << 1
+
+func TestTriggerEvents(t *testing.T) {
+	world := physics.NewWorld()
+	world.Gravity = mgl64.Vec3{}
+
+	trigger := physics.NewBoxBody(0, mgl64.Vec3{1, 1, 1}, 0)
+	trigger.IsTrigger = true
+	ball := physics.NewSphereBody(1, 0.5, 1)
+	ball.Position = mgl64.Vec3{-3, 0, 0}
+	ball.LinearVelocity = mgl64.Vec3{6, 0, 0}
+	world.AddBody(trigger)
+	world.AddBody(ball)
+
+	var enters, stays, exits int
+	for i := 0; i < 60; i++ {
+		world.Step()
+		events := world.TriggerEvents()
+		for _, event := range append(append(events.Enter, events.Stay...), events.Exit...) {
+			if event != (physics.TriggerEvent{TriggerID: 0, OtherID: 1}) {
+				t.Fatalf("unexpected trigger event %v", event)
+			}
+		}
+		enters += len(events.Enter)
+		stays += len(events.Stay)
+		exits += len(events.Exit)
+	}
+
+	if enters != 1 || exits != 1 {
+		t.Errorf("expected a single enter and exit but got %d enters and %d exits", enters, exits)
+	}
+	if stays == 0 {
+		t.Error("expected stay events while the ball was inside the trigger")
+	}
+	// triggers don't push anything
+	if !ball.LinearVelocity.ApproxEqual(mgl64.Vec3{6, 0, 0}) {
+		t.Errorf("expected the ball to pass through the trigger untouched but its velocity was %v", ball.LinearVelocity)
+	}
+}
+
+func TestTriggerEventsWithinUpdate(t *testing.T) {
+	world := physics.NewWorld()
+	world.Gravity = mgl64.Vec3{}
+
+	trigger := physics.NewSphereBody(0, 1, 0)
+	trigger.IsTrigger = true
+	ball := physics.NewSphereBody(1, 0.1, 1)
+	ball.Position = mgl64.Vec3{-1.2, 0, 0}
+	ball.LinearVelocity = mgl64.Vec3{6, 0, 0}
+	world.AddBody(trigger)
+	world.AddBody(ball)
+
+	// the ball enters the trigger partway through the update
+	world.Update(time.Duration(4.5 * world.Timestep * float64(time.Second)))
+	if events := world.TriggerEvents(); len(events.Enter) != 1 {
+		t.Errorf("expected the enter from within the update to be kept but got %v", events)
+	}
+}
+
+func TestSleepingBodyStaysInTrigger(t *testing.T) {
+	world := physics.NewWorld()
+
+	ground := physics.NewBoxBody(0, mgl64.Vec3{5, 0.5, 5}, 0)
+	ground.Position = mgl64.Vec3{0, -0.5, 0}
+	trigger := physics.NewBoxBody(1, mgl64.Vec3{2, 2, 2}, 0)
+	trigger.IsTrigger = true
+	box := physics.NewBoxBody(2, mgl64.Vec3{0.5, 0.5, 0.5}, 1)
+	box.Position = mgl64.Vec3{0, 0.5, 0}
+	world.AddBody(ground)
+	world.AddBody(trigger)
+	world.AddBody(box)
+
+	for i := 0; i < 120; i++ {
+		world.Step()
+		if exits := world.TriggerEvents().Exit; len(exits) > 0 {
+			t.Fatalf("expected the box to stay in the trigger but got exits %v on step %d", exits, i)
+		}
+	}
+	if !box.Sleeping() {
+		t.Fatal("expected the box to fall asleep")
+	}
+	if stays := world.TriggerEvents().Stay; len(stays) != 1 {
+		t.Errorf("expected the sleeping box to still be in the trigger but got %v", stays)
+	}
+
+	world.RemoveBody(box.ID)
+	world.Step()
+	if exits := world.TriggerEvents().Exit; len(exits) != 1 || exits[0].OtherID != box.ID {
+		t.Errorf("expected removing the box to exit the trigger but got %v", exits)
+	}
+}
+
+// projectiles hit other bodies but pass through each other
+func TestCollisionLayers(t *testing.T) {
+	world := physics.NewWorld()
+	world.Gravity = mgl64.Vec3{}
+
+	projectileA := physics.NewSphereBody(0, 0.5, 1)
+	projectileA.Position = mgl64.Vec3{-2, 0, 0}
+	projectileA.LinearVelocity = mgl64.Vec3{5, 0, 0}
+	projectileB := physics.NewSphereBody(1, 0.5, 1)
+	projectileB.Position = mgl64.Vec3{2, 0, 0}
+	projectileB.LinearVelocity = mgl64.Vec3{-5, 0, 0}
+	for _, projectile := range []*physics.RigidBody{projectileA, projectileB} {
+		projectile.Layer = projectileLayer
+		projectile.Mask = physics.AllLayers &^ projectileLayer
+	}
+
+	wall := physics.NewBoxBody(2, mgl64.Vec3{0.5, 5, 5}, 0)
+	wall.Position = mgl64.Vec3{5, 0, 0}
+
+	world.AddBody(projectileA)
+	world.AddBody(projectileB)
+	world.AddBody(wall)
+
+	for i := 0; i < 120; i++ {
+		world.Step()
+	}
+
+	if math.Abs(projectileA.Position.X()-4) > 0.05 {
+		t.Errorf("expected the projectile to pass the other projectile and stop at the wall but it ended up at %v", projectileA.Position)
+	}
+	if projectileB.Position.X() > -2 {
+		t.Errorf("expected the projectiles to pass through each other but it ended up at %v", projectileB.Position)
+	}
+}
+
+func TestPartitionBroadPhase(t *testing.T) {
+	newPile := func(partition spatialpartition.Broadphase) *physics.World {
+		world := physics.NewWorld()
+		world.Partition = partition
+
+		ground := physics.NewBoxBody(0, mgl64.Vec3{20, 0.5, 20}, 0)
+		ground.Position = mgl64.Vec3{0, -0.5, 0}
+		world.AddBody(ground)
+
+		for i := 1; i <= 20; i++ {
+			ball := physics.NewSphereBody(i, 0.5, 1)
+			ball.Position = mgl64.Vec3{float64(i%4) * 0.9, float64(i), float64(i%3) * 0.9}
+			world.AddBody(ball)
+		}
+		return world
+	}
+
+	bruteForce := newPile(nil)
+	partitioned := newPile(spatialpartition.NewSpatialPartition(4, 16))
+	tree := newPile(spatialpartition.NewAABBTree(0.1))
+	for i := 0; i < 180; i++ {
+		bruteForce.Step()
+		partitioned.Step()
+		tree.Step()
+	}
+
+	for i, body := range bruteForce.Bodies() {
+		for _, world := range []*physics.World{partitioned, tree} {
+			other := world.Bodies()[i]
+			if !reflect.DeepEqual(body.Position, other.Position) {
+				t.Fatalf("expected the broad phase %T to find the same contacts but body %d ended up at %v instead of %v", world.Partition, body.ID, other.Position, body.Position)
+			}
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package physics
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Joint constrains the relative motion of two bodies. joints are solved alongside contacts
+// and keep their accumulated impulses between steps to warm start the solver. to attach a
+// body to the world, join it to a static body.
+type Joint interface {
+	Bodies() (*RigidBody, *RigidBody)
+	prepare(settings SolverSettings, dt float64)
+	solve()
+	solvePosition()
+}
+
+// constraintRow is a single scalar velocity constraint J * v + bias = 0 where the jacobian
+// J is split into its linear and angular parts for each body
+type constraintRow struct {
+	linearA  mgl64.Vec3
+	angularA mgl64.Vec3
+	linearB  mgl64.Vec3
+	angularB mgl64.Vec3
+
+	mass float64
+	bias float64
+	// gamma softens the constraint, turning it into a spring
+	gamma float64
+
+	impulse    float64
+	minImpulse float64
+	maxImpulse float64
+}
+
+// setLinear sets up a row that constrains the anchors rA and rB along direction
+func (r *constraintRow) setLinear(rA, rB, direction mgl64.Vec3) {
+	r.linearA = direction.Mul(-1)
+	r.angularA = rA.Cross(direction).Mul(-1)
+	r.linearB = direction
+	r.angularB = rB.Cross(direction)
+}
+
+// setAngular sets up a row that constrains the relative angular velocity about axis
+func (r *constraintRow) setAngular(axis mgl64.Vec3) {
+	r.linearA = mgl64.Vec3{}
+	r.angularA = axis.Mul(-1)
+	r.linearB = mgl64.Vec3{}
+	r.angularB = axis
+}
+
+// inverseMass returns the inverse of the mass felt along the row, ignoring softness
+func (r *constraintRow) inverseMass(a, b *RigidBody) float64 {
+	return a.InverseMass*r.linearA.Dot(r.linearA) +
+		r.angularA.Dot(a.InverseInertiaWorld().Mul3x1(r.angularA)) +
+		b.InverseMass*r.linearB.Dot(r.linearB) +
+		r.angularB.Dot(b.InverseInertiaWorld().Mul3x1(r.angularB))
+}
+
+func (r *constraintRow) prepare(a, b *RigidBody, settings SolverSettings) {
+	k := r.inverseMass(a, b) + r.gamma
+
+	r.mass = 0
+	if k > 0 {
+		r.mass = 1 / k
+	}
+
+	if settings.WarmStarting {
+		r.apply(a, b, r.impulse)
+	} else {
+		r.impulse = 0
+	}
+}
+
+func (r *constraintRow) solve(a, b *RigidBody) {
+	jv := r.linearA.Dot(a.LinearVelocity) + r.angularA.Dot(a.AngularVelocity) +
+		r.linearB.Dot(b.LinearVelocity) + r.angularB.Dot(b.AngularVelocity)
+
+	lambda := -r.mass * (jv + r.bias + r.gamma*r.impulse)
+
+	oldImpulse := r.impulse
+	r.impulse = mgl64.Clamp(oldImpulse+lambda, r.minImpulse, r.maxImpulse)
+	r.apply(a, b, r.impulse-oldImpulse)
+}
+
+func (r *constraintRow) apply(a, b *RigidBody, lambda float64) {
+	a.LinearVelocity = a.LinearVelocity.Add(r.linearA.Mul(lambda * a.InverseMass))
+	a.AngularVelocity = a.AngularVelocity.Add(a.InverseInertiaWorld().Mul3x1(r.angularA.Mul(lambda)))
+	b.LinearVelocity = b.LinearVelocity.Add(r.linearB.Mul(lambda * b.InverseMass))
+	b.AngularVelocity = b.AngularVelocity.Add(b.InverseInertiaWorld().Mul3x1(r.angularB.Mul(lambda)))
+}
+
+// applyPosition moves the bodies along the row's jacobian, used to correct drift directly
+func (r *constraintRow) applyPosition(a, b *RigidBody, lambda float64) {
+	if !a.IsStatic() {
+		a.Position = a.Position.Add(r.linearA.Mul(lambda * a.InverseMass))
+		a.Orientation = rotateBy(a.Orientation, a.InverseInertiaWorld().Mul3x1(r.angularA.Mul(lambda)))
+	}
+	if !b.IsStatic() {
+		b.Position = b.Position.Add(r.linearB.Mul(lambda * b.InverseMass))
+		b.Orientation = rotateBy(b.Orientation, b.InverseInertiaWorld().Mul3x1(r.angularB.Mul(lambda)))
+	}
+}
+
+// rotateBy rotates orientation by the small rotation vector rotation
+func rotateBy(orientation mgl64.Quat, rotation mgl64.Vec3) mgl64.Quat {
+	spin := mgl64.Quat{W: 0, V: rotation.Mul(0.5)}.Mul(orientation)
+	return orientation.Add(spin).Normalize()
+}
+
+func unbounded(r *constraintRow) {
+	r.minImpulse = math.Inf(-1)
+	r.maxImpulse = math.Inf(1)
+}
+
+// anchorPoint holds an anchor in the local space of each body
+type anchorPoint struct {
+	localA mgl64.Vec3
+	localB mgl64.Vec3
+}
+
+func newAnchorPoint(a, b *RigidBody, anchor mgl64.Vec3) anchorPoint {
+	return anchorPoint{
+		localA: a.Orientation.Conjugate().Rotate(anchor.Sub(a.Position)),
+		localB: b.Orientation.Conjugate().Rotate(anchor.Sub(b.Position)),
+	}
+}
+
+// worldOffsets returns the anchor offsets from each body's center of mass in world space
+func (p anchorPoint) worldOffsets(a, b *RigidBody) (mgl64.Vec3, mgl64.Vec3) {
+	return a.Orientation.Rotate(p.localA), b.Orientation.Rotate(p.localB)
+}
+
+// pointConstraint keeps the anchors of both bodies at the same point, it's the base of
+// most of the joints. the three axes are solved together as a block since solving them one
+// at a time converges slowly when the anchors are offset from the centers of mass.
+type pointConstraint struct {
+	anchor anchorPoint
+
+	rA      mgl64.Vec3
+	rB      mgl64.Vec3
+	mass    mgl64.Mat3
+	bias    mgl64.Vec3
+	impulse mgl64.Vec3
+}
+
+func (c *pointConstraint) prepare(a, b *RigidBody, settings SolverSettings, dt float64) {
+	c.rA, c.rB = c.anchor.worldOffsets(a, b)
+	separation := b.Position.Add(c.rB).Sub(a.Position.Add(c.rA))
+	c.bias = separation.Mul(settings.Baumgarte / dt)
+
+	k := pointEffectiveMass(a, b, c.rA, c.rB)
+	c.mass = mgl64.Mat3{}
+	if k.Det() != 0 {
+		c.mass = k.Inv()
+	}
+
+	if settings.WarmStarting {
+		c.apply(a, b, c.impulse)
+	} else {
+		c.impulse = mgl64.Vec3{}
+	}
+}
+
+func (c *pointConstraint) solve(a, b *RigidBody) {
+	velocityA := a.LinearVelocity.Add(a.AngularVelocity.Cross(c.rA))
+	velocityB := b.LinearVelocity.Add(b.AngularVelocity.Cross(c.rB))
+	cdot := velocityB.Sub(velocityA)
+
+	lambda := c.mass.Mul3x1(cdot.Add(c.bias).Mul(-1))
+	c.impulse = c.impulse.Add(lambda)
+	c.apply(a, b, lambda)
+}
+
+// solvePosition moves the bodies so that the anchors line up again
+func (c *pointConstraint) solvePosition(a, b *RigidBody) {
+	rA, rB := c.anchor.worldOffsets(a, b)
+	separation := b.Position.Add(rB).Sub(a.Position.Add(rA))
+	if separation.Len() < epsilon {
+		return
+	}
+
+	k := pointEffectiveMass(a, b, rA, rB)
+	if k.Det() == 0 {
+		return
+	}
+
+	impulse := k.Inv().Mul3x1(separation.Mul(-1))
+	a.applyPositionImpulse(impulse.Mul(-1), rA)
+	b.applyPositionImpulse(impulse, rB)
+}
+
+// apply applies the impulse to b and the opposite impulse to a
+func (c *pointConstraint) apply(a, b *RigidBody, impulse mgl64.Vec3) {
+	a.applyImpulse(impulse.Mul(-1), c.rA)
+	b.applyImpulse(impulse, c.rB)
+}
+
+// pointEffectiveMass returns the inverse of the mass felt by an impulse at the anchors
+func pointEffectiveMass(a, b *RigidBody, rA, rB mgl64.Vec3) mgl64.Mat3 {
+	skewA := skew(rA)
+	skewB := skew(rB)
+	return mgl64.Ident3().Mul(a.InverseMass + b.InverseMass).
+		Sub(skewA.Mul3(a.InverseInertiaWorld()).Mul3(skewA)).
+		Sub(skewB.Mul3(b.InverseInertiaWorld()).Mul3(skewB))
+}
+
+// skew returns the matrix that performs the cross product v x u when multiplied with u
+func skew(v mgl64.Vec3) mgl64.Mat3 {
+	return mgl64.Mat3FromRows(
+		mgl64.Vec3{0, -v.Z(), v.Y()},
+		mgl64.Vec3{v.Z(), 0, -v.X()},
+		mgl64.Vec3{-v.Y(), v.X(), 0},
+	)
+}
+
+// separation returns the distance between the two anchors, which is zero when the joint
+// is satisfied
+func (c *pointConstraint) separation(a, b *RigidBody) float64 {
+	rA, rB := c.anchor.worldOffsets(a, b)
+	return b.Position.Add(rB).Sub(a.Position.Add(rA)).Len()
+}
+
+// relativeRotation returns the rotation of b relative to its rest orientation in a's frame
+func relativeRotation(a, b *RigidBody, rest mgl64.Quat) mgl64.Quat {
+	delta := a.Orientation.Mul(rest).Conjugate().Mul(b.Orientation)
+	// pick the shortest rotation
+	if delta.W < 0 {
+		delta = delta.Scale(-1)
+	}
+	return delta
+}
+
+// twistAngle returns the rotation of b about localAxis (in b's local space) relative to its
+// rest orientation
+func twistAngle(a, b *RigidBody, rest mgl64.Quat, localAxis mgl64.Vec3) float64 {
+	delta := relativeRotation(a, b, rest)
+	return 2 * math.Atan2(delta.V.Dot(localAxis), delta.W)
+}
+
+// perpendiculars returns two unit vectors perpendicular to axis and each other
+func perpendiculars(axis mgl64.Vec3) (mgl64.Vec3, mgl64.Vec3) {
+	basis := tangentBasis(axis)
+	return basis[0], basis[1]
+}
+
+// prepareAngularLimit sets up rows that keep angle within [lower, upper] about axis. both rows
+// are always prepared, away from a limit the row's bias lets the bodies rotate up to it within
+// the step so the row only pushes back once that much would overshoot it.
+func prepareAngularLimit(lowerRow, upperRow *constraintRow, a, b *RigidBody, axis mgl64.Vec3, angle, lower, upper float64, settings SolverSettings, dt float64) {
+	lowerRow.setAngular(axis)
+	lowerRow.bias = angularLimitBias(angle-lower, settings, dt)
+	lowerRow.minImpulse = 0
+	lowerRow.maxImpulse = math.Inf(1)
+
+	upperRow.setAngular(axis.Mul(-1))
+	upperRow.bias = angularLimitBias(upper-angle, settings, dt)
+	upperRow.minImpulse = 0
+	upperRow.maxImpulse = math.Inf(1)
+
+	lowerRow.prepare(a, b, settings)
+	upperRow.prepare(a, b, settings)
+}
+
+// angularLimitBias lets the bodies rotate up to the limit within a step and pushes them back
+// if they're past it
+func angularLimitBias(c float64, settings SolverSettings, dt float64) float64 {
+	if c < 0 {
+		return settings.Baumgarte / dt * c
+	}
+	return c / dt
+}
@@ -0,0 +1,399 @@
+package physics
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// BallSocketJoint pins a point on each body together while letting them rotate freely
+type BallSocketJoint struct {
+	A *RigidBody
+	B *RigidBody
+
+	point pointConstraint
+}
+
+// NewBallSocketJoint creates a joint with its anchor at a point in world space
+func NewBallSocketJoint(a, b *RigidBody, anchor mgl64.Vec3) *BallSocketJoint {
+	return &BallSocketJoint{A: a, B: b, point: pointConstraint{anchor: newAnchorPoint(a, b, anchor)}}
+}
+
+func (j *BallSocketJoint) Bodies() (*RigidBody, *RigidBody) {
+	return j.A, j.B
+}
+
+// Separation returns the distance between the two anchors
+func (j *BallSocketJoint) Separation() float64 {
+	return j.point.separation(j.A, j.B)
+}
+
+func (j *BallSocketJoint) prepare(settings SolverSettings, dt float64) {
+	j.point.prepare(j.A, j.B, settings, dt)
+}
+
+func (j *BallSocketJoint) solvePosition() {
+	j.point.solvePosition(j.A, j.B)
+}
+
+func (j *BallSocketJoint) solve() {
+	j.point.solve(j.A, j.B)
+}
+
+// FixedJoint locks the position and orientation of two bodies relative to each other
+type FixedJoint struct {
+	A *RigidBody
+	B *RigidBody
+
+	point       pointConstraint
+	rest        mgl64.Quat
+	angularRows [3]constraintRow
+}
+
+func NewFixedJoint(a, b *RigidBody, anchor mgl64.Vec3) *FixedJoint {
+	return &FixedJoint{
+		A:     a,
+		B:     b,
+		point: pointConstraint{anchor: newAnchorPoint(a, b, anchor)},
+		rest:  a.Orientation.Conjugate().Mul(b.Orientation),
+	}
+}
+
+func (j *FixedJoint) Bodies() (*RigidBody, *RigidBody) {
+	return j.A, j.B
+}
+
+func (j *FixedJoint) Separation() float64 {
+	return j.point.separation(j.A, j.B)
+}
+
+// AngularError returns how far in radians the bodies have rotated away from each other
+func (j *FixedJoint) AngularError() float64 {
+	delta := relativeRotation(j.A, j.B, j.rest)
+	return 2 * math.Acos(math.Min(delta.W, 1))
+}
+
+func (j *FixedJoint) prepare(settings SolverSettings, dt float64) {
+	j.point.prepare(j.A, j.B, settings, dt)
+
+	// small angle approximation of the rotation error in world space
+	delta := relativeRotation(j.A, j.B, j.rest)
+	angularError := j.B.Orientation.Rotate(delta.V.Mul(2))
+
+	axes := [3]mgl64.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for i, axis := range axes {
+		row := &j.angularRows[i]
+		row.setAngular(axis)
+		row.bias = settings.Baumgarte / dt * angularError.Dot(axis)
+		unbounded(row)
+		row.prepare(j.A, j.B, settings)
+	}
+}
+
+func (j *FixedJoint) solvePosition() {
+	j.point.solvePosition(j.A, j.B)
+}
+
+func (j *FixedJoint) solve() {
+	for i := range j.angularRows {
+		j.angularRows[i].solve(j.A, j.B)
+	}
+	j.point.solve(j.A, j.B)
+}
+
+// HingeJoint lets the bodies rotate relative to each other about a single axis, like a door.
+// the rotation can be limited to a range of angles and driven by a motor.
+type HingeJoint struct {
+	A *RigidBody
+	B *RigidBody
+
+	EnableLimit bool
+	LowerAngle  float64
+	UpperAngle  float64
+
+	EnableMotor bool
+	// MotorSpeed is the target angular speed of b relative to a in radians per second
+	MotorSpeed     float64
+	MaxMotorTorque float64
+
+	point      pointConstraint
+	rest       mgl64.Quat
+	localAxisA mgl64.Vec3
+	localAxisB mgl64.Vec3
+
+	axisRows [2]constraintRow
+	lowerRow constraintRow
+	upperRow constraintRow
+	motorRow constraintRow
+}
+
+// NewHingeJoint creates a hinge at a point in world space rotating about an axis in world space
+func NewHingeJoint(a, b *RigidBody, anchor mgl64.Vec3, axis mgl64.Vec3) *HingeJoint {
+	axis = axis.Normalize()
+	return &HingeJoint{
+		A:          a,
+		B:          b,
+		point:      pointConstraint{anchor: newAnchorPoint(a, b, anchor)},
+		rest:       a.Orientation.Conjugate().Mul(b.Orientation),
+		localAxisA: a.Orientation.Conjugate().Rotate(axis),
+		localAxisB: b.Orientation.Conjugate().Rotate(axis),
+	}
+}
+
+func (j *HingeJoint) Bodies() (*RigidBody, *RigidBody) {
+	return j.A, j.B
+}
+
+func (j *HingeJoint) Separation() float64 {
+	return j.point.separation(j.A, j.B)
+}
+
+// Angle returns the rotation of b about the hinge relative to where it started
+func (j *HingeJoint) Angle() float64 {
+	return twistAngle(j.A, j.B, j.rest, j.localAxisB)
+}
+
+// AxisError returns the angle in radians between the hinge axis of each body
+func (j *HingeJoint) AxisError() float64 {
+	axisA := j.A.Orientation.Rotate(j.localAxisA)
+	axisB := j.B.Orientation.Rotate(j.localAxisB)
+	return math.Acos(mgl64.Clamp(axisA.Dot(axisB), -1, 1))
+}
+
+func (j *HingeJoint) prepare(settings SolverSettings, dt float64) {
+	j.point.prepare(j.A, j.B, settings, dt)
+
+	axisA := j.A.Orientation.Rotate(j.localAxisA)
+	axisB := j.B.Orientation.Rotate(j.localAxisB)
+
+	// keep b's axis perpendicular to the two vectors perpendicular to a's axis
+	p1, p2 := perpendiculars(axisA)
+	for i, p := range [2]mgl64.Vec3{p1, p2} {
+		row := &j.axisRows[i]
+		row.setAngular(axisB.Cross(p))
+		row.bias = settings.Baumgarte / dt * axisB.Dot(p)
+		unbounded(row)
+		row.prepare(j.A, j.B, settings)
+	}
+
+	if j.EnableLimit {
+		prepareAngularLimit(&j.lowerRow, &j.upperRow, j.A, j.B, axisB, j.Angle(), j.LowerAngle, j.UpperAngle, settings, dt)
+	} else {
+		j.lowerRow.impulse = 0
+		j.upperRow.impulse = 0
+	}
+
+	if j.EnableMotor {
+		j.motorRow.setAngular(axisB)
+		j.motorRow.bias = -j.MotorSpeed
+		j.motorRow.minImpulse = -j.MaxMotorTorque * dt
+		j.motorRow.maxImpulse = j.MaxMotorTorque * dt
+		j.motorRow.prepare(j.A, j.B, settings)
+	} else {
+		j.motorRow.impulse = 0
+	}
+}
+
+func (j *HingeJoint) solvePosition() {
+	j.point.solvePosition(j.A, j.B)
+}
+
+func (j *HingeJoint) solve() {
+	if j.EnableMotor {
+		j.motorRow.solve(j.A, j.B)
+	}
+	if j.EnableLimit {
+		j.lowerRow.solve(j.A, j.B)
+		j.upperRow.solve(j.A, j.B)
+	}
+	for i := range j.axisRows {
+		j.axisRows[i].solve(j.A, j.B)
+	}
+	j.point.solve(j.A, j.B)
+}
+
+// DistanceJoint keeps two anchor points at a fixed distance from each other. setting a
+// frequency turns the joint into a damped spring, like a rope segment or a suspension.
+type DistanceJoint struct {
+	A *RigidBody
+	B *RigidBody
+
+	Length float64
+	// Frequency of the spring in hertz, zero makes the joint rigid
+	Frequency    float64
+	DampingRatio float64
+
+	localAnchorA mgl64.Vec3
+	localAnchorB mgl64.Vec3
+	row          constraintRow
+}
+
+// NewDistanceJoint creates a joint between anchors in world space, the length is set to
+// the current distance between them
+func NewDistanceJoint(a, b *RigidBody, anchorA, anchorB mgl64.Vec3) *DistanceJoint {
+	return &DistanceJoint{
+		A:            a,
+		B:            b,
+		Length:       anchorB.Sub(anchorA).Len(),
+		localAnchorA: a.Orientation.Conjugate().Rotate(anchorA.Sub(a.Position)),
+		localAnchorB: b.Orientation.Conjugate().Rotate(anchorB.Sub(b.Position)),
+	}
+}
+
+func (j *DistanceJoint) Bodies() (*RigidBody, *RigidBody) {
+	return j.A, j.B
+}
+
+// CurrentLength returns the distance between the two anchors
+func (j *DistanceJoint) CurrentLength() float64 {
+	rA := j.A.Orientation.Rotate(j.localAnchorA)
+	rB := j.B.Orientation.Rotate(j.localAnchorB)
+	return j.B.Position.Add(rB).Sub(j.A.Position.Add(rA)).Len()
+}
+
+func (j *DistanceJoint) prepare(settings SolverSettings, dt float64) {
+	rA := j.A.Orientation.Rotate(j.localAnchorA)
+	rB := j.B.Orientation.Rotate(j.localAnchorB)
+	delta := j.B.Position.Add(rB).Sub(j.A.Position.Add(rA))
+
+	length := delta.Len()
+	direction := mgl64.Vec3{0, 1, 0}
+	if length > epsilon {
+		direction = delta.Mul(1 / length)
+	}
+	c := length - j.Length
+
+	row := &j.row
+	row.setLinear(rA, rB, direction)
+	unbounded(row)
+	row.gamma = 0
+	row.bias = settings.Baumgarte / dt * c
+
+	if k := row.inverseMass(j.A, j.B); j.Frequency > 0 && k > 0 {
+		// soft constraint, see Erin Catto's "Soft Constraints" GDC talk
+		mass := 1 / k
+		omega := 2 * math.Pi * j.Frequency
+		stiffness := mass * omega * omega
+		damping := 2 * mass * j.DampingRatio * omega
+
+		row.gamma = 1 / (dt * (damping + dt*stiffness))
+		row.bias = c * dt * stiffness * row.gamma
+	}
+	row.prepare(j.A, j.B, settings)
+}
+
+func (j *DistanceJoint) solve() {
+	j.row.solve(j.A, j.B)
+}
+
+// solvePosition pulls rigid joints back to their length, springs are left alone
+func (j *DistanceJoint) solvePosition() {
+	if j.Frequency > 0 {
+		return
+	}
+
+	rA := j.A.Orientation.Rotate(j.localAnchorA)
+	rB := j.B.Orientation.Rotate(j.localAnchorB)
+	delta := j.B.Position.Add(rB).Sub(j.A.Position.Add(rA))
+	length := delta.Len()
+	if length < epsilon {
+		return
+	}
+
+	var row constraintRow
+	row.setLinear(rA, rB, delta.Mul(1/length))
+	if k := row.inverseMass(j.A, j.B); k > 0 {
+		row.applyPosition(j.A, j.B, -(length-j.Length)/k)
+	}
+}
+
+// ConeTwistJoint is a ball socket where the swing of b's twist axis away from a's is
+// limited to a cone and the twist about the axis is limited to a range, like a shoulder
+// in a ragdoll
+type ConeTwistJoint struct {
+	A *RigidBody
+	B *RigidBody
+
+	// SwingSpan is the half angle of the cone in radians
+	SwingSpan float64
+	// TwistSpan limits the twist to [-TwistSpan, TwistSpan] radians
+	TwistSpan float64
+
+	point      pointConstraint
+	rest       mgl64.Quat
+	localAxisA mgl64.Vec3
+	localAxisB mgl64.Vec3
+
+	swingRow      constraintRow
+	twistLowerRow constraintRow
+	twistUpperRow constraintRow
+}
+
+func NewConeTwistJoint(a, b *RigidBody, anchor mgl64.Vec3, twistAxis mgl64.Vec3, swingSpan, twistSpan float64) *ConeTwistJoint {
+	twistAxis = twistAxis.Normalize()
+	return &ConeTwistJoint{
+		A:          a,
+		B:          b,
+		SwingSpan:  swingSpan,
+		TwistSpan:  twistSpan,
+		point:      pointConstraint{anchor: newAnchorPoint(a, b, anchor)},
+		rest:       a.Orientation.Conjugate().Mul(b.Orientation),
+		localAxisA: a.Orientation.Conjugate().Rotate(twistAxis),
+		localAxisB: b.Orientation.Conjugate().Rotate(twistAxis),
+	}
+}
+
+func (j *ConeTwistJoint) Bodies() (*RigidBody, *RigidBody) {
+	return j.A, j.B
+}
+
+func (j *ConeTwistJoint) Separation() float64 {
+	return j.point.separation(j.A, j.B)
+}
+
+// SwingAngle returns the angle between the twist axes of the two bodies
+func (j *ConeTwistJoint) SwingAngle() float64 {
+	axisA := j.A.Orientation.Rotate(j.localAxisA)
+	axisB := j.B.Orientation.Rotate(j.localAxisB)
+	return math.Acos(mgl64.Clamp(axisA.Dot(axisB), -1, 1))
+}
+
+// TwistAngle returns the rotation of b about its twist axis relative to where it started
+func (j *ConeTwistJoint) TwistAngle() float64 {
+	return twistAngle(j.A, j.B, j.rest, j.localAxisB)
+}
+
+func (j *ConeTwistJoint) prepare(settings SolverSettings, dt float64) {
+	j.point.prepare(j.A, j.B, settings, dt)
+
+	axisA := j.A.Orientation.Rotate(j.localAxisA)
+	axisB := j.B.Orientation.Rotate(j.localAxisB)
+
+	// swing is measured about the axis perpendicular to both twist axes
+	swingAxis := axisA.Cross(axisB)
+	if swingAxis.LenSqr() > epsilon {
+		swingAxis = swingAxis.Normalize()
+		row := &j.swingRow
+		row.setAngular(swingAxis.Mul(-1))
+		row.bias = angularLimitBias(j.SwingSpan-j.SwingAngle(), settings, dt)
+		row.minImpulse = 0
+		row.maxImpulse = math.Inf(1)
+		row.prepare(j.A, j.B, settings)
+	} else {
+		j.swingRow = constraintRow{}
+	}
+
+	twist := j.TwistAngle()
+	prepareAngularLimit(&j.twistLowerRow, &j.twistUpperRow, j.A, j.B, axisB, twist, -j.TwistSpan, j.TwistSpan, settings, dt)
+}
+
+func (j *ConeTwistJoint) solvePosition() {
+	j.point.solvePosition(j.A, j.B)
+}
+
+func (j *ConeTwistJoint) solve() {
+	j.swingRow.solve(j.A, j.B)
+	j.twistLowerRow.solve(j.A, j.B)
+	j.twistUpperRow.solve(j.A, j.B)
+	j.point.solve(j.A, j.B)
+}
@@ -0,0 +1,234 @@
+package physics_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/physics"
+)
+
+func newJointWorld() *physics.World {
+	world := physics.NewWorld()
+	world.Sleep.Enabled = false
+	return world
+}
+
+func newAnchorBody(id int, position mgl64.Vec3) *physics.RigidBody {
+	anchor := physics.NewSphereBody(id, 0.1, 0)
+	anchor.Position = position
+	return anchor
+}
+
+func TestBallSocketPendulumDrift(t *testing.T) {
+	world := newJointWorld()
+	anchor := newAnchorBody(0, mgl64.Vec3{0, 10, 0})
+	bob := physics.NewSphereBody(1, 0.25, 1)
+	bob.Position = mgl64.Vec3{2, 10, 0}
+	world.AddBody(anchor)
+	world.AddBody(bob)
+
+	joint := physics.NewBallSocketJoint(anchor, bob, anchor.Position)
+	world.AddJoint(joint)
+
+	var maxSeparation, maxHeight float64
+	for i := 0; i < 60*60; i++ {
+		world.Step()
+		maxSeparation = math.Max(maxSeparation, joint.Separation())
+		maxHeight = math.Max(maxHeight, bob.Position.Y())
+	}
+
+	if maxSeparation > 0.01 {
+		t.Errorf("expected the joint to hold together but the anchors drifted %f apart", maxSeparation)
+	}
+	if maxHeight > 10.01 {
+		t.Errorf("expected the pendulum to not gain energy but it swung up to %f", maxHeight)
+	}
+}
+
+func TestBallSocketChainDrift(t *testing.T) {
+	world := newJointWorld()
+	anchor := newAnchorBody(0, mgl64.Vec3{0, 20, 0})
+	world.AddBody(anchor)
+
+	var joints []*physics.BallSocketJoint
+	prev := anchor
+	for i := 1; i <= 10; i++ {
+		link := physics.NewCapsuleBody(i, 0.1, 0.4, 1)
+		link.Position = mgl64.Vec3{float64(i) - 0.5, 20, 0}
+		link.Orientation = mgl64.QuatRotate(math.Pi/2, mgl64.Vec3{0, 0, 1})
+		world.AddBody(link)
+
+		joint := physics.NewBallSocketJoint(prev, link, mgl64.Vec3{float64(i) - 1, 20, 0})
+		world.AddJoint(joint)
+		joints = append(joints, joint)
+		prev = link
+	}
+
+	var maxSeparation float64
+	for i := 0; i < 60*30; i++ {
+		world.Step()
+		for _, joint := range joints {
+			maxSeparation = math.Max(maxSeparation, joint.Separation())
+		}
+	}
+
+	if maxSeparation > 0.05 {
+		t.Errorf("expected the chain to hold together but the anchors drifted %f apart", maxSeparation)
+	}
+}
+
+func TestFixedJointDrift(t *testing.T) {
+	world := newJointWorld()
+	anchor := newAnchorBody(0, mgl64.Vec3{0, 10, 0})
+	body := physics.NewCapsuleBody(1, 0.25, 0.5, 1)
+	body.Position = mgl64.Vec3{1, 10, 0}
+	world.AddBody(anchor)
+	world.AddBody(body)
+
+	joint := physics.NewFixedJoint(anchor, body, anchor.Position)
+	world.AddJoint(joint)
+
+	for i := 0; i < 60*20; i++ {
+		world.Step()
+	}
+
+	if joint.Separation() > 0.01 {
+		t.Errorf("expected the fixed joint to hold together but the anchors drifted %f apart", joint.Separation())
+	}
+	if joint.AngularError() > 0.01 {
+		t.Errorf("expected the fixed joint to hold its orientation but it rotated %f radians", joint.AngularError())
+	}
+}
+
+// a door swinging about a vertical hinge, driven by a motor into its limit
+func TestHingeMotorAndLimit(t *testing.T) {
+	world := newJointWorld()
+	frame := newAnchorBody(0, mgl64.Vec3{0, 1, 0})
+	door := physics.NewCapsuleBody(1, 0.1, 0.5, 1)
+	door.Position = mgl64.Vec3{0.6, 1, 0}
+	door.Orientation = mgl64.QuatRotate(math.Pi/2, mgl64.Vec3{0, 0, 1})
+	world.AddBody(frame)
+	world.AddBody(door)
+
+	hinge := physics.NewHingeJoint(frame, door, frame.Position, mgl64.Vec3{0, 1, 0})
+	hinge.EnableMotor = true
+	hinge.MotorSpeed = 1
+	hinge.MaxMotorTorque = 100
+	world.AddJoint(hinge)
+
+	for i := 0; i < 30; i++ {
+		world.Step()
+	}
+	if math.Abs(door.AngularVelocity.Y()-1) > 0.05 {
+		t.Errorf("expected the motor to spin the door at 1 rad/s but got %v", door.AngularVelocity)
+	}
+
+	hinge.EnableLimit = true
+	hinge.LowerAngle = -math.Pi / 4
+	hinge.UpperAngle = math.Pi / 4
+
+	var maxSeparation, maxAxisError, maxAngle float64
+	for i := 0; i < 60*10; i++ {
+		world.Step()
+		maxSeparation = math.Max(maxSeparation, hinge.Separation())
+		maxAxisError = math.Max(maxAxisError, hinge.AxisError())
+		maxAngle = math.Max(maxAngle, hinge.Angle())
+	}
+
+	if maxAngle > math.Pi/4+0.02 {
+		t.Errorf("expected the door to stop at its limit of %f but reached %f", math.Pi/4, maxAngle)
+	}
+	if math.Abs(hinge.Angle()-math.Pi/4) > 0.02 {
+		t.Errorf("expected the motor to hold the door at its limit but it was at %f", hinge.Angle())
+	}
+	if maxSeparation > 0.01 {
+		t.Errorf("expected the hinge to hold together but the anchors drifted %f apart", maxSeparation)
+	}
+	if maxAxisError > 0.01 {
+		t.Errorf("expected the hinge axes to stay aligned but they drifted %f radians apart", maxAxisError)
+	}
+}
+
+func TestRigidDistanceJoint(t *testing.T) {
+	world := newJointWorld()
+	anchor := newAnchorBody(0, mgl64.Vec3{0, 10, 0})
+	bob := physics.NewSphereBody(1, 0.25, 1)
+	bob.Position = mgl64.Vec3{3, 10, 0}
+	world.AddBody(anchor)
+	world.AddBody(bob)
+
+	joint := physics.NewDistanceJoint(anchor, bob, anchor.Position, bob.Position)
+	world.AddJoint(joint)
+
+	var maxError float64
+	for i := 0; i < 60*30; i++ {
+		world.Step()
+		maxError = math.Max(maxError, math.Abs(joint.CurrentLength()-joint.Length))
+	}
+
+	if maxError > 0.01 {
+		t.Errorf("expected the distance to stay at %f but it was off by up to %f", joint.Length, maxError)
+	}
+}
+
+// a weight hanging from a critically damped spring settles where the spring force
+// balances gravity
+func TestDistanceJointSpring(t *testing.T) {
+	world := newJointWorld()
+	anchor := newAnchorBody(0, mgl64.Vec3{0, 10, 0})
+	bob := physics.NewSphereBody(1, 0.25, 1)
+	bob.Position = mgl64.Vec3{0, 8, 0}
+	world.AddBody(anchor)
+	world.AddBody(bob)
+
+	joint := physics.NewDistanceJoint(anchor, bob, anchor.Position, bob.Position)
+	joint.Frequency = 1
+	joint.DampingRatio = 1
+	world.AddJoint(joint)
+
+	for i := 0; i < 60*10; i++ {
+		world.Step()
+	}
+
+	omega := 2 * math.Pi * joint.Frequency
+	expectedStretch := 9.8 / (omega * omega)
+	stretch := joint.CurrentLength() - joint.Length
+	if math.Abs(stretch-expectedStretch) > 0.01 {
+		t.Errorf("expected the spring to stretch %f but it stretched %f", expectedStretch, stretch)
+	}
+}
+
+func TestConeTwistLimits(t *testing.T) {
+	world := newJointWorld()
+	shoulder := newAnchorBody(0, mgl64.Vec3{0, 10, 0})
+	arm := physics.NewCapsuleBody(1, 0.1, 0.5, 1)
+	arm.Position = mgl64.Vec3{0, 9.4, 0}
+	arm.LinearVelocity = mgl64.Vec3{10, 0, 0}
+	arm.AngularVelocity = mgl64.Vec3{0, 20, 0}
+	world.AddBody(shoulder)
+	world.AddBody(arm)
+
+	swingSpan := math.Pi / 6
+	twistSpan := math.Pi / 8
+	joint := physics.NewConeTwistJoint(shoulder, arm, shoulder.Position, mgl64.Vec3{0, -1, 0}, swingSpan, twistSpan)
+	world.AddJoint(joint)
+
+	var maxSwing, maxTwist, maxSeparation float64
+	for i := 0; i < 60*10; i++ {
+		world.Step()
+		maxSwing = math.Max(maxSwing, joint.SwingAngle())
+		maxTwist = math.Max(maxTwist, math.Abs(joint.TwistAngle()))
+		maxSeparation = math.Max(maxSeparation, joint.Separation())
+	}
+
+	if maxSwing > swingSpan+0.05 {
+		t.Errorf("expected swing to stay within %f but reached %f", swingSpan, maxSwing)
+	}
+	if maxTwist > twistSpan+0.05 {
+		t.Errorf("expected twist to stay within %f but reached %f", twistSpan, maxTwist)
+	}
+	if maxSeparation > 0.02 {
+		t.Errorf("expected the joint to hold together but the anchors drifted %f apart", maxSeparation)
+	}
+}
@@ -11,14 +11,17 @@ const (
 	// RigidBodyTypeCapsule RigidBodyType = "CAPSULE"
 )
 
+// Capsule is a capsule aligned with the body's local y axis, centered on the body's position
 type Capsule struct {
-	Position mgl64.Vec3
+	Radius     float64
+	HalfHeight float64
 }
 
 type Sphere struct {
-	Position mgl64.Vec3
-	Radius   float64
+	Radius float64
 }
 
+// Box is collided as an axis aligned box, see NewBoxBody
 type Box struct {
+	HalfExtents mgl64.Vec3
 }
@@ -0,0 +1,228 @@
+package physics_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/physics"
+)
+
+func newGround(id int) *physics.RigidBody {
+	ground := physics.NewBoxBody(id, mgl64.Vec3{50, 1, 50}, 0)
+	ground.Position = mgl64.Vec3{0, -1, 0}
+	return ground
+}
+
+func momentum(bodies ...*physics.RigidBody) mgl64.Vec3 {
+	var total mgl64.Vec3
+	for _, body := range bodies {
+		total = total.Add(body.LinearVelocity.Mul(1 / body.InverseMass))
+	}
+	return total
+}
+
+func TestBoxStackIsStable(t *testing.T) {
+	world := physics.NewWorld()
+	world.AddBody(newGround(0))
+
+	var boxes []*physics.RigidBody
+	for i := 0; i < 5; i++ {
+		box := physics.NewBoxBody(i+1, mgl64.Vec3{0.5, 0.5, 0.5}, 1)
+		box.Position = mgl64.Vec3{0, 0.5 + float64(i)*1.01, 0}
+		world.AddBody(box)
+		boxes = append(boxes, box)
+	}
+
+	for i := 0; i < 600; i++ {
+		world.Step()
+	}
+
+	for i, box := range boxes {
+		expectedY := 0.5 + float64(i)
+		if math.Abs(box.Position.Y()-expectedY) > 0.05 {
+			t.Errorf("expected box %d to rest at height %f but was at %v", i, expectedY, box.Position)
+		}
+		if math.Abs(box.Position.X()) > 0.001 || math.Abs(box.Position.Z()) > 0.001 {
+			t.Errorf("expected box %d to not drift sideways but was at %v", i, box.Position)
+		}
+		if !box.Sleeping() {
+			t.Errorf("expected box %d to be asleep", i)
+		}
+	}
+}
+
+func TestBoxTipsOffLedge(t *testing.T) {
+	world := physics.NewWorld()
+	ledge := physics.NewBoxBody(0, mgl64.Vec3{1, 1, 1}, 0)
+	ledge.Position = mgl64.Vec3{0, -1, 0}
+	world.AddBody(ledge)
+
+	// most of the box hangs over the edge of the ledge
+	box := physics.NewBoxBody(1, mgl64.Vec3{0.5, 0.5, 0.5}, 1)
+	box.Position = mgl64.Vec3{1.2, 0.5, 0}
+	world.AddBody(box)
+
+	for i := 0; i < 60; i++ {
+		world.Step()
+	}
+
+	if box.Position.Y() > -0.5 {
+		t.Errorf("expected the box to fall off the ledge but was at %v", box.Position)
+	}
+	if up := box.Orientation.Rotate(mgl64.Vec3{0, 1, 0}); up.Y() > 0.95 {
+		t.Errorf("expected the box to tip over the edge but its up axis was %v", up)
+	}
+}
+
+func TestSleepingBodyWakesWhenHit(t *testing.T) {
+	world := physics.NewWorld()
+	world.AddBody(newGround(0))
+
+	box := physics.NewBoxBody(1, mgl64.Vec3{0.5, 0.5, 0.5}, 1)
+	box.Position = mgl64.Vec3{0, 0.5, 0}
+	world.AddBody(box)
+
+	for i := 0; i < 120; i++ {
+		world.Step()
+	}
+	if !box.Sleeping() {
+		t.Fatal("expected box to fall asleep")
+	}
+
+	ball := physics.NewSphereBody(2, 0.5, 1)
+	ball.Position = mgl64.Vec3{0, 3, 0}
+	world.AddBody(ball)
+
+	for i := 0; i < 60; i++ {
+		world.Step()
+	}
+	if ball.Position.Y() < 1.4 {
+		t.Errorf("expected ball to land on the box but was at %v", ball.Position)
+	}
+}
+
+// two equal spheres colliding head on with perfect restitution swap velocities
+func TestElasticCollisionConservesEnergy(t *testing.T) {
+	world := physics.NewWorld()
+	world.Gravity = mgl64.Vec3{}
+	world.Sleep.Enabled = false
+
+	a := physics.NewSphereBody(1, 0.5, 1)
+	a.Position = mgl64.Vec3{-2, 0, 0}
+	a.LinearVelocity = mgl64.Vec3{5, 0, 0}
+	a.Restitution = 1
+	a.Friction = 0
+
+	b := physics.NewSphereBody(2, 0.5, 1)
+	b.Position = mgl64.Vec3{2, 0, 0}
+	b.LinearVelocity = mgl64.Vec3{-5, 0, 0}
+	b.Restitution = 1
+	b.Friction = 0
+
+	world.AddBody(a)
+	world.AddBody(b)
+
+	energyBefore := a.KineticEnergy() + b.KineticEnergy()
+	momentumBefore := momentum(a, b)
+
+	for i := 0; i < 60; i++ {
+		world.Step()
+	}
+
+	energyAfter := a.KineticEnergy() + b.KineticEnergy()
+	if math.Abs(energyAfter-energyBefore)/energyBefore > 0.01 {
+		t.Errorf("expected kinetic energy to be conserved, before %f after %f", energyBefore, energyAfter)
+	}
+	if !momentum(a, b).ApproxEqualThreshold(momentumBefore, 1e-9) {
+		t.Errorf("expected momentum to be conserved, before %v after %v", momentumBefore, momentum(a, b))
+	}
+	if a.LinearVelocity.X() > -4.9 || b.LinearVelocity.X() < 4.9 {
+		t.Errorf("expected the spheres to bounce off each other but got velocities %v and %v", a.LinearVelocity, b.LinearVelocity)
+	}
+}
+
+// a perfectly elastic ball dropped on the ground keeps bouncing back to its starting height
+func TestBouncingBallConservesEnergy(t *testing.T) {
+	world := physics.NewWorld()
+	world.Sleep.Enabled = false
+	ground := newGround(0)
+	ground.Restitution = 1
+	world.AddBody(ground)
+
+	ball := physics.NewSphereBody(1, 0.5, 1)
+	ball.Position = mgl64.Vec3{0, 5, 0}
+	ball.Restitution = 1
+	world.AddBody(ball)
+
+	var bounces int
+	var maxHeight float64
+	prevVelocity := ball.LinearVelocity.Y()
+	for i := 0; i < 60*10; i++ {
+		world.Step()
+		velocity := ball.LinearVelocity.Y()
+		if prevVelocity < 0 && velocity > 0 {
+			bounces++
+			maxHeight = 0
+		}
+		maxHeight = math.Max(maxHeight, ball.Position.Y())
+		prevVelocity = velocity
+	}
+
+	if bounces < 3 {
+		t.Fatalf("expected the ball to bounce at least 3 times but it bounced %d times", bounces)
+	}
+	if math.Abs(maxHeight-5)/5 > 0.05 {
+		t.Errorf("expected the ball to bounce back to a height of 5 but only reached %f", maxHeight)
+	}
+}
+
+func TestFrictionStopsSlidingBox(t *testing.T) {
+	world := physics.NewWorld()
+	world.AddBody(newGround(0))
+
+	box := physics.NewBoxBody(1, mgl64.Vec3{0.5, 0.5, 0.5}, 1)
+	box.Position = mgl64.Vec3{0, 0.5, 0}
+	box.LinearVelocity = mgl64.Vec3{3, 0, 0}
+	world.AddBody(box)
+
+	for i := 0; i < 120; i++ {
+		world.Step()
+	}
+
+	// friction of 0.5 decelerates at 4.9 m/s^2 so the box comes to a stop after ~0.92m
+	if math.Abs(box.LinearVelocity.X()) > 0.01 {
+		t.Errorf("expected box to stop sliding but had velocity %v", box.LinearVelocity)
+	}
+	if math.Abs(box.Position.X()-0.92) > 0.05 {
+		t.Errorf("expected box to slide ~0.92 units but was at %v", box.Position)
+	}
+}
+
+func TestSphereRollsDownRamp(t *testing.T) {
+	world := physics.NewWorld()
+	world.Sleep.Enabled = false
+	world.TriMesh = &collider.TriMesh{
+		Triangles: []collider.Triangle{
+			collider.NewTriangle([3]mgl64.Vec3{{-10, 10, -10}, {-10, 10, 10}, {10, -10, 10}}),
+			collider.NewTriangle([3]mgl64.Vec3{{-10, 10, -10}, {10, -10, 10}, {10, -10, -10}}),
+		},
+	}
+
+	ball := physics.NewSphereBody(1, 0.5, 1)
+	ball.Position = mgl64.Vec3{0, 0.75, 0}
+	ball.Friction = 1
+	world.AddBody(ball)
+
+	for i := 0; i < 60; i++ {
+		world.Step()
+	}
+
+	if ball.Position.X() < 1 {
+		t.Errorf("expected ball to roll down the ramp but was at %v", ball.Position)
+	}
+	if ball.AngularVelocity.Z() > -1 {
+		t.Errorf("expected ball to be rolling but had angular velocity %v", ball.AngularVelocity)
+	}
+}
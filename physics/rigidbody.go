@@ -0,0 +1,238 @@
+package physics
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+type RigidBody struct {
+	ID           int
+	ColliderType ColliderType
+	Sphere       Sphere
+	Capsule      Capsule
+	Box          Box
+
+	Position        mgl64.Vec3
+	Orientation     mgl64.Quat
+	LinearVelocity  mgl64.Vec3
+	AngularVelocity mgl64.Vec3
+
+	// a zero inverse mass makes the body static
+	InverseMass         float64
+	InverseInertiaLocal mgl64.Mat3
+
+	// Layer is the set of layers the body is in and Mask is the set of layers it collides with
+	Layer uint32
+	Mask  uint32
+	// IsTrigger bodies report overlaps through trigger events instead of colliding
+	IsTrigger bool
+
+	Restitution    float64
+	Friction       float64
+	LinearDamping  float64
+	AngularDamping float64
+
+	force  mgl64.Vec3
+	torque mgl64.Vec3
+
+	// velocities from before forces were applied this step, used for restitution
+	preStepLinearVelocity  mgl64.Vec3
+	preStepAngularVelocity mgl64.Vec3
+
+	sleeping   bool
+	sleepTimer float64
+}
+
+func newRigidBody(id int, mass float64) *RigidBody {
+	body := &RigidBody{
+		ID:          id,
+		Orientation: mgl64.QuatIdent(),
+		Friction:    0.5,
+		Layer:       DefaultLayer,
+		Mask:        AllLayers,
+	}
+	if mass > 0 {
+		body.InverseMass = 1 / mass
+	}
+	return body
+}
+
+// NewSphereBody creates a sphere body, a mass of 0 creates a static body
+func NewSphereBody(id int, radius float64, mass float64) *RigidBody {
+	body := newRigidBody(id, mass)
+	body.ColliderType = ColliderTypeSphere
+	body.Sphere = Sphere{Radius: radius}
+	if mass > 0 {
+		i := 2.0 / 5 * mass * radius * radius
+		body.InverseInertiaLocal = mgl64.Diag3(mgl64.Vec3{1 / i, 1 / i, 1 / i})
+	}
+	return body
+}
+
+// NewCapsuleBody creates a capsule body aligned with the local y axis. halfHeight is half
+// the distance between the centers of the two hemispheres.
+func NewCapsuleBody(id int, radius float64, halfHeight float64, mass float64) *RigidBody {
+	body := newRigidBody(id, mass)
+	body.ColliderType = ColliderTypeCapsule
+	body.Capsule = Capsule{Radius: radius, HalfHeight: halfHeight}
+	if mass > 0 {
+		// split the mass between the cylinder and the hemispheres by volume
+		h := 2 * halfHeight
+		r2 := radius * radius
+		cylinderVolume := math.Pi * r2 * h
+		sphereVolume := 4.0 / 3 * math.Pi * r2 * radius
+		cylinderMass := mass * cylinderVolume / (cylinderVolume + sphereVolume)
+		sphereMass := mass - cylinderMass
+
+		iy := cylinderMass*r2/2 + sphereMass*2*r2/5
+		ixz := cylinderMass*(h*h/12+r2/4) + sphereMass*(2*r2/5+h*h/4+3*h*radius/8)
+		body.InverseInertiaLocal = mgl64.Diag3(mgl64.Vec3{1 / ixz, 1 / iy, 1 / ixz})
+	}
+	return body
+}
+
+// NewBoxBody creates a box body, halfExtents are along the body's local axes
+func NewBoxBody(id int, halfExtents mgl64.Vec3, mass float64) *RigidBody {
+	body := newRigidBody(id, mass)
+	body.ColliderType = ColliderTypeBox
+	body.Box = Box{HalfExtents: halfExtents}
+	if mass > 0 {
+		x2, y2, z2 := halfExtents.X()*halfExtents.X(), halfExtents.Y()*halfExtents.Y(), halfExtents.Z()*halfExtents.Z()
+		ix := mass / 3 * (y2 + z2)
+		iy := mass / 3 * (x2 + z2)
+		iz := mass / 3 * (x2 + y2)
+		body.InverseInertiaLocal = mgl64.Diag3(mgl64.Vec3{1 / ix, 1 / iy, 1 / iz})
+	}
+	return body
+}
+
+func (b *RigidBody) IsStatic() bool {
+	return b.InverseMass == 0
+}
+
+func (b *RigidBody) Sleeping() bool {
+	return b.sleeping
+}
+
+func (b *RigidBody) WakeUp() {
+	b.sleeping = false
+	b.sleepTimer = 0
+}
+
+func (b *RigidBody) ApplyForce(force mgl64.Vec3) {
+	b.force = b.force.Add(force)
+	b.WakeUp()
+}
+
+// ApplyForceAtPoint applies a force at a point in world space, which also produces a torque
+func (b *RigidBody) ApplyForceAtPoint(force mgl64.Vec3, point mgl64.Vec3) {
+	b.force = b.force.Add(force)
+	b.torque = b.torque.Add(point.Sub(b.Position).Cross(force))
+	b.WakeUp()
+}
+
+// ApplyImpulse applies an impulse at a point in world space
+func (b *RigidBody) ApplyImpulse(impulse mgl64.Vec3, point mgl64.Vec3) {
+	b.applyImpulse(impulse, point.Sub(b.Position))
+	b.WakeUp()
+}
+
+func (b *RigidBody) applyImpulse(impulse mgl64.Vec3, r mgl64.Vec3) {
+	b.LinearVelocity = b.LinearVelocity.Add(impulse.Mul(b.InverseMass))
+	b.AngularVelocity = b.AngularVelocity.Add(b.InverseInertiaWorld().Mul3x1(r.Cross(impulse)))
+}
+
+// InverseInertiaWorld returns the inverse inertia tensor rotated into world space
+func (b *RigidBody) InverseInertiaWorld() mgl64.Mat3 {
+	rotation := b.Orientation.Mat4().Mat3()
+	return rotation.Mul3(b.InverseInertiaLocal).Mul3(rotation.Transpose())
+}
+
+// VelocityAtPoint returns the velocity of a point in world space that is attached to the body
+func (b *RigidBody) VelocityAtPoint(point mgl64.Vec3) mgl64.Vec3 {
+	return b.LinearVelocity.Add(b.AngularVelocity.Cross(point.Sub(b.Position)))
+}
+
+func (b *RigidBody) KineticEnergy() float64 {
+	if b.IsStatic() {
+		return 0
+	}
+
+	linear := 0.5 * b.LinearVelocity.LenSqr() / b.InverseMass
+
+	var angular float64
+	inertiaInverse := b.InverseInertiaWorld()
+	if inertiaInverse.Det() != 0 {
+		inertia := inertiaInverse.Inv()
+		angular = 0.5 * b.AngularVelocity.Dot(inertia.Mul3x1(b.AngularVelocity))
+	}
+
+	return linear + angular
+}
+
+func (b *RigidBody) CapsuleCollider() collider.Capsule {
+	offset := b.Orientation.Rotate(mgl64.Vec3{0, b.Capsule.HalfHeight, 0})
+	return collider.NewCapsule(b.Position.Add(offset), b.Position.Sub(offset), b.Capsule.Radius)
+}
+
+func (b *RigidBody) SphereCollider() collider.Sphere {
+	return collider.NewSphere(b.Position, b.Sphere.Radius)
+}
+
+func (b *RigidBody) BoxCollider() collider.OrientedBoundingBox {
+	return collider.NewOrientedBoundingBox(b.Position, b.Box.HalfExtents, b.Orientation)
+}
+
+func (b *RigidBody) BoundingBox() collider.BoundingBox {
+	switch b.ColliderType {
+	case ColliderTypeSphere:
+		r := mgl64.Vec3{b.Sphere.Radius, b.Sphere.Radius, b.Sphere.Radius}
+		return collider.BoundingBox{MinVertex: b.Position.Sub(r), MaxVertex: b.Position.Add(r)}
+	case ColliderTypeCapsule:
+		capsule := b.CapsuleCollider()
+		r := mgl64.Vec3{capsule.Radius, capsule.Radius, capsule.Radius}
+		box := collider.BoundingBoxFromVertices([]mgl64.Vec3{capsule.Top, capsule.Bottom})
+		return collider.BoundingBox{MinVertex: box.MinVertex.Sub(r), MaxVertex: box.MaxVertex.Add(r)}
+	case ColliderTypeBox:
+		return b.BoxCollider().BoundingBox()
+	}
+	return collider.BoundingBox{MinVertex: b.Position, MaxVertex: b.Position}
+}
+
+// integrateVelocity applies gravity and accumulated forces
+func (b *RigidBody) integrateVelocity(gravity mgl64.Vec3, dt float64) {
+	b.preStepLinearVelocity = b.LinearVelocity
+	b.preStepAngularVelocity = b.AngularVelocity
+
+	acceleration := gravity.Add(b.force.Mul(b.InverseMass))
+	b.LinearVelocity = b.LinearVelocity.Add(acceleration.Mul(dt))
+	b.AngularVelocity = b.AngularVelocity.Add(b.InverseInertiaWorld().Mul3x1(b.torque).Mul(dt))
+
+	b.LinearVelocity = b.LinearVelocity.Mul(1 / (1 + dt*b.LinearDamping))
+	b.AngularVelocity = b.AngularVelocity.Mul(1 / (1 + dt*b.AngularDamping))
+}
+
+// integratePosition moves the body using its (already solved) velocity
+func (b *RigidBody) integratePosition(dt float64) {
+	b.Position = b.Position.Add(b.LinearVelocity.Mul(dt))
+
+	spin := mgl64.Quat{W: 0, V: b.AngularVelocity.Mul(0.5 * dt)}.Mul(b.Orientation)
+	b.Orientation = b.Orientation.Add(spin).Normalize()
+}
+
+// applyPositionImpulse moves and rotates the body directly as if impulse was applied at the
+// offset r from its center of mass for a unit of time
+func (b *RigidBody) applyPositionImpulse(impulse, r mgl64.Vec3) {
+	if b.IsStatic() {
+		return
+	}
+	b.Position = b.Position.Add(impulse.Mul(b.InverseMass))
+	b.Orientation = rotateBy(b.Orientation, b.InverseInertiaWorld().Mul3x1(r.Cross(impulse)))
+}
+
+func (b *RigidBody) clearForces() {
+	b.force = mgl64.Vec3{}
+	b.torque = mgl64.Vec3{}
+}
@@ -0,0 +1,203 @@
+package physics
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision"
+)
+
+// contactKey identifies a contact point across steps so that its accumulated impulses can
+// be used to warm start the solver
+type contactKey struct {
+	bodyA     int
+	bodyB     int
+	triIndex  int
+	featureID collision.FeatureID
+}
+
+// the normal constraints of a manifold are coupled through the rotation of the bodies, so an
+// impulse at one corner of a face tips the body onto the others. relaxing them a few times each
+// iteration keeps a box landing flat from coming out of the solver tilted.
+const manifoldRelaxations = 4
+
+type cachedImpulse struct {
+	normal   float64
+	tangents [2]float64
+}
+
+type contactPointConstraint struct {
+	key contactKey
+
+	rA mgl64.Vec3
+	rB mgl64.Vec3
+
+	penetration  float64
+	normalMass   float64
+	tangentMass  [2]float64
+	velocityBias float64
+
+	normalImpulse   float64
+	tangentImpulses [2]float64
+}
+
+type contactConstraint struct {
+	a *RigidBody
+	b *RigidBody
+
+	normal      mgl64.Vec3
+	tangents    [2]mgl64.Vec3
+	friction    float64
+	restitution float64
+
+	points     [collision.MaxManifoldPoints]contactPointConstraint
+	pointCount int
+}
+
+func newContactConstraint(a, b *RigidBody, contact collision.Contact) *contactConstraint {
+	c := &contactConstraint{
+		a:           a,
+		b:           b,
+		normal:      contact.Normal,
+		tangents:    tangentBasis(contact.Normal),
+		friction:    math.Sqrt(a.Friction * b.Friction),
+		restitution: math.Max(a.Restitution, b.Restitution),
+	}
+
+	triIndex := -1
+	if contact.TriIndex != nil {
+		triIndex = *contact.TriIndex
+	}
+
+	for _, point := range contact.ManifoldPoints() {
+		c.points[c.pointCount] = contactPointConstraint{
+			key:         contactKey{bodyA: a.ID, bodyB: b.ID, triIndex: triIndex, featureID: point.FeatureID},
+			rA:          point.Point.Sub(a.Position),
+			rB:          point.Point.Sub(b.Position),
+			penetration: point.Penetration,
+		}
+		c.pointCount++
+	}
+
+	return c
+}
+
+// prepare computes the effective masses and velocity biases and applies the cached
+// impulses from the previous step
+func (c *contactConstraint) prepare(settings SolverSettings, dt float64, cache map[contactKey]cachedImpulse) {
+	for i := 0; i < c.pointCount; i++ {
+		p := &c.points[i]
+
+		p.normalMass = inverseEffectiveMass(c.a, c.b, p.rA, p.rB, c.normal)
+		for j := 0; j < 2; j++ {
+			p.tangentMass[j] = inverseEffectiveMass(c.a, c.b, p.rA, p.rB, c.tangents[j])
+		}
+
+		// push apart penetrating bodies, leaving a little bit of slop so that resting
+		// contacts don't jitter
+		p.velocityBias = settings.Baumgarte / dt * math.Max(p.penetration-settings.PenetrationSlop, 0)
+		if p.penetration < 0 {
+			// a point that isn't touching yet lets the bodies close the gap but no more
+			p.velocityBias = p.penetration / dt
+		}
+
+		// bounce off the velocity from before gravity was applied this step, otherwise each
+		// bounce would gain a step's worth of acceleration
+		relativeNormalVelocity := c.preStepRelativeVelocity(p).Dot(c.normal)
+		if relativeNormalVelocity < -settings.RestitutionThreshold {
+			p.velocityBias = math.Max(p.velocityBias, -c.restitution*relativeNormalVelocity)
+		}
+
+		if cached, ok := cache[p.key]; ok && settings.WarmStarting {
+			p.normalImpulse = cached.normal
+			p.tangentImpulses = cached.tangents
+			impulse := c.normal.Mul(p.normalImpulse).
+				Add(c.tangents[0].Mul(p.tangentImpulses[0])).
+				Add(c.tangents[1].Mul(p.tangentImpulses[1]))
+			c.apply(p, impulse)
+		}
+	}
+}
+
+func (c *contactConstraint) solve() {
+	// friction is solved first since non penetration is more important
+	for i := 0; i < c.pointCount; i++ {
+		p := &c.points[i]
+		maxFriction := c.friction * p.normalImpulse
+		for j := 0; j < 2; j++ {
+			if p.tangentMass[j] == 0 {
+				continue
+			}
+			vt := c.relativeVelocity(p).Dot(c.tangents[j])
+			lambda := -vt / p.tangentMass[j]
+
+			oldImpulse := p.tangentImpulses[j]
+			p.tangentImpulses[j] = mgl64.Clamp(oldImpulse+lambda, -maxFriction, maxFriction)
+			c.apply(p, c.tangents[j].Mul(p.tangentImpulses[j]-oldImpulse))
+		}
+	}
+
+	for k := 0; k < manifoldRelaxations; k++ {
+		for i := 0; i < c.pointCount; i++ {
+			p := &c.points[i]
+			if p.normalMass == 0 {
+				continue
+			}
+			vn := c.relativeVelocity(p).Dot(c.normal)
+			lambda := (p.velocityBias - vn) / p.normalMass
+
+			oldImpulse := p.normalImpulse
+			p.normalImpulse = math.Max(oldImpulse+lambda, 0)
+			c.apply(p, c.normal.Mul(p.normalImpulse-oldImpulse))
+		}
+	}
+}
+
+func (c *contactConstraint) store(cache map[contactKey]cachedImpulse) {
+	for i := 0; i < c.pointCount; i++ {
+		p := &c.points[i]
+		cache[p.key] = cachedImpulse{normal: p.normalImpulse, tangents: p.tangentImpulses}
+	}
+}
+
+// relativeVelocity is the velocity of a relative to b at the contact point
+func (c *contactConstraint) relativeVelocity(p *contactPointConstraint) mgl64.Vec3 {
+	va := c.a.LinearVelocity.Add(c.a.AngularVelocity.Cross(p.rA))
+	vb := c.b.LinearVelocity.Add(c.b.AngularVelocity.Cross(p.rB))
+	return va.Sub(vb)
+}
+
+func (c *contactConstraint) preStepRelativeVelocity(p *contactPointConstraint) mgl64.Vec3 {
+	va := c.a.preStepLinearVelocity.Add(c.a.preStepAngularVelocity.Cross(p.rA))
+	vb := c.b.preStepLinearVelocity.Add(c.b.preStepAngularVelocity.Cross(p.rB))
+	return va.Sub(vb)
+}
+
+// apply applies the impulse to a and the opposite impulse to b
+func (c *contactConstraint) apply(p *contactPointConstraint, impulse mgl64.Vec3) {
+	c.a.applyImpulse(impulse, p.rA)
+	c.b.applyImpulse(impulse.Mul(-1), p.rB)
+}
+
+// inverseEffectiveMass returns the inverse of the mass felt by an impulse along direction
+// applied at offsets rA and rB from the centers of mass of the two bodies
+func inverseEffectiveMass(a, b *RigidBody, rA, rB mgl64.Vec3, direction mgl64.Vec3) float64 {
+	rnA := rA.Cross(direction)
+	rnB := rB.Cross(direction)
+	angularA := a.InverseInertiaWorld().Mul3x1(rnA).Dot(rnA)
+	angularB := b.InverseInertiaWorld().Mul3x1(rnB).Dot(rnB)
+	return a.InverseMass + b.InverseMass + angularA + angularB
+}
+
+// tangentBasis returns two unit vectors perpendicular to the normal and each other. the
+// basis only depends on the normal so tangent impulses can be warm started.
+func tangentBasis(normal mgl64.Vec3) [2]mgl64.Vec3 {
+	var t1 mgl64.Vec3
+	if math.Abs(normal.X()) >= 0.57735 {
+		t1 = mgl64.Vec3{normal.Y(), -normal.X(), 0}
+	} else {
+		t1 = mgl64.Vec3{0, normal.Z(), -normal.Y()}
+	}
+	t1 = t1.Normalize()
+	return [2]mgl64.Vec3{t1, normal.Cross(t1)}
+}
@@ -0,0 +1,394 @@
+package physics
+
+import (
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/spatialpartition"
+)
+
+const (
+	// id used by contacts against the world's static trimesh
+	staticGeometryID = -1
+
+	epsilon float64 = 0.000001
+)
+
+type SolverSettings struct {
+	Iterations int
+	// PositionIterations is the number of passes spent pulling joints back together after
+	// positions are integrated, velocities alone let joints drift apart when bodies move fast
+	PositionIterations int
+	// Baumgarte is the fraction of the penetration that's resolved each step
+	Baumgarte float64
+	// PenetrationSlop is the penetration allowed before bodies are pushed apart
+	PenetrationSlop float64
+	// RestitutionThreshold is the closing speed below which collisions don't bounce
+	RestitutionThreshold float64
+	WarmStarting         bool
+}
+
+type SleepSettings struct {
+	Enabled          bool
+	LinearThreshold  float64
+	AngularThreshold float64
+	// TimeToSleep is how long in seconds a body has to be still before it's put to sleep
+	TimeToSleep float64
+}
+
+// World steps rigid bodies at a fixed timestep. bodies are stepped in the order they were
+// added so that results are reproducible.
+type World struct {
+	Gravity  mgl64.Vec3
+	Timestep float64
+	Solver   SolverSettings
+	Sleep    SleepSettings
+
+	// TriMesh is optional static geometry that bodies collide with
+	TriMesh *collider.TriMesh
+	// Partition is an optional broad phase, without it every pair of bodies is checked. body
+	// ids are used as entity ids so they need to be unique within the partition.
+	Partition spatialpartition.Broadphase
+
+	bodies       []*RigidBody
+	joints       []Joint
+	staticBody   *RigidBody
+	contactCache map[contactKey]cachedImpulse
+	accumulator  float64
+
+	// bodyIndices and bodyEntities are reused by candidatePairs each step
+	bodyIndices  map[int]int
+	bodyEntities []spatialpartition.Entity
+
+	triggerOverlaps map[TriggerEvent]bool
+	triggerEvents   TriggerEvents
+	removedOverlaps []TriggerEvent
+}
+
+func NewWorld() *World {
+	staticBody := newRigidBody(staticGeometryID, 0)
+	staticBody.Friction = 1
+
+	return &World{
+		Gravity:  mgl64.Vec3{0, -9.8, 0},
+		Timestep: 1.0 / 60,
+		Solver: SolverSettings{
+			Iterations:           10,
+			PositionIterations:   3,
+			Baumgarte:            0.2,
+			PenetrationSlop:      0.005,
+			RestitutionThreshold: 1,
+			WarmStarting:         true,
+		},
+		Sleep: SleepSettings{
+			Enabled:          true,
+			LinearThreshold:  0.05,
+			AngularThreshold: 0.05,
+			TimeToSleep:      0.5,
+		},
+		staticBody:   staticBody,
+		contactCache: map[contactKey]cachedImpulse{},
+
+		triggerOverlaps: map[TriggerEvent]bool{},
+	}
+}
+
+func (w *World) AddBody(body *RigidBody) {
+	w.bodies = append(w.bodies, body)
+}
+
+func (w *World) RemoveBody(id int) {
+	for i, body := range w.bodies {
+		if body.ID == id {
+			w.bodies = append(w.bodies[:i], w.bodies[i+1:]...)
+			w.removeTriggerOverlaps(id)
+			if w.Partition != nil {
+				w.Partition.DeleteEntity(id)
+			}
+			return
+		}
+	}
+}
+
+func (w *World) Bodies() []*RigidBody {
+	return w.bodies
+}
+
+// AddJoint adds a joint to the world. bodies that are joined together don't collide with
+// each other.
+func (w *World) AddJoint(joint Joint) {
+	w.joints = append(w.joints, joint)
+}
+
+func (w *World) RemoveJoint(joint Joint) {
+	for i, j := range w.joints {
+		if j == joint {
+			w.joints = append(w.joints[:i], w.joints[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *World) joined(a, b *RigidBody) bool {
+	for _, joint := range w.joints {
+		jointA, jointB := joint.Bodies()
+		if (jointA == a && jointB == b) || (jointA == b && jointB == a) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *World) Joints() []Joint {
+	return w.joints
+}
+
+// Update advances the simulation by delta in fixed timesteps, any leftover time is carried
+// over to the next call. the number of steps taken is returned.
+func (w *World) Update(delta time.Duration) int {
+	w.accumulator += delta.Seconds()
+	w.triggerEvents.clear()
+
+	steps := 0
+	for w.accumulator >= w.Timestep {
+		w.step()
+		w.accumulator -= w.Timestep
+		steps++
+	}
+	return steps
+}
+
+// Step advances the simulation by a single timestep
+func (w *World) Step() {
+	w.triggerEvents.clear()
+	w.step()
+}
+
+// TriggerEvents returns the trigger events from the last call to Step or Update. events from
+// every step taken by Update are kept, in the order they happened.
+func (w *World) TriggerEvents() TriggerEvents {
+	return w.triggerEvents
+}
+
+func (w *World) step() {
+	dt := w.Timestep
+
+	for _, body := range w.bodies {
+		if body.IsStatic() || body.sleeping {
+			continue
+		}
+		body.integrateVelocity(w.Gravity, dt)
+	}
+
+	constraints := w.findContacts()
+	joints := w.activeJoints()
+
+	for _, joint := range joints {
+		joint.prepare(w.Solver, dt)
+	}
+	for _, constraint := range constraints {
+		constraint.prepare(w.Solver, dt, w.contactCache)
+	}
+	for i := 0; i < w.Solver.Iterations; i++ {
+		for _, joint := range joints {
+			joint.solve()
+		}
+		for _, constraint := range constraints {
+			constraint.solve()
+		}
+	}
+
+	// only keep impulses around for contacts that still exist
+	w.contactCache = map[contactKey]cachedImpulse{}
+	for _, constraint := range constraints {
+		constraint.store(w.contactCache)
+	}
+
+	for _, body := range w.bodies {
+		if body.IsStatic() || body.sleeping {
+			continue
+		}
+		body.integratePosition(dt)
+		body.clearForces()
+	}
+
+	for i := 0; i < w.Solver.PositionIterations; i++ {
+		for _, joint := range joints {
+			joint.solvePosition()
+		}
+	}
+
+	if w.Sleep.Enabled {
+		w.updateSleeping(constraints, joints, dt)
+	}
+}
+
+func (w *World) findContacts() []*contactConstraint {
+	var constraints []*contactConstraint
+
+	for _, body := range w.bodies {
+		if w.TriMesh != nil && !body.IsStatic() && !body.sleeping && !body.IsTrigger {
+			for _, contact := range generateTriMeshContacts(body, w.TriMesh) {
+				constraints = append(constraints, newContactConstraint(body, w.staticBody, contact))
+			}
+		}
+	}
+
+	var overlaps []TriggerEvent
+	for _, pair := range w.candidatePairs() {
+		a, b := pair.a, pair.b
+		if !LayersCollide(a, b) || w.joined(a, b) {
+			continue
+		}
+
+		if a.IsTrigger || b.IsTrigger {
+			// sleeping bodies are still checked so that they don't exit triggers they're
+			// resting in
+			if a.IsStatic() && b.IsStatic() {
+				continue
+			}
+			if _, collided := generateContact(a, b); collided {
+				overlaps = append(overlaps, triggerOverlaps(a, b)...)
+			}
+			continue
+		}
+
+		if !w.shouldCollide(a, b) {
+			continue
+		}
+
+		contact, collided := generateContact(a, b)
+		if !collided || contact.PointCount == 0 {
+			continue
+		}
+
+		// a moving body ran into a sleeping one
+		if a.sleeping {
+			a.WakeUp()
+		}
+		if b.sleeping {
+			b.WakeUp()
+		}
+
+		constraints = append(constraints, newContactConstraint(a, b, contact))
+	}
+	w.updateTriggers(overlaps)
+
+	return constraints
+}
+
+// activeJoints returns the joints that have an awake body, waking up the other body
+func (w *World) activeJoints() []Joint {
+	var joints []Joint
+	for _, joint := range w.joints {
+		a, b := joint.Bodies()
+		if !w.shouldCollide(a, b) {
+			continue
+		}
+		if a.sleeping {
+			a.WakeUp()
+		}
+		if b.sleeping {
+			b.WakeUp()
+		}
+		joints = append(joints, joint)
+	}
+	return joints
+}
+
+func (w *World) shouldCollide(a, b *RigidBody) bool {
+	aResting := a.IsStatic() || a.sleeping
+	bResting := b.IsStatic() || b.sleeping
+	return !(aResting && bResting)
+}
+
+// updateSleeping puts bodies to sleep once they've been still for long enough. bodies that
+// are touching form an island and only go to sleep together, otherwise a body in the middle
+// of a stack could fall asleep while the bodies on it are still settling.
+func (w *World) updateSleeping(constraints []*contactConstraint, joints []Joint, dt float64) {
+	linearThresholdSqr := w.Sleep.LinearThreshold * w.Sleep.LinearThreshold
+	angularThresholdSqr := w.Sleep.AngularThreshold * w.Sleep.AngularThreshold
+
+	indices := map[*RigidBody]int{}
+	for i, body := range w.bodies {
+		indices[body] = i
+		if body.IsStatic() || body.sleeping {
+			continue
+		}
+
+		if body.LinearVelocity.LenSqr() < linearThresholdSqr && body.AngularVelocity.LenSqr() < angularThresholdSqr {
+			body.sleepTimer += dt
+		} else {
+			body.sleepTimer = 0
+		}
+	}
+
+	islands := newUnionFind(len(w.bodies))
+	for _, constraint := range constraints {
+		if constraint.a.IsStatic() || constraint.b.IsStatic() {
+			continue
+		}
+		islands.union(indices[constraint.a], indices[constraint.b])
+	}
+	for _, joint := range joints {
+		a, b := joint.Bodies()
+		if a.IsStatic() || b.IsStatic() {
+			continue
+		}
+		islands.union(indices[a], indices[b])
+	}
+
+	canSleep := map[int]bool{}
+	for i, body := range w.bodies {
+		if body.IsStatic() {
+			continue
+		}
+		root := islands.find(i)
+		if _, ok := canSleep[root]; !ok {
+			canSleep[root] = true
+		}
+		if !body.sleeping && body.sleepTimer < w.Sleep.TimeToSleep {
+			canSleep[root] = false
+		}
+	}
+
+	for i, body := range w.bodies {
+		if body.IsStatic() || body.sleeping || !canSleep[islands.find(i)] {
+			continue
+		}
+		body.sleeping = true
+		body.LinearVelocity = mgl64.Vec3{}
+		body.AngularVelocity = mgl64.Vec3{}
+		body.preStepLinearVelocity = mgl64.Vec3{}
+		body.preStepAngularVelocity = mgl64.Vec3{}
+	}
+}
+
+type unionFind struct {
+	parents []int
+}
+
+func newUnionFind(size int) *unionFind {
+	parents := make([]int, size)
+	for i := range parents {
+		parents[i] = i
+	}
+	return &unionFind{parents: parents}
+}
+
+func (u *unionFind) find(i int) int {
+	for u.parents[i] != i {
+		u.parents[i] = u.parents[u.parents[i]]
+		i = u.parents[i]
+	}
+	return i
+}
+
+func (u *unionFind) union(i, j int) {
+	rootI := u.find(i)
+	rootJ := u.find(j)
+	if rootI != rootJ {
+		u.parents[rootJ] = rootI
+	}
+}
@@ -0,0 +1,395 @@
+package spatialpartition
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+const nullNode = -1
+
+// AABBTree is a dynamic bounding volume hierarchy. unlike SpatialPartition it isn't bounded
+// and entity ids can be anything. leaves hold a fat bounding box that's grown by Margin so
+// that entities that move a little don't have to be reinserted.
+type AABBTree struct {
+	Margin float64
+
+	nodes       []treeNode
+	root        int
+	freeList    int
+	entityNodes map[int]int
+}
+
+type treeNode struct {
+	box    collider.BoundingBox
+	parent int
+	left   int
+	right  int
+	// height is 0 for leaves and -1 for nodes on the free list
+	height int
+	entity Entity
+	// entityBox is the entity's bounding box from when it was last indexed
+	entityBox collider.BoundingBox
+
+	// next is the next node in the free list
+	next int
+}
+
+func (n *treeNode) isLeaf() bool {
+	return n.left == nullNode
+}
+
+func NewAABBTree(margin float64) *AABBTree {
+	return &AABBTree{
+		Margin:      margin,
+		root:        nullNode,
+		freeList:    nullNode,
+		entityNodes: map[int]int{},
+	}
+}
+
+// IndexEntities inserts new entities and moves existing ones. entities that are still within
+// their fat bounding box stay where they are in the tree.
+func (t *AABBTree) IndexEntities(entityList []Entity) {
+	for _, entity := range entityList {
+		box := entity.BoundingBox()
+		if leaf, ok := t.entityNodes[entity.GetID()]; ok {
+			t.nodes[leaf].entity = entity
+			t.nodes[leaf].entityBox = box
+			if contains(t.nodes[leaf].box, box) {
+				continue
+			}
+			t.removeLeaf(leaf)
+			t.nodes[leaf].box = t.fatten(box)
+			t.insertLeaf(leaf)
+			continue
+		}
+
+		leaf := t.allocateNode()
+		t.nodes[leaf].box = t.fatten(box)
+		t.nodes[leaf].entity = entity
+		t.nodes[leaf].entityBox = box
+		t.entityNodes[entity.GetID()] = leaf
+		t.insertLeaf(leaf)
+	}
+}
+
+// QueryEntities returns the entities whose fat bounding boxes overlap the bounding box
+func (t *AABBTree) QueryEntities(boundingBox collider.BoundingBox) []Entity {
+	var entities []Entity
+	t.query(boundingBox, func(n *treeNode) {
+		entities = append(entities, n.entity)
+	})
+	return entities
+}
+
+func (t *AABBTree) DeleteEntity(entityID int) {
+	leaf, ok := t.entityNodes[entityID]
+	if !ok {
+		return
+	}
+	t.removeLeaf(leaf)
+	t.freeNode(leaf)
+	delete(t.entityNodes, entityID)
+}
+
+// Pairs walks the tree against itself, only descending into subtrees whose fat boxes overlap.
+// leaves are paired up by the bounding boxes their entities had when they were last indexed.
+func (t *AABBTree) Pairs() []EntityPair {
+	var pairs []EntityPair
+	if t.root != nullNode {
+		t.selfPairs(t.root, &pairs)
+	}
+	sortEntityPairs(pairs)
+	return pairs
+}
+
+// selfPairs finds the pairs within the subtree
+func (t *AABBTree) selfPairs(index int, pairs *[]EntityPair) {
+	n := &t.nodes[index]
+	if n.isLeaf() {
+		return
+	}
+	t.selfPairs(n.left, pairs)
+	t.selfPairs(n.right, pairs)
+	t.crossPairs(n.left, n.right, pairs)
+}
+
+// crossPairs finds the pairs with one entity in each subtree
+func (t *AABBTree) crossPairs(a, b int, pairs *[]EntityPair) {
+	nodeA, nodeB := &t.nodes[a], &t.nodes[b]
+	if !collision.CheckOverlapAABBAABB(&nodeA.box, &nodeB.box) {
+		return
+	}
+
+	if nodeA.isLeaf() && nodeB.isLeaf() {
+		if !collision.CheckOverlapAABBAABB(&nodeA.entityBox, &nodeB.entityBox) {
+			return
+		}
+		if nodeA.entity.GetID() < nodeB.entity.GetID() {
+			*pairs = append(*pairs, EntityPair{A: nodeA.entity, B: nodeB.entity})
+		} else {
+			*pairs = append(*pairs, EntityPair{A: nodeB.entity, B: nodeA.entity})
+		}
+		return
+	}
+
+	// split the bigger subtree so both sides shrink at about the same rate
+	if nodeB.isLeaf() || (!nodeA.isLeaf() && surfaceArea(nodeA.box) > surfaceArea(nodeB.box)) {
+		left, right := nodeA.left, nodeA.right
+		t.crossPairs(left, b, pairs)
+		t.crossPairs(right, b, pairs)
+		return
+	}
+	left, right := nodeB.left, nodeB.right
+	t.crossPairs(a, left, pairs)
+	t.crossPairs(a, right, pairs)
+}
+
+// Height returns the height of the tree, a tree with a single entity has a height of 0
+func (t *AABBTree) Height() int {
+	if t.root == nullNode {
+		return 0
+	}
+	return t.nodes[t.root].height
+}
+
+func (t *AABBTree) query(box collider.BoundingBox, visit func(leaf *treeNode)) {
+	if t.root == nullNode {
+		return
+	}
+
+	stack := []int{t.root}
+	for len(stack) > 0 {
+		index := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		n := &t.nodes[index]
+		if !collision.CheckOverlapAABBAABB(&n.box, &box) {
+			continue
+		}
+		if n.isLeaf() {
+			visit(n)
+			continue
+		}
+		stack = append(stack, n.left, n.right)
+	}
+}
+
+func (t *AABBTree) fatten(box collider.BoundingBox) collider.BoundingBox {
+	margin := mgl64.Vec3{t.Margin, t.Margin, t.Margin}
+	return collider.BoundingBox{MinVertex: box.MinVertex.Sub(margin), MaxVertex: box.MaxVertex.Add(margin)}
+}
+
+func (t *AABBTree) allocateNode() int {
+	node := treeNode{parent: nullNode, left: nullNode, right: nullNode}
+	if t.freeList == nullNode {
+		t.nodes = append(t.nodes, node)
+		return len(t.nodes) - 1
+	}
+	index := t.freeList
+	t.freeList = t.nodes[index].next
+	t.nodes[index] = node
+	return index
+}
+
+func (t *AABBTree) freeNode(index int) {
+	t.nodes[index] = treeNode{height: -1, next: t.freeList}
+	t.freeList = index
+}
+
+// insertLeaf finds the sibling that grows the tree's surface area the least when paired
+// with the leaf
+// Box2D - b2DynamicTree::InsertLeaf
+func (t *AABBTree) insertLeaf(leaf int) {
+	if t.root == nullNode {
+		t.root = leaf
+		t.nodes[leaf].parent = nullNode
+		return
+	}
+
+	leafBox := t.nodes[leaf].box
+	index := t.root
+	for !t.nodes[index].isLeaf() {
+		n := t.nodes[index]
+		area := surfaceArea(n.box)
+		combinedArea := surfaceArea(union(n.box, leafBox))
+
+		// the cost of making a new parent for this node and the leaf
+		cost := 2 * combinedArea
+		// the minimum cost of pushing the leaf further down the tree
+		inheritanceCost := 2 * (combinedArea - area)
+
+		childCost := func(child int) float64 {
+			c := t.nodes[child]
+			childArea := surfaceArea(union(leafBox, c.box))
+			if !c.isLeaf() {
+				childArea -= surfaceArea(c.box)
+			}
+			return childArea + inheritanceCost
+		}
+		costLeft, costRight := childCost(n.left), childCost(n.right)
+
+		if cost < costLeft && cost < costRight {
+			break
+		}
+		if costLeft < costRight {
+			index = n.left
+		} else {
+			index = n.right
+		}
+	}
+
+	sibling := index
+	oldParent := t.nodes[sibling].parent
+	newParent := t.allocateNode()
+	t.nodes[newParent].parent = oldParent
+	t.nodes[newParent].box = union(leafBox, t.nodes[sibling].box)
+	t.nodes[newParent].height = t.nodes[sibling].height + 1
+	t.nodes[newParent].left = sibling
+	t.nodes[newParent].right = leaf
+	t.nodes[sibling].parent = newParent
+	t.nodes[leaf].parent = newParent
+
+	if oldParent == nullNode {
+		t.root = newParent
+	} else {
+		t.replaceChild(oldParent, sibling, newParent)
+	}
+
+	t.refit(t.nodes[leaf].parent)
+}
+
+func (t *AABBTree) removeLeaf(leaf int) {
+	if leaf == t.root {
+		t.root = nullNode
+		return
+	}
+
+	parent := t.nodes[leaf].parent
+	grandParent := t.nodes[parent].parent
+	sibling := t.nodes[parent].left
+	if sibling == leaf {
+		sibling = t.nodes[parent].right
+	}
+
+	t.nodes[leaf].parent = nullNode
+	t.freeNode(parent)
+	if grandParent == nullNode {
+		t.root = sibling
+		t.nodes[sibling].parent = nullNode
+		return
+	}
+
+	t.replaceChild(grandParent, parent, sibling)
+	t.nodes[sibling].parent = grandParent
+	t.refit(grandParent)
+}
+
+// refit walks up from index rebalancing and recomputing the bounding boxes
+func (t *AABBTree) refit(index int) {
+	for index != nullNode {
+		index = t.balance(index)
+
+		n := &t.nodes[index]
+		left, right := &t.nodes[n.left], &t.nodes[n.right]
+		n.height = 1 + maxInt(left.height, right.height)
+		n.box = union(left.box, right.box)
+
+		index = n.parent
+	}
+}
+
+// balance rotates the taller child of a up if a is unbalanced and returns the node that's
+// now in a's place
+// Box2D - b2DynamicTree::Balance
+func (t *AABBTree) balance(a int) int {
+	nodeA := &t.nodes[a]
+	if nodeA.isLeaf() || nodeA.height < 2 {
+		return a
+	}
+
+	b, c := nodeA.left, nodeA.right
+	balance := t.nodes[c].height - t.nodes[b].height
+	if balance > 1 {
+		t.rotateUp(a, c, true)
+		return c
+	}
+	if balance < -1 {
+		t.rotateUp(a, b, false)
+		return b
+	}
+	return a
+}
+
+// rotateUp swaps a with its child, which becomes a's parent. the child's taller child stays
+// with it and its shorter one is given to a in the child's old place.
+func (t *AABBTree) rotateUp(a int, child int, childIsRight bool) {
+	nodeA, nodeChild := &t.nodes[a], &t.nodes[child]
+	f, g := nodeChild.left, nodeChild.right
+
+	nodeChild.left = a
+	nodeChild.parent = nodeA.parent
+	nodeA.parent = child
+	if nodeChild.parent == nullNode {
+		t.root = child
+	} else {
+		t.replaceChild(nodeChild.parent, a, child)
+	}
+
+	keep, give := f, g
+	if t.nodes[g].height > t.nodes[f].height {
+		keep, give = g, f
+	}
+	nodeChild.right = keep
+	if childIsRight {
+		nodeA.right = give
+	} else {
+		nodeA.left = give
+	}
+	t.nodes[give].parent = a
+
+	nodeA.box = union(t.nodes[nodeA.left].box, t.nodes[nodeA.right].box)
+	nodeA.height = 1 + maxInt(t.nodes[nodeA.left].height, t.nodes[nodeA.right].height)
+	nodeChild.box = union(nodeA.box, t.nodes[keep].box)
+	nodeChild.height = 1 + maxInt(nodeA.height, t.nodes[keep].height)
+}
+
+func (t *AABBTree) replaceChild(parent int, oldChild int, newChild int) {
+	if t.nodes[parent].left == oldChild {
+		t.nodes[parent].left = newChild
+	} else {
+		t.nodes[parent].right = newChild
+	}
+}
+
+func union(a, b collider.BoundingBox) collider.BoundingBox {
+	return collider.BoundingBox{
+		MinVertex: mgl64.Vec3{math.Min(a.MinVertex[0], b.MinVertex[0]), math.Min(a.MinVertex[1], b.MinVertex[1]), math.Min(a.MinVertex[2], b.MinVertex[2])},
+		MaxVertex: mgl64.Vec3{math.Max(a.MaxVertex[0], b.MaxVertex[0]), math.Max(a.MaxVertex[1], b.MaxVertex[1]), math.Max(a.MaxVertex[2], b.MaxVertex[2])},
+	}
+}
+
+// contains returns whether inner is entirely inside outer
+func contains(outer, inner collider.BoundingBox) bool {
+	for i := 0; i < 3; i++ {
+		if inner.MinVertex[i] < outer.MinVertex[i] || inner.MaxVertex[i] > outer.MaxVertex[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func surfaceArea(box collider.BoundingBox) float64 {
+	d := box.MaxVertex.Sub(box.MinVertex)
+	return 2 * (d[0]*d[1] + d[1]*d[2] + d[2]*d[0])
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
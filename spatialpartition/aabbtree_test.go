@@ -0,0 +1,234 @@
+package spatialpartition_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/spatialpartition"
+)
+
+type testEntity struct {
+	id       int
+	position mgl64.Vec3
+	size     float64
+}
+
+func (e *testEntity) GetID() int {
+	return e.id
+}
+
+func (e *testEntity) Position() mgl64.Vec3 {
+	return e.position
+}
+
+func (e *testEntity) BoundingBox() collider.BoundingBox {
+	half := mgl64.Vec3{e.size / 2, e.size / 2, e.size / 2}
+	return collider.BoundingBox{MinVertex: e.position.Sub(half), MaxVertex: e.position.Add(half)}
+}
+
+func randomEntities(r *rand.Rand, count int, extent float64) []*testEntity {
+	var entities []*testEntity
+	for i := 0; i < count; i++ {
+		entities = append(entities, &testEntity{
+			// ids are spread out to make sure they aren't used as indices
+			id:       i*7919 + 3,
+			position: mgl64.Vec3{(r.Float64()*2 - 1) * extent, (r.Float64()*2 - 1) * extent, (r.Float64()*2 - 1) * extent},
+			size:     0.5 + r.Float64()*2,
+		})
+	}
+	return entities
+}
+
+func toEntities(testEntities []*testEntity) []spatialpartition.Entity {
+	entities := make([]spatialpartition.Entity, len(testEntities))
+	for i, e := range testEntities {
+		entities[i] = e
+	}
+	return entities
+}
+
+func bruteForceQuery(entities []*testEntity, box collider.BoundingBox) []int {
+	var ids []int
+	for _, e := range entities {
+		entityBox := e.BoundingBox()
+		if collision.CheckOverlapAABBAABB(&entityBox, &box) {
+			ids = append(ids, e.id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func bruteForcePairs(entities []*testEntity) [][2]int {
+	var pairs [][2]int
+	for i, a := range entities {
+		boxA := a.BoundingBox()
+		for _, b := range entities[i+1:] {
+			boxB := b.BoundingBox()
+			if collision.CheckOverlapAABBAABB(&boxA, &boxB) {
+				pair := [2]int{a.id, b.id}
+				if pair[1] < pair[0] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
+
+func pairIDs(pairs []spatialpartition.EntityPair) [][2]int {
+	var ids [][2]int
+	for _, pair := range pairs {
+		ids = append(ids, [2]int{pair.A.GetID(), pair.B.GetID()})
+	}
+	return ids
+}
+
+// queryIDs returns the ids of the query results that really overlap the box, the broad
+// phases are allowed to return extra entities
+func queryIDs(results []spatialpartition.Entity, box collider.BoundingBox) []int {
+	var ids []int
+	for _, e := range results {
+		entityBox := e.BoundingBox()
+		if collision.CheckOverlapAABBAABB(&entityBox, &box) {
+			ids = append(ids, e.GetID())
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalPairs(a, b [][2]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAABBTreeQueryMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	entities := randomEntities(r, 500, 50)
+	tree := spatialpartition.NewAABBTree(0.5)
+	tree.IndexEntities(toEntities(entities))
+
+	for i := 0; i < 100; i++ {
+		center := mgl64.Vec3{(r.Float64()*2 - 1) * 50, (r.Float64()*2 - 1) * 50, (r.Float64()*2 - 1) * 50}
+		half := mgl64.Vec3{5, 5, 5}
+		box := collider.BoundingBox{MinVertex: center.Sub(half), MaxVertex: center.Add(half)}
+
+		expected := bruteForceQuery(entities, box)
+		if actual := queryIDs(tree.QueryEntities(box), box); !equalInts(expected, actual) {
+			t.Fatalf("query %d: expected %v but got %v", i, expected, actual)
+		}
+	}
+}
+
+func TestAABBTreeMoveAndDelete(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	entities := randomEntities(r, 300, 30)
+	tree := spatialpartition.NewAABBTree(0.5)
+	tree.IndexEntities(toEntities(entities))
+
+	for step := 0; step < 50; step++ {
+		// some entities jiggle within their fat boxes and some move far away
+		for i, e := range entities {
+			if i%5 == 0 {
+				e.position = e.position.Add(mgl64.Vec3{r.Float64()*20 - 10, r.Float64()*20 - 10, r.Float64()*20 - 10})
+			} else {
+				e.position = e.position.Add(mgl64.Vec3{r.Float64()*0.2 - 0.1, 0, 0})
+			}
+		}
+		tree.IndexEntities(toEntities(entities))
+
+		if step%10 == 0 {
+			deleted := entities[len(entities)-1]
+			entities = entities[:len(entities)-1]
+			tree.DeleteEntity(deleted.id)
+		}
+
+		if expected, actual := bruteForcePairs(entities), pairIDs(tree.Pairs()); !equalPairs(expected, actual) {
+			t.Fatalf("step %d: expected %d pairs but got %d", step, len(expected), len(actual))
+		}
+	}
+
+	for _, e := range entities {
+		tree.DeleteEntity(e.id)
+	}
+	if results := tree.QueryEntities(collider.BoundingBox{MinVertex: mgl64.Vec3{-1000, -1000, -1000}, MaxVertex: mgl64.Vec3{1000, 1000, 1000}}); len(results) != 0 {
+		t.Errorf("expected an empty tree but got %d entities", len(results))
+	}
+}
+
+func TestAABBTreeStaysBalanced(t *testing.T) {
+	tree := spatialpartition.NewAABBTree(0.1)
+
+	// inserting in order is the worst case for a tree without rotations
+	count := 1024
+	for i := 0; i < count; i++ {
+		tree.IndexEntities([]spatialpartition.Entity{&testEntity{id: i, position: mgl64.Vec3{float64(i) * 2, 0, 0}, size: 1}})
+	}
+
+	if maxHeight := 2 * int(math.Log2(float64(count))); tree.Height() > maxHeight {
+		t.Errorf("expected a height of at most %d but got %d", maxHeight, tree.Height())
+	}
+}
+
+func TestAABBTreeIsUnbounded(t *testing.T) {
+	tree := spatialpartition.NewAABBTree(0.1)
+	far := &testEntity{id: 1 << 40, position: mgl64.Vec3{-1e6, 5e5, -3e6}, size: 1}
+	tree.IndexEntities([]spatialpartition.Entity{far})
+
+	box := far.BoundingBox()
+	if results := tree.QueryEntities(box); len(results) != 1 || results[0].GetID() != far.id {
+		t.Errorf("expected to find the far away entity but got %v", results)
+	}
+}
+
+func TestBroadphasesFindTheSamePairs(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	// the grid spans -20 to 20
+	entities := randomEntities(r, 200, 15)
+
+	expected := bruteForcePairs(entities)
+	broadphases := []spatialpartition.Broadphase{
+		spatialpartition.NewSpatialPartition(5, 8),
+		spatialpartition.NewAABBTree(0.5),
+		spatialpartition.NewHashGrid(3),
+		spatialpartition.NewOctree(mgl64.Vec3{}, 20, spatialpartition.DefaultOctreeSettings()),
+	}
+	for _, broadphase := range broadphases {
+		broadphase.IndexEntities(toEntities(entities))
+		if actual := pairIDs(broadphase.Pairs()); !equalPairs(expected, actual) {
+			t.Errorf("%T: expected %d pairs but got %d", broadphase, len(expected), len(actual))
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package spatialpartition
+
+import (
+	"sort"
+
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+// Broadphase narrows down which entities can be touching before any expensive checks are
+// done. both SpatialPartition and AABBTree implement it.
+type Broadphase interface {
+	// IndexEntities adds the entities or updates them if they're already indexed
+	IndexEntities(entityList []Entity)
+	// QueryEntities returns the entities that could overlap the bounding box, which can
+	// include some that don't
+	QueryEntities(boundingBox collider.BoundingBox) []Entity
+	DeleteEntity(entityID int)
+	// Pairs returns every pair of indexed entities whose bounding boxes overlap
+	Pairs() []EntityPair
+}
+
+// EntityPair is a pair of entities where A has the lower id
+type EntityPair struct {
+	A Entity
+	B Entity
+}
+
+func sortEntityPairs(pairs []EntityPair) {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].A.GetID() != pairs[j].A.GetID() {
+			return pairs[i].A.GetID() < pairs[j].A.GetID()
+		}
+		return pairs[i].B.GetID() < pairs[j].B.GetID()
+	})
+}
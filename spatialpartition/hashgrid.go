@@ -0,0 +1,188 @@
+package spatialpartition
+
+import (
+	"math"
+	"sort"
+
+	"github.com/kkevinchou/kitolib/collision"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+// HashGrid is a uniform grid that only allocates the cells entities are in, so it has no
+// bounds and works for worlds of any size. cells are keyed by their integer coordinates,
+// the cell at <0, 0, 0> spans <0, 0, 0> to <CellSize, CellSize, CellSize>.
+type HashGrid struct {
+	CellSize float64
+
+	cells map[PartitionKey]map[int]Entity
+	// entityCells is the range of cells each entity is in, from the min cell to the max cell
+	entityCells map[int][2]PartitionKey
+}
+
+func NewHashGrid(cellSize float64) *HashGrid {
+	return &HashGrid{
+		CellSize:    cellSize,
+		cells:       map[PartitionKey]map[int]Entity{},
+		entityCells: map[int][2]PartitionKey{},
+	}
+}
+
+func (g *HashGrid) IndexEntities(entityList []Entity) {
+	for _, entity := range entityList {
+		id := entity.GetID()
+		cellRange := g.cellRange(entity.BoundingBox())
+
+		oldRange, ok := g.entityCells[id]
+		if ok && oldRange == cellRange {
+			// still in the same cells, only the entity needs updating
+			forEachCell(cellRange, func(key PartitionKey) {
+				g.cells[key][id] = entity
+			})
+			continue
+		}
+		if ok {
+			g.removeFromCells(id, oldRange)
+		}
+
+		forEachCell(cellRange, func(key PartitionKey) {
+			cell, ok := g.cells[key]
+			if !ok {
+				cell = map[int]Entity{}
+				g.cells[key] = cell
+			}
+			cell[id] = entity
+		})
+		g.entityCells[id] = cellRange
+	}
+}
+
+// QueryEntities returns the entities in the cells the bounding box touches sorted by id
+func (g *HashGrid) QueryEntities(boundingBox collider.BoundingBox) []Entity {
+	cellRange := g.cellRange(boundingBox)
+	found := map[int]Entity{}
+	collect := func(cell map[int]Entity) {
+		for id, e := range cell {
+			found[id] = e
+		}
+	}
+
+	// big queries are cheaper to answer by going through the cells that exist
+	if cellCount(cellRange) > len(g.cells) {
+		for key, cell := range g.cells {
+			if inRange(key, cellRange) {
+				collect(cell)
+			}
+		}
+	} else {
+		forEachCell(cellRange, func(key PartitionKey) {
+			if cell, ok := g.cells[key]; ok {
+				collect(cell)
+			}
+		})
+	}
+
+	return sortedEntities(found)
+}
+
+func (g *HashGrid) DeleteEntity(entityID int) {
+	cellRange, ok := g.entityCells[entityID]
+	if !ok {
+		return
+	}
+	g.removeFromCells(entityID, cellRange)
+	delete(g.entityCells, entityID)
+}
+
+// Pairs returns the pairs of entities that share a cell and have overlapping bounding boxes
+func (g *HashGrid) Pairs() []EntityPair {
+	var pairs []EntityPair
+	seen := map[[2]int]bool{}
+
+	for _, cell := range g.cells {
+		entities := sortedEntities(cell)
+		for a := 0; a < len(entities); a++ {
+			boxA := entities[a].BoundingBox()
+			for b := a + 1; b < len(entities); b++ {
+				key := [2]int{entities[a].GetID(), entities[b].GetID()}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				boxB := entities[b].BoundingBox()
+				if collision.CheckOverlapAABBAABB(&boxA, &boxB) {
+					pairs = append(pairs, EntityPair{A: entities[a], B: entities[b]})
+				}
+			}
+		}
+	}
+
+	sortEntityPairs(pairs)
+	return pairs
+}
+
+// CellCount returns the number of cells that have been allocated
+func (g *HashGrid) CellCount() int {
+	return len(g.cells)
+}
+
+// removeFromCells removes the entity from its cells, dropping the cells that end up empty
+func (g *HashGrid) removeFromCells(entityID int, cellRange [2]PartitionKey) {
+	forEachCell(cellRange, func(key PartitionKey) {
+		cell := g.cells[key]
+		delete(cell, entityID)
+		if len(cell) == 0 {
+			delete(g.cells, key)
+		}
+	})
+}
+
+func (g *HashGrid) cellRange(boundingBox collider.BoundingBox) [2]PartitionKey {
+	var min, max PartitionKey
+	for i := 0; i < 3; i++ {
+		min[i] = int(math.Floor(boundingBox.MinVertex[i] / g.CellSize))
+		max[i] = int(math.Floor(boundingBox.MaxVertex[i] / g.CellSize))
+	}
+	return [2]PartitionKey{min, max}
+}
+
+func forEachCell(cellRange [2]PartitionKey, f func(key PartitionKey)) {
+	min, max := cellRange[0], cellRange[1]
+	for i := min[0]; i <= max[0]; i++ {
+		for j := min[1]; j <= max[1]; j++ {
+			for k := min[2]; k <= max[2]; k++ {
+				f(PartitionKey{i, j, k})
+			}
+		}
+	}
+}
+
+func cellCount(cellRange [2]PartitionKey) int {
+	count := 1
+	for i := 0; i < 3; i++ {
+		count *= cellRange[1][i] - cellRange[0][i] + 1
+		// avoid overflowing on huge queries, at this point it's bigger than any grid
+		if count > math.MaxInt32 {
+			return math.MaxInt32
+		}
+	}
+	return count
+}
+
+func inRange(key PartitionKey, cellRange [2]PartitionKey) bool {
+	for i := 0; i < 3; i++ {
+		if key[i] < cellRange[0][i] || key[i] > cellRange[1][i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedEntities(entities map[int]Entity) []Entity {
+	result := make([]Entity, 0, len(entities))
+	for _, e := range entities {
+		result = append(result, e)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].GetID() < result[j].GetID() })
+	return result
+}
@@ -0,0 +1,86 @@
+package spatialpartition_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/spatialpartition"
+)
+
+func TestHashGridQueryMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	// centered on the origin so half the coordinates are negative
+	entities := randomEntities(r, 500, 100)
+	grid := spatialpartition.NewHashGrid(4)
+	grid.IndexEntities(toEntities(entities))
+
+	for i := 0; i < 100; i++ {
+		center := mgl64.Vec3{(r.Float64()*2 - 1) * 100, (r.Float64()*2 - 1) * 100, (r.Float64()*2 - 1) * 100}
+		half := mgl64.Vec3{8, 8, 8}
+		box := collider.BoundingBox{MinVertex: center.Sub(half), MaxVertex: center.Add(half)}
+
+		expected := bruteForceQuery(entities, box)
+		if actual := queryIDs(grid.QueryEntities(box), box); !equalInts(expected, actual) {
+			t.Fatalf("query %d: expected %v but got %v", i, expected, actual)
+		}
+	}
+
+	// a query that covers far more cells than exist
+	everything := collider.BoundingBox{MinVertex: mgl64.Vec3{-1e9, -1e9, -1e9}, MaxVertex: mgl64.Vec3{1e9, 1e9, 1e9}}
+	if results := grid.QueryEntities(everything); len(results) != len(entities) {
+		t.Errorf("expected a huge query to find all %d entities but got %d", len(entities), len(results))
+	}
+}
+
+func TestHashGridAllocatesCellsLazily(t *testing.T) {
+	grid := spatialpartition.NewHashGrid(10)
+	entities := []*testEntity{
+		{id: -5, position: mgl64.Vec3{-1e7 + 5, 3, -1e7 + 5}, size: 1},
+		{id: 1 << 50, position: mgl64.Vec3{1e7 + 5, -4e6 + 5, 2e7 + 5}, size: 1},
+		{id: 7, position: mgl64.Vec3{5, 5, 5}, size: 1},
+	}
+	grid.IndexEntities(toEntities(entities))
+
+	if grid.CellCount() != 3 {
+		t.Errorf("expected one cell per entity but got %d", grid.CellCount())
+	}
+	for _, e := range entities {
+		results := grid.QueryEntities(e.BoundingBox())
+		if len(results) != 1 || results[0].GetID() != e.id {
+			t.Errorf("expected to find entity %d but got %v", e.id, results)
+		}
+	}
+
+	// an entity on a cell boundary is in both cells
+	grid.IndexEntities([]spatialpartition.Entity{&testEntity{id: 8, position: mgl64.Vec3{-10, 5, 5}, size: 1}})
+	if grid.CellCount() != 5 {
+		t.Errorf("expected the entity on the boundary to add 2 cells but there are %d", grid.CellCount())
+	}
+}
+
+func TestHashGridMoveAndDelete(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	entities := randomEntities(r, 200, 40)
+	grid := spatialpartition.NewHashGrid(5)
+	grid.IndexEntities(toEntities(entities))
+
+	for step := 0; step < 20; step++ {
+		for _, e := range entities {
+			e.position = e.position.Add(mgl64.Vec3{r.Float64()*6 - 3, r.Float64()*6 - 3, r.Float64()*6 - 3})
+		}
+		grid.IndexEntities(toEntities(entities))
+
+		if expected, actual := bruteForcePairs(entities), pairIDs(grid.Pairs()); !equalPairs(expected, actual) {
+			t.Fatalf("step %d: expected %d pairs but got %d", step, len(expected), len(actual))
+		}
+	}
+
+	for _, e := range entities {
+		grid.DeleteEntity(e.id)
+	}
+	if grid.CellCount() != 0 {
+		t.Errorf("expected empty cells to be freed but %d are left", grid.CellCount())
+	}
+}
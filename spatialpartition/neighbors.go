@@ -0,0 +1,172 @@
+package spatialpartition
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Neighbor is an entity found by QueryRadius or QueryNearest. Distance is measured to the
+// closest point on the entity's bounding box, so it's the distance to Position() for
+// entities that are points and zero when the query point is inside the bounding box.
+type Neighbor struct {
+	Entity   Entity
+	Distance float64
+}
+
+// QueryRadius returns the entities within radius of the point sorted from closest to
+// furthest. filter is optional and entities it returns false for are skipped.
+func (s *SpatialPartition) QueryRadius(point mgl64.Vec3, radius float64, filter func(entity Entity) bool) []Neighbor {
+	found := s.searchRings(point, filter, func(covered float64, found []Neighbor) bool {
+		return covered >= radius
+	})
+
+	var neighbors []Neighbor
+	for _, neighbor := range found {
+		if neighbor.Distance <= radius {
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+	sortNeighbors(neighbors)
+	return neighbors
+}
+
+// QueryNearest returns the k entities closest to the point sorted from closest to furthest,
+// or fewer if there aren't k entities. filter is optional and entities it returns false for
+// are skipped.
+func (s *SpatialPartition) QueryNearest(point mgl64.Vec3, k int, filter func(entity Entity) bool) []Neighbor {
+	if k <= 0 {
+		return nil
+	}
+
+	found := s.searchRings(point, filter, func(covered float64, found []Neighbor) bool {
+		if len(found) < k {
+			return false
+		}
+		sortNeighbors(found)
+		return found[k-1].Distance <= covered
+	})
+
+	sortNeighbors(found)
+	if len(found) > k {
+		found = found[:k]
+	}
+	return found
+}
+
+// searchRings visits the partitions in rings around the one containing the point, one ring
+// at a time, until stop returns true or every partition has been visited. stop is passed how
+// far from the point the visited partitions are known to cover, anything further away can
+// only be in a later ring.
+func (s *SpatialPartition) searchRings(point mgl64.Vec3, filter func(entity Entity) bool, stop func(covered float64, found []Neighbor) bool) []Neighbor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	minVertex := s.calcMinPartitionVertex()
+	dimension := float64(s.PartitionDimension)
+
+	var center [3]int
+	maxRing := 0
+	for i := 0; i < 3; i++ {
+		center[i] = int(math.Floor((point[i] - minVertex[i]) / dimension))
+		maxRing = maxInt(maxRing, maxInt(center[i], s.PartitionCount-1-center[i]))
+	}
+
+	var found []Neighbor
+	seen := map[int]bool{}
+	for ring := 0; ring <= maxRing; ring++ {
+		s.forEachPartitionInRing(center, ring, func(partition *Partition) {
+			for id, e := range partition.entities {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				if filter != nil && !filter(e) {
+					continue
+				}
+				found = append(found, Neighbor{Entity: e, Distance: distanceToBoundingBox(point, e)})
+			}
+		})
+
+		if stop(s.ringCoverage(point, center, ring), found) {
+			break
+		}
+	}
+	return found
+}
+
+// forEachPartitionInRing visits the partitions that are exactly ring partitions away from
+// center along at least one axis
+func (s *SpatialPartition) forEachPartitionInRing(center [3]int, ring int, visit func(partition *Partition)) {
+	for i := center[0] - ring; i <= center[0]+ring; i++ {
+		if i < 0 || i >= s.PartitionCount {
+			continue
+		}
+		for j := center[1] - ring; j <= center[1]+ring; j++ {
+			if j < 0 || j >= s.PartitionCount {
+				continue
+			}
+			for k := center[2] - ring; k <= center[2]+ring; k++ {
+				if k < 0 || k >= s.PartitionCount {
+					continue
+				}
+				onRing := absInt(i-center[0]) == ring || absInt(j-center[1]) == ring || absInt(k-center[2]) == ring
+				if !onRing {
+					// skip the inside of the cube, it was visited by earlier rings
+					if ring > 0 {
+						k = center[2] + ring - 1
+					}
+					continue
+				}
+				visit(&s.Partitions[i][j][k])
+			}
+		}
+	}
+}
+
+// ringCoverage returns the distance from the point to the nearest side of the cube of
+// partitions visited so far. sides that are past the edge of the grid don't count since
+// there's nothing beyond them.
+func (s *SpatialPartition) ringCoverage(point mgl64.Vec3, center [3]int, ring int) float64 {
+	minVertex := s.calcMinPartitionVertex()
+	dimension := float64(s.PartitionDimension)
+
+	covered := math.Inf(1)
+	for i := 0; i < 3; i++ {
+		if center[i]-ring > 0 {
+			low := minVertex[i] + float64(center[i]-ring)*dimension
+			covered = math.Min(covered, point[i]-low)
+		}
+		if center[i]+ring < s.PartitionCount-1 {
+			high := minVertex[i] + float64(center[i]+ring+1)*dimension
+			covered = math.Min(covered, high-point[i])
+		}
+	}
+	return covered
+}
+
+func distanceToBoundingBox(point mgl64.Vec3, entity Entity) float64 {
+	box := entity.BoundingBox()
+	var closest mgl64.Vec3
+	for i := 0; i < 3; i++ {
+		closest[i] = math.Max(box.MinVertex[i], math.Min(box.MaxVertex[i], point[i]))
+	}
+	return point.Sub(closest).Len()
+}
+
+func sortNeighbors(neighbors []Neighbor) {
+	sort.Slice(neighbors, func(i, j int) bool {
+		if neighbors[i].Distance != neighbors[j].Distance {
+			return neighbors[i].Distance < neighbors[j].Distance
+		}
+		return neighbors[i].Entity.GetID() < neighbors[j].Entity.GetID()
+	})
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
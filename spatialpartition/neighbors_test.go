@@ -0,0 +1,102 @@
+package spatialpartition_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/spatialpartition"
+)
+
+type expectedNeighbor struct {
+	id       int
+	distance float64
+}
+
+func bruteForceNeighbors(entities []*testEntity, point mgl64.Vec3, filter func(e spatialpartition.Entity) bool) []expectedNeighbor {
+	var neighbors []expectedNeighbor
+	for _, e := range entities {
+		if filter != nil && !filter(e) {
+			continue
+		}
+		box := e.BoundingBox()
+		var closest mgl64.Vec3
+		for i := 0; i < 3; i++ {
+			closest[i] = math.Max(box.MinVertex[i], math.Min(box.MaxVertex[i], point[i]))
+		}
+		neighbors = append(neighbors, expectedNeighbor{id: e.id, distance: point.Sub(closest).Len()})
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		if neighbors[i].distance != neighbors[j].distance {
+			return neighbors[i].distance < neighbors[j].distance
+		}
+		return neighbors[i].id < neighbors[j].id
+	})
+	return neighbors
+}
+
+func checkNeighbors(t *testing.T, query string, expected []expectedNeighbor, actual []spatialpartition.Neighbor) {
+	t.Helper()
+	if len(expected) != len(actual) {
+		t.Fatalf("%s: expected %d neighbors but got %d", query, len(expected), len(actual))
+	}
+	for i := range expected {
+		if expected[i].id != actual[i].Entity.GetID() || math.Abs(expected[i].distance-actual[i].Distance) > 1e-9 {
+			t.Fatalf("%s: expected neighbor %d to be %d at %f but got %d at %f", query, i, expected[i].id, expected[i].distance, actual[i].Entity.GetID(), actual[i].Distance)
+		}
+	}
+}
+
+func TestQueryRadius(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	entities := randomEntities(r, 300, 18)
+	partition := spatialpartition.NewSpatialPartition(5, 8)
+	partition.IndexEntities(toEntities(entities))
+
+	evenIDs := func(e spatialpartition.Entity) bool { return e.GetID()%2 == 0 }
+	for i := 0; i < 50; i++ {
+		// some of the points are outside of the grid
+		point := mgl64.Vec3{(r.Float64()*2 - 1) * 25, (r.Float64()*2 - 1) * 25, (r.Float64()*2 - 1) * 25}
+		radius := r.Float64() * 12
+
+		var filter func(e spatialpartition.Entity) bool
+		if i%2 == 1 {
+			filter = evenIDs
+		}
+
+		var expected []expectedNeighbor
+		for _, neighbor := range bruteForceNeighbors(entities, point, filter) {
+			if neighbor.distance <= radius {
+				expected = append(expected, neighbor)
+			}
+		}
+		checkNeighbors(t, "radius", expected, partition.QueryRadius(point, radius, filter))
+	}
+}
+
+func TestQueryNearest(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	entities := randomEntities(r, 300, 18)
+	partition := spatialpartition.NewSpatialPartition(5, 8)
+	partition.IndexEntities(toEntities(entities))
+
+	evenIDs := func(e spatialpartition.Entity) bool { return e.GetID()%2 == 0 }
+	for i := 0; i < 50; i++ {
+		point := mgl64.Vec3{(r.Float64()*2 - 1) * 25, (r.Float64()*2 - 1) * 25, (r.Float64()*2 - 1) * 25}
+		k := 1 + r.Intn(10)
+
+		var filter func(e spatialpartition.Entity) bool
+		if i%2 == 1 {
+			filter = evenIDs
+		}
+
+		expected := bruteForceNeighbors(entities, point, filter)[:k]
+		checkNeighbors(t, "nearest", expected, partition.QueryNearest(point, k, filter))
+	}
+
+	if neighbors := partition.QueryNearest(mgl64.Vec3{}, 1000, nil); len(neighbors) != len(entities) {
+		t.Errorf("expected asking for more neighbors than there are to return all %d but got %d", len(entities), len(neighbors))
+	}
+}
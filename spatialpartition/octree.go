@@ -0,0 +1,404 @@
+package spatialpartition
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision"
+	"github.com/kkevinchou/kitolib/collision/collider"
+)
+
+type OctreeSettings struct {
+	// MaxEntities is how many entities a node can hold before it's split
+	MaxEntities int
+	// MinEntities is the number of entities under a node at which its children are merged back
+	// into it. it should be lower than MaxEntities so that nodes don't keep splitting and merging.
+	MinEntities int
+	MaxDepth    int
+	// Looseness is how much bigger a node's bounds are than its share of space. entities that
+	// are less than half as big as a node's share always fit in it with the usual value of 2.
+	Looseness float64
+}
+
+func DefaultOctreeSettings() OctreeSettings {
+	return OctreeSettings{
+		MaxEntities: 8,
+		MinEntities: 4,
+		MaxDepth:    8,
+		Looseness:   2,
+	}
+}
+
+// Octree is a loose octree, entities are stored in the deepest node whose loose bounds fit
+// their bounding box so each entity is only in one node. entities that don't fit in the
+// root are kept in the root.
+type Octree struct {
+	Settings OctreeSettings
+
+	root        *octreeNode
+	entityNodes map[int]*octreeNode
+}
+
+type octreeNode struct {
+	center   mgl64.Vec3
+	halfSize float64
+	depth    int
+
+	parent   *octreeNode
+	children []*octreeNode
+	entities map[int]Entity
+
+	// count is the number of entities in this node and every node under it
+	count int
+}
+
+// NewOctree creates an octree whose root covers the cube centered on center that extends
+// halfSize along each axis
+func NewOctree(center mgl64.Vec3, halfSize float64, settings OctreeSettings) *Octree {
+	return &Octree{
+		Settings:    settings,
+		root:        &octreeNode{center: center, halfSize: halfSize, entities: map[int]Entity{}},
+		entityNodes: map[int]*octreeNode{},
+	}
+}
+
+func (o *Octree) IndexEntities(entityList []Entity) {
+	for _, entity := range entityList {
+		box := entity.BoundingBox()
+		if node, ok := o.entityNodes[entity.GetID()]; ok {
+			// leaves can't push the entity any deeper so it can stay while it still fits
+			if node.children == nil && (node == o.root || contains(o.looseBounds(node), box)) {
+				node.entities[entity.GetID()] = entity
+				continue
+			}
+			o.remove(entity.GetID())
+		}
+		o.insert(entity, box)
+	}
+}
+
+// QueryEntities returns the entities whose bounding boxes overlap the bounding box
+func (o *Octree) QueryEntities(boundingBox collider.BoundingBox) []Entity {
+	var entities []Entity
+	o.traverse(func(node *octreeNode) bool {
+		bounds := o.looseBounds(node)
+		return collision.CheckOverlapAABBAABB(&bounds, &boundingBox)
+	}, func(entity Entity) {
+		box := entity.BoundingBox()
+		if collision.CheckOverlapAABBAABB(&box, &boundingBox) {
+			entities = append(entities, entity)
+		}
+	})
+	return entities
+}
+
+func (o *Octree) DeleteEntity(entityID int) {
+	if _, ok := o.entityNodes[entityID]; ok {
+		o.remove(entityID)
+	}
+}
+
+func (o *Octree) Pairs() []EntityPair {
+	ids := make([]int, 0, len(o.entityNodes))
+	for id := range o.entityNodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var pairs []EntityPair
+	for _, id := range ids {
+		entity := o.entityNodes[id].entities[id]
+		for _, other := range o.QueryEntities(entity.BoundingBox()) {
+			if other.GetID() > id {
+				pairs = append(pairs, EntityPair{A: entity, B: other})
+			}
+		}
+	}
+	sortEntityPairs(pairs)
+	return pairs
+}
+
+// QueryFrustum returns the entities whose bounding boxes are at least partially inside the
+// frustum
+func (o *Octree) QueryFrustum(frustum Frustum) []Entity {
+	var entities []Entity
+	o.traverse(func(node *octreeNode) bool {
+		return frustum.IntersectsBoundingBox(o.looseBounds(node))
+	}, func(entity Entity) {
+		if frustum.IntersectsBoundingBox(entity.BoundingBox()) {
+			entities = append(entities, entity)
+		}
+	})
+	return entities
+}
+
+// Raycast returns the entities whose bounding boxes the ray passes through within
+// maxDistance, sorted by the distance along the ray to where it enters them. the ray's
+// direction doesn't need to be normalized.
+func (o *Octree) Raycast(ray collider.Ray, maxDistance float64) []Neighbor {
+	direction := ray.Direction.Normalize()
+	var hits []Neighbor
+	o.traverse(func(node *octreeNode) bool {
+		_, hit := rayBoxDistance(ray.Origin, direction, o.looseBounds(node), maxDistance)
+		return hit
+	}, func(entity Entity) {
+		if distance, hit := rayBoxDistance(ray.Origin, direction, entity.BoundingBox(), maxDistance); hit {
+			hits = append(hits, Neighbor{Entity: entity, Distance: distance})
+		}
+	})
+	sortNeighbors(hits)
+	return hits
+}
+
+// NodeCount returns the number of nodes in the tree, including the root
+func (o *Octree) NodeCount() int {
+	count := 0
+	o.forEachNode(o.root, func(node *octreeNode) { count++ })
+	return count
+}
+
+// OctreeDebugNode describes a node for debugging and drawing the tree
+type OctreeDebugNode struct {
+	// Bounds are the node's loose bounds
+	Bounds      collider.BoundingBox
+	Depth       int
+	EntityCount int
+}
+
+// DebugNodes returns every node in the tree, parents before their children
+func (o *Octree) DebugNodes() []OctreeDebugNode {
+	var nodes []OctreeDebugNode
+	o.forEachNode(o.root, func(node *octreeNode) {
+		nodes = append(nodes, OctreeDebugNode{Bounds: o.looseBounds(node), Depth: node.depth, EntityCount: len(node.entities)})
+	})
+	return nodes
+}
+
+// Dump returns the tree's nodes as text, one line per node indented by depth
+func (o *Octree) Dump() string {
+	var b strings.Builder
+	for _, node := range o.DebugNodes() {
+		fmt.Fprintf(&b, "%s%v - %v: %d entities\n", strings.Repeat("  ", node.Depth), node.Bounds.MinVertex, node.Bounds.MaxVertex, node.EntityCount)
+	}
+	return b.String()
+}
+
+func (o *Octree) insert(entity Entity, box collider.BoundingBox) {
+	node := o.root
+	for node.children != nil {
+		child := o.childFor(node, box)
+		if child == nil {
+			break
+		}
+		node = child
+	}
+
+	node.entities[entity.GetID()] = entity
+	o.entityNodes[entity.GetID()] = node
+	for n := node; n != nil; n = n.parent {
+		n.count++
+	}
+
+	if node.children == nil && len(node.entities) > o.Settings.MaxEntities && node.depth < o.Settings.MaxDepth {
+		o.split(node)
+	}
+}
+
+func (o *Octree) remove(entityID int) {
+	node := o.entityNodes[entityID]
+	delete(node.entities, entityID)
+	delete(o.entityNodes, entityID)
+	for n := node; n != nil; n = n.parent {
+		n.count--
+	}
+
+	// merge from the top down so that a whole sparse branch collapses at once
+	var merge *octreeNode
+	for n := node; n != nil; n = n.parent {
+		if n.children != nil && n.count <= o.Settings.MinEntities {
+			merge = n
+		}
+	}
+	if merge != nil {
+		o.merge(merge)
+	}
+}
+
+// split gives the node children and moves down the entities that fit in them
+func (o *Octree) split(node *octreeNode) {
+	childHalfSize := node.halfSize / 2
+	node.children = make([]*octreeNode, 8)
+	for i := range node.children {
+		offset := mgl64.Vec3{-childHalfSize, -childHalfSize, -childHalfSize}
+		for axis := 0; axis < 3; axis++ {
+			if i&(1<<axis) != 0 {
+				offset[axis] = childHalfSize
+			}
+		}
+		node.children[i] = &octreeNode{
+			center:   node.center.Add(offset),
+			halfSize: childHalfSize,
+			depth:    node.depth + 1,
+			parent:   node,
+			entities: map[int]Entity{},
+		}
+	}
+
+	for id, entity := range node.entities {
+		child := o.childFor(node, entity.BoundingBox())
+		if child == nil {
+			continue
+		}
+		delete(node.entities, id)
+		child.entities[id] = entity
+		child.count++
+		o.entityNodes[id] = child
+	}
+
+	for _, child := range node.children {
+		if len(child.entities) > o.Settings.MaxEntities && child.depth < o.Settings.MaxDepth {
+			o.split(child)
+		}
+	}
+}
+
+// merge moves every entity under the node into it and drops its children
+func (o *Octree) merge(node *octreeNode) {
+	for _, child := range node.children {
+		o.forEachNode(child, func(n *octreeNode) {
+			for id, entity := range n.entities {
+				node.entities[id] = entity
+				o.entityNodes[id] = node
+			}
+		})
+	}
+	node.children = nil
+}
+
+// childFor returns the child of the node that the bounding box fits in, or nil if it doesn't
+// fit in any of them. with loose bounds the child containing the center is the only one it
+// can fit in.
+func (o *Octree) childFor(node *octreeNode, box collider.BoundingBox) *octreeNode {
+	center := box.MinVertex.Add(box.MaxVertex).Mul(0.5)
+	index := 0
+	for axis := 0; axis < 3; axis++ {
+		if center[axis] >= node.center[axis] {
+			index |= 1 << axis
+		}
+	}
+
+	child := node.children[index]
+	if !contains(o.looseBounds(child), box) {
+		return nil
+	}
+	return child
+}
+
+// traverse visits the entities in the nodes that visitNode returns true for. the root is
+// always visited since it also holds the entities that don't fit in it.
+func (o *Octree) traverse(visitNode func(node *octreeNode) bool, visitEntity func(entity Entity)) {
+	stack := []*octreeNode{o.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node != o.root && !visitNode(node) {
+			continue
+		}
+
+		for _, entity := range sortedEntities(node.entities) {
+			visitEntity(entity)
+		}
+		for _, child := range node.children {
+			if child.count > 0 {
+				stack = append(stack, child)
+			}
+		}
+	}
+}
+
+func (o *Octree) forEachNode(node *octreeNode, visit func(node *octreeNode)) {
+	visit(node)
+	for _, child := range node.children {
+		o.forEachNode(child, visit)
+	}
+}
+
+func (o *Octree) looseBounds(node *octreeNode) collider.BoundingBox {
+	half := node.halfSize * o.Settings.Looseness
+	extents := mgl64.Vec3{half, half, half}
+	return collider.BoundingBox{MinVertex: node.center.Sub(extents), MaxVertex: node.center.Add(extents)}
+}
+
+// rayBoxDistance returns the distance along the ray to where it enters the box, or zero if
+// it starts inside
+func rayBoxDistance(origin, direction mgl64.Vec3, box collider.BoundingBox, maxDistance float64) (float64, bool) {
+	tMin, tMax := 0.0, maxDistance
+	for axis := 0; axis < 3; axis++ {
+		if math.Abs(direction[axis]) < 1e-12 {
+			if origin[axis] < box.MinVertex[axis] || origin[axis] > box.MaxVertex[axis] {
+				return 0, false
+			}
+			continue
+		}
+
+		t1 := (box.MinVertex[axis] - origin[axis]) / direction[axis]
+		t2 := (box.MaxVertex[axis] - origin[axis]) / direction[axis]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin = math.Max(tMin, t1)
+		tMax = math.Min(tMax, t2)
+		if tMin > tMax {
+			return 0, false
+		}
+	}
+	return tMin, true
+}
+
+// Frustum is six planes whose normals point into the frustum
+type Frustum [6]collider.Plane
+
+// FrustumFromMatrix extracts the frustum from an OpenGL style view projection matrix
+// Fast Extraction of Viewing Frustum Planes from the World-View-Projection Matrix - Gribb, Hartmann
+func FrustumFromMatrix(viewProjection mgl64.Mat4) Frustum {
+	row := func(i int) mgl64.Vec4 { return viewProjection.Row(i) }
+	coefficients := [6]mgl64.Vec4{
+		row(3).Add(row(0)), // left
+		row(3).Sub(row(0)), // right
+		row(3).Add(row(1)), // bottom
+		row(3).Sub(row(1)), // top
+		row(3).Add(row(2)), // near
+		row(3).Sub(row(2)), // far
+	}
+
+	var frustum Frustum
+	for i, c := range coefficients {
+		normal := c.Vec3()
+		length := normal.Len()
+		normal = normal.Mul(1 / length)
+		frustum[i] = collider.Plane{Point: normal.Mul(-c[3] / length), Normal: normal}
+	}
+	return frustum
+}
+
+// IntersectsBoundingBox returns false when the box is entirely outside one of the planes.
+// boxes near the frustum's corners can be outside of it and still pass.
+func (f Frustum) IntersectsBoundingBox(box collider.BoundingBox) bool {
+	for _, plane := range f {
+		// the corner furthest along the normal
+		var corner mgl64.Vec3
+		for axis := 0; axis < 3; axis++ {
+			corner[axis] = box.MinVertex[axis]
+			if plane.Normal[axis] >= 0 {
+				corner[axis] = box.MaxVertex[axis]
+			}
+		}
+		if corner.Sub(plane.Point).Dot(plane.Normal) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,209 @@
+package spatialpartition_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/checks"
+	"github.com/kkevinchou/kitolib/collision/collider"
+	"github.com/kkevinchou/kitolib/spatialpartition"
+)
+
+// townAndWilderness packs most of the entities into a small town and spreads the rest out
+func townAndWilderness(r *rand.Rand) []*testEntity {
+	var entities []*testEntity
+	for i := 0; i < 600; i++ {
+		extent := 90.0
+		var center mgl64.Vec3
+		if i%4 != 0 {
+			extent = 5
+			center = mgl64.Vec3{40, 0, -40}
+		}
+		entities = append(entities, &testEntity{
+			id:       i,
+			position: center.Add(mgl64.Vec3{(r.Float64()*2 - 1) * extent, (r.Float64()*2 - 1) * extent, (r.Float64()*2 - 1) * extent}),
+			size:     0.2 + r.Float64()*2,
+		})
+	}
+	return entities
+}
+
+func entityIDs(entities []spatialpartition.Entity) []int {
+	var ids []int
+	for _, e := range entities {
+		ids = append(ids, e.GetID())
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func TestOctreeQueryMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(10))
+	entities := townAndWilderness(r)
+	octree := spatialpartition.NewOctree(mgl64.Vec3{}, 100, spatialpartition.DefaultOctreeSettings())
+	octree.IndexEntities(toEntities(entities))
+
+	for i := 0; i < 100; i++ {
+		center := mgl64.Vec3{(r.Float64()*2 - 1) * 100, (r.Float64()*2 - 1) * 100, (r.Float64()*2 - 1) * 100}
+		if i%2 == 0 {
+			center = mgl64.Vec3{40, 0, -40}.Add(mgl64.Vec3{r.Float64() * 3, r.Float64() * 3, r.Float64() * 3})
+		}
+		half := mgl64.Vec3{4, 4, 4}
+		box := collider.BoundingBox{MinVertex: center.Sub(half), MaxVertex: center.Add(half)}
+
+		if expected, actual := bruteForceQuery(entities, box), entityIDs(octree.QueryEntities(box)); !equalInts(expected, actual) {
+			t.Fatalf("query %d: expected %v but got %v", i, expected, actual)
+		}
+	}
+}
+
+func TestOctreeSplitsAndMerges(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	entities := townAndWilderness(r)
+	settings := spatialpartition.DefaultOctreeSettings()
+	octree := spatialpartition.NewOctree(mgl64.Vec3{}, 100, settings)
+	octree.IndexEntities(toEntities(entities))
+
+	nodes := octree.DebugNodes()
+	if len(nodes) != octree.NodeCount() || len(nodes) <= 1 {
+		t.Fatalf("expected the octree to split but it has %d nodes", len(nodes))
+	}
+	for _, node := range nodes {
+		if node.EntityCount > settings.MaxEntities && node.Depth < settings.MaxDepth {
+			t.Errorf("expected nodes to split once they have more than %d entities but one has %d", settings.MaxEntities, node.EntityCount)
+		}
+	}
+
+	// the town is denser so it should be deeper
+	var townDepth, wildernessDepth int
+	for _, node := range nodes {
+		center := node.Bounds.MinVertex.Add(node.Bounds.MaxVertex).Mul(0.5)
+		if center.Sub(mgl64.Vec3{40, 0, -40}).Len() < 10 {
+			townDepth = maxDepth(townDepth, node.Depth)
+		} else {
+			wildernessDepth = maxDepth(wildernessDepth, node.Depth)
+		}
+	}
+	if townDepth <= wildernessDepth {
+		t.Errorf("expected the town to be subdivided further than the wilderness but got depths %d and %d", townDepth, wildernessDepth)
+	}
+
+	if dump := octree.Dump(); strings.Count(dump, "\n") != len(nodes) {
+		t.Errorf("expected a line per node in the dump but got:\n%s", dump)
+	}
+
+	for _, e := range entities[settings.MinEntities:] {
+		octree.DeleteEntity(e.id)
+	}
+	if octree.NodeCount() != 1 {
+		t.Errorf("expected the tree to merge back into the root but it has %d nodes", octree.NodeCount())
+	}
+	if results := octree.QueryEntities(collider.BoundingBox{MinVertex: mgl64.Vec3{-200, -200, -200}, MaxVertex: mgl64.Vec3{200, 200, 200}}); len(results) != settings.MinEntities {
+		t.Errorf("expected %d entities to be left but got %d", settings.MinEntities, len(results))
+	}
+}
+
+func maxDepth(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func TestOctreeMovingEntities(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	entities := randomEntities(r, 300, 40)
+	octree := spatialpartition.NewOctree(mgl64.Vec3{}, 32, spatialpartition.DefaultOctreeSettings())
+	octree.IndexEntities(toEntities(entities))
+
+	for step := 0; step < 30; step++ {
+		for _, e := range entities {
+			// some wander outside of the root
+			e.position = e.position.Add(mgl64.Vec3{r.Float64()*4 - 2, r.Float64()*4 - 2, r.Float64()*4 - 2})
+		}
+		octree.IndexEntities(toEntities(entities))
+
+		if expected, actual := bruteForcePairs(entities), pairIDs(octree.Pairs()); !equalPairs(expected, actual) {
+			t.Fatalf("step %d: expected %d pairs but got %d", step, len(expected), len(actual))
+		}
+	}
+}
+
+func TestOctreeFrustum(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	entities := townAndWilderness(r)
+	octree := spatialpartition.NewOctree(mgl64.Vec3{}, 100, spatialpartition.DefaultOctreeSettings())
+	octree.IndexEntities(toEntities(entities))
+
+	view := mgl64.LookAtV(mgl64.Vec3{0, 0, 0}, mgl64.Vec3{40, 0, -40}, mgl64.Vec3{0, 1, 0})
+	projection := mgl64.Perspective(mgl64.DegToRad(60), 16.0/9, 0.1, 100)
+	frustum := spatialpartition.FrustumFromMatrix(projection.Mul4(view))
+
+	var expected []int
+	for _, e := range entities {
+		if frustum.IntersectsBoundingBox(e.BoundingBox()) {
+			expected = append(expected, e.id)
+		}
+	}
+	actual := entityIDs(octree.QueryFrustum(frustum))
+	if !equalInts(expected, actual) {
+		t.Fatalf("expected %d entities in the frustum but got %d", len(expected), len(actual))
+	}
+
+	inFront := collider.BoundingBox{MinVertex: mgl64.Vec3{9, -1, -11}, MaxVertex: mgl64.Vec3{11, 1, -9}}
+	behind := collider.BoundingBox{MinVertex: mgl64.Vec3{-11, -1, 9}, MaxVertex: mgl64.Vec3{-9, 1, 11}}
+	tooFar := collider.BoundingBox{MinVertex: mgl64.Vec3{99, -1, -101}, MaxVertex: mgl64.Vec3{101, 1, -99}}
+	if !frustum.IntersectsBoundingBox(inFront) {
+		t.Error("expected a box in front of the camera to be in the frustum")
+	}
+	if frustum.IntersectsBoundingBox(behind) {
+		t.Error("expected a box behind the camera to not be in the frustum")
+	}
+	if frustum.IntersectsBoundingBox(tooFar) {
+		t.Error("expected a box past the far plane to not be in the frustum")
+	}
+}
+
+func TestOctreeRaycast(t *testing.T) {
+	r := rand.New(rand.NewSource(14))
+	entities := townAndWilderness(r)
+	octree := spatialpartition.NewOctree(mgl64.Vec3{}, 100, spatialpartition.DefaultOctreeSettings())
+	octree.IndexEntities(toEntities(entities))
+
+	for i := 0; i < 50; i++ {
+		origin := mgl64.Vec3{(r.Float64()*2 - 1) * 50, (r.Float64()*2 - 1) * 50, (r.Float64()*2 - 1) * 50}
+		target := mgl64.Vec3{40, 0, -40}.Add(mgl64.Vec3{r.Float64()*6 - 3, r.Float64()*6 - 3, r.Float64()*6 - 3})
+		ray := collider.Ray{Origin: origin, Direction: target.Sub(origin).Normalize()}
+		maxDistance := 200.0
+
+		var expected []expectedNeighbor
+		for _, e := range entities {
+			box := e.BoundingBox()
+			if point, hit := checks.IntersectRayAABB(ray, &box); hit {
+				if distance := point.Sub(origin).Len(); distance <= maxDistance {
+					expected = append(expected, expectedNeighbor{id: e.id, distance: distance})
+				}
+			}
+		}
+		sort.Slice(expected, func(i, j int) bool {
+			if expected[i].distance != expected[j].distance {
+				return expected[i].distance < expected[j].distance
+			}
+			return expected[i].id < expected[j].id
+		})
+
+		hits := octree.Raycast(ray, maxDistance)
+		if len(hits) != len(expected) {
+			t.Fatalf("ray %d: expected %d hits but got %d", i, len(expected), len(hits))
+		}
+		for j := range hits {
+			if hits[j].Entity.GetID() != expected[j].id || math.Abs(hits[j].Distance-expected[j].distance) > 1e-6 {
+				t.Fatalf("ray %d: expected hit %d to be %d at %f but got %d at %f", i, j, expected[j].id, expected[j].distance, hits[j].Entity.GetID(), hits[j].Distance)
+			}
+		}
+	}
+}
@@ -2,8 +2,10 @@ package spatialpartition
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision"
 	"github.com/kkevinchou/kitolib/collision/collider"
 )
 
@@ -25,16 +27,53 @@ func (p *Partition) String() string {
 
 type PartitionKey [3]int
 
+// SpatialPartition is safe for concurrent use. queries can run in parallel with each other
+// and wait for any indexing that's in progress. Partitions is not guarded and should only be
+// read when nothing is being indexed.
 type SpatialPartition struct {
 	Partitions         [][][]Partition
 	PartitionDimension int
 	PartitionCount     int
 
-	entityPartitionCache map[int]map[PartitionKey]any
-	entityPositionCache  map[int]mgl64.Vec3
+	indexed map[int]*indexedEntity
+	// occupied is the set of partitions that have entities in them
+	occupied map[PartitionKey]bool
 
-	// pool partition keys to avoid reallocating new arrays and resizing slices
-	pooledPartitionKeys []PartitionKey
+	// indexKeys is reused between calls to IndexEntities, which holds the write lock
+	indexKeys []PartitionKey
+	mu        sync.RWMutex
+}
+
+// indexedEntity is where an entity was last indexed
+type indexedEntity struct {
+	boundingBox collider.BoundingBox
+	// keys are the partitions the entity is in, starting with the lowest
+	keys []PartitionKey
+}
+
+// queryScratch is the memory a query needs, pooled so that queries don't allocate it every
+// time and concurrent queries don't share it
+type queryScratch struct {
+	keys []PartitionKey
+	seen map[int]bool
+}
+
+var queryScratchPool = sync.Pool{
+	New: func() any {
+		return &queryScratch{seen: map[int]bool{}}
+	},
+}
+
+func getQueryScratch() *queryScratch {
+	return queryScratchPool.Get().(*queryScratch)
+}
+
+func putQueryScratch(scratch *queryScratch) {
+	scratch.keys = scratch.keys[:0]
+	for id := range scratch.seen {
+		delete(scratch.seen, id)
+	}
+	queryScratchPool.Put(scratch)
 }
 
 // NewSpatialPartition creates a spatial partition with the bottom at <0, 0, 0>
@@ -54,12 +93,13 @@ func NewSpatialPartition(partitionDimension int, partitionCount int) *SpatialPar
 
 func (s *SpatialPartition) initialize() {
 	s.Partitions = initializePartitions(s.PartitionDimension, s.PartitionCount)
-	s.pooledPartitionKeys = make([]PartitionKey, s.PartitionCount*s.PartitionCount*s.PartitionCount)
-	s.entityPartitionCache = map[int]map[PartitionKey]any{}
-	s.entityPositionCache = map[int]mgl64.Vec3{}
+	s.indexed = map[int]*indexedEntity{}
+	s.occupied = map[PartitionKey]bool{}
 }
 
 func (s *SpatialPartition) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.initialize()
 }
 
@@ -68,16 +108,20 @@ func (s *SpatialPartition) QueryEntities(boundingBox collider.BoundingBox) []Ent
 	// determine which partitions the entity touches
 	// collect all entities that belong to each of the partitions
 
-	partitions := s.IntersectingPartitions(boundingBox)
-	candidates := []Entity{}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scratch := getQueryScratch()
+	defer putQueryScratch(scratch)
 
-	var boolFlags [100000]bool
+	scratch.keys = s.appendIntersectingPartitions(scratch.keys, boundingBox)
+	candidates := []Entity{}
 
-	for _, partitionKey := range partitions {
+	for _, partitionKey := range scratch.keys {
 		partition := &s.Partitions[partitionKey[0]][partitionKey[1]][partitionKey[2]]
 		for _, e := range partition.entities {
-			if !boolFlags[e.GetID()] {
-				boolFlags[e.GetID()] = true
+			if !scratch.seen[e.GetID()] {
+				scratch.seen[e.GetID()] = true
 				candidates = append(candidates, e)
 			}
 		}
@@ -86,32 +130,90 @@ func (s *SpatialPartition) QueryEntities(boundingBox collider.BoundingBox) []Ent
 	return candidates
 }
 
+// Pairs returns the pairs of entities that share a partition and have overlapping bounding
+// boxes. only partitions with entities in them are looked at.
+func (s *SpatialPartition) Pairs() []EntityPair {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var pairs []EntityPair
+	for key := range s.occupied {
+		partition := &s.Partitions[key[0]][key[1]][key[2]]
+		for idA, a := range partition.entities {
+			indexedA := s.indexed[idA]
+			for idB, b := range partition.entities {
+				if idA >= idB {
+					continue
+				}
+
+				// entities that share more than one partition are only paired up in the lowest
+				// one, which saves having to remember which pairs have been seen
+				indexedB := s.indexed[idB]
+				if lowestSharedPartition(indexedA.keys[0], indexedB.keys[0]) != key {
+					continue
+				}
+
+				if collision.CheckOverlapAABBAABB(&indexedA.boundingBox, &indexedB.boundingBox) {
+					pairs = append(pairs, EntityPair{A: a, B: b})
+				}
+			}
+		}
+	}
+
+	sortEntityPairs(pairs)
+	return pairs
+}
+
+// lowestSharedPartition returns the lowest partition two entities are both in given the lowest
+// partition of each, assuming they share at least one
+func lowestSharedPartition(a, b PartitionKey) PartitionKey {
+	for i := range a {
+		if b[i] > a[i] {
+			a[i] = b[i]
+		}
+	}
+	return a
+}
+
+// IndexEntities adds the entities or moves them to their new partitions. entities whose
+// bounding box hasn't changed since they were last indexed are skipped.
 func (s *SpatialPartition) IndexEntities(entityList []Entity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	for _, entity := range entityList {
-		if position, ok := s.entityPositionCache[entity.GetID()]; ok {
-			if position == entity.Position() {
-				continue
-			}
+		boundingBox := entity.BoundingBox()
+		indexed, ok := s.indexed[entity.GetID()]
+		if ok && indexed.boundingBox == boundingBox {
+			continue
 		}
-		s.entityPositionCache[entity.GetID()] = entity.Position()
 
 		// remove from old partitions
-		oldPartitions := s.entityPartitionCache[entity.GetID()]
-		for partitionKey := range oldPartitions {
-			partition := &s.Partitions[partitionKey[0]][partitionKey[1]][partitionKey[2]]
-			delete(partition.entities, entity.GetID())
+		if ok {
+			s.removeFromPartitions(entity.GetID(), indexed.keys)
+		} else {
+			indexed = &indexedEntity{}
+			s.indexed[entity.GetID()] = indexed
 		}
+		indexed.boundingBox = boundingBox
 
 		// add to new partitions
-		boundingBox := entity.BoundingBox()
-		newPartitions := s.IntersectingPartitions(boundingBox)
-		for _, partitionKey := range newPartitions {
-			partition := &s.Partitions[partitionKey[0]][partitionKey[1]][partitionKey[2]]
-			partition.entities[entity.GetID()] = entity
-			if _, ok := s.entityPartitionCache[entity.GetID()]; !ok {
-				s.entityPartitionCache[entity.GetID()] = map[PartitionKey]any{}
-			}
-			s.entityPartitionCache[entity.GetID()][partition.Key] = partitionKey
+		indexed.keys = s.appendIntersectingPartitions(indexed.keys[:0], boundingBox)
+		for _, partitionKey := range indexed.keys {
+			s.Partitions[partitionKey[0]][partitionKey[1]][partitionKey[2]].entities[entity.GetID()] = entity
+			s.occupied[partitionKey] = true
+		}
+	}
+}
+
+// removeFromPartitions removes the entity from the partitions, dropping the ones that end up
+// empty from the occupied set
+func (s *SpatialPartition) removeFromPartitions(entityID int, keys []PartitionKey) {
+	for _, partitionKey := range keys {
+		partition := &s.Partitions[partitionKey[0]][partitionKey[1]][partitionKey[2]]
+		delete(partition.entities, entityID)
+		if len(partition.entities) == 0 {
+			delete(s.occupied, partitionKey)
 		}
 	}
 }
@@ -139,31 +241,31 @@ func initializePartitions(partitionDimension int, partitionCount int) [][][]Part
 }
 
 func (s *SpatialPartition) IntersectingPartitions(boundingBox collider.BoundingBox) []PartitionKey {
+	return s.appendIntersectingPartitions(nil, boundingBox)
+}
+
+// appendIntersectingPartitions appends the keys of the partitions the bounding box touches
+// to keys, letting callers reuse their own slice
+func (s *SpatialPartition) appendIntersectingPartitions(keys []PartitionKey, boundingBox collider.BoundingBox) []PartitionKey {
 	i1, j1, k1, found1 := s.VertexToPartitionClamped(boundingBox.MinVertex, true, false)
 	if !found1 {
-		return nil
+		return keys
 	}
 
 	i2, j2, k2, found2 := s.VertexToPartitionClamped(boundingBox.MaxVertex, false, true)
 	if !found2 {
-		return nil
+		return keys
 	}
 
-	index := -1
 	for i := 0; i <= i2-i1; i++ {
 		for j := 0; j <= j2-j1; j++ {
 			for k := 0; k <= k2-k1; k++ {
-				index += 1
-				s.pooledPartitionKeys[index] = PartitionKey{i1 + i, j1 + j, k1 + k}
+				keys = append(keys, PartitionKey{i1 + i, j1 + j, k1 + k})
 			}
 		}
 	}
 
-	if index == -1 {
-		return nil
-	}
-
-	return s.pooledPartitionKeys[0 : index+1]
+	return keys
 }
 
 func (s *SpatialPartition) calcMinPartitionVertex() mgl64.Vec3 {
@@ -239,10 +341,11 @@ func (s *SpatialPartition) VertexToPartitionClamped(vertex mgl64.Vec3, clampMin,
 }
 
 func (s *SpatialPartition) DeleteEntity(entityID int) {
-	partitions := s.entityPartitionCache[entityID]
-	for partitionKey := range partitions {
-		delete(s.Partitions[partitionKey[0]][partitionKey[1]][partitionKey[2]].entities, entityID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if indexed, ok := s.indexed[entityID]; ok {
+		s.removeFromPartitions(entityID, indexed.keys)
+		delete(s.indexed, entityID)
 	}
-	delete(s.entityPartitionCache, entityID)
-	delete(s.entityPositionCache, entityID)
 }
@@ -1,9 +1,13 @@
 package spatialpartition_test
 
 import (
-	"fmt"
+	"math/rand"
+	"sync"
 	"testing"
 
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/kitolib/collision/collider"
+
 	"github.com/kkevinchou/kitolib/spatialpartition"
 )
 
@@ -15,10 +19,8 @@ func TestPartition(t *testing.T) {
 	actualPartitionCount := 0
 	for i := range p.Partitions {
 		for j := range p.Partitions[i] {
-			for k := range p.Partitions[i][j] {
+			for range p.Partitions[i][j] {
 				actualPartitionCount += 1
-				partition := p.Partitions[i][j][k]
-				fmt.Println(i, j, k, "=====", partition.AABB.MinVertex, partition.AABB.MaxVertex)
 			}
 		}
 	}
@@ -26,5 +28,125 @@ func TestPartition(t *testing.T) {
 	if actualPartitionCount != expectedPartitionCount {
 		t.Errorf("expected %d partitions, but got %d", expectedPartitionCount, actualPartitionCount)
 	}
-	t.Fail()
+}
+
+func TestPartitionPairs(t *testing.T) {
+	partition := spatialpartition.NewSpatialPartition(5, 8)
+	// a and b span several partitions together, c is on its own
+	a := &testEntity{id: 1, position: mgl64.Vec3{0, 0, 0}, size: 12}
+	b := &testEntity{id: 2, position: mgl64.Vec3{4, 4, 4}, size: 8}
+	c := &testEntity{id: 3, position: mgl64.Vec3{14, 0, 0}, size: 1}
+	partition.IndexEntities([]spatialpartition.Entity{a, b, c})
+	if pairs := pairIDs(partition.Pairs()); !equalPairs(pairs, [][2]int{{1, 2}}) {
+		t.Errorf("expected the overlapping pair once but got %v", pairs)
+	}
+
+	// growing in place like a rotating capsule's box does should still reindex
+	c.size = 14
+	partition.IndexEntities([]spatialpartition.Entity{a, b, c})
+	if pairs := pairIDs(partition.Pairs()); !equalPairs(pairs, [][2]int{{1, 2}, {2, 3}}) {
+		t.Errorf("expected the grown entity to be paired but got %v", pairs)
+	}
+
+	partition.DeleteEntity(b.id)
+	if pairs := pairIDs(partition.Pairs()); len(pairs) != 0 {
+		t.Errorf("expected no pairs after deleting but got %v", pairs)
+	}
+}
+
+// run with -race, readers query the partition while a writer keeps moving entities around
+func TestConcurrentQueriesWhileIndexing(t *testing.T) {
+	r := rand.New(rand.NewSource(8))
+	entities := randomEntities(r, 200, 15)
+	partition := spatialpartition.NewSpatialPartition(5, 8)
+	partition.IndexEntities(toEntities(entities))
+
+	// static entities that never move so the readers have something to check
+	var static []spatialpartition.Entity
+	for i := 0; i < 10; i++ {
+		static = append(static, &testEntity{id: 1000 + i, position: mgl64.Vec3{float64(i) - 5, 0, 0}, size: 0.5})
+	}
+	partition.IndexEntities(static)
+	staticBox := collider.BoundingBox{MinVertex: mgl64.Vec3{-5.5, -0.25, -0.25}, MaxVertex: mgl64.Vec3{4.5, 0.25, 0.25}}
+
+	done := make(chan bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				found := map[int]bool{}
+				for _, e := range partition.QueryEntities(staticBox) {
+					found[e.GetID()] = true
+				}
+				for _, e := range static {
+					if !found[e.GetID()] {
+						t.Errorf("reader %d: expected to always find static entity %d", i, e.GetID())
+						return
+					}
+				}
+				partition.QueryNearest(mgl64.Vec3{}, 5, nil)
+				partition.QueryRadius(mgl64.Vec3{1, 2, 3}, 4, nil)
+			}
+		}(i)
+	}
+
+	for step := 0; step < 100; step++ {
+		// moved copies so that readers never see an entity change under them
+		moved := make([]*testEntity, len(entities))
+		for i, e := range entities {
+			copied := *e
+			copied.position = e.position.Add(mgl64.Vec3{r.Float64() - 0.5, r.Float64() - 0.5, r.Float64() - 0.5})
+			moved[i] = &copied
+		}
+		entities = moved
+		partition.IndexEntities(toEntities(entities))
+		if step%10 == 0 {
+			partition.DeleteEntity(entities[step].id)
+		}
+	}
+
+	close(done)
+	wg.Wait()
+}
+
+func benchmarkPartition() (*spatialpartition.SpatialPartition, []collider.BoundingBox) {
+	r := rand.New(rand.NewSource(9))
+	partition := spatialpartition.NewSpatialPartition(10, 20)
+	partition.IndexEntities(toEntities(randomEntities(r, 5000, 90)))
+
+	var boxes []collider.BoundingBox
+	for i := 0; i < 1024; i++ {
+		center := mgl64.Vec3{(r.Float64()*2 - 1) * 90, (r.Float64()*2 - 1) * 90, (r.Float64()*2 - 1) * 90}
+		half := mgl64.Vec3{5, 5, 5}
+		boxes = append(boxes, collider.BoundingBox{MinVertex: center.Sub(half), MaxVertex: center.Add(half)})
+	}
+	return partition, boxes
+}
+
+func BenchmarkQueryEntities(b *testing.B) {
+	partition, boxes := benchmarkPartition()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		partition.QueryEntities(boxes[i%len(boxes)])
+	}
+}
+
+func BenchmarkQueryEntitiesParallel(b *testing.B) {
+	partition, boxes := benchmarkPartition()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			partition.QueryEntities(boxes[i%len(boxes)])
+			i++
+		}
+	})
 }